@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"runtime"
@@ -11,12 +12,15 @@ import (
 	"time"
 
 	"github.com/fatih/color"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/internal/snmp"
 	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/plugins/common/ratelimiter"
 	"github.com/influxdata/telegraf/plugins/processors"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 )
@@ -24,12 +28,29 @@ import (
 // Agent runs a set of plugins.
 type Agent struct {
 	Config *config.Config
+
+	// RequestReload is called for configuration reloads requested via the
+	// control API. Reloading via the control API is unavailable if nil.
+	RequestReload func()
+
+	// Trace receives the path of every metric through the pipeline in test
+	// mode. Tracing is disabled if nil.
+	Trace io.Writer
+
+	reloadC chan *reloadRequest
+	tracer  *tracer
+	spans   trace.Tracer
+
+	// pluginsMu protects the plugin lists of the configuration against
+	// modifications on reload while accessed by the control API
+	pluginsMu sync.RWMutex
 }
 
 // NewAgent returns an Agent for the given Config.
 func NewAgent(cfg *config.Config) *Agent {
 	a := &Agent{
-		Config: cfg,
+		Config:  cfg,
+		reloadC: make(chan *reloadRequest),
 	}
 	return a
 }
@@ -48,6 +69,9 @@ func NewAgent(cfg *config.Config) *Agent {
 type inputUnit struct {
 	dst    chan<- telegraf.Metric
 	inputs []*models.RunningInput
+
+	// update receives the inputs to add and remove on configuration reload
+	update chan *unitUpdate[*models.RunningInput]
 }
 
 //  ______     ┌───────────┐     ______
@@ -99,6 +123,41 @@ type aggregatorUnit struct {
 type outputUnit struct {
 	src     <-chan telegraf.Metric
 	outputs []*models.RunningOutput
+
+	// update receives the outputs to add and remove on configuration reload
+	update chan *unitUpdate[*models.RunningOutput]
+}
+
+// stageUnit is the processing stage consisting of the processors, the
+// aggregators and the processors run after the aggregators. The stage writes
+// to its own sink channel instead of the output channel, so it can be replaced
+// on configuration reload without closing the output channel.
+type stageUnit struct {
+	src       chan<- telegraf.Metric
+	sink      <-chan telegraf.Metric
+	startTime time.Time
+
+	apu []*processorUnit
+	au  *aggregatorUnit
+	pu  []*processorUnit
+}
+
+// pipelineUnit connects the input channel to the current processing stage and
+// forwards the metrics leaving the stage to the output channel.
+
+//                       ┌───────┐
+//  ______     ┌─────┐   │       │     ______     ┌─────┐     ______
+// ()_____)──▶ │ Fwd │──▶│ Stage │──▶ ()_____)──▶ │ Fwd │──▶ ()_____)
+//             └─────┘   │       │                └─────┘
+//                       └───────┘
+
+type pipelineUnit struct {
+	src   <-chan telegraf.Metric
+	dst   chan<- telegraf.Metric
+	stage *stageUnit
+
+	// swap receives requests for replacing the stage on configuration reload
+	swap chan *stageSwap
 }
 
 // Run starts and runs the Agent until the context is done.
@@ -135,6 +194,24 @@ func (a *Agent) Run(ctx context.Context) error {
 		}
 	}
 
+	if err := a.startControl(ctx); err != nil {
+		return fmt.Errorf("starting control API failed: %w", err)
+	}
+
+	if a.Config.Agent.LogLevelFile != "" {
+		go a.watchLogLevels(ctx)
+	}
+
+	if err := a.startSelfstat(ctx); err != nil {
+		return fmt.Errorf("starting internal statistics endpoint failed: %w", err)
+	}
+
+	stopTracing, err := a.startTracing(ctx)
+	if err != nil {
+		return fmt.Errorf("starting tracing failed: %w", err)
+	}
+	defer stopTracing()
+
 	startTime := time.Now()
 
 	log.Printf("D! [agent] Connecting outputs")
@@ -143,29 +220,20 @@ func (a *Agent) Run(ctx context.Context) error {
 		return err
 	}
 
-	var apu []*processorUnit
-	var au *aggregatorUnit
-	if len(a.Config.Aggregators) != 0 {
-		aggC := next
-		if len(a.Config.AggProcessors) != 0 && !*a.Config.Agent.SkipProcessorsAfterAggregators {
-			aggC, apu, err = a.startProcessors(next, a.Config.AggProcessors)
-			if err != nil {
-				return err
-			}
-		}
-
-		next, au = a.startAggregators(aggC, next, a.Config.Aggregators)
+	stage, err := a.startStage(startTime, a.Config.Processors, a.Config.AggProcessors, a.Config.Aggregators)
+	if err != nil {
+		return err
 	}
 
-	var pu []*processorUnit
-	if len(a.Config.Processors) != 0 {
-		next, pu, err = a.startProcessors(next, a.Config.Processors)
-		if err != nil {
-			return err
-		}
+	src := make(chan telegraf.Metric, 100)
+	pu := &pipelineUnit{
+		src:   src,
+		dst:   next,
+		stage: stage,
+		swap:  make(chan *stageSwap),
 	}
 
-	iu, err := a.startInputs(next, a.Config.Inputs)
+	iu, err := a.startInputs(src, a.Config.Inputs)
 	if err != nil {
 		return err
 	}
@@ -177,33 +245,27 @@ func (a *Agent) Run(ctx context.Context) error {
 		a.runOutputs(ou)
 	}()
 
-	if au != nil {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			a.runProcessors(apu)
-		}()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.runPipeline(pu)
+	}()
 
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			a.runAggregators(startTime, au)
-		}()
-	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.runInputs(ctx, startTime, iu)
+	}()
 
-	if pu != nil {
+	if a.Config.Persister != nil && a.Config.Agent.StatefileInterval > 0 {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			a.runProcessors(pu)
+			a.runPersister(ctx, time.Duration(a.Config.Agent.StatefileInterval))
 		}()
 	}
 
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		a.runInputs(ctx, startTime, iu)
-	}()
+	a.handleReloads(ctx, iu, pu, ou)
 
 	wg.Wait()
 
@@ -218,6 +280,25 @@ func (a *Agent) Run(ctx context.Context) error {
 	return err
 }
 
+// runPersister periodically writes the plugin states until the context is
+// done. The final states are written on shutdown of the agent.
+func (a *Agent) runPersister(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			log.Printf("D! [agent] Persisting plugin states")
+			if err := a.Config.Persister.Store(); err != nil {
+				log.Printf("E! [agent] Persisting plugin states failed: %v", err)
+			}
+		}
+	}
+}
+
 // InitPlugins runs the Init function on plugins.
 func (a *Agent) InitPlugins() error {
 	for _, input := range a.Config.Inputs {
@@ -256,7 +337,52 @@ func (a *Agent) InitPlugins() error {
 			return fmt.Errorf("could not initialize output %s: %w", output.LogName(), err)
 		}
 	}
-	return nil
+	return models.LinkDeadLetters(a.Config.Outputs)
+}
+
+// CheckPlugins runs the Init function on plugins like InitPlugins but reports
+// a finding for each failing plugin instead of stopping at the first error.
+func (a *Agent) CheckPlugins() []config.Finding {
+	var findings []config.Finding
+	report := func(category, name, source string, line int, id string, err error) {
+		if err == nil {
+			return
+		}
+		findings = append(findings, config.Finding{
+			Rule:     config.RuleInit,
+			Severity: config.SeverityError,
+			Message:  err.Error(),
+			File:     source,
+			Line:     line,
+			Plugin:   category + "." + name,
+			PluginID: id,
+		})
+	}
+
+	for _, input := range a.Config.Inputs {
+		// Share the snmp translator setting with plugins that need it.
+		if tp, ok := input.Input.(snmp.TranslatorPlugin); ok {
+			tp.SetTranslator(a.Config.Agent.SnmpTranslator)
+		}
+		report("inputs", input.Config.Name, input.Config.Source, input.Config.Line, input.Config.ID, input.Init())
+	}
+	for _, processor := range a.Config.Processors {
+		report("processors", processor.Config.Name, processor.Config.Source, processor.Config.Line, processor.Config.ID, processor.Init())
+	}
+	// The processors running after the aggregators are separate instances
+	// created from the same configuration as the processors above. Their
+	// findings would be duplicates so those instances are not initialized.
+	for _, aggregator := range a.Config.Aggregators {
+		report("aggregators", aggregator.Config.Name, aggregator.Config.Source, aggregator.Config.Line, aggregator.Config.ID, aggregator.Init())
+	}
+	for _, output := range a.Config.Outputs {
+		report("outputs", output.Config.Name, output.Config.Source, output.Config.Line, output.Config.ID, output.Init())
+	}
+	if err := models.LinkDeadLetters(a.Config.Outputs); err != nil {
+		findings = append(findings, config.Finding{Rule: config.RuleInit, Severity: config.SeverityError, Message: err.Error()})
+	}
+
+	return findings
 }
 
 // initPersister initializes the persister and registers the plugins.
@@ -341,53 +467,65 @@ func (a *Agent) initPersister() error {
 	return nil
 }
 
-func (*Agent) startInputs(dst chan<- telegraf.Metric, inputs []*models.RunningInput) (*inputUnit, error) {
+func (a *Agent) startInputs(dst chan<- telegraf.Metric, inputs []*models.RunningInput) (*inputUnit, error) {
 	log.Printf("D! [agent] Starting service inputs")
 
 	unit := &inputUnit{
-		dst: dst,
+		dst:    dst,
+		update: make(chan *unitUpdate[*models.RunningInput]),
 	}
 
 	for _, input := range inputs {
-		// Service input plugins are not normally subject to timestamp
-		// rounding except for when precision is set on the input plugin.
-		//
-		// This only applies to the accumulator passed to Start(), the
-		// Gather() accumulator does apply rounding according to the
-		// precision and interval agent/plugin settings.
-		var interval time.Duration
-		var precision time.Duration
-		if input.Config.Precision != 0 {
-			precision = input.Config.Precision
-		}
-
-		acc := NewAccumulator(input, dst)
-		acc.SetPrecision(getPrecision(precision, interval))
-
-		if err := input.Start(acc); err != nil {
-			// If the model tells us to remove the plugin we do so without error
-			var fatalErr *internal.FatalError
-			if errors.As(err, &fatalErr) {
-				log.Printf("I! [agent] Failed to start %s, shutting down plugin: %s", input.LogName(), err)
-				continue
-			}
-
+		started, err := a.startInput(dst, input)
+		if err != nil {
 			stopRunningInputs(unit.inputs)
-
-			return nil, fmt.Errorf("starting input %s: %w", input.LogName(), err)
+			return nil, err
 		}
-		if err := input.Probe(); err != nil {
-			// Probe failures are non-fatal to the agent but should only remove the plugin
-			log.Printf("I! [agent] Failed to probe %s, shutting down plugin: %s", input.LogName(), err)
-			input.Stop()
-			continue
+		if started {
+			unit.inputs = append(unit.inputs, input)
 		}
-		unit.inputs = append(unit.inputs, input)
 	}
 
 	return unit, nil
 }
 
+// startInput calls Start on a service input and probes the plugin. The
+// returned flag is false if the plugin should be removed without error.
+func (*Agent) startInput(dst chan<- telegraf.Metric, input *models.RunningInput) (bool, error) {
+	// Service input plugins are not normally subject to timestamp
+	// rounding except for when precision is set on the input plugin.
+	//
+	// This only applies to the accumulator passed to Start(), the
+	// Gather() accumulator does apply rounding according to the
+	// precision and interval agent/plugin settings.
+	var interval time.Duration
+	var precision time.Duration
+	if input.Config.Precision != 0 {
+		precision = input.Config.Precision
+	}
+
+	acc := NewAccumulator(input, dst)
+	acc.SetPrecision(getPrecision(precision, interval))
+
+	if err := input.Start(acc); err != nil {
+		// If the model tells us to remove the plugin we do so without error
+		var fatalErr *internal.FatalError
+		if errors.As(err, &fatalErr) {
+			log.Printf("I! [agent] Failed to start %s, shutting down plugin: %s", input.LogName(), err)
+			return false, nil
+		}
+
+		return false, fmt.Errorf("starting input %s: %w", input.LogName(), err)
+	}
+	if err := input.Probe(); err != nil {
+		// Probe failures are non-fatal to the agent but should only remove the plugin
+		log.Printf("I! [agent] Failed to probe %s, shutting down plugin: %s", input.LogName(), err)
+		input.Stop()
+		return false, nil
+	}
+	return true, nil
+}
+
 // runInputs starts and triggers the periodic gather for Inputs.
 //
 // When the context is done the timers are stopped and this function returns
@@ -397,52 +535,37 @@ func (a *Agent) runInputs(
 	startTime time.Time,
 	unit *inputUnit,
 ) {
-	var wg sync.WaitGroup
-	tickers := make([]Ticker, 0, len(unit.inputs))
+	tasks := make(map[*models.RunningInput]*pluginTask, len(unit.inputs))
 	for _, input := range unit.inputs {
-		// Overwrite agent interval if this plugin has its own.
-		interval := time.Duration(a.Config.Agent.Interval)
-		if input.Config.Interval != 0 {
-			interval = input.Config.Interval
-		}
-
-		// Overwrite agent precision if this plugin has its own.
-		precision := time.Duration(a.Config.Agent.Precision)
-		if input.Config.Precision != 0 {
-			precision = input.Config.Precision
-		}
-
-		// Overwrite agent collection_jitter if this plugin has its own.
-		jitter := time.Duration(a.Config.Agent.CollectionJitter)
-		if input.Config.CollectionJitter != 0 {
-			jitter = input.Config.CollectionJitter
-		}
-
-		// Overwrite agent collection_offset if this plugin has its own.
-		offset := time.Duration(a.Config.Agent.CollectionOffset)
-		if input.Config.CollectionOffset != 0 {
-			offset = input.Config.CollectionOffset
-		}
+		tasks[input] = a.runInput(ctx, startTime, input, unit.dst)
+	}
 
-		var ticker Ticker
-		if a.Config.Agent.RoundInterval {
-			ticker = NewAlignedTicker(startTime, interval, jitter, offset)
-		} else {
-			ticker = NewUnalignedTicker(interval, jitter, offset)
+	for {
+		select {
+		case update := <-unit.update:
+			for _, input := range update.removed {
+				task, found := tasks[input]
+				if !found {
+					continue
+				}
+				task.stop()
+				input.Stop()
+				delete(tasks, input)
+			}
+			for _, input := range update.added {
+				tasks[input] = a.runInput(ctx, time.Now(), input, unit.dst)
+			}
+			unit.inputs = update.apply(unit.inputs)
+			close(update.done)
+			continue
+		case <-ctx.Done():
 		}
-		tickers = append(tickers, ticker)
-
-		acc := NewAccumulator(input, unit.dst)
-		acc.SetPrecision(getPrecision(precision, interval))
+		break
+	}
 
-		wg.Add(1)
-		go func(input *models.RunningInput) {
-			defer wg.Done()
-			a.gatherLoop(ctx, acc, input, ticker, interval)
-		}(input)
+	for _, task := range tasks {
+		<-task.done
 	}
-	defer stopTickers(tickers)
-	wg.Wait()
 
 	log.Printf("D! [agent] Stopping service inputs")
 	stopRunningInputs(unit.inputs)
@@ -451,9 +574,62 @@ func (a *Agent) runInputs(
 	log.Printf("D! [agent] Input channel closed")
 }
 
+// runInput starts the periodic gather of a single input until the context is
+// done or the returned task is stopped.
+func (a *Agent) runInput(
+	ctx context.Context,
+	startTime time.Time,
+	input *models.RunningInput,
+	dst chan<- telegraf.Metric,
+) *pluginTask {
+	// Overwrite agent interval if this plugin has its own.
+	interval := time.Duration(a.Config.Agent.Interval)
+	if input.Config.Interval != 0 {
+		interval = input.Config.Interval
+	}
+
+	// Overwrite agent precision if this plugin has its own.
+	precision := time.Duration(a.Config.Agent.Precision)
+	if input.Config.Precision != 0 {
+		precision = input.Config.Precision
+	}
+
+	// Overwrite agent collection_jitter if this plugin has its own.
+	jitter := time.Duration(a.Config.Agent.CollectionJitter)
+	if input.Config.CollectionJitter != 0 {
+		jitter = input.Config.CollectionJitter
+	}
+
+	// Overwrite agent collection_offset if this plugin has its own.
+	offset := time.Duration(a.Config.Agent.CollectionOffset)
+	if input.Config.CollectionOffset != 0 {
+		offset = input.Config.CollectionOffset
+	}
+
+	var ticker Ticker
+	if a.Config.Agent.RoundInterval {
+		ticker = NewAlignedTicker(startTime, interval, jitter, offset)
+	} else {
+		ticker = NewUnalignedTicker(interval, jitter, offset)
+	}
+
+	acc := NewAccumulator(input, dst)
+	acc.SetPrecision(getPrecision(precision, interval))
+
+	ctx, cancel := context.WithCancel(ctx)
+	task := &pluginTask{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(task.done)
+		defer ticker.Stop()
+		a.gatherLoop(ctx, acc, input, ticker, interval)
+	}()
+
+	return task
+}
+
 // testStartInputs is a variation of startInputs for use in --test and --once mode.
 // It differs by logging Start errors and returning only plugins successfully started.
-func (*Agent) testStartInputs(dst chan<- telegraf.Metric, inputs []*models.RunningInput) *inputUnit {
+func (a *Agent) testStartInputs(dst chan<- telegraf.Metric, inputs []*models.RunningInput) *inputUnit {
 	log.Printf("D! [agent] Starting service inputs")
 
 	unit := &inputUnit{
@@ -465,7 +641,7 @@ func (*Agent) testStartInputs(dst chan<- telegraf.Metric, inputs []*models.Runni
 		// This only applies to the accumulator passed to Start(), the
 		// Gather() accumulator does apply rounding according to the
 		// precision agent setting.
-		acc := NewAccumulator(input, dst)
+		acc := a.newAccumulator(input, dst)
 		acc.SetPrecision(time.Nanosecond)
 
 		if err := input.Start(acc); err != nil {
@@ -525,7 +701,7 @@ func (a *Agent) testRunInputs(
 				time.Sleep(500 * time.Millisecond)
 			}
 
-			acc := NewAccumulator(input, unit.dst)
+			acc := a.newAccumulator(input, unit.dst)
 			acc.SetPrecision(getPrecision(precision, interval))
 
 			if err := input.Input.Gather(acc); err != nil {
@@ -572,6 +748,14 @@ func (a *Agent) gatherLoop(
 	for {
 		select {
 		case <-ticker.Elapsed():
+			if input.Paused() {
+				continue
+			}
+			err := a.gatherOnce(acc, input, ticker, interval)
+			if err != nil {
+				acc.AddError(err)
+			}
+		case <-input.GatherRequested():
 			err := a.gatherOnce(acc, input, ticker, interval)
 			if err != nil {
 				acc.AddError(err)
@@ -583,7 +767,10 @@ func (a *Agent) gatherLoop(
 }
 
 // gatherOnce runs the input's Gather function once, logging a warning each interval it fails to complete before.
-func (*Agent) gatherOnce(acc telegraf.Accumulator, input *models.RunningInput, ticker Ticker, interval time.Duration) error {
+func (a *Agent) gatherOnce(acc telegraf.Accumulator, input *models.RunningInput, ticker Ticker, interval time.Duration) (err error) {
+	_, span := a.startSpan(context.Background(), "gather", attribute.String("telegraf.input", input.LogName()))
+	defer func() { endSpan(span, err) }()
+
 	done := make(chan error)
 	go func() {
 		defer panicRecover(input)
@@ -604,6 +791,7 @@ func (*Agent) gatherOnce(acc telegraf.Accumulator, input *models.RunningInput, t
 			log.Printf("W! [%s] Collection took longer than expected; not complete after interval of %s",
 				input.LogName(), interval)
 			input.IncrGatherTimeouts()
+			span.AddEvent("gather timeout")
 		case <-ticker.Elapsed():
 			log.Printf("D! [%s] Previous collection has not completed; scheduled collection skipped",
 				input.LogName())
@@ -612,7 +800,7 @@ func (*Agent) gatherOnce(acc telegraf.Accumulator, input *models.RunningInput, t
 }
 
 // startProcessors sets up the processor chain and calls Start on all processors.  If an error occurs any started processors are Stopped.
-func (*Agent) startProcessors(dst chan<- telegraf.Metric, runningProcessors models.RunningProcessors) (chan<- telegraf.Metric, []*processorUnit, error) {
+func (a *Agent) startProcessors(dst chan<- telegraf.Metric, runningProcessors models.RunningProcessors) (chan<- telegraf.Metric, []*processorUnit, error) {
 	var src chan telegraf.Metric
 	units := make([]*processorUnit, 0, len(runningProcessors))
 	// The processor chain is constructed from the output side starting from
@@ -624,7 +812,7 @@ func (*Agent) startProcessors(dst chan<- telegraf.Metric, runningProcessors mode
 		processor := runningProcessors[i]
 
 		src = make(chan telegraf.Metric, 100)
-		acc := NewAccumulator(processor, dst)
+		acc := a.newAccumulator(processor, dst)
 
 		err := processor.Start(acc)
 		if err != nil {
@@ -648,14 +836,14 @@ func (*Agent) startProcessors(dst chan<- telegraf.Metric, runningProcessors mode
 }
 
 // runProcessors begins processing metrics and runs until the source channel is closed and all metrics have been written.
-func (*Agent) runProcessors(units []*processorUnit) {
+func (a *Agent) runProcessors(units []*processorUnit) {
 	var wg sync.WaitGroup
 	for _, unit := range units {
 		wg.Add(1)
 		go func(unit *processorUnit) {
 			defer wg.Done()
 
-			acc := NewAccumulator(unit.processor, unit.dst)
+			acc := a.newAccumulator(unit.processor, unit.dst)
 			for m := range unit.src {
 				if err := unit.processor.Add(m, acc); err != nil {
 					acc.AddError(err)
@@ -692,7 +880,7 @@ func (a *Agent) runAggregators(
 
 	// Before calling Add, initialize the aggregation window.  This ensures
 	// that any metric created after start time will be aggregated.
-	for _, agg := range a.Config.Aggregators {
+	for _, agg := range unit.aggregators {
 		since, until := updateWindow(startTime, a.Config.Agent.RoundInterval, agg.Period())
 		agg.UpdateWindow(since, until)
 	}
@@ -703,7 +891,7 @@ func (a *Agent) runAggregators(
 		defer wg.Done()
 		for metric := range unit.src {
 			var dropOriginal bool
-			for _, agg := range a.Config.Aggregators {
+			for _, agg := range unit.aggregators {
 				if ok := agg.Add(metric); ok {
 					dropOriginal = true
 				}
@@ -718,7 +906,7 @@ func (a *Agent) runAggregators(
 		cancel()
 	}()
 
-	for _, agg := range a.Config.Aggregators {
+	for _, agg := range unit.aggregators {
 		wg.Add(1)
 		go func(agg *models.RunningAggregator) {
 			defer wg.Done()
@@ -726,7 +914,7 @@ func (a *Agent) runAggregators(
 			interval := time.Duration(a.Config.Agent.Interval)
 			precision := time.Duration(a.Config.Agent.Precision)
 
-			acc := NewAccumulator(agg, unit.aggC)
+			acc := a.newAccumulator(agg, unit.aggC)
 			acc.SetPrecision(getPrecision(precision, interval))
 			a.push(ctx, agg, acc)
 		}(agg)
@@ -775,6 +963,129 @@ func (*Agent) push(ctx context.Context, aggregator *models.RunningAggregator, ac
 	}
 }
 
+// startStage sets up the processing stage consisting of the given processors
+// and aggregators and calls Start on all processors.
+func (a *Agent) startStage(
+	startTime time.Time,
+	processors, aggProcessors models.RunningProcessors,
+	aggregators []*models.RunningAggregator,
+) (*stageUnit, error) {
+	sink := make(chan telegraf.Metric, 100)
+	unit := &stageUnit{
+		sink:      sink,
+		startTime: startTime,
+	}
+
+	var next chan<- telegraf.Metric = sink
+	var err error
+	if len(aggregators) != 0 {
+		aggC := next
+		if len(aggProcessors) != 0 && !*a.Config.Agent.SkipProcessorsAfterAggregators {
+			aggC, unit.apu, err = a.startProcessors(next, aggProcessors)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		next, unit.au = a.startAggregators(aggC, next, aggregators)
+	}
+
+	if len(processors) != 0 {
+		next, unit.pu, err = a.startProcessors(next, processors)
+		if err != nil {
+			for _, u := range unit.apu {
+				u.processor.Stop()
+			}
+			return nil, err
+		}
+	}
+	unit.src = next
+
+	return unit, nil
+}
+
+// discardStage stops the processors of a started stage that never ran.
+func discardStage(unit *stageUnit) {
+	for _, u := range unit.pu {
+		u.processor.Stop()
+	}
+	for _, u := range unit.apu {
+		u.processor.Stop()
+	}
+}
+
+// runStage runs the processors and aggregators of the stage and forwards the
+// metrics leaving the stage to the destination channel. The returned channel
+// is closed once the source channel of the stage is closed and all metrics
+// have been forwarded.
+func (a *Agent) runStage(unit *stageUnit, dst chan<- telegraf.Metric) <-chan struct{} {
+	var wg sync.WaitGroup
+	if unit.au != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.runProcessors(unit.apu)
+		}()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.runAggregators(unit.startTime, unit.au)
+		}()
+	}
+
+	if unit.pu != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.runProcessors(unit.pu)
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for m := range unit.sink {
+			dst <- m
+		}
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	return done
+}
+
+// runPipeline forwards the metrics from the inputs to the processing stage
+// until the source channel is closed. Afterwards, the stage is drained and the
+// destination channel is closed. If a replacement is requested on reload the
+// running stage is drained before running the already started new one.
+func (a *Agent) runPipeline(unit *pipelineUnit) {
+	done := a.runStage(unit.stage, unit.dst)
+	for {
+		select {
+		case m, ok := <-unit.src:
+			if !ok {
+				close(unit.stage.src)
+				<-done
+				close(unit.dst)
+				log.Printf("D! [agent] Pipeline channel closed")
+				return
+			}
+			unit.stage.src <- m
+		case req := <-unit.swap:
+			close(unit.stage.src)
+			<-done
+
+			unit.stage = req.stage
+			done = a.runStage(unit.stage, unit.dst)
+			close(req.done)
+		}
+	}
+}
+
 // startOutputs calls Connect on all outputs and returns the source channel.
 // If an error occurs calling Connect, all started plugins have Close called.
 func (a *Agent) startOutputs(
@@ -782,7 +1093,10 @@ func (a *Agent) startOutputs(
 	outputs []*models.RunningOutput,
 ) (chan<- telegraf.Metric, *outputUnit, error) {
 	src := make(chan telegraf.Metric, 100)
-	unit := &outputUnit{src: src}
+	unit := &outputUnit{
+		src:    src,
+		update: make(chan *unitUpdate[*models.RunningOutput]),
+	}
 	for _, output := range outputs {
 		if err := a.connectOutput(ctx, output); err != nil {
 			var fatalErr *internal.FatalError
@@ -806,7 +1120,11 @@ func (a *Agent) startOutputs(
 }
 
 // connectOutput connects to all outputs.
-func (*Agent) connectOutput(ctx context.Context, output *models.RunningOutput) error {
+func (a *Agent) connectOutput(ctx context.Context, output *models.RunningOutput) error {
+	if a.spans != nil {
+		output.SetTracer(a.spans)
+	}
+
 	log.Printf("D! [agent] Attempting connection to [%s]", output.LogName())
 	if err := output.Connect(); err != nil {
 		log.Printf("E! [agent] Failed to connect to [%s], retrying in 15s, error was %q", output.LogName(), err)
@@ -829,56 +1147,136 @@ func (*Agent) connectOutput(ctx context.Context, output *models.RunningOutput) e
 func (a *Agent) runOutputs(
 	unit *outputUnit,
 ) {
-	var wg sync.WaitGroup
-
-	// Start flush loop
-	interval := time.Duration(a.Config.Agent.FlushInterval)
-	jitter := time.Duration(a.Config.Agent.FlushJitter)
-
 	ctx, cancel := context.WithCancel(context.Background())
 
+	tasks := make(map[*models.RunningOutput]*pluginTask, len(unit.outputs))
 	for _, output := range unit.outputs {
-		interval := interval
-		// Overwrite agent flush_interval if this plugin has its own.
-		if output.Config.FlushInterval != 0 {
-			interval = output.Config.FlushInterval
-		}
-
-		jitter := jitter
-		// Overwrite agent flush_jitter if this plugin has its own.
-		if output.Config.FlushJitter != 0 {
-			jitter = output.Config.FlushJitter
-		}
-
-		wg.Add(1)
-		go func(output *models.RunningOutput) {
-			defer wg.Done()
-
-			ticker := NewRollingTicker(interval, jitter)
-			defer ticker.Stop()
-
-			a.flushLoop(ctx, output, ticker)
-		}(output)
+		tasks[output] = a.runOutput(ctx, output)
+	}
+	groups := models.NewFailoverGroups(unit.outputs)
+	if err := models.LinkDeadLetters(unit.outputs); err != nil {
+		log.Printf("E! [agent] Linking dead-letter routes failed: %v", err)
 	}
+	targets := make([]*models.RunningOutput, 0, len(unit.outputs))
 
-	for metric := range unit.src {
-		for i, output := range unit.outputs {
-			if i == len(unit.outputs)-1 {
-				output.AddMetricNoCopy(metric)
-			} else {
-				output.AddMetric(metric)
+	for {
+		select {
+		case metric, ok := <-unit.src:
+			if ok {
+				// Only send metrics to the active output of failover groups
+				targets = targets[:0]
+				for _, output := range unit.outputs {
+					if group, found := groups[output]; found && group.Active() != output {
+						continue
+					}
+					targets = append(targets, output)
+				}
+
+				// Outputs with a shared buffer only reference the metric so the
+				// fan-out must be completed explicitly
+				if a.Config.Agent.BufferStrategy == "shared" {
+					for _, output := range targets {
+						output.AddMetric(metric)
+					}
+					models.ReleaseShared(metric)
+					continue
+				}
+				for i, output := range targets {
+					if i == len(targets)-1 {
+						output.AddMetricNoCopy(metric)
+					} else {
+						output.AddMetric(metric)
+					}
+				}
+				continue
+			}
+		case update := <-unit.update:
+			for _, output := range update.removed {
+				task, found := tasks[output]
+				if !found {
+					continue
+				}
+				// Stopping the flush loop writes the buffered metrics a last time
+				task.stop()
+				output.Close()
+				delete(tasks, output)
+			}
+			for _, output := range update.added {
+				tasks[output] = a.runOutput(ctx, output)
 			}
+			unit.outputs = update.apply(unit.outputs)
+			groups = models.NewFailoverGroups(unit.outputs)
+			if err := models.LinkDeadLetters(unit.outputs); err != nil {
+				log.Printf("E! [agent] Linking dead-letter routes failed: %v", err)
+			}
+			close(update.done)
+			continue
 		}
+		break
 	}
 
 	log.Println("I! [agent] Hang on, flushing any cached metrics before shutdown")
 	cancel()
-	wg.Wait()
+	for _, task := range tasks {
+		<-task.done
+	}
 
 	log.Println("I! [agent] Stopping running outputs")
 	stopRunningOutputs(unit.outputs)
 }
 
+// runOutput starts the flush loop of a single output until the context is
+// done or the returned task is stopped.
+func (a *Agent) runOutput(ctx context.Context, output *models.RunningOutput) *pluginTask {
+	// Overwrite agent flush_interval if this plugin has its own.
+	interval := time.Duration(a.Config.Agent.FlushInterval)
+	if output.Config.FlushInterval != 0 {
+		interval = output.Config.FlushInterval
+	}
+
+	// Overwrite agent flush_jitter if this plugin has its own.
+	jitter := time.Duration(a.Config.Agent.FlushJitter)
+	if output.Config.FlushJitter != 0 {
+		jitter = output.Config.FlushJitter
+	}
+
+	output.SetRateLimiters(
+		newWriteLimiter(output.Config.MaxMetricsPerSecond),
+		newWriteLimiter(output.Config.MaxBytesPerSecond),
+	)
+
+	ctx, cancel := context.WithCancel(ctx)
+	task := &pluginTask{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(task.done)
+
+		ticker := NewRollingTicker(interval, jitter)
+		defer ticker.Stop()
+
+		a.flushLoop(ctx, output, ticker)
+	}()
+
+	return task
+}
+
+// newWriteLimiter creates a limiter for the given amount per second or returns
+// nil if the amount is unlimited.
+func newWriteLimiter(limit int64) models.WriteLimiter {
+	if limit <= 0 {
+		return nil
+	}
+	cfg := &ratelimiter.RateLimitConfig{
+		Limit:  config.Size(limit),
+		Period: config.Duration(time.Second),
+	}
+	limiter, err := cfg.CreateRateLimiter()
+	if err != nil {
+		log.Printf("E! [agent] Creating rate-limiter failed: %v", err)
+		return nil
+	}
+	return limiter
+}
+
 // flushLoop runs an output's flush function periodically until the context is
 // done.
 func (a *Agent) flushLoop(
@@ -901,30 +1299,35 @@ func (a *Agent) flushLoop(
 		// Favor shutdown over other methods.
 		select {
 		case <-ctx.Done():
-			logError(a.flushOnce(output, ticker, output.Write))
+			logError(a.flushOnce(output, ticker, output.WriteContext))
 			return
 		default:
 		}
 
 		select {
 		case <-ctx.Done():
-			logError(a.flushOnce(output, ticker, output.Write))
+			logError(a.flushOnce(output, ticker, output.WriteContext))
 			return
 		case <-ticker.Elapsed():
-			logError(a.flushOnce(output, ticker, output.Write))
+			logError(a.flushOnce(output, ticker, output.WriteContext))
 		case <-flushRequested:
-			logError(a.flushOnce(output, ticker, output.Write))
+			logError(a.flushOnce(output, ticker, output.WriteContext))
+		case <-output.FlushRequested():
+			logError(a.flushOnce(output, ticker, output.WriteContext))
 		case <-output.BatchReady:
-			logError(a.flushBatch(output, output.WriteBatch))
+			logError(a.flushBatch(output, output.WriteBatchContext))
 		}
 	}
 }
 
 // flushOnce runs the output's Write function once, logging a warning each interval it fails to complete before the flush interval elapses.
-func (*Agent) flushOnce(output *models.RunningOutput, ticker Ticker, writeFunc func() error) error {
+func (a *Agent) flushOnce(output *models.RunningOutput, ticker Ticker, writeFunc func(context.Context) error) (err error) {
+	ctx, span := a.startSpan(context.Background(), "flush", attribute.String("telegraf.output", output.LogName()))
+	defer func() { endSpan(span, err) }()
+
 	done := make(chan error)
 	go func() {
-		done <- writeFunc()
+		done <- writeFunc(ctx)
 	}()
 
 	for {
@@ -936,20 +1339,28 @@ func (*Agent) flushOnce(output *models.RunningOutput, ticker Ticker, writeFunc f
 			log.Printf("W! [agent] [%q] did not complete within its flush interval",
 				output.LogName())
 			output.LogBufferStatus()
+			span.AddEvent("flush interval exceeded")
 		}
 	}
 }
 
 // flushBatch runs the output's Write function once Unlike flushOnce the interval elapsing is not considered during these flushes.
-func (*Agent) flushBatch(output *models.RunningOutput, writeFunc func() error) error {
-	err := writeFunc()
+func (a *Agent) flushBatch(output *models.RunningOutput, writeFunc func(context.Context) error) error {
+	ctx, span := a.startSpan(context.Background(), "flush batch", attribute.String("telegraf.output", output.LogName()))
+	err := writeFunc(ctx)
+	endSpan(span, err)
 	output.LogBufferStatus()
 	return err
 }
 
 // Test runs the inputs, processors and aggregators for a single gather and
-// writes the metrics to stdout.
+// writes the metrics to stdout. If Trace is set, the metrics leaving each
+// plugin and the decisions of the output filters are written to it.
 func (a *Agent) Test(ctx context.Context, wait time.Duration) error {
+	if a.Trace != nil {
+		a.tracer = newTracer(a.Trace)
+	}
+
 	src := make(chan telegraf.Metric, 100)
 
 	var wg sync.WaitGroup
@@ -958,6 +1369,9 @@ func (a *Agent) Test(ctx context.Context, wait time.Duration) error {
 		defer wg.Done()
 		s := &influx.Serializer{SortFields: true, UintSupport: true}
 		for metric := range src {
+			if a.tracer != nil {
+				a.tracer.traceOutputs(metric, a.Config.Outputs)
+			}
 			octets, err := s.Serialize(metric)
 			if err == nil {
 				fmt.Print("> ", string(octets))
@@ -1207,9 +1621,3 @@ func panicRecover(input *models.RunningInput) {
 			"https://github.com/influxdata/telegraf/issues/new/choose")
 	}
 }
-
-func stopTickers(tickers []Ticker) {
-	for _, ticker := range tickers {
-		ticker.Stop()
-	}
-}
@@ -17,3 +17,13 @@ func watchForFlushSignal(flushRequested chan os.Signal) {
 func stopListeningForFlushSignal(flushRequested chan os.Signal) {
 	signal.Stop(flushRequested)
 }
+
+const logLevelSignal = syscall.SIGUSR2
+
+func watchForLogLevelSignal(logLevelRequested chan os.Signal) {
+	signal.Notify(logLevelRequested, logLevelSignal)
+}
+
+func stopListeningForLogLevelSignal(logLevelRequested chan os.Signal) {
+	signal.Stop(logLevelRequested)
+}
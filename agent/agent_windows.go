@@ -11,3 +11,11 @@ func watchForFlushSignal(_ chan os.Signal) {
 func stopListeningForFlushSignal(_ chan os.Signal) {
 	// not supported
 }
+
+func watchForLogLevelSignal(_ chan os.Signal) {
+	// not supported
+}
+
+func stopListeningForLogLevelSignal(_ chan os.Signal) {
+	// not supported
+}
@@ -0,0 +1,324 @@
+package agent
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/plugins/common/tls"
+)
+
+// errInputPaused is returned for requests to gather a paused input
+var errInputPaused = errors.New("input paused")
+
+type controlInput struct {
+	ID              string    `json:"id"`
+	Name            string    `json:"name"`
+	Alias           string    `json:"alias,omitempty"`
+	Paused          bool      `json:"paused"`
+	LastGather      time.Time `json:"last_gather,omitzero"`
+	LastError       string    `json:"last_error,omitempty"`
+	LastErrorTime   time.Time `json:"last_error_time,omitzero"`
+	MetricsGathered int64     `json:"metrics_gathered"`
+}
+
+type controlOutput struct {
+	ID              string    `json:"id"`
+	Name            string    `json:"name"`
+	Alias           string    `json:"alias,omitempty"`
+	LastWrite       time.Time `json:"last_write,omitzero"`
+	LastError       string    `json:"last_error,omitempty"`
+	LastErrorTime   time.Time `json:"last_error_time,omitzero"`
+	BufferSize      int64     `json:"buffer_size"`
+	BufferLimit     int64     `json:"buffer_limit"`
+	MetricsAdded    int64     `json:"metrics_added"`
+	MetricsWritten  int64     `json:"metrics_written"`
+	MetricsRejected int64     `json:"metrics_rejected"`
+	MetricsDropped  int64     `json:"metrics_dropped"`
+}
+
+type controlPlugin struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Alias string `json:"alias,omitempty"`
+}
+
+type controlLogLevel struct {
+	Level string `json:"level"`
+	TTL   string `json:"ttl,omitempty"`
+}
+
+type controlPlugins struct {
+	Inputs      []controlInput  `json:"inputs"`
+	Processors  []controlPlugin `json:"processors"`
+	Aggregators []controlPlugin `json:"aggregators"`
+	Outputs     []controlOutput `json:"outputs"`
+}
+
+// startControl starts the HTTP control API if configured. The server is shut
+// down when the context is done.
+func (a *Agent) startControl(ctx context.Context) error {
+	cfg := a.Config.Agent
+	if cfg.ControlAddress == "" {
+		return nil
+	}
+
+	serverCfg := &tls.ServerConfig{
+		TLSCert:           cfg.ControlTLSCert,
+		TLSKey:            cfg.ControlTLSKey,
+		TLSAllowedCACerts: cfg.ControlTLSAllowedCACerts,
+	}
+	tlsCfg, err := serverCfg.TLSConfig()
+	if err != nil {
+		return err
+	}
+	if tlsCfg == nil || len(tlsCfg.Certificates) == 0 {
+		return errors.New("control API requires 'control_tls_cert' and 'control_tls_key'")
+	}
+	if cfg.ControlToken.Empty() && len(cfg.ControlTLSAllowedCACerts) == 0 {
+		return errors.New("control API requires 'control_token' or 'control_tls_allowed_cacerts' for authentication")
+	}
+
+	listener, err := net.Listen("tcp", cfg.ControlAddress)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Handler:           a.controlHandler(),
+		TLSConfig:         tlsCfg,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := server.ServeTLS(listener, "", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("E! [agent] Serving control API failed: %v", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("E! [agent] Shutting down control API failed: %v", err)
+		}
+	}()
+	log.Printf("I! [agent] Serving control API on %s", listener.Addr())
+
+	return nil
+}
+
+func (a *Agent) controlHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/plugins", a.handlePlugins)
+	mux.HandleFunc("POST /v1/inputs/{id}/gather", a.handleInput(func(input *models.RunningInput) error {
+		// Metrics of paused inputs are discarded so gathering is pointless
+		if input.Paused() {
+			return errInputPaused
+		}
+		input.RequestGather()
+		return nil
+	}))
+	mux.HandleFunc("POST /v1/inputs/{id}/pause", a.handleInput(func(input *models.RunningInput) error {
+		input.Pause()
+		return nil
+	}))
+	mux.HandleFunc("POST /v1/inputs/{id}/resume", a.handleInput(func(input *models.RunningInput) error {
+		input.Resume()
+		return nil
+	}))
+	mux.HandleFunc("POST /v1/outputs/{id}/flush", a.handleOutput(func(output *models.RunningOutput) {
+		output.RequestFlush()
+	}))
+	mux.HandleFunc("POST /v1/plugins/{id}/log_level", a.handleLogLevel)
+	mux.HandleFunc("POST /v1/reload", a.handleReload)
+
+	return a.authenticate(mux)
+}
+
+func (a *Agent) authenticate(next http.Handler) http.Handler {
+	token := &a.Config.Agent.ControlToken
+	if token.Empty() {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Get the token for each request as it might be rotated by the store
+		expected, err := token.Get()
+		if err != nil {
+			log.Printf("E! [agent] Getting control token failed: %v", err)
+			http.Error(w, "getting token failed", http.StatusInternalServerError)
+			return
+		}
+		defer expected.Destroy()
+
+		provided, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(provided), expected.Bytes()) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a *Agent) handlePlugins(w http.ResponseWriter, _ *http.Request) {
+	a.pluginsMu.RLock()
+	inputs := a.Config.Inputs
+	processors := a.Config.Processors
+	aggregators := a.Config.Aggregators
+	outputs := a.Config.Outputs
+	a.pluginsMu.RUnlock()
+
+	response := controlPlugins{
+		Inputs:      make([]controlInput, 0, len(inputs)),
+		Processors:  make([]controlPlugin, 0, len(processors)),
+		Aggregators: make([]controlPlugin, 0, len(aggregators)),
+		Outputs:     make([]controlOutput, 0, len(outputs)),
+	}
+	for _, input := range inputs {
+		status := input.Status()
+		response.Inputs = append(response.Inputs, controlInput{
+			ID:              input.ID(),
+			Name:            input.Config.Name,
+			Alias:           input.Config.Alias,
+			Paused:          input.Paused(),
+			LastGather:      status.LastRun,
+			LastError:       status.LastError,
+			LastErrorTime:   status.LastErrorTime,
+			MetricsGathered: input.MetricsGathered.Get(),
+		})
+	}
+	for _, processor := range processors {
+		response.Processors = append(response.Processors, controlPlugin{
+			ID:    processor.ID(),
+			Name:  processor.Config.Name,
+			Alias: processor.Config.Alias,
+		})
+	}
+	for _, aggregator := range aggregators {
+		response.Aggregators = append(response.Aggregators, controlPlugin{
+			ID:    aggregator.ID(),
+			Name:  aggregator.Config.Name,
+			Alias: aggregator.Config.Alias,
+		})
+	}
+	for _, output := range outputs {
+		status := output.Status()
+		stats := output.BufferStats()
+		response.Outputs = append(response.Outputs, controlOutput{
+			ID:              output.ID(),
+			Name:            output.Config.Name,
+			Alias:           output.Config.Alias,
+			LastWrite:       status.LastRun,
+			LastError:       status.LastError,
+			LastErrorTime:   status.LastErrorTime,
+			BufferSize:      stats.BufferSize.Get(),
+			BufferLimit:     stats.BufferLimit.Get(),
+			MetricsAdded:    stats.MetricsAdded.Get(),
+			MetricsWritten:  stats.MetricsWritten.Get(),
+			MetricsRejected: stats.MetricsRejected.Get(),
+			MetricsDropped:  stats.MetricsDropped.Get(),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("E! [agent] Encoding control API response failed: %v", err)
+	}
+}
+
+// handleInput applies the action to all inputs with the requested ID. Inputs
+// with identical settings share the same ID.
+func (a *Agent) handleInput(action func(*models.RunningInput) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		a.pluginsMu.RLock()
+		var found bool
+		var err error
+		for _, input := range a.Config.Inputs {
+			if input.ID() == id {
+				if aerr := action(input); aerr != nil {
+					err = aerr
+				}
+				found = true
+			}
+		}
+		a.pluginsMu.RUnlock()
+
+		if !found {
+			http.Error(w, "input not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// handleOutput applies the action to all outputs with the requested ID.
+func (a *Agent) handleOutput(action func(*models.RunningOutput)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		a.pluginsMu.RLock()
+		var found bool
+		for _, output := range a.Config.Outputs {
+			if output.ID() == id {
+				action(output)
+				found = true
+			}
+		}
+		a.pluginsMu.RUnlock()
+
+		if !found {
+			http.Error(w, "output not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func (a *Agent) handleLogLevel(w http.ResponseWriter, r *http.Request) {
+	var request controlLogLevel
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	ttl := defaultLogLevelTTL
+	if request.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(request.TTL); err != nil {
+			http.Error(w, "invalid ttl: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	n, err := a.setLogLevel(r.PathValue("id"), request.Level, ttl)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if n == 0 {
+		http.Error(w, "plugin not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (a *Agent) handleReload(w http.ResponseWriter, _ *http.Request) {
+	if a.RequestReload == nil {
+		http.Error(w, "reloading not supported", http.StatusNotImplemented)
+		return
+	}
+	a.RequestReload()
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,139 @@
+package agent
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestControlPlugins(t *testing.T) {
+	a := newControlTestAgent(t)
+	handler := a.controlHandler()
+
+	// Requests without the token must be rejected
+	req := httptest.NewRequest(http.MethodGet, "/v1/plugins", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var actual controlPlugins
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &actual))
+	require.Len(t, actual.Inputs, 1)
+	require.Equal(t, a.Config.Inputs[0].ID(), actual.Inputs[0].ID)
+	require.Equal(t, "reload_test", actual.Inputs[0].Name)
+	require.False(t, actual.Inputs[0].Paused)
+	require.Empty(t, actual.Processors)
+	require.Empty(t, actual.Aggregators)
+	require.Len(t, actual.Outputs, 1)
+	require.Equal(t, a.Config.Outputs[0].ID(), actual.Outputs[0].ID)
+	require.Equal(t, int64(10000), actual.Outputs[0].BufferLimit)
+}
+
+func TestControlActions(t *testing.T) {
+	a := newControlTestAgent(t)
+	handler := a.controlHandler()
+	input := a.Config.Inputs[0]
+	output := a.Config.Outputs[0]
+
+	post := func(path string) int {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	require.Equal(t, http.StatusAccepted, post("/v1/inputs/"+input.ID()+"/pause"))
+	require.True(t, input.Paused())
+	// Gathering is refused as the metrics of paused inputs are discarded
+	require.Equal(t, http.StatusConflict, post("/v1/inputs/"+input.ID()+"/gather"))
+	require.Empty(t, input.GatherRequested())
+	require.Equal(t, http.StatusAccepted, post("/v1/inputs/"+input.ID()+"/resume"))
+	require.False(t, input.Paused())
+
+	require.Equal(t, http.StatusAccepted, post("/v1/inputs/"+input.ID()+"/gather"))
+	require.Len(t, input.GatherRequested(), 1)
+
+	require.Equal(t, http.StatusAccepted, post("/v1/outputs/"+output.ID()+"/flush"))
+	require.Len(t, output.FlushRequested(), 1)
+
+	require.Equal(t, http.StatusNotFound, post("/v1/inputs/unknown/gather"))
+	require.Equal(t, http.StatusNotFound, post("/v1/outputs/unknown/flush"))
+
+	require.Equal(t, http.StatusNotImplemented, post("/v1/reload"))
+	var reloads int
+	a.RequestReload = func() { reloads++ }
+	require.Equal(t, http.StatusAccepted, post("/v1/reload"))
+	require.Equal(t, 1, reloads)
+}
+
+func TestControlLogLevel(t *testing.T) {
+	a := newControlTestAgent(t)
+	handler := a.controlHandler()
+	input := a.Config.Inputs[0]
+
+	post := func(path, body string) int {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Other tests might have changed the global log-level so use the current
+	// level instead of assuming the default
+	level := input.Log().Level()
+	path := "/v1/plugins/" + input.ID() + "/log_level"
+	require.Equal(t, http.StatusAccepted, post(path, `{"level": "debug", "ttl": "50ms"}`))
+	require.Equal(t, telegraf.Debug, input.Log().Level())
+	require.Eventually(t, func() bool {
+		return input.Log().Level() == level
+	}, time.Second, 10*time.Millisecond)
+
+	require.Equal(t, http.StatusBadRequest, post(path, `{"level": "foo"}`))
+	require.Equal(t, http.StatusBadRequest, post(path, `{"level": "debug", "ttl": "-1m"}`))
+	require.Equal(t, http.StatusNotFound, post("/v1/plugins/unknown/log_level", `{"level": "debug"}`))
+}
+
+func TestControlRequiresTLSAndAuth(t *testing.T) {
+	a := newControlTestAgent(t)
+	a.Config.Agent.ControlAddress = "localhost:0"
+	require.ErrorContains(t, a.startControl(t.Context()), "requires 'control_tls_cert'")
+
+	pki := testutil.NewPKI("../testutil/pki")
+	a.Config.Agent.ControlTLSCert = pki.ServerCertPath()
+	a.Config.Agent.ControlTLSKey = pki.ServerKeyPath()
+	a.Config.Agent.ControlToken = config.Secret{}
+	require.ErrorContains(t, a.startControl(t.Context()), "for authentication")
+
+	a.Config.Agent.ControlToken = config.NewSecret([]byte("secret"))
+	require.NoError(t, a.startControl(t.Context()))
+}
+
+func newControlTestAgent(t *testing.T) *Agent {
+	t.Helper()
+
+	cfg := config.NewConfig()
+	require.NoError(t, cfg.LoadConfigData([]byte(`
+[agent]
+  control_token = "secret"
+[[inputs.reload_test]]
+[[outputs.reload_test]]
+`), config.EmptySourcePath))
+
+	return NewAgent(cfg)
+}
@@ -0,0 +1,150 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// defaultLogLevelTTL is the duration of log-level overrides if not specified
+const defaultLogLevelTTL = 15 * time.Minute
+
+// levelOverrider is implemented by loggers supporting temporary log-level
+// overrides
+type levelOverrider interface {
+	SetLevelTemporarily(level telegraf.LogLevel, ttl time.Duration)
+}
+
+type loggingPlugin struct {
+	id      string
+	logName string
+	log     telegraf.Logger
+}
+
+func (a *Agent) loggingPlugins() []loggingPlugin {
+	a.pluginsMu.RLock()
+	defer a.pluginsMu.RUnlock()
+
+	var plugins []loggingPlugin
+	for _, p := range a.Config.Inputs {
+		plugins = append(plugins, loggingPlugin{p.ID(), p.LogName(), p.Log()})
+	}
+	for _, p := range a.Config.Processors {
+		plugins = append(plugins, loggingPlugin{p.ID(), p.LogName(), p.Log()})
+	}
+	for _, p := range a.Config.AggProcessors {
+		plugins = append(plugins, loggingPlugin{p.ID(), p.LogName(), p.Log()})
+	}
+	for _, p := range a.Config.Aggregators {
+		plugins = append(plugins, loggingPlugin{p.ID(), p.LogName(), p.Log()})
+	}
+	for _, p := range a.Config.Outputs {
+		plugins = append(plugins, loggingPlugin{p.ID(), p.LogName(), p.Log()})
+	}
+	return plugins
+}
+
+// setLogLevel temporarily overrides the log-level of all plugins matching the
+// selector, i.e. the plugin ID or the name used in log messages such as
+// "inputs.cpu" or "inputs.cpu::alias". The number of matching plugins is
+// returned.
+func (a *Agent) setLogLevel(selector, name string, ttl time.Duration) (int, error) {
+	level := telegraf.LogLevelFromString(name)
+	if level == telegraf.None {
+		return 0, fmt.Errorf("invalid log-level %q", name)
+	}
+	if ttl <= 0 {
+		return 0, fmt.Errorf("invalid duration %v, must be positive", ttl)
+	}
+
+	var n int
+	for _, p := range a.loggingPlugins() {
+		if p.id != selector && p.logName != selector {
+			continue
+		}
+		l, ok := p.log.(levelOverrider)
+		if !ok {
+			continue
+		}
+		l.SetLevelTemporarily(level, ttl)
+		n++
+	}
+	if n > 0 {
+		log.Printf("I! [agent] Set log-level of %q to %s for %s", selector, level, ttl)
+	}
+	return n, nil
+}
+
+// watchLogLevels applies the log-level overrides of the configured file on
+// receiving the signal until the context is done.
+func (a *Agent) watchLogLevels(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	watchForLogLevelSignal(signals)
+	defer stopListeningForLogLevelSignal(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			if err := a.applyLogLevelFile(a.Config.Agent.LogLevelFile); err != nil {
+				log.Printf("E! [agent] Applying log-levels failed: %v", err)
+			}
+		}
+	}
+}
+
+// applyLogLevelFile applies the log-level overrides in the given file. Each
+// line contains the plugin selector, the level and optionally the duration of
+// the override, e.g. "inputs.cpu debug 10m". Empty lines and lines starting
+// with '#' are ignored.
+func (a *Agent) applyLogLevelFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var errs []error
+	scanner := bufio.NewScanner(f)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 || len(fields) > 3 {
+			errs = append(errs, fmt.Errorf("line %d: expected plugin, level and optional duration", lineno))
+			continue
+		}
+		ttl := defaultLogLevelTTL
+		if len(fields) == 3 {
+			if ttl, err = time.ParseDuration(fields[2]); err != nil {
+				errs = append(errs, fmt.Errorf("line %d: %w", lineno, err))
+				continue
+			}
+		}
+
+		n, err := a.setLogLevel(fields[0], fields[1], ttl)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", lineno, err))
+			continue
+		}
+		if n == 0 {
+			errs = append(errs, fmt.Errorf("line %d: no plugin %q found", lineno, fields[0]))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
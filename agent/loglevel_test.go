@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+)
+
+func TestLogLevelFile(t *testing.T) {
+	a := newControlTestAgent(t)
+	input := a.Config.Inputs[0]
+	output := a.Config.Outputs[0]
+
+	path := filepath.Join(t.TempDir(), "loglevels")
+	content := `
+# Debug the flapping input
+inputs.reload_test debug 50ms
+` + output.ID() + ` trace
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+
+	// Other tests might have changed the global log-level so use the current
+	// level instead of assuming the default
+	level := input.Log().Level()
+	require.NoError(t, a.applyLogLevelFile(path))
+	require.Equal(t, telegraf.Debug, input.Log().Level())
+	require.Equal(t, telegraf.Trace, output.Log().Level())
+
+	// Only the input reverts within the test
+	require.Eventually(t, func() bool {
+		return input.Log().Level() == level
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, telegraf.Trace, output.Log().Level())
+
+	content = `
+inputs.unknown debug
+inputs.reload_test foo
+inputs.reload_test debug 1x
+inputs.reload_test
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	err := a.applyLogLevelFile(path)
+	require.ErrorContains(t, err, `line 2: no plugin "inputs.unknown" found`)
+	require.ErrorContains(t, err, `line 3: invalid log-level "foo"`)
+	require.ErrorContains(t, err, "line 4: time: unknown unit")
+	require.ErrorContains(t, err, "line 5: expected plugin, level and optional duration")
+}
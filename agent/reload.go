@@ -0,0 +1,414 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/snmp"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/persister"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+// ErrRestartRequired is returned by Reload if the configuration changes cannot
+// be applied to the running agent and a full restart is necessary.
+var ErrRestartRequired = errors.New("restart required")
+
+type reloadRequest struct {
+	cfg    *config.Config
+	result chan error
+}
+
+// pluginTask allows to stop the loop of an individual plugin on reload.
+type pluginTask struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (t *pluginTask) stop() {
+	t.cancel()
+	<-t.done
+}
+
+// unitUpdate contains the plugins to add to and remove from a running unit.
+// The done channel is closed by the unit after applying the update.
+type unitUpdate[T comparable] struct {
+	added   []T
+	removed []T
+	done    chan struct{}
+}
+
+func (u *unitUpdate[T]) apply(plugins []T) []T {
+	updated := make([]T, 0, len(plugins)+len(u.added))
+	for _, p := range plugins {
+		if !slices.Contains(u.removed, p) {
+			updated = append(updated, p)
+		}
+	}
+	return append(updated, u.added...)
+}
+
+// stageSwap requests the pipeline to replace the processing stage by the
+// given, already started, stage. The done channel is closed by the pipeline
+// after running the new stage.
+type stageSwap struct {
+	stage *stageUnit
+	done  chan struct{}
+}
+
+// Reload applies the given configuration to the running agent. Only plugins
+// added, removed or changed compared to the running configuration are
+// started or stopped, unchanged inputs and outputs keep running including
+// the buffered metrics and connections of the outputs. If any processor or
+// aggregator changed, a new processing stage is started from the loaded
+// instances and replaces the running stage only after starting succeeded.
+// The state of unchanged stateful processors and aggregators is transferred
+// to the new instances.
+// An error wrapping ErrRestartRequired is returned if the changes cannot be
+// applied incrementally, e.g. for changed agent settings. In this case the
+// running agent is left untouched if the error is returned before applying
+// any change. For all other errors the agent should be restarted.
+func (a *Agent) Reload(ctx context.Context, cfg *config.Config) error {
+	req := &reloadRequest{
+		cfg:    cfg,
+		result: make(chan error, 1),
+	}
+	select {
+	case a.reloadC <- req:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return <-req.result
+}
+
+// handleReloads applies the reload requests until the context is done.
+func (a *Agent) handleReloads(ctx context.Context, iu *inputUnit, pu *pipelineUnit, ou *outputUnit) {
+	for {
+		select {
+		case req := <-a.reloadC:
+			req.result <- a.reload(ctx, req.cfg, iu, pu, ou)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (a *Agent) reload(ctx context.Context, cfg *config.Config, iu *inputUnit, pu *pipelineUnit, ou *outputUnit) error {
+	// Use the running default for processor skipping to not report a change
+	if cfg.Agent.SkipProcessorsAfterAggregators == nil {
+		cfg.Agent.SkipProcessorsAfterAggregators = a.Config.Agent.SkipProcessorsAfterAggregators
+	}
+
+	if len(cfg.Outputs) == 0 || len(cfg.Inputs) == 0 {
+		return fmt.Errorf("%w: no inputs or outputs configured", ErrRestartRequired)
+	}
+
+	diff := config.NewDiff(a.Config, cfg)
+	if diff.RestartReason != "" {
+		return fmt.Errorf("%w: %s", ErrRestartRequired, diff.RestartReason)
+	}
+	diff.RenewPipeline()
+
+	// Release the instances superseded by the already running plugins
+	for _, output := range diff.Outputs.Discarded {
+		output.Discard()
+	}
+
+	if err := a.initAdded(diff); err != nil {
+		for _, output := range diff.Outputs.Added {
+			output.Discard()
+		}
+		return err
+	}
+
+	// Start the new processing stage before touching the running plugins to
+	// keep the current stage in place if starting fails. Otherwise, metrics
+	// would reach the outputs without being processed.
+	var stage *stageUnit
+	if diff.PipelineChanged() {
+		transferStates(diff)
+
+		var err error
+		stage, err = a.startStage(time.Now(), diff.Processors.Merged, diff.AggProcessors.Merged, diff.Aggregators.Merged)
+		if err != nil {
+			for _, output := range diff.Outputs.Added {
+				output.Discard()
+			}
+			return err
+		}
+	}
+
+	// Apply the changes in output-to-input direction to have new outputs and
+	// processors available when new inputs start to produce metrics.
+	if err := a.reloadOutputs(ctx, diff, ou); err != nil {
+		if stage != nil {
+			discardStage(stage)
+		}
+		return err
+	}
+
+	if stage != nil {
+		req := &stageSwap{
+			stage: stage,
+			done:  make(chan struct{}),
+		}
+		select {
+		case pu.swap <- req:
+		case <-ctx.Done():
+			discardStage(stage)
+			return ctx.Err()
+		}
+		<-req.done
+	}
+
+	if err := a.reloadInputs(ctx, diff, iu); err != nil {
+		return err
+	}
+
+	if a.Config.Persister != nil {
+		if err := a.reloadPersister(diff); err != nil {
+			return err
+		}
+	}
+
+	a.pluginsMu.Lock()
+	a.Config.Inputs = diff.Inputs.Merged
+	a.Config.Processors = diff.Processors.Merged
+	a.Config.AggProcessors = diff.AggProcessors.Merged
+	a.Config.Aggregators = diff.Aggregators.Merged
+	a.Config.Outputs = diff.Outputs.Merged
+	a.pluginsMu.Unlock()
+
+	// Let the loaded configuration reference the running plugin instances
+	diff.Apply(cfg)
+
+	log.Printf("I! [agent] Reloaded configuration: inputs +%d/-%d, processors +%d/-%d, aggregators +%d/-%d, outputs +%d/-%d",
+		len(diff.Inputs.Added), len(diff.Inputs.Removed),
+		len(diff.Processors.Added), len(diff.Processors.Removed),
+		len(diff.Aggregators.Added), len(diff.Aggregators.Removed),
+		len(diff.Outputs.Added), len(diff.Outputs.Removed),
+	)
+
+	return nil
+}
+
+// transferStates copies the state of the running processors and aggregators
+// to their replacement. Metrics processed by the running instances until the
+// stage is swapped are not reflected in the transferred state.
+func transferStates(diff *config.Diff) {
+	transfer := func(name string, from, to interface{}) {
+		if p, ok := from.(processors.HasUnwrap); ok {
+			from = p.Unwrap()
+		}
+		if p, ok := to.(processors.HasUnwrap); ok {
+			to = p.Unwrap()
+		}
+		src, ok := from.(telegraf.StatefulPlugin)
+		if !ok {
+			return
+		}
+		dst, ok := to.(telegraf.StatefulPlugin)
+		if !ok {
+			return
+		}
+		if err := persister.Transfer(src, dst); err != nil {
+			log.Printf("W! [agent] Transferring state of %s failed: %v", name, err)
+		}
+	}
+
+	for running, loaded := range diff.Processors.Renewed {
+		transfer(running.LogName(), running.Processor, loaded.Processor)
+	}
+	for running, loaded := range diff.AggProcessors.Renewed {
+		transfer(running.LogName(), running.Processor, loaded.Processor)
+	}
+	for running, loaded := range diff.Aggregators.Renewed {
+		transfer(running.LogName(), running.Aggregator, loaded.Aggregator)
+	}
+}
+
+// initAdded runs the Init function on the added plugins.
+func (a *Agent) initAdded(diff *config.Diff) error {
+	for _, input := range diff.Inputs.Added {
+		// Share the snmp translator setting with plugins that need it.
+		if tp, ok := input.Input.(snmp.TranslatorPlugin); ok {
+			tp.SetTranslator(a.Config.Agent.SnmpTranslator)
+		}
+		if err := input.Init(); err != nil {
+			return fmt.Errorf("could not initialize input %s: %w", input.LogName(), err)
+		}
+	}
+	for _, processor := range diff.Processors.Added {
+		if err := processor.Init(); err != nil {
+			return fmt.Errorf("could not initialize processor %s: %w", processor.LogName(), err)
+		}
+	}
+	for _, aggregator := range diff.Aggregators.Added {
+		if err := aggregator.Init(); err != nil {
+			return fmt.Errorf("could not initialize aggregator %s: %w", aggregator.LogName(), err)
+		}
+	}
+	if !*a.Config.Agent.SkipProcessorsAfterAggregators {
+		for _, processor := range diff.AggProcessors.Added {
+			if err := processor.Init(); err != nil {
+				return fmt.Errorf("could not initialize processor %s: %w", processor.LogName(), err)
+			}
+		}
+	}
+	for _, output := range diff.Outputs.Added {
+		if err := output.Init(); err != nil {
+			return fmt.Errorf("could not initialize output %s: %w", output.LogName(), err)
+		}
+	}
+	return nil
+}
+
+// reloadOutputs connects the added outputs and replaces the removed ones in
+// the running output unit.
+func (a *Agent) reloadOutputs(ctx context.Context, diff *config.Diff, unit *outputUnit) error {
+	if len(diff.Outputs.Added) == 0 && len(diff.Outputs.Removed) == 0 {
+		return nil
+	}
+
+	added := make([]*models.RunningOutput, 0, len(diff.Outputs.Added))
+	for i, output := range diff.Outputs.Added {
+		if err := a.connectOutput(ctx, output); err != nil {
+			var fatalErr *internal.FatalError
+			if errors.As(err, &fatalErr) {
+				// If the model tells us to remove the plugin we do so without error
+				log.Printf("I! [agent] Failed to connect to [%s], error was %q;  shutting down plugin...", output.LogName(), err)
+				output.Close()
+				continue
+			}
+
+			stopRunningOutputs(added)
+			for _, o := range diff.Outputs.Added[i:] {
+				o.Discard()
+			}
+			return fmt.Errorf("connecting output %s: %w", output.LogName(), err)
+		}
+		added = append(added, output)
+	}
+
+	update := &unitUpdate[*models.RunningOutput]{
+		added:   added,
+		removed: diff.Outputs.Removed,
+		done:    make(chan struct{}),
+	}
+	select {
+	case unit.update <- update:
+	case <-ctx.Done():
+		stopRunningOutputs(added)
+		return ctx.Err()
+	}
+	<-update.done
+
+	return nil
+}
+
+// reloadInputs starts the added service inputs and replaces the removed ones
+// in the running input unit.
+func (a *Agent) reloadInputs(ctx context.Context, diff *config.Diff, unit *inputUnit) error {
+	if len(diff.Inputs.Added) == 0 && len(diff.Inputs.Removed) == 0 {
+		return nil
+	}
+
+	added := make([]*models.RunningInput, 0, len(diff.Inputs.Added))
+	for _, input := range diff.Inputs.Added {
+		started, err := a.startInput(unit.dst, input)
+		if err != nil {
+			stopRunningInputs(added)
+			return err
+		}
+		if started {
+			added = append(added, input)
+		}
+	}
+
+	update := &unitUpdate[*models.RunningInput]{
+		added:   added,
+		removed: diff.Inputs.Removed,
+		done:    make(chan struct{}),
+	}
+	select {
+	case unit.update <- update:
+	case <-ctx.Done():
+		stopRunningInputs(added)
+		return ctx.Err()
+	}
+	<-update.done
+
+	return nil
+}
+
+// reloadPersister registers the added stateful plugins and removes the
+// registration of the removed ones.
+func (a *Agent) reloadPersister(diff *config.Diff) error {
+	for _, input := range diff.Inputs.Removed {
+		a.Config.Persister.Unregister(input.ID())
+	}
+	for _, processor := range diff.Processors.Removed {
+		a.Config.Persister.Unregister(processor.ID())
+	}
+	for _, aggregator := range diff.Aggregators.Removed {
+		a.Config.Persister.Unregister(aggregator.ID())
+	}
+	for _, processor := range diff.AggProcessors.Removed {
+		a.Config.Persister.Unregister(processor.ID())
+	}
+	for _, output := range diff.Outputs.Removed {
+		a.Config.Persister.Unregister(output.ID())
+	}
+
+	for _, input := range diff.Inputs.Added {
+		if plugin, ok := input.Input.(telegraf.StatefulPlugin); ok {
+			if err := a.Config.Persister.Register(input.ID(), plugin); err != nil {
+				return fmt.Errorf("could not register input %s: %w", input.LogName(), err)
+			}
+		}
+	}
+	for _, processor := range diff.Processors.Added {
+		var plugin telegraf.StatefulPlugin
+		var ok bool
+		if p, unwrap := processor.Processor.(processors.HasUnwrap); unwrap {
+			plugin, ok = p.Unwrap().(telegraf.StatefulPlugin)
+		} else {
+			plugin, ok = processor.Processor.(telegraf.StatefulPlugin)
+		}
+		if ok {
+			if err := a.Config.Persister.Register(processor.ID(), plugin); err != nil {
+				return fmt.Errorf("could not register processor %s: %w", processor.LogName(), err)
+			}
+		}
+	}
+	for _, aggregator := range diff.Aggregators.Added {
+		if plugin, ok := aggregator.Aggregator.(telegraf.StatefulPlugin); ok {
+			if err := a.Config.Persister.Register(aggregator.ID(), plugin); err != nil {
+				return fmt.Errorf("could not register aggregator %s: %w", aggregator.LogName(), err)
+			}
+		}
+	}
+	for _, processor := range diff.AggProcessors.Added {
+		if plugin, ok := processor.Processor.(telegraf.StatefulPlugin); ok {
+			if err := a.Config.Persister.Register(processor.ID(), plugin); err != nil {
+				return fmt.Errorf("could not register aggregating processor %s: %w", processor.LogName(), err)
+			}
+		}
+	}
+	for _, output := range diff.Outputs.Added {
+		if plugin, ok := output.Output.(telegraf.StatefulPlugin); ok {
+			if err := a.Config.Persister.Register(output.ID(), plugin); err != nil {
+				return fmt.Errorf("could not register output %s: %w", output.LogName(), err)
+			}
+		}
+	}
+
+	return nil
+}
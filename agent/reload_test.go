@@ -0,0 +1,234 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+func TestReload(t *testing.T) {
+	running := config.NewConfig()
+	require.NoError(t, running.LoadConfigData([]byte(`
+[agent]
+  interval = "10ms"
+  flush_interval = "10ms"
+  skip_processors_after_aggregators = true
+[[inputs.reload_test]]
+  value = 1
+[[inputs.reload_test]]
+  value = 10
+[[outputs.reload_test]]
+`), config.EmptySourcePath))
+	output := running.Outputs[0].Output.(*reloadTestOutput)
+
+	a := NewAgent(running)
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- a.Run(ctx)
+	}()
+	require.Eventually(t, func() bool {
+		return output.has(1) && output.has(10)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Replace one input and add a processor; the output must be kept
+	kept := running.Inputs[1]
+	loaded := config.NewConfig()
+	require.NoError(t, loaded.LoadConfigData([]byte(`
+[agent]
+  interval = "10ms"
+  flush_interval = "10ms"
+  skip_processors_after_aggregators = true
+[[inputs.reload_test]]
+  value = 2
+[[inputs.reload_test]]
+  value = 10
+[[processors.reload_test]]
+[[outputs.reload_test]]
+`), config.EmptySourcePath))
+	require.NoError(t, a.Reload(ctx, loaded))
+	require.Same(t, output, a.Config.Outputs[0].Output)
+	require.Same(t, kept, a.Config.Inputs[1])
+	require.Len(t, a.Config.Processors, 1)
+
+	output.reset()
+	require.Eventually(t, func() bool {
+		return output.has(2) && output.has(10) && output.processed()
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, 1, output.connects)
+
+	// The removed input must not produce metrics anymore. Reset again to
+	// ignore the metrics gathered before the reload but written afterwards.
+	output.reset()
+	require.Eventually(t, func() bool {
+		return output.has(2) && output.has(10)
+	}, 5*time.Second, 10*time.Millisecond)
+	require.False(t, output.has(1))
+	require.Equal(t, loaded.Inputs, a.Config.Inputs)
+
+	// A processor failing to start must keep the running stage in place
+	failing := config.NewConfig()
+	require.NoError(t, failing.LoadConfigData([]byte(`
+[agent]
+  interval = "10ms"
+  flush_interval = "10ms"
+  skip_processors_after_aggregators = true
+[[inputs.reload_test]]
+  value = 2
+[[inputs.reload_test]]
+  value = 10
+[[processors.reload_test]]
+[[processors.reload_test_failing]]
+[[outputs.reload_test]]
+`), config.EmptySourcePath))
+	err := a.Reload(ctx, failing)
+	require.ErrorContains(t, err, "start failed")
+	require.NotErrorIs(t, err, ErrRestartRequired)
+	require.Len(t, a.Config.Processors, 1)
+
+	output.reset()
+	require.Eventually(t, func() bool {
+		return output.has(2) && output.has(10) && output.processed()
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Changing agent settings cannot be applied incrementally
+	restart := config.NewConfig()
+	require.NoError(t, restart.LoadConfigData([]byte(`
+[agent]
+  interval = "20ms"
+  flush_interval = "10ms"
+  skip_processors_after_aggregators = true
+[[inputs.reload_test]]
+  value = 2
+[[outputs.reload_test]]
+`), config.EmptySourcePath))
+	require.ErrorIs(t, a.Reload(ctx, restart), ErrRestartRequired)
+
+	cancel()
+	require.NoError(t, <-runErr)
+	require.Equal(t, 1, output.closes)
+}
+
+type reloadTestInput struct {
+	Value int64 `toml:"value"`
+}
+
+func (*reloadTestInput) SampleConfig() string {
+	return ""
+}
+
+func (i *reloadTestInput) Gather(acc telegraf.Accumulator) error {
+	acc.AddFields("test", map[string]interface{}{"value": i.Value}, nil)
+	return nil
+}
+
+type reloadTestProcessor struct{}
+
+func (*reloadTestProcessor) SampleConfig() string {
+	return ""
+}
+
+func (*reloadTestProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, m := range in {
+		m.AddTag("processed", "true")
+	}
+	return in
+}
+
+type reloadTestFailingProcessor struct{}
+
+func (*reloadTestFailingProcessor) SampleConfig() string {
+	return ""
+}
+
+func (*reloadTestFailingProcessor) Start(telegraf.Accumulator) error {
+	return errors.New("start failed")
+}
+
+func (*reloadTestFailingProcessor) Add(m telegraf.Metric, acc telegraf.Accumulator) error {
+	acc.AddMetric(m)
+	return nil
+}
+
+func (*reloadTestFailingProcessor) Stop() {}
+
+type reloadTestOutput struct {
+	connects int
+	closes   int
+	values   map[int64]bool
+	tagged   bool
+	sync.Mutex
+}
+
+func (*reloadTestOutput) SampleConfig() string {
+	return ""
+}
+
+func (o *reloadTestOutput) Connect() error {
+	o.connects++
+	return nil
+}
+
+func (o *reloadTestOutput) Close() error {
+	o.closes++
+	return nil
+}
+
+func (o *reloadTestOutput) Write(metrics []telegraf.Metric) error {
+	o.Lock()
+	defer o.Unlock()
+	for _, m := range metrics {
+		v, _ := m.GetField("value")
+		o.values[v.(int64)] = true
+		if m.HasTag("processed") {
+			o.tagged = true
+		}
+	}
+	return nil
+}
+
+func (o *reloadTestOutput) has(v int64) bool {
+	o.Lock()
+	defer o.Unlock()
+	return o.values[v]
+}
+
+func (o *reloadTestOutput) processed() bool {
+	o.Lock()
+	defer o.Unlock()
+	return o.tagged
+}
+
+func (o *reloadTestOutput) reset() {
+	o.Lock()
+	defer o.Unlock()
+	o.values = make(map[int64]bool)
+	o.tagged = false
+}
+
+func init() {
+	inputs.Add("reload_test", func() telegraf.Input {
+		return &reloadTestInput{}
+	})
+	processors.Add("reload_test", func() telegraf.Processor {
+		return &reloadTestProcessor{}
+	})
+	processors.AddStreaming("reload_test_failing", func() telegraf.StreamingProcessor {
+		return &reloadTestFailingProcessor{}
+	})
+	outputs.Add("reload_test", func() telegraf.Output {
+		return &reloadTestOutput{values: make(map[int64]bool)}
+	})
+}
@@ -0,0 +1,150 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"log"
+	"maps"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	serializers_prometheus "github.com/influxdata/telegraf/plugins/serializers/prometheus"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// startSelfstat serves the internal statistics on a dedicated "/metrics"
+// endpoint if configured. The endpoint does not depend on the metric pipeline
+// so the statistics are available even if outputs fail. The server is shut
+// down when the context is done.
+func (a *Agent) startSelfstat(ctx context.Context) error {
+	address := a.Config.Agent.SelfstatAddress
+	if address == "" {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Handler:           selfstatHandler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("E! [agent] Serving internal statistics failed: %v", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("E! [agent] Shutting down internal statistics endpoint failed: %v", err)
+		}
+	}()
+	log.Printf("I! [agent] Serving internal statistics on http://%s/metrics", listener.Addr())
+
+	return nil
+}
+
+func selfstatHandler() http.Handler {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(&selfstatCollector{})
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+		ErrorHandling:     promhttp.ContinueOnError,
+		EnableOpenMetrics: true,
+	}))
+	return mux
+}
+
+// selfstatCollector is an unchecked Prometheus collector exposing the
+// registered selfstat statistics and histograms
+type selfstatCollector struct{}
+
+func (*selfstatCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (*selfstatCollector) Collect(ch chan<- prometheus.Metric) {
+	samples := selfstat.Samples()
+	histograms := selfstat.Histograms()
+
+	// Metrics of the same family must have the same label names but tags
+	// like "alias" are only set for some plugins. Collect all label names of a
+	// family and use empty values for missing ones, which is equivalent to not
+	// having the label in Prometheus.
+	labels := make(map[string]map[string]bool)
+	addLabels := func(name string, tags map[string]string) {
+		if _, found := labels[name]; !found {
+			labels[name] = make(map[string]bool)
+		}
+		for k := range tags {
+			labels[name][k] = true
+		}
+	}
+	for _, s := range samples {
+		addLabels(selfstatFamily(s.Measurement, s.Field, s.Kind), s.Tags)
+	}
+	for _, h := range histograms {
+		addLabels(selfstatFamily(h.Measurement, h.Field, selfstat.Gauge), h.Tags)
+	}
+
+	for _, s := range samples {
+		name := selfstatFamily(s.Measurement, s.Field, s.Kind)
+		desc, values := selfstatDesc(name, s.Measurement, s.Field, labels[name], s.Tags)
+
+		valueType := prometheus.CounterValue
+		if s.Kind == selfstat.Gauge {
+			valueType = prometheus.GaugeValue
+		}
+		m, err := prometheus.NewConstMetric(desc, valueType, float64(s.Value), values...)
+		if err != nil {
+			m = prometheus.NewInvalidMetric(desc, err)
+		}
+		ch <- m
+	}
+
+	for _, h := range histograms {
+		name := selfstatFamily(h.Measurement, h.Field, selfstat.Gauge)
+		desc, values := selfstatDesc(name, h.Measurement, h.Field, labels[name], h.Tags)
+
+		m, err := prometheus.NewConstHistogram(desc, h.Count, h.Sum, h.Buckets, values...)
+		if err != nil {
+			m = prometheus.NewInvalidMetric(desc, err)
+		}
+		ch <- m
+	}
+}
+
+// selfstatFamily returns the metric family name of a stat. Counters require
+// the "_total" suffix in OpenMetrics and would be exposed as "unknown" type
+// without it.
+func selfstatFamily(measurement, field string, kind selfstat.Kind) string {
+	name, _ := serializers_prometheus.SanitizeMetricName(measurement + "_" + field)
+	if kind == selfstat.Counter && !strings.HasSuffix(name, "_total") {
+		name += "_total"
+	}
+	return name
+}
+
+func selfstatDesc(name, measurement, field string, labels map[string]bool, tags map[string]string) (*prometheus.Desc, []string) {
+	keys := slices.Sorted(maps.Keys(labels))
+	names := make([]string, 0, len(keys))
+	values := make([]string, 0, len(keys))
+	for _, k := range keys {
+		label, _ := serializers_prometheus.SanitizeLabelName(k)
+		names = append(names, label)
+		values = append(values, tags[k])
+	}
+
+	help := "Telegraf internal statistic " + field + " of " + measurement
+	return prometheus.NewDesc(name, help, names, nil), values
+}
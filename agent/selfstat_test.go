@@ -0,0 +1,49 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+func TestSelfstatHandler(t *testing.T) {
+	selfstat.Register("selfstat_test", "requests", map[string]string{"input": "a"}).Incr(5)
+	selfstat.Register("selfstat_test", "requests", map[string]string{"input": "b", "alias": "bar"}).Incr(2)
+	selfstat.RegisterGauge("selfstat_test", "queue_size", map[string]string{"input": "a"}).Set(7)
+	selfstat.RegisterGauge("selfstat_test", "pending_messages", map[string]string{"input": "a"}).Set(3)
+	h := selfstat.RegisterHistogram("selfstat_test", "duration_seconds", map[string]string{"input": "a"}, []float64{0.1, 1})
+	h.Observe(0.05)
+	h.Observe(0.5)
+
+	handler := selfstatHandler()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Header().Get("Content-Type"), "application/openmetrics-text")
+
+	body := rec.Body.String()
+	for _, line := range []string{
+		"# TYPE internal_selfstat_test_requests counter",
+		`internal_selfstat_test_requests_total{alias="",input="a"} 5`,
+		`internal_selfstat_test_requests_total{alias="bar",input="b"} 2`,
+		"# TYPE internal_selfstat_test_queue_size gauge",
+		`internal_selfstat_test_queue_size{input="a"} 7`,
+		"# TYPE internal_selfstat_test_pending_messages gauge",
+		`internal_selfstat_test_pending_messages{input="a"} 3`,
+		"# TYPE internal_selfstat_test_duration_seconds histogram",
+		`internal_selfstat_test_duration_seconds_bucket{input="a",le="0.1"} 1`,
+		`internal_selfstat_test_duration_seconds_bucket{input="a",le="1.0"} 2`,
+		`internal_selfstat_test_duration_seconds_bucket{input="a",le="+Inf"} 2`,
+		`internal_selfstat_test_duration_seconds_count{input="a"} 2`,
+		"# EOF",
+	} {
+		require.Contains(t, body, line)
+	}
+	require.NotContains(t, body, "internal_selfstat_test_pending_messages_total")
+}
@@ -0,0 +1,111 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/influxdata/telegraf/internal"
+)
+
+// startTracing sets up recording spans of the agent's own work, i.e. input
+// gathers, output flushes and written batches, if configured. The returned
+// function flushes the pending spans and must be called on shutdown.
+func (a *Agent) startTracing(ctx context.Context) (func(), error) {
+	cfg := a.Config.Agent
+	if cfg.TracingExporter == "" {
+		return func() {}, nil
+	}
+
+	ratio := cfg.TracingSampleRatio
+	if ratio == 0 {
+		ratio = 1
+	}
+	if ratio < 0 || ratio > 1 {
+		return nil, fmt.Errorf("invalid 'tracing_sample_ratio' %v, must be between 0 and 1", ratio)
+	}
+
+	var exporter sdktrace.SpanExporter
+	var file *os.File
+	switch cfg.TracingExporter {
+	case "file":
+		if cfg.TracingFile == "" {
+			return nil, errors.New("'tracing_file' required for the file exporter")
+		}
+		f, err := os.OpenFile(cfg.TracingFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+		if err != nil {
+			return nil, fmt.Errorf("opening tracing file failed: %w", err)
+		}
+		e, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		exporter, file = e, f
+	case "otlp":
+		if cfg.TracingEndpoint == "" {
+			return nil, errors.New("'tracing_endpoint' required for the otlp exporter")
+		}
+		e, err := otlptracegrpc.New(ctx, otlptracegrpc.WithEndpointURL(cfg.TracingEndpoint))
+		if err != nil {
+			return nil, fmt.Errorf("creating OTLP exporter failed: %w", err)
+		}
+		exporter = e
+	default:
+		return nil, fmt.Errorf("invalid 'tracing_exporter' %q", cfg.TracingExporter)
+	}
+
+	res := resource.NewSchemaless(
+		attribute.String("service.name", "telegraf"),
+		attribute.String("service.version", internal.Version),
+		attribute.String("host.name", cfg.Hostname),
+	)
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	a.spans = provider.Tracer("github.com/influxdata/telegraf/agent")
+	log.Printf("I! [agent] Recording traces using the %s exporter", cfg.TracingExporter)
+
+	shutdown := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := provider.Shutdown(ctx); err != nil {
+			log.Printf("E! [agent] Shutting down tracing failed: %v", err)
+		}
+		if file != nil {
+			file.Close()
+		}
+	}
+	return shutdown, nil
+}
+
+// startSpan starts a span if tracing is enabled and otherwise returns a
+// non-recording span
+func (a *Agent) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if a.spans == nil {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+	return a.spans.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan ends the span and marks it as failed with the given error
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,157 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+type recordedSpan struct {
+	Name        string
+	SpanContext struct {
+		SpanID string
+	}
+	Parent struct {
+		SpanID string
+	}
+	Attributes []struct {
+		Key   string
+		Value struct {
+			Value interface{}
+		}
+	}
+	Status struct {
+		Code string
+	}
+}
+
+func (s *recordedSpan) attribute(key string) interface{} {
+	for _, attr := range s.Attributes {
+		if attr.Key == key {
+			return attr.Value.Value
+		}
+	}
+	return nil
+}
+
+func TestTracingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.json")
+	cfg := config.NewConfig()
+	cfg.Agent.TracingExporter = "file"
+	cfg.Agent.TracingFile = path
+	a := NewAgent(cfg)
+
+	stop, err := a.startTracing(t.Context())
+	require.NoError(t, err)
+
+	input := models.NewRunningInput(&reloadTestInput{Value: 1}, &models.InputConfig{Name: "mock"})
+	ticker := NewRollingTicker(time.Minute, 0)
+	defer ticker.Stop()
+	require.NoError(t, a.gatherOnce(&testutil.Accumulator{}, input, ticker, time.Minute))
+
+	plugin := &spanOutput{err: errors.New("write failed")}
+	output := models.NewRunningOutput(plugin, &models.OutputConfig{Name: "spans"}, 10, 100)
+	require.NoError(t, a.connectOutput(t.Context(), output))
+	output.AddMetric(testutil.TestMetric(1))
+	output.AddMetric(testutil.TestMetric(2))
+	require.ErrorContains(t, a.flushOnce(output, ticker, output.WriteContext), "write failed")
+	stop()
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	spans := make(map[string]recordedSpan)
+	decoder := json.NewDecoder(f)
+	for {
+		var s recordedSpan
+		if err := decoder.Decode(&s); errors.Is(err, io.EOF) {
+			break
+		} else {
+			require.NoError(t, err)
+		}
+		spans[s.Name] = s
+	}
+	require.Len(t, spans, 3)
+
+	gather := spans["gather"]
+	require.Equal(t, "inputs.mock", gather.attribute("telegraf.input"))
+	require.Equal(t, "Unset", gather.Status.Code)
+
+	flush := spans["flush"]
+	require.Equal(t, "outputs.spans", flush.attribute("telegraf.output"))
+	require.Equal(t, "Error", flush.Status.Code)
+
+	batch := spans["write batch"]
+	require.Equal(t, flush.SpanContext.SpanID, batch.Parent.SpanID)
+	require.InDelta(t, 2, batch.attribute("telegraf.batch_size"), 0)
+	require.Equal(t, "Error", batch.Status.Code)
+}
+
+func TestTracingInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		agent    config.AgentConfig
+		expected string
+	}{
+		{
+			name:     "invalid exporter",
+			agent:    config.AgentConfig{TracingExporter: "foo"},
+			expected: `invalid 'tracing_exporter' "foo"`,
+		},
+		{
+			name:     "file without path",
+			agent:    config.AgentConfig{TracingExporter: "file"},
+			expected: "'tracing_file' required",
+		},
+		{
+			name:     "otlp without endpoint",
+			agent:    config.AgentConfig{TracingExporter: "otlp"},
+			expected: "'tracing_endpoint' required",
+		},
+		{
+			name:     "invalid ratio",
+			agent:    config.AgentConfig{TracingExporter: "otlp", TracingSampleRatio: 2},
+			expected: "invalid 'tracing_sample_ratio'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.NewConfig()
+			cfg.Agent = &tt.agent
+			_, err := NewAgent(cfg).startTracing(t.Context())
+			require.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
+
+type spanOutput struct {
+	err error
+}
+
+func (*spanOutput) SampleConfig() string {
+	return ""
+}
+
+func (*spanOutput) Connect() error {
+	return nil
+}
+
+func (*spanOutput) Close() error {
+	return nil
+}
+
+func (o *spanOutput) Write([]telegraf.Metric) error {
+	return o.err
+}
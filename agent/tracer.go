@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+)
+
+// tracer prints the path of the metrics through the pipeline in test mode.
+// Each line names the stage the metric just left, i.e. the input, processor
+// or aggregator, followed by the metric in line-protocol.
+type tracer struct {
+	sync.Mutex
+
+	w          io.Writer
+	serializer *influx.Serializer
+}
+
+func newTracer(w io.Writer) *tracer {
+	return &tracer{
+		w:          w,
+		serializer: &influx.Serializer{SortFields: true, UintSupport: true},
+	}
+}
+
+// trace prints the metric as leaving the given stage
+func (t *tracer) trace(stage string, m telegraf.Metric) {
+	t.Lock()
+	defer t.Unlock()
+
+	octets, err := t.serializer.Serialize(m)
+	if err != nil {
+		fmt.Fprintf(t.w, "~ %s: serializing metric failed: %v\n", stage, err)
+		return
+	}
+	fmt.Fprintf(t.w, "~ %s: %s", stage, octets)
+}
+
+// traceOutputs prints for each output whether its filter accepts the metric
+// and, if not, the rule dropping the metric
+func (t *tracer) traceOutputs(m telegraf.Metric, outputs []*models.RunningOutput) {
+	t.Lock()
+	defer t.Unlock()
+
+	for _, output := range outputs {
+		ok, reason, err := output.Config.Filter.Explain(m)
+		switch {
+		case err != nil:
+			fmt.Fprintf(t.w, "~ %s: filtering failed: %v\n", output.LogName(), err)
+		case ok:
+			fmt.Fprintf(t.w, "~ %s: accepted\n", output.LogName())
+		default:
+			fmt.Fprintf(t.w, "~ %s: dropped by %s\n", output.LogName(), reason)
+		}
+	}
+}
+
+// tracingMaker traces all metrics created by the wrapped plugin
+type tracingMaker struct {
+	MetricMaker
+	tracer *tracer
+}
+
+func (m *tracingMaker) MakeMetric(metric telegraf.Metric) telegraf.Metric {
+	metric = m.MetricMaker.MakeMetric(metric)
+	if metric != nil {
+		m.tracer.trace(m.LogName(), metric)
+	}
+	return metric
+}
+
+// newAccumulator creates an accumulator for the given plugin, tracing the
+// metrics created by the plugin if tracing is enabled
+func (a *Agent) newAccumulator(maker MetricMaker, metrics chan<- telegraf.Metric) telegraf.Accumulator {
+	if a.tracer != nil {
+		maker = &tracingMaker{MetricMaker: maker, tracer: a.tracer}
+	}
+	return NewAccumulator(maker, metrics)
+}
@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/models"
+)
+
+func TestTracerAccumulator(t *testing.T) {
+	var buf bytes.Buffer
+	a := NewAgent(config.NewConfig())
+	a.tracer = newTracer(&buf)
+
+	metrics := make(chan telegraf.Metric, 10)
+	defer close(metrics)
+	acc := a.newAccumulator(&TestMetricMaker{}, metrics)
+	acc.AddFields("cpu", map[string]interface{}{"value": 42}, map[string]string{"host": "localhost"}, time.Unix(0, 0))
+
+	require.Len(t, metrics, 1)
+	require.Equal(t, "~ TestPlugin: cpu,host=localhost value=42i 0\n", buf.String())
+}
+
+func TestTracerOutputs(t *testing.T) {
+	accepting := models.NewRunningOutput(&tracerOutput{}, &models.OutputConfig{
+		Name:   "accepting",
+		Filter: models.Filter{NamePass: []string{"cpu"}},
+	}, 1000, 10000)
+	dropping := models.NewRunningOutput(&tracerOutput{}, &models.OutputConfig{
+		Name: "dropping",
+		Filter: models.Filter{
+			TagDropFilters: []models.TagFilter{{Name: "host", Values: []string{"local*"}}},
+		},
+	}, 1000, 10000)
+	outputs := []*models.RunningOutput{accepting, dropping}
+	for _, output := range outputs {
+		require.NoError(t, output.Config.Filter.Compile())
+	}
+
+	var buf bytes.Buffer
+	m := metric.New("cpu", map[string]string{"host": "localhost"}, map[string]interface{}{"value": 42}, time.Unix(0, 0))
+	newTracer(&buf).traceOutputs(m, outputs)
+
+	expected := "~ outputs.accepting: accepted\n" +
+		"~ outputs.dropping: dropped by tagdrop host=[\"local*\"]\n"
+	require.Equal(t, expected, buf.String())
+}
+
+type tracerOutput struct{}
+
+func (*tracerOutput) SampleConfig() string {
+	return ""
+}
+
+func (*tracerOutput) Connect() error {
+	return nil
+}
+
+func (*tracerOutput) Close() error {
+	return nil
+}
+
+func (*tracerOutput) Write([]telegraf.Metric) error {
+	return nil
+}
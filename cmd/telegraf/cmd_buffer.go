@@ -0,0 +1,205 @@
+// Command handling for the write-ahead-log buffer "buffer" command
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+)
+
+func getBufferCommands(outputBuffer io.Writer) []*cli.Command {
+	directoryFlag := &cli.StringFlag{
+		Name:     "directory",
+		Usage:    "buffer directory as configured by 'buffer_directory' in the agent section",
+		Required: true,
+	}
+
+	return []*cli.Command{
+		{
+			Name:  "buffer",
+			Usage: "commands for inspecting, exporting and purging persisted WAL buffers",
+			Description: `
+The 'buffer' commands operate on the segments persisted by the 'wal' buffer
+strategy of an output. Purging refuses to modify the buffer of an output while
+it is in use by a running Telegraf instance.`,
+			Subcommands: []*cli.Command{
+				{
+					Name:  "inspect",
+					Usage: "print the segments of an output's buffer or list all buffered outputs",
+					Description: `
+To list the IDs of all outputs with a persisted buffer run
+
+> telegraf buffer inspect --directory /var/lib/telegraf/buffer
+
+To print the segments of a particular output run
+
+> telegraf buffer inspect --directory /var/lib/telegraf/buffer --id <output ID>
+`,
+					Flags: []cli.Flag{
+						directoryFlag,
+						&cli.StringFlag{
+							Name:  "id",
+							Usage: "ID of the output plugin",
+						},
+					},
+					Action: func(cCtx *cli.Context) error {
+						directory := cCtx.String("directory")
+						id := cCtx.String("id")
+						if id == "" {
+							return listBuffers(outputBuffer, directory)
+						}
+						path, err := bufferPath(directory, id)
+						if err != nil {
+							return err
+						}
+						return inspectBuffer(outputBuffer, path)
+					},
+				},
+				{
+					Name:  "export",
+					Usage: "print the unwritten metrics of an output's buffer in line-protocol",
+					Description: `
+Tracking metrics are skipped as they are discarded when restarting Telegraf.
+
+> telegraf buffer export --directory /var/lib/telegraf/buffer --id <output ID> > metrics.lp
+`,
+					Flags: []cli.Flag{
+						directoryFlag,
+						&cli.StringFlag{
+							Name:     "id",
+							Usage:    "ID of the output plugin",
+							Required: true,
+						},
+					},
+					Action: func(cCtx *cli.Context) error {
+						path, err := bufferPath(cCtx.String("directory"), cCtx.String("id"))
+						if err != nil {
+							return err
+						}
+
+						serializer := &influx.Serializer{}
+						if err := serializer.Init(); err != nil {
+							return err
+						}
+						return models.ExportWAL(path, func(m telegraf.Metric) error {
+							return serializer.Write(outputBuffer, m)
+						})
+					},
+				},
+				{
+					Name:  "purge",
+					Usage: "remove all metrics from an output's buffer",
+					Flags: []cli.Flag{
+						directoryFlag,
+						&cli.StringFlag{
+							Name:     "id",
+							Usage:    "ID of the output plugin",
+							Required: true,
+						},
+					},
+					Action: func(cCtx *cli.Context) error {
+						path, err := bufferPath(cCtx.String("directory"), cCtx.String("id"))
+						if err != nil {
+							return err
+						}
+						if err := models.PurgeWAL(path); err != nil {
+							if errors.Is(err, models.ErrWALLocked) {
+								return fmt.Errorf("buffer %q is in use, stop Telegraf before purging", cCtx.String("id"))
+							}
+							return err
+						}
+						fmt.Fprintf(outputBuffer, "Purged buffer %q\n", cCtx.String("id"))
+						return nil
+					},
+				},
+			},
+		},
+	}
+}
+
+func bufferPath(directory, id string) (string, error) {
+	if id != filepath.Base(id) {
+		return "", fmt.Errorf("invalid output ID %q", id)
+	}
+	path := filepath.Join(directory, id)
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("no buffer found for output ID %q", id)
+		}
+		return "", err
+	}
+	return path, nil
+}
+
+func listBuffers(w io.Writer, directory string) error {
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSEGMENTS\tRECORDS\tSIZE")
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		info, err := models.InspectWAL(filepath.Join(directory, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("inspecting buffer %q failed: %w", entry.Name(), err)
+		}
+		if len(info.Segments) == 0 {
+			continue
+		}
+		records, size := bufferTotals(info)
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\n", entry.Name(), len(info.Segments), records, size)
+	}
+	return tw.Flush()
+}
+
+func inspectBuffer(w io.Writer, path string) error {
+	info, err := models.InspectWAL(path)
+	if err != nil {
+		return err
+	}
+
+	records, size := bufferTotals(info)
+	fmt.Fprintf(w, "Cursor:   %d\n", info.Cursor)
+	fmt.Fprintf(w, "Segments: %d\n", len(info.Segments))
+	fmt.Fprintf(w, "Records:  %d\n", records)
+	fmt.Fprintf(w, "Size:     %d bytes\n\n", size)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tFIRST\tRECORDS\tSIZE\tCOMPRESSED\tMODIFIED")
+	for _, s := range info.Segments {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%t\t%s\n",
+			s.Filename, s.First, s.Records, s.Size, s.Compressed, s.Modified.Format(time.RFC3339))
+	}
+	return tw.Flush()
+}
+
+// bufferTotals returns the number of unacknowledged records and the total
+// size of all segments
+func bufferTotals(info *models.WALInfo) (records int, size int64) {
+	for _, s := range info.Segments {
+		size += s.Size
+		end := s.First + uint64(s.Records)
+		switch {
+		case end <= info.Cursor:
+		case s.First >= info.Cursor:
+			records += s.Records
+		default:
+			records += int(end - info.Cursor)
+		}
+	}
+	return records, size
+}
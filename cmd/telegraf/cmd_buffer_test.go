@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/models"
+)
+
+func TestBufferCommands(t *testing.T) {
+	directory := t.TempDir()
+
+	// Create a buffer with acknowledged and unacknowledged metrics
+	buf, err := models.NewBuffer("test", "abc", "", 0, "wal", directory, models.WALConfig{SegmentSize: 128})
+	require.NoError(t, err)
+	for i := range 5 {
+		buf.Add(metric.New("test", map[string]string{"id": "a"}, map[string]interface{}{"value": i}, time.Unix(int64(i), 0)))
+	}
+	tx := buf.BeginTransaction(2)
+	tx.AcceptAll()
+	buf.EndTransaction(tx)
+	require.NoError(t, buf.Close())
+
+	run := func(args ...string) (string, error) {
+		out := new(bytes.Buffer)
+		args = append([]string{os.Args[0], "buffer"}, args...)
+		err := runApp(args, out, NewMockServer(), NewMockConfig(out), NewMockTelegraf())
+		return strings.ReplaceAll(out.String(), "\r", ""), err
+	}
+
+	// List the buffered outputs
+	out, err := run("inspect", "--directory", directory)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 2)
+	require.Equal(t, []string{"ID", "SEGMENTS", "RECORDS", "SIZE"}, strings.Fields(lines[0]))
+	fields := strings.Fields(lines[1])
+	require.Equal(t, "abc", fields[0])
+	require.Equal(t, "3", fields[2])
+
+	// Inspect the segments of the output
+	out, err = run("inspect", "--directory", directory, "--id", "abc")
+	require.NoError(t, err)
+	require.Contains(t, out, "Cursor:   2\n")
+	require.Contains(t, out, "Records:  3\n")
+	require.Contains(t, out, "FILE")
+	require.Contains(t, out, ".wal")
+
+	// Export the unacknowledged metrics
+	out, err = run("export", "--directory", directory, "--id", "abc")
+	require.NoError(t, err)
+	expected := "test,id=a value=2i 2000000000\n" +
+		"test,id=a value=3i 3000000000\n" +
+		"test,id=a value=4i 4000000000\n"
+	require.Equal(t, expected, out)
+
+	// Purge the buffer
+	out, err = run("purge", "--directory", directory, "--id", "abc")
+	require.NoError(t, err)
+	require.Equal(t, "Purged buffer \"abc\"\n", out)
+	entries, err := os.ReadDir(filepath.Join(directory, "abc"))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "lock", entries[0].Name())
+
+	out, err = run("inspect", "--directory", directory)
+	require.NoError(t, err)
+	require.Equal(t, "ID  SEGMENTS  RECORDS  SIZE\n", out)
+}
+
+func TestBufferCommandsInvalidID(t *testing.T) {
+	directory := t.TempDir()
+
+	for _, cmd := range []string{"inspect", "export", "purge"} {
+		t.Run(cmd, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			args := []string{os.Args[0], "buffer", cmd, "--directory", directory, "--id", "../abc"}
+			err := runApp(args, out, NewMockServer(), NewMockConfig(out), NewMockTelegraf())
+			require.ErrorContains(t, err, `invalid output ID "../abc"`)
+
+			args = []string{os.Args[0], "buffer", cmd, "--directory", directory, "--id", "unknown"}
+			err = runApp(args, out, NewMockServer(), NewMockConfig(out), NewMockTelegraf())
+			require.ErrorContains(t, err, `no buffer found for output ID "unknown"`)
+		})
+	}
+}
+
+func TestBufferCommandsPurgeInUse(t *testing.T) {
+	directory := t.TempDir()
+
+	buf, err := models.NewBuffer("test", "abc", "", 0, "wal", directory, models.WALConfig{})
+	require.NoError(t, err)
+	buf.Add(metric.New("test", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0)))
+
+	out := new(bytes.Buffer)
+	args := []string{os.Args[0], "buffer", "purge", "--directory", directory, "--id", "abc"}
+	err = runApp(args, out, NewMockServer(), NewMockConfig(out), NewMockTelegraf())
+	require.ErrorContains(t, err, `buffer "abc" is in use`)
+	require.Equal(t, 1, buf.Len())
+
+	// Purging succeeds once the buffer is closed
+	require.NoError(t, buf.Close())
+	err = runApp(args, out, NewMockServer(), NewMockConfig(out), NewMockTelegraf())
+	require.NoError(t, err)
+}
@@ -9,6 +9,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 
 	"github.com/fatih/color"
 	"github.com/urfave/cli/v2"
@@ -41,15 +42,40 @@ func getConfigCommands(configHandlingFlags []cli.Flag, outputBuffer io.Writer) [
 		The 'check' command reads the configuration files specified via '--config' or
 		'--config-directory' and tries to initialize, but not start, the plugins.
 		Syntax and semantic errors detectable without starting the plugins will
-		be reported.
+		be reported. All issues are collected instead of stopping at the first
+		error, each with the file, line and plugin ID where possible. In addition,
+		the configuration is checked for inputs whose metrics are not accepted by
+		any output, filters that can never match, duplicate plugins and deprecated
+		plugins or options.
 		If no configuration file is	explicitly specified the command reads the
 		default locations and uses those configuration files.
 
 		To check the file 'mysettings.conf' use
 
 		> telegraf config check --config mysettings.conf
+
+		To additionally resolve all secrets and produce a SARIF report use
+
+		> telegraf config check --config mysettings.conf --resolve-secrets --format sarif
+
+		The command fails if any error is found or, with '--strict', if any
+		warning is found.
 		`,
-					Flags: configHandlingFlags,
+					Flags: append(slices.Clone(configHandlingFlags),
+						&cli.StringFlag{
+							Name:  "format",
+							Usage: "output format of the findings, available are 'text', 'json' and 'sarif'",
+							Value: "text",
+						},
+						&cli.BoolFlag{
+							Name:  "resolve-secrets",
+							Usage: "resolve all secrets against their secret-stores",
+						},
+						&cli.BoolFlag{
+							Name:  "strict",
+							Usage: "fail on warnings in addition to errors",
+						},
+					),
 					Action: func(cCtx *cli.Context) error {
 						// Setup logging
 						logConfig := &logger.Config{Debug: cCtx.Bool("debug")}
@@ -78,12 +104,12 @@ func getConfigCommands(configHandlingFlags []cli.Flag, outputBuffer io.Writer) [
 							configFiles = paths
 						}
 
-						// Load the config and try to initialize the plugins
+						// Load the config collecting all findings and try to
+						// initialize the plugins
 						c := config.NewConfig()
 						c.Agent.Quiet = cCtx.Bool("quiet")
-						if err := c.LoadAll(configFiles...); err != nil {
-							return err
-						}
+						findings := c.Check(configFiles, cCtx.Bool("resolve-secrets"))
+						defer c.CloseSecretStores(nil)
 
 						ag := agent.NewAgent(c)
 
@@ -96,7 +122,24 @@ func getConfigCommands(configHandlingFlags []cli.Flag, outputBuffer io.Writer) [
 							c.Agent.SkipProcessorsAfterAggregators = &skipProcessorsAfterAggregators
 						}
 
-						return ag.InitPlugins()
+						findings = append(findings, ag.CheckPlugins()...)
+
+						if err := printFindings(outputBuffer, cCtx.String("format"), findings); err != nil {
+							return err
+						}
+
+						var errs, warnings int
+						for _, f := range findings {
+							if f.Severity == config.SeverityError {
+								errs++
+							} else {
+								warnings++
+							}
+						}
+						if errs > 0 || (cCtx.Bool("strict") && warnings > 0) {
+							return fmt.Errorf("found %d error(s) and %d warning(s)", errs, warnings)
+						}
+						return nil
 					},
 				},
 				{
@@ -4,6 +4,7 @@ package main
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"sort"
 	"strings"
@@ -11,6 +12,8 @@ import (
 	"github.com/awnumar/memguard"
 	"github.com/urfave/cli/v2"
 	"golang.org/x/term"
+
+	"github.com/influxdata/telegraf"
 )
 
 func processFilterOnlySecretStoreFlags(ctx *cli.Context) Filters {
@@ -103,22 +106,10 @@ To also reveal the actual secret, i.e. the value, you can pass the
 							if err != nil {
 								return fmt.Errorf("unable to get secret-store %q: %w", storeID, err)
 							}
-							keys, err := store.List()
+							err = printSecrets(storeID, store, reveal)
+							closeSecretStore(store)
 							if err != nil {
-								return fmt.Errorf("unable to get secrets from store %q: %w", storeID, err)
-							}
-							sort.Strings(keys)
-
-							fmt.Printf("Known secrets for store %q:\n", storeID)
-							for _, k := range keys {
-								var v []byte
-								if reveal {
-									if v, err = store.Get(k); err != nil {
-										return fmt.Errorf("unable to get value of secret %q from store %q: %w", k, storeID, err)
-									}
-								}
-								fmt.Printf("    %-30s  %s\n", k, string(v))
-								memguard.WipeBytes(v)
+								return err
 							}
 						}
 
@@ -174,6 +165,7 @@ with the ID 'mystore'.
 						if err != nil {
 							return fmt.Errorf("unable to get secret-store: %w", err)
 						}
+						defer closeSecretStore(store)
 						value, err := store.Get(key)
 						if err != nil {
 							return fmt.Errorf("unable to get secret: %w", err)
@@ -248,6 +240,7 @@ you will be prompted to enter the value of the secret.
 						if err != nil {
 							return fmt.Errorf("unable to get secret-store: %w", err)
 						}
+						defer closeSecretStore(store)
 						if err := store.Set(key, value); err != nil {
 							return fmt.Errorf("unable to set secret: %w", err)
 						}
@@ -259,3 +252,33 @@ you will be prompted to enter the value of the secret.
 		},
 	}
 }
+
+// closeSecretStore stops the background tasks of stores supporting it
+func closeSecretStore(store telegraf.SecretStore) {
+	if closer, ok := store.(io.Closer); ok {
+		//nolint:errcheck // the command terminates anyway
+		closer.Close()
+	}
+}
+
+// printSecrets lists the keys of the given store and optionally their values
+func printSecrets(storeID string, store telegraf.SecretStore, reveal bool) error {
+	keys, err := store.List()
+	if err != nil {
+		return fmt.Errorf("unable to get secrets from store %q: %w", storeID, err)
+	}
+	sort.Strings(keys)
+
+	fmt.Printf("Known secrets for store %q:\n", storeID)
+	for _, k := range keys {
+		var v []byte
+		if reveal {
+			if v, err = store.Get(k); err != nil {
+				return fmt.Errorf("unable to get value of secret %q from store %q: %w", k, storeID, err)
+			}
+		}
+		fmt.Printf("    %-30s  %s\n", k, string(v))
+		memguard.WipeBytes(v)
+	}
+	return nil
+}
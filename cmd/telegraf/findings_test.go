@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+)
+
+func TestPrintFindings(t *testing.T) {
+	// Use a fixed version independent of the build flags
+	version := internal.Version
+	internal.Version = "1.2.3"
+	defer func() { internal.Version = version }()
+
+	findings := []config.Finding{
+		{
+			Rule:     config.RuleInit,
+			Severity: config.SeverityError,
+			Message:  "missing required field",
+			File:     "conf/telegraf.conf",
+			Line:     12,
+			Plugin:   "inputs.http",
+			PluginID: "abc",
+		},
+		{
+			Rule:     config.RuleDeprecatedOpts,
+			Severity: config.SeverityWarning,
+			Message:  `option "timeout" is deprecated`,
+			File:     "telegraf.conf",
+			Plugin:   "outputs.file",
+		},
+		{
+			Rule:     config.RuleInit,
+			Severity: config.SeverityError,
+			Message:  "no output found for dead-letter route",
+		},
+	}
+
+	for _, format := range []string{"text", "json", "sarif"} {
+		t.Run(format, func(t *testing.T) {
+			expected, err := os.ReadFile(filepath.Join("testdata", "findings", "expected."+format))
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			require.NoError(t, printFindings(&buf, format, findings))
+			require.Equal(t, string(expected), buf.String())
+		})
+	}
+}
+
+func TestPrintFindingsEmpty(t *testing.T) {
+	expected := map[string]string{
+		"text":  "No issues found\n",
+		"json":  "[]\n",
+		"sarif": `"results": []`,
+	}
+
+	for format, output := range expected {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, printFindings(&buf, format, nil))
+			require.Contains(t, buf.String(), output)
+		})
+	}
+}
+
+func TestPrintFindingsInvalidFormat(t *testing.T) {
+	var buf bytes.Buffer
+	require.EqualError(t, printFindings(&buf, "xml", nil), `invalid format "xml"`)
+}
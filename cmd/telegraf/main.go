@@ -237,6 +237,7 @@ func runApp(args []string, outputBuffer io.Writer, pprof Server, c TelegrafConfi
 			oldEnvBehavior:          cCtx.Bool("old-env-behavior"),
 			printPluginConfigSource: cCtx.Bool("print-plugin-config-source"),
 			test:                    cCtx.Bool("test"),
+			trace:                   cCtx.Bool("trace"),
 			debug:                   cCtx.Bool("debug"),
 			once:                    cCtx.Bool("once"),
 			quiet:                   cCtx.Bool("quiet"),
@@ -262,6 +263,7 @@ func runApp(args []string, outputBuffer io.Writer, pprof Server, c TelegrafConfi
 	)
 	commands = append(commands, getPluginCommands(outputBuffer)...)
 	commands = append(commands, getServiceCommands(outputBuffer)...)
+	commands = append(commands, getBufferCommands(outputBuffer)...)
 
 	app := &cli.App{
 		Name:   "Telegraf",
@@ -334,6 +336,11 @@ func runApp(args []string, outputBuffer io.Writer, pprof Server, c TelegrafConfi
 					Usage: "enable test mode: gather metrics, print them out, and exit. " +
 						"Note: Test mode only runs inputs, not processors, aggregators, or outputs",
 				},
+				&cli.BoolFlag{
+					Name: "trace",
+					Usage: "print the metrics leaving each input, processor and aggregator in test mode " +
+						"and whether the filters of each output accept them",
+				},
 				//
 				// Duration flags
 				&cli.DurationFlag{
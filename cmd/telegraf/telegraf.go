@@ -10,6 +10,7 @@ import (
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -33,6 +34,8 @@ import (
 
 var stop chan struct{}
 
+var errNoRunningAgent = errors.New("no running agent")
+
 type GlobalFlags struct {
 	config                  []string
 	configDir               []string
@@ -47,6 +50,7 @@ type GlobalFlags struct {
 	oldEnvBehavior          bool
 	printPluginConfigSource bool
 	test                    bool
+	trace                   bool
 	debug                   bool
 	once                    bool
 	quiet                   bool
@@ -81,6 +85,15 @@ type Telegraf struct {
 
 	cfg *config.Config
 
+	// agent is the agent currently running and accepting configuration
+	// reloads, nil if no agent is running or in test mode
+	agent   *agent.Agent
+	agentMu sync.Mutex
+
+	// signals receives the signals of the current run, also used for
+	// requesting configuration reloads from within the agent
+	signals chan os.Signal
+
 	GlobalFlags
 	WindowFlags
 }
@@ -111,10 +124,11 @@ func (t *Telegraf) Init(pprofErr <-chan error, f Filters, g GlobalFlags, w Windo
 }
 
 func (t *Telegraf) ListSecretStores() ([]string, error) {
-	c, err := t.loadConfiguration()
+	c, err := t.loadConfiguration(nil)
 	if err != nil {
 		return nil, err
 	}
+	defer c.CloseSecretStores(nil)
 
 	ids := make([]string, 0, len(c.SecretStores))
 	for k := range c.SecretStores {
@@ -125,12 +139,16 @@ func (t *Telegraf) ListSecretStores() ([]string, error) {
 
 func (t *Telegraf) GetSecretStore(id string) (telegraf.SecretStore, error) {
 	t.quiet = true
-	c, err := t.loadConfiguration()
+	c, err := t.loadConfiguration(nil)
 	if err != nil {
 		return nil, err
 	}
 
+	// Only keep the requested store open, the caller is responsible for
+	// closing it
 	store, found := c.SecretStores[id]
+	delete(c.SecretStores, id)
+	c.CloseSecretStores(nil)
 	if !found {
 		return nil, errors.New("unknown secret store")
 	}
@@ -146,73 +164,145 @@ func (t *Telegraf) reloadLoop() error {
 		reload <- false
 		ctx, cancel := context.WithCancel(context.Background())
 
+		// Configuration loaded during an incremental reload that requires a
+		// full restart. This avoids loading the configuration twice.
+		var preloaded *config.Config
+
 		signals := make(chan os.Signal, 1)
 		signal.Notify(signals, os.Interrupt, syscall.SIGHUP,
 			syscall.SIGTERM, syscall.SIGINT)
-		if t.watchConfig != "" {
-			for _, fConfig := range t.configFiles {
-				if isURL(fConfig) {
-					continue
-				}
-
-				if _, err := os.Stat(fConfig); err != nil {
-					log.Printf("W! Cannot watch config %s: %s", fConfig, err)
-				} else {
-					go t.watchLocalConfig(ctx, signals, fConfig)
-				}
-			}
-			for _, fConfigDirectory := range t.configDir {
-				if _, err := os.Stat(fConfigDirectory); err != nil {
-					log.Printf("W! Cannot watch config directory %s: %s", fConfigDirectory, err)
-				} else {
-					go t.watchLocalConfig(ctx, signals, fConfigDirectory)
-				}
-			}
-		}
-		if t.configURLWatchInterval > 0 {
-			remoteConfigs := make([]string, 0)
-			for _, fConfig := range t.configFiles {
-				if isURL(fConfig) {
-					remoteConfigs = append(remoteConfigs, fConfig)
-				}
-			}
-			if len(remoteConfigs) > 0 {
-				go t.watchRemoteConfigs(ctx, signals, t.configURLWatchInterval, remoteConfigs)
-			}
-		}
+		t.signals = signals
+		watchCtx, watchCancel := context.WithCancel(ctx)
+		t.watchConfigs(watchCtx, signals)
 		go func() {
-			select {
-			case sig := <-signals:
-				if sig == syscall.SIGHUP {
-					log.Println("I! Reloading Telegraf config")
-					// May need to update the list of known config files
-					// if a delete or create occured. That way on the reload
-					// we ensure we watch the correct files.
-					if err := t.getConfigFiles(); err != nil {
-						log.Println("E! Error loading config files: ", err)
+			for {
+				select {
+				case sig := <-signals:
+					if sig == syscall.SIGHUP {
+						log.Println("I! Reloading Telegraf config")
+						// May need to update the list of known config files
+						// if a delete or create occured. That way on the reload
+						// we ensure we watch the correct files.
+						if err := t.getConfigFiles(); err != nil {
+							log.Println("E! Error loading config files: ", err)
+						}
+
+						// Try to only restart the changed plugins and keep
+						// the agent running. Restart the watchers as they
+						// terminate after reporting a change.
+						watchCancel()
+						c, err := t.reloadAgent(ctx)
+						if err == nil {
+							watchCtx, watchCancel = context.WithCancel(ctx)
+							t.watchConfigs(watchCtx, signals)
+							continue
+						}
+						if errors.Is(err, agent.ErrRestartRequired) {
+							log.Printf("I! Restarting agent as %v", err)
+							preloaded = c
+						} else if !errors.Is(err, errNoRunningAgent) {
+							log.Printf("E! Reloading config incrementally failed, restarting agent: %v", err)
+						}
+
+						<-reload
+						reload <- true
 					}
-					<-reload
-					reload <- true
+					cancel()
+				case err := <-t.pprofErr:
+					log.Printf("E! pprof server failed: %v", err)
+					cancel()
+				case <-stop:
+					cancel()
 				}
-				cancel()
-			case err := <-t.pprofErr:
-				log.Printf("E! pprof server failed: %v", err)
-				cancel()
-			case <-stop:
-				cancel()
+				watchCancel()
+				return
 			}
 		}()
 
 		err := t.runAgent(ctx, reloadConfig)
+		if t.cfg != nil {
+			// Stop the secret-stores not used by the next run
+			t.cfg.CloseSecretStores(preloaded)
+		}
 		if err != nil && !errors.Is(err, context.Canceled) {
 			return fmt.Errorf("[telegraf] Error running agent: %w", err)
 		}
 		reloadConfig = true
+		if preloaded != nil {
+			t.cfg = preloaded
+			reloadConfig = false
+		}
 	}
 
 	return nil
 }
 
+// watchConfigs starts the watchers for the local and remote configuration
+// files if requested. The watchers send a SIGHUP on the signals channel when
+// detecting a change and terminate afterwards.
+func (t *Telegraf) watchConfigs(ctx context.Context, signals chan os.Signal) {
+	if t.watchConfig != "" {
+		for _, fConfig := range t.configFiles {
+			if isURL(fConfig) {
+				continue
+			}
+
+			if _, err := os.Stat(fConfig); err != nil {
+				log.Printf("W! Cannot watch config %s: %s", fConfig, err)
+			} else {
+				go t.watchLocalConfig(ctx, signals, fConfig)
+			}
+		}
+		for _, fConfigDirectory := range t.configDir {
+			if _, err := os.Stat(fConfigDirectory); err != nil {
+				log.Printf("W! Cannot watch config directory %s: %s", fConfigDirectory, err)
+			} else {
+				go t.watchLocalConfig(ctx, signals, fConfigDirectory)
+			}
+		}
+	}
+	if t.configURLWatchInterval > 0 {
+		remoteConfigs := make([]string, 0)
+		for _, fConfig := range t.configFiles {
+			if isURL(fConfig) {
+				remoteConfigs = append(remoteConfigs, fConfig)
+			}
+		}
+		if len(remoteConfigs) > 0 {
+			go t.watchRemoteConfigs(ctx, signals, t.configURLWatchInterval, remoteConfigs)
+		}
+	}
+}
+
+// reloadAgent loads the configuration and applies it to the running agent
+// restarting only the changed plugins. The loaded configuration is returned
+// to be reused for a full restart if the agent cannot apply the changes.
+func (t *Telegraf) reloadAgent(ctx context.Context) (*config.Config, error) {
+	t.agentMu.Lock()
+	ag := t.agent
+	t.agentMu.Unlock()
+	if ag == nil {
+		return nil, errNoRunningAgent
+	}
+
+	c, err := t.loadConfiguration(t.cfg)
+	if err != nil {
+		c.CloseSecretStores(t.cfg)
+		return nil, err
+	}
+
+	if err := ag.Reload(ctx, c); err != nil {
+		if errors.Is(err, agent.ErrRestartRequired) {
+			return c, err
+		}
+		c.CloseSecretStores(t.cfg)
+		return nil, err
+	}
+	t.cfg.CloseSecretStores(c)
+	t.cfg = c
+	return c, nil
+}
+
 func (t *Telegraf) watchLocalConfig(ctx context.Context, signals chan os.Signal, fConfig string) {
 	var mytomb tomb.Tomb
 	var watcher watch.FileWatcher
@@ -309,9 +399,14 @@ func (*Telegraf) watchRemoteConfigs(ctx context.Context, signals chan os.Signal,
 	}
 }
 
-func (t *Telegraf) loadConfiguration() (*config.Config, error) {
+// loadConfiguration loads the configuration files. If given, the secret-stores
+// of the running configuration are reused for unchanged stores.
+func (t *Telegraf) loadConfiguration(running *config.Config) (*config.Config, error) {
 	// If no other options are specified, load the config file and run.
 	c := config.NewConfig()
+	if running != nil {
+		c.ReuseSecretStores(running)
+	}
 	c.Agent.Quiet = t.quiet
 	c.Agent.ConfigURLRetryAttempts = t.configURLRetryAttempts
 	c.OutputFilters = t.outputFilters
@@ -356,9 +451,10 @@ func (t *Telegraf) runAgent(ctx context.Context, reloadConfig bool) error {
 	c := t.cfg
 	var err error
 	if reloadConfig {
-		if c, err = t.loadConfiguration(); err != nil {
+		if c, err = t.loadConfiguration(nil); err != nil {
 			return err
 		}
+		t.cfg = c
 	}
 
 	if !t.test && t.testWait == 0 && len(c.Outputs) == 0 {
@@ -388,6 +484,9 @@ func (t *Telegraf) runAgent(ctx context.Context, reloadConfig bool) error {
 		RotationMaxSize:         int64(c.Agent.LogfileRotationMaxSize),
 		RotationMaxArchives:     c.Agent.LogfileRotationMaxArchives,
 		LogWithTimezone:         c.Agent.LogWithTimezone,
+		RateLimit:               c.Agent.LogRateLimit,
+		RateBurst:               c.Agent.LogRateBurst,
+		DeduplicationWindow:     time.Duration(c.Agent.LogDeduplicationWindow),
 	}
 
 	if err := logger.SetupLogging(logConfig); err != nil {
@@ -459,6 +558,9 @@ func (t *Telegraf) runAgent(ctx context.Context, reloadConfig bool) error {
 
 	if t.test || t.testWait != 0 {
 		wait := time.Duration(t.testWait) * time.Second
+		if t.trace {
+			ag.Trace = os.Stdout
+		}
 		return ag.Test(ctx, wait)
 	}
 
@@ -483,6 +585,23 @@ func (t *Telegraf) runAgent(ctx context.Context, reloadConfig bool) error {
 		}
 	}
 
+	signals := t.signals
+	ag.RequestReload = func() {
+		select {
+		case signals <- syscall.SIGHUP:
+		default:
+		}
+	}
+
+	t.agentMu.Lock()
+	t.agent = ag
+	t.agentMu.Unlock()
+	defer func() {
+		t.agentMu.Lock()
+		t.agent = nil
+		t.agentMu.Unlock()
+	}()
+
 	return ag.Run(ctx)
 }
 
@@ -12,7 +12,7 @@ func (t *Telegraf) Run() error {
 	stop = make(chan struct{})
 	defer close(stop)
 
-	cfg, err := t.loadConfiguration()
+	cfg, err := t.loadConfiguration(nil)
 	if err != nil {
 		return err
 	}
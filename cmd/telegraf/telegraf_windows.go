@@ -83,7 +83,7 @@ func (t *Telegraf) Run() error {
 	}
 
 	// Load the configuration file(s)
-	cfg, err := t.loadConfiguration()
+	cfg, err := t.loadConfiguration(nil)
 	if err != nil {
 		return err
 	}
@@ -112,7 +112,7 @@ func (t *Telegraf) Execute(_ []string, r <-chan svc.ChangeRequest, changes chan<
 	defer svclog.Close()
 
 	// Load the configuration file(s)
-	cfg, err := t.loadConfiguration()
+	cfg, err := t.loadConfiguration(nil)
 	if err != nil {
 		if lerr := svclog.Error(100, err.Error()); lerr != nil {
 			log.Printf("E! Logging error %q failed: %s", err, lerr)
@@ -0,0 +1,464 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/toml/ast"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+// Severities of findings
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Rules reported by checking the configuration
+const (
+	RuleLoad           = "load"
+	RuleInit           = "init"
+	RuleSecret         = "secret"
+	RuleUnroutedInput  = "unrouted-input"
+	RuleNeverMatching  = "never-matching-filter"
+	RuleDuplicateID    = "duplicate-id"
+	RuleDeprecated     = "deprecated"
+	RuleDeprecatedOpts = "deprecated-option"
+)
+
+var lineRe = regexp.MustCompile(`line (\d+)`)
+
+// Finding is an issue detected when checking the configuration
+type Finding struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Plugin   string `json:"plugin,omitempty"`
+	PluginID string `json:"plugin_id,omitempty"`
+}
+
+func (f Finding) String() string {
+	var location string
+	if f.File != "" {
+		location = f.File
+		if f.Line > 0 {
+			location += ":" + strconv.Itoa(f.Line)
+		}
+		location += ": "
+	}
+
+	plugin := ""
+	if f.Plugin != "" {
+		plugin = " " + f.Plugin
+		if f.PluginID != "" {
+			plugin += " (" + f.PluginID + ")"
+		}
+	}
+	return fmt.Sprintf("%s%s [%s]%s: %s", location, f.Severity, f.Rule, plugin, f.Message)
+}
+
+// pluginSource describes the location and identity of a loaded plugin
+type pluginSource struct {
+	category string
+	name     string
+	source   string
+	line     int
+	id       string
+	plugin   interface{}
+	filter   *models.Filter
+
+	// shadow denotes the instance of a processor running after the
+	// aggregators sharing the configuration with the instance running before
+	shadow bool
+}
+
+func (p *pluginSource) finding(rule, severity, msg string) Finding {
+	return Finding{
+		Rule:     rule,
+		Severity: severity,
+		Message:  msg,
+		File:     p.source,
+		Line:     p.line,
+		Plugin:   p.category + "." + p.name,
+		PluginID: p.id,
+	}
+}
+
+// Check loads the given configuration files and collects all findings
+// instead of stopping at the first error. Plugins failing to load are skipped
+// and the remaining configuration is checked for secrets referencing unknown
+// stores and linted. If resolveSecrets is set, all secrets are resolved
+// against their stores. Plugins are not initialized.
+func (c *Config) Check(files []string, resolveSecrets bool) []Finding {
+	c.checking = true
+	defer func() { c.checking = false }()
+
+	for _, fn := range files {
+		if err := c.LoadConfig(fn); err != nil {
+			finding := Finding{
+				Rule:     RuleLoad,
+				Severity: SeverityError,
+				Message:  err.Error(),
+				File:     fn,
+			}
+			if match := lineRe.FindStringSubmatch(err.Error()); match != nil {
+				finding.Line, _ = strconv.Atoi(match[1])
+			}
+			c.findings = append(c.findings, finding)
+		}
+	}
+
+	// Finish loading in the same way as LoadAll
+	sort.Stable(c.Processors)
+	sort.Stable(c.AggProcessors)
+	if c.Agent.SnmpTranslator == "" {
+		c.Agent.SnmpTranslator = "netsnmp"
+	}
+	if count := secretCount.Load(); count > 0 {
+		c.NumberSecrets = uint64(count)
+	}
+
+	plugins := c.pluginSources()
+	c.checkSecrets(plugins, resolveSecrets)
+	c.lintDuplicateIDs(plugins)
+	c.lintDeprecations(plugins)
+	c.lintFilters(plugins)
+	c.lintUnroutedInputs()
+
+	return c.findings
+}
+
+// collectPluginFindings records the error and unused fields of a plugin when
+// checking the configuration and resets the error state to continue loading
+// the remaining plugins. It returns false if not checking.
+func (c *Config) collectPluginFindings(category, name, source string, tbl *ast.Table, err error) bool {
+	if !c.checking {
+		return false
+	}
+
+	p := &pluginSource{category: category, name: name, source: source, line: tbl.Line}
+	p.id, _ = generatePluginID(category+"."+name, tbl)
+	if err != nil {
+		c.findings = append(c.findings, p.finding(RuleLoad, SeverityError, err.Error()))
+	}
+	if len(c.UnusedFields) > 0 {
+		msg := fmt.Sprintf("configuration specified the fields %q, but they were not used. "+
+			"This is either a typo or this config option does not exist in this version.", keys(c.UnusedFields))
+		c.findings = append(c.findings, p.finding(RuleLoad, SeverityError, msg))
+		c.UnusedFields = make(map[string]bool)
+	}
+	c.errs = nil
+
+	return true
+}
+
+// pluginSources returns the loaded plugins together with their location
+func (c *Config) pluginSources() []*pluginSource {
+	plugins := make([]*pluginSource, 0, len(c.Inputs)+len(c.Processors)+len(c.AggProcessors)+len(c.Aggregators)+len(c.Outputs))
+	for _, input := range c.Inputs {
+		plugins = append(plugins, &pluginSource{
+			category: "inputs",
+			name:     input.Config.Name,
+			source:   input.Config.Source,
+			line:     input.Config.Line,
+			id:       input.Config.ID,
+			plugin:   input.Input,
+			filter:   &input.Config.Filter,
+		})
+	}
+	// Processors are instantiated twice, before and after the aggregators,
+	// sharing the same configuration
+	for i, processor := range slices.Concat(c.Processors, c.AggProcessors) {
+		var plugin interface{} = processor.Processor
+		if p, ok := processor.Processor.(processors.HasUnwrap); ok {
+			plugin = p.Unwrap()
+		}
+		plugins = append(plugins, &pluginSource{
+			category: "processors",
+			name:     processor.Config.Name,
+			source:   processor.Config.Source,
+			line:     processor.Config.Line,
+			id:       processor.Config.ID,
+			plugin:   plugin,
+			filter:   &processor.Config.Filter,
+			shadow:   i >= len(c.Processors),
+		})
+	}
+	for _, aggregator := range c.Aggregators {
+		plugins = append(plugins, &pluginSource{
+			category: "aggregators",
+			name:     aggregator.Config.Name,
+			source:   aggregator.Config.Source,
+			line:     aggregator.Config.Line,
+			id:       aggregator.Config.ID,
+			plugin:   aggregator.Aggregator,
+			filter:   &aggregator.Config.Filter,
+		})
+	}
+	for _, output := range c.Outputs {
+		plugins = append(plugins, &pluginSource{
+			category: "outputs",
+			name:     output.Config.Name,
+			source:   output.Config.Source,
+			line:     output.Config.Line,
+			id:       output.Config.ID,
+			plugin:   output.Output,
+			filter:   &output.Config.Filter,
+		})
+	}
+	return plugins
+}
+
+// checkSecrets links the secrets of all plugins to their stores and
+// optionally resolves them
+func (c *Config) checkSecrets(plugins []*pluginSource, resolve bool) {
+	secretType := reflect.TypeOf(Secret{})
+	for _, p := range plugins {
+		walkPluginStruct(reflect.ValueOf(p.plugin), func(field reflect.StructField, value reflect.Value) {
+			if value.Type() != secretType || !value.CanAddr() {
+				return
+			}
+			s := value.Addr().Interface().(*Secret)
+
+			if len(s.GetUnlinked()) > 0 {
+				if err := c.linkSecret(s); err != nil {
+					c.secretFinding(p, field, err)
+					return
+				}
+			}
+
+			if resolve {
+				buf, err := s.Get()
+				if err != nil {
+					c.secretFinding(p, field, err)
+					return
+				}
+				buf.Destroy()
+			}
+		})
+	}
+}
+
+func (c *Config) secretFinding(p *pluginSource, field reflect.StructField, err error) {
+	if p.shadow {
+		return
+	}
+	msg := fmt.Sprintf("option %q: %v", optionName(field), err)
+	c.findings = append(c.findings, p.finding(RuleSecret, SeverityError, msg))
+}
+
+// lintDuplicateIDs reports plugins with identical settings producing
+// duplicate data
+func (c *Config) lintDuplicateIDs(plugins []*pluginSource) {
+	first := make(map[string]*pluginSource, len(plugins))
+	for _, p := range plugins {
+		if p.shadow || p.id == "" {
+			continue
+		}
+		key := p.category + "." + p.name + ":" + p.id
+		if prev, found := first[key]; found {
+			msg := fmt.Sprintf("plugin has the same ID as the plugin defined in %s:%d", prev.source, prev.line)
+			c.findings = append(c.findings, p.finding(RuleDuplicateID, SeverityWarning, msg))
+			continue
+		}
+		first[key] = p
+	}
+}
+
+// lintDeprecations reports deprecated plugins and options
+func (c *Config) lintDeprecations(plugins []*pluginSource) {
+	for _, p := range plugins {
+		if p.shadow {
+			continue
+		}
+		info := c.collectDeprecationInfo(p.category, p.name, p.plugin, false)
+		if info.logLevel != telegraf.None {
+			msg := fmt.Sprintf("plugin is deprecated since %s", info.info.Since)
+			if info.info.Notice != "" {
+				msg += ": " + info.info.Notice
+			}
+			c.findings = append(c.findings, p.finding(RuleDeprecated, severityOf(info.logLevel), msg))
+		}
+		for _, option := range info.Options {
+			if option.logLevel == telegraf.None {
+				continue
+			}
+			msg := fmt.Sprintf("option %q is deprecated since %s", option.Name, option.info.Since)
+			if option.info.Notice != "" {
+				msg += ": " + option.info.Notice
+			}
+			c.findings = append(c.findings, p.finding(RuleDeprecatedOpts, severityOf(option.logLevel), msg))
+		}
+	}
+}
+
+// lintFilters reports filters never passing any metric, i.e. all names
+// accepted by the pass-filter are rejected by the drop-filter
+func (c *Config) lintFilters(plugins []*pluginSource) {
+	for _, p := range plugins {
+		if p.shadow {
+			continue
+		}
+		f := p.filter
+		if blocked(f.NamePass, f.NameDrop) {
+			msg := fmt.Sprintf("all names of 'namepass' %q are dropped by 'namedrop' %q", f.NamePass, f.NameDrop)
+			c.findings = append(c.findings, p.finding(RuleNeverMatching, SeverityWarning, msg))
+		}
+		if blocked(f.FieldInclude, f.FieldExclude) {
+			msg := fmt.Sprintf("all fields of 'fieldinclude' %q are removed by 'fieldexclude' %q", f.FieldInclude, f.FieldExclude)
+			c.findings = append(c.findings, p.finding(RuleNeverMatching, SeverityWarning, msg))
+		}
+
+		// Tag-pass filters match if any of the tags matches so the filter
+		// only never matches if all of the tags are dropped
+		if len(f.TagPassFilters) == 0 {
+			continue
+		}
+		never := true
+		for _, pass := range f.TagPassFilters {
+			idx := slices.IndexFunc(f.TagDropFilters, func(drop models.TagFilter) bool { return drop.Name == pass.Name })
+			if idx < 0 || !blocked(pass.Values, f.TagDropFilters[idx].Values) {
+				never = false
+				break
+			}
+		}
+		if never {
+			msg := "all tag values of 'tagpass' are dropped by 'tagdrop'"
+			c.findings = append(c.findings, p.finding(RuleNeverMatching, SeverityWarning, msg))
+		}
+	}
+}
+
+// lintUnroutedInputs reports inputs whose metrics are not accepted by any
+// output due to the output's filters. As processors and aggregators might
+// modify metrics, the check is only performed without those plugins. Names
+// and tags unknown before gathering are assumed to pass the filters.
+func (c *Config) lintUnroutedInputs() {
+	if len(c.Outputs) == 0 || len(c.Processors) > 0 || len(c.Aggregators) > 0 {
+		return
+	}
+
+	for _, input := range c.Inputs {
+		// The metric name is only known if overridden, otherwise the plugin
+		// might produce arbitrary names
+		name := input.Config.NameOverride
+		nameKnown := name != ""
+		if nameKnown {
+			name = input.Config.MeasurementPrefix + name + input.Config.MeasurementSuffix
+		}
+		tags := make(map[string]string, len(c.Tags)+len(input.Config.Tags))
+		for k, v := range c.Tags {
+			tags[k] = v
+		}
+		for k, v := range input.Config.Tags {
+			tags[k] = v
+		}
+		probe := metric.New(name, tags, map[string]interface{}{"value": 0}, time.Time{})
+
+		routed := false
+		for _, output := range c.Outputs {
+			if staticFilter(output.Config.Filter, nameKnown, tags).selects(probe) {
+				routed = true
+				break
+			}
+		}
+		if !routed {
+			p := &pluginSource{
+				category: "inputs",
+				name:     input.Config.Name,
+				source:   input.Config.Source,
+				line:     input.Config.Line,
+				id:       input.Config.ID,
+			}
+			msg := "metrics of the input are not accepted by any output due to the output filters"
+			c.findings = append(c.findings, p.finding(RuleUnroutedInput, SeverityWarning, msg))
+		}
+	}
+}
+
+type probeFilter struct {
+	models.Filter
+}
+
+// staticFilter returns a copy of the filter only containing the selection
+// criteria decidable with the given static name and tags
+func staticFilter(f models.Filter, nameKnown bool, tags map[string]string) *probeFilter {
+	pf := &probeFilter{Filter: models.Filter{
+		NamePass:           f.NamePass,
+		NamePassSeparators: f.NamePassSeparators,
+		NameDrop:           f.NameDrop,
+		NameDropSeparators: f.NameDropSeparators,
+	}}
+	if !nameKnown {
+		pf.NamePass = nil
+		pf.NameDrop = nil
+	}
+
+	// Any unknown tag might match the tag-pass filter
+	for _, tf := range f.TagPassFilters {
+		if _, found := tags[tf.Name]; !found {
+			pf.TagPassFilters = nil
+			break
+		}
+		pf.TagPassFilters = append(pf.TagPassFilters, models.TagFilter{Name: tf.Name, Values: tf.Values})
+	}
+	for _, tf := range f.TagDropFilters {
+		if _, found := tags[tf.Name]; found {
+			pf.TagDropFilters = append(pf.TagDropFilters, models.TagFilter{Name: tf.Name, Values: tf.Values})
+		}
+	}
+	return pf
+}
+
+func (pf *probeFilter) selects(m telegraf.Metric) bool {
+	if err := pf.Compile(); err != nil {
+		return true
+	}
+	ok, err := pf.Select(m)
+	return ok || err != nil
+}
+
+// blocked returns true if all literal patterns of the pass list are matched
+// by the drop list. Pass lists containing wildcards are assumed to match.
+func blocked(pass, drop []string) bool {
+	if len(pass) == 0 || len(drop) == 0 {
+		return false
+	}
+	dropFilter, err := filter.Compile(drop)
+	if err != nil {
+		return false
+	}
+	for _, p := range pass {
+		if strings.ContainsAny(p, "*?[") || !dropFilter.Match(p) {
+			return false
+		}
+	}
+	return true
+}
+
+func optionName(field reflect.StructField) string {
+	if name := field.Tag.Get("toml"); name != "" {
+		return name
+	}
+	return field.Name
+}
+
+func severityOf(level telegraf.LogLevel) string {
+	if level == telegraf.Error {
+		return SeverityError
+	}
+	return SeverityWarning
+}
@@ -14,6 +14,7 @@ import (
 	"reflect"
 	"regexp"
 	"runtime"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -37,6 +38,7 @@ import (
 	"github.com/influxdata/telegraf/plugins/processors"
 	"github.com/influxdata/telegraf/plugins/secretstores"
 	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/influxdata/telegraf/selfstat"
 )
 
 var (
@@ -81,6 +83,16 @@ type Config struct {
 
 	SecretStores      map[string]telegraf.SecretStore
 	secretStoreSource map[string][]string
+	secretStoreIDs    map[string]string
+
+	// secretRefs holds the linked secrets by their dynamic secret-store
+	// references for notifying about changes
+	secretRefs   map[string][]*Secret
+	secretRefsMu sync.Mutex
+
+	// running is the configuration of the running agent providing the
+	// secret-stores to reuse when reloading
+	running *Config
 
 	Agent       *AgentConfig
 	Inputs      []*models.RunningInput
@@ -103,6 +115,11 @@ type Config struct {
 
 	seenAgentTable     bool
 	seenAgentTableOnce sync.Once
+
+	// checking denotes collecting all findings instead of stopping at the
+	// first error when loading the configuration
+	checking bool
+	findings []Finding
 }
 
 // Ordered plugins used to keep the order in which they appear in a file
@@ -139,6 +156,8 @@ func NewConfig() *Config {
 		AggProcessors:      make([]*models.RunningProcessor, 0),
 		SecretStores:       make(map[string]telegraf.SecretStore),
 		secretStoreSource:  make(map[string][]string),
+		secretStoreIDs:     make(map[string]string),
+		secretRefs:         make(map[string][]*Secret),
 		fileProcessors:     make([]*OrderedPlugin, 0),
 		fileAggProcessors:  make([]*OrderedPlugin, 0),
 		InputFilters:       make([]string, 0),
@@ -262,6 +281,23 @@ type AgentConfig struct {
 	// Pick a timezone to use when logging or type 'local' for local time.
 	LogWithTimezone string `toml:"log_with_timezone"`
 
+	// Maximum number of messages per second logged by each plugin. Messages
+	// exceeding the limit are suppressed. When set to 0 no rate-limiting is
+	// performed.
+	LogRateLimit float64 `toml:"log_rate_limit"`
+
+	// Number of messages a plugin may log in a burst exceeding the rate-limit.
+	// Defaults to the rate-limit.
+	LogRateBurst int `toml:"log_rate_burst"`
+
+	// Identical messages of a plugin within the window are collapsed into a
+	// single summary. When set to 0 no deduplication is performed.
+	LogDeduplicationWindow Duration `toml:"log_deduplication_window"`
+
+	// File containing temporary log-level overrides of plugins, applied when
+	// receiving SIGUSR2.
+	LogLevelFile string `toml:"log_level_file"`
+
 	Hostname     string
 	OmitHostname bool
 
@@ -275,6 +311,11 @@ type AgentConfig struct {
 	// the state in the file will be restored for the plugins.
 	Statefile string `toml:"statefile"`
 
+	// Interval for periodically writing the state of plugins to the statefile
+	// in addition to termination. This limits the loss of states in case
+	// Telegraf is killed or crashes. Disabled if zero.
+	StatefileInterval Duration `toml:"statefile_interval"`
+
 	// Flag to always keep tags explicitly defined in the plugin itself and
 	// ensure those tags always pass filtering.
 	AlwaysIncludeLocalTags bool `toml:"always_include_local_tags"`
@@ -293,12 +334,66 @@ type AgentConfig struct {
 	ConfigURLRetryAttempts int `toml:"config_url_retry_attempts"`
 
 	// BufferStrategy is the metric buffer type to use for a given output plugin.
-	// Supported types currently are "memory" and "disk".
+	// Supported types currently are "memory", "disk", "wal" and "shared".
 	BufferStrategy string `toml:"buffer_strategy"`
 
 	// BufferDirectory is the directory to store buffer files for serialized
-	// to disk metrics when using the "disk" buffer strategy.
+	// to disk metrics when using the "disk" or "wal" buffer strategy.
 	BufferDirectory string `toml:"buffer_directory"`
+
+	// BufferWALMaxSize and BufferWALMaxAge limit the size and age of the
+	// segments kept by the "wal" buffer strategy. The oldest segments are
+	// dropped when exceeding the limits.
+	BufferWALMaxSize Size     `toml:"buffer_wal_max_size"`
+	BufferWALMaxAge  Duration `toml:"buffer_wal_max_age"`
+
+	// BufferWALSegmentSize is the size after which the "wal" buffer strategy
+	// starts a new segment.
+	BufferWALSegmentSize Size `toml:"buffer_wal_segment_size"`
+
+	// BufferWALCompression is the compression algorithm for completed segments
+	// of the "wal" buffer strategy. Supported are "none" and "zstd".
+	BufferWALCompression string `toml:"buffer_wal_compression"`
+
+	// ControlAddress is the address to serve the HTTP control API on, e.g.
+	// "localhost:8089". The control API is disabled if empty.
+	ControlAddress string `toml:"control_address"`
+
+	// ControlToken is the bearer token required for accessing the control API.
+	ControlToken Secret `toml:"control_token"`
+
+	// TLS certificate and key of the control API as well as the CAs for
+	// verifying client certificates.
+	ControlTLSCert           string   `toml:"control_tls_cert"`
+	ControlTLSKey            string   `toml:"control_tls_key"`
+	ControlTLSAllowedCACerts []string `toml:"control_tls_allowed_cacerts"`
+
+	// SelfstatAddress is the address to serve the internal statistics on in
+	// Prometheus and OpenMetrics format, e.g. "localhost:9274". Serving the
+	// statistics is disabled if empty.
+	SelfstatAddress string `toml:"selfstat_address"`
+
+	// SelfstatDurationBuckets are the upper bounds, in seconds, of the
+	// buckets of the gather, write, processing and serialization duration
+	// histograms.
+	SelfstatDurationBuckets []float64 `toml:"selfstat_duration_buckets"`
+
+	// TracingExporter is the exporter for spans of the agent's own work, i.e.
+	// input gathers, output flushes and written batches. Supported are "file"
+	// and "otlp". Tracing is disabled if empty.
+	TracingExporter string `toml:"tracing_exporter"`
+
+	// TracingFile is the file the "file" exporter appends the spans to as
+	// JSON.
+	TracingFile string `toml:"tracing_file"`
+
+	// TracingEndpoint is the URL of the OTLP gRPC endpoint for the "otlp"
+	// exporter, e.g. "http://localhost:4317".
+	TracingEndpoint string `toml:"tracing_endpoint"`
+
+	// TracingSampleRatio is the ratio of recorded traces up to 1.
+	// All traces are recorded if unset.
+	TracingSampleRatio float64 `toml:"tracing_sample_ratio"`
 }
 
 // InputNames returns a list of strings of the configured inputs.
@@ -653,6 +748,21 @@ func (c *Config) LoadConfigData(data []byte, path string) error {
 		})
 	}
 
+	switch c.Agent.BufferWALCompression {
+	case "", "none", "zstd":
+	default:
+		return fmt.Errorf("invalid 'buffer_wal_compression' %q", c.Agent.BufferWALCompression)
+	}
+
+	// Histograms of plugin durations are registered when creating the plugins
+	// so the buckets must be known before
+	for _, upper := range c.Agent.SelfstatDurationBuckets {
+		if upper <= 0 {
+			return fmt.Errorf("invalid 'selfstat_duration_buckets' value %v, must be positive", upper)
+		}
+	}
+	selfstat.SetDurationBuckets(c.Agent.SelfstatDurationBuckets)
+
 	// Set up the persister if requested
 	if c.Agent.Statefile != "" {
 		c.Persister = &persister.Persister{
@@ -685,12 +795,20 @@ func (c *Config) LoadConfigData(data []byte, path string) error {
 				switch pluginSubTable := pluginVal.(type) {
 				// legacy [outputs.influxdb] support
 				case *ast.Table:
-					if err = c.addOutput(pluginName, path, pluginSubTable); err != nil {
+					err = c.addOutput(pluginName, path, pluginSubTable)
+					if c.collectPluginFindings("outputs", pluginName, path, pluginSubTable, err) {
+						continue
+					}
+					if err != nil {
 						return fmt.Errorf("error parsing %s, %w", pluginName, err)
 					}
 				case []*ast.Table:
 					for _, t := range pluginSubTable {
-						if err = c.addOutput(pluginName, path, t); err != nil {
+						err = c.addOutput(pluginName, path, t)
+						if c.collectPluginFindings("outputs", pluginName, path, t, err) {
+							continue
+						}
+						if err != nil {
 							return fmt.Errorf("error parsing %s array, %w", pluginName, err)
 						}
 					}
@@ -710,12 +828,20 @@ func (c *Config) LoadConfigData(data []byte, path string) error {
 				switch pluginSubTable := pluginVal.(type) {
 				// legacy [inputs.cpu] support
 				case *ast.Table:
-					if err = c.addInput(pluginName, path, pluginSubTable); err != nil {
+					err = c.addInput(pluginName, path, pluginSubTable)
+					if c.collectPluginFindings("inputs", pluginName, path, pluginSubTable, err) {
+						continue
+					}
+					if err != nil {
 						return fmt.Errorf("error parsing %s, %w", pluginName, err)
 					}
 				case []*ast.Table:
 					for _, t := range pluginSubTable {
-						if err = c.addInput(pluginName, path, t); err != nil {
+						err = c.addInput(pluginName, path, t)
+						if c.collectPluginFindings("inputs", pluginName, path, t, err) {
+							continue
+						}
+						if err != nil {
 							return fmt.Errorf("error parsing %s, %w", pluginName, err)
 						}
 					}
@@ -735,7 +861,11 @@ func (c *Config) LoadConfigData(data []byte, path string) error {
 				switch pluginSubTable := pluginVal.(type) {
 				case []*ast.Table:
 					for _, t := range pluginSubTable {
-						if err = c.addProcessor(pluginName, path, t); err != nil {
+						err = c.addProcessor(pluginName, path, t)
+						if c.collectPluginFindings("processors", pluginName, path, t, err) {
+							continue
+						}
+						if err != nil {
 							return fmt.Errorf("error parsing %s, %w", pluginName, err)
 						}
 					}
@@ -759,7 +889,11 @@ func (c *Config) LoadConfigData(data []byte, path string) error {
 				switch pluginSubTable := pluginVal.(type) {
 				case []*ast.Table:
 					for _, t := range pluginSubTable {
-						if err = c.addAggregator(pluginName, path, t); err != nil {
+						err = c.addAggregator(pluginName, path, t)
+						if c.collectPluginFindings("aggregators", pluginName, path, t, err) {
+							continue
+						}
+						if err != nil {
 							return fmt.Errorf("error parsing %s, %w", pluginName, err)
 						}
 					}
@@ -779,7 +913,11 @@ func (c *Config) LoadConfigData(data []byte, path string) error {
 				switch pluginSubTable := pluginVal.(type) {
 				case []*ast.Table:
 					for _, t := range pluginSubTable {
-						if err = c.addSecretStore(pluginName, path, t); err != nil {
+						err = c.addSecretStore(pluginName, path, t)
+						if c.collectPluginFindings("secretstores", pluginName, path, t, err) {
+							continue
+						}
+						if err != nil {
 							return fmt.Errorf("error parsing %s, %w", pluginName, err)
 						}
 					}
@@ -796,7 +934,11 @@ func (c *Config) LoadConfigData(data []byte, path string) error {
 		// Assume it's an input for legacy config file support if no other
 		// identifiers are present
 		default:
-			if err = c.addInput(name, path, subTable); err != nil {
+			err = c.addInput(name, path, subTable)
+			if c.collectPluginFindings("inputs", name, path, subTable, err) {
+				continue
+			}
+			if err != nil {
 				return fmt.Errorf("error parsing %s, %w", name, err)
 			}
 		}
@@ -980,6 +1122,26 @@ func (c *Config) addSecretStore(name, source string, table *ast.Table) error {
 		return fmt.Errorf("invalid secret-store ID %q, must only contain letters, numbers or underscore", storeID)
 	}
 
+	// Keep a hash of the store's settings to detect changes on reload
+	hash, err := generatePluginID("secretstores."+name, table)
+	if err != nil {
+		return err
+	}
+
+	if _, found := c.SecretStores[storeID]; found {
+		return fmt.Errorf("duplicate ID %q for secretstore %q", storeID, name)
+	}
+
+	// Use the instance of the running configuration for unchanged stores
+	if c.running != nil && c.running.secretStoreIDs[storeID] == hash {
+		if store, found := c.running.SecretStores[storeID]; found {
+			c.SecretStores[storeID] = store
+			c.secretStoreIDs[storeID] = hash
+			c.secretStoreSource[name] = append(c.secretStoreSource[name], source)
+			return nil
+		}
+	}
+
 	creator, ok := secretstores.SecretStores[name]
 	if !ok {
 		// Handle removed, deprecated plugins
@@ -1006,39 +1168,193 @@ func (c *Config) addSecretStore(name, source string, table *ast.Table) error {
 		return fmt.Errorf("error initializing secret-store %q: %w", storeID, err)
 	}
 
-	if _, found := c.SecretStores[storeID]; found {
-		return fmt.Errorf("duplicate ID %q for secretstore %q", storeID, name)
-	}
 	c.SecretStores[storeID] = store
-	if _, found := c.secretStoreSource[name]; !found {
-		c.secretStoreSource[name] = make([]string, 0)
-	}
+	c.secretStoreIDs[storeID] = hash
 	c.secretStoreSource[name] = append(c.secretStoreSource[name], source)
 	return nil
 }
 
+// ReuseSecretStores makes the configuration use the instances of the given
+// running configuration for secret-stores with unchanged settings instead of
+// creating new ones. This way reloading the configuration neither runs a
+// store twice nor stops the change notifications for the running plugins.
+func (c *Config) ReuseSecretStores(running *Config) {
+	c.running = running
+}
+
+// CloseSecretStores closes the secret-stores of the configuration supporting
+// it, e.g. to stop background tasks. Stores reused by the given configuration,
+// usually the one replacing this configuration on reload, are kept open. Pass
+// nil to close all stores.
+func (c *Config) CloseSecretStores(next *Config) {
+	for storeID, store := range c.SecretStores {
+		if next != nil && next.SecretStores[storeID] == store {
+			continue
+		}
+		closer, ok := store.(io.Closer)
+		if !ok {
+			continue
+		}
+		if err := closer.Close(); err != nil {
+			log.Printf("E! [secretstores.%s] Closing secret-store failed: %v", storeID, err)
+		}
+	}
+}
+
 func (c *Config) LinkSecrets() error {
+	// Release the running configuration after taking over the secret
+	// references to not keep all previous configurations alive
+	defer func() { c.running = nil }()
+
+	for storeID, store := range c.SecretStores {
+		if c.running != nil && c.running.SecretStores[storeID] == store {
+			// Keep notifying the secrets of the running plugins
+			prefix := "@{" + storeID + ":"
+			c.running.secretRefsMu.Lock()
+			for ref, secrets := range c.running.secretRefs {
+				if strings.HasPrefix(ref, prefix) {
+					c.secretRefs[ref] = slices.Clone(secrets)
+				}
+			}
+			c.running.secretRefsMu.Unlock()
+		}
+		if notifier, ok := store.(telegraf.SecretStoreNotifier); ok {
+			notifier.SetNotifier(func(key string) {
+				c.notifySecretChange(storeID, key)
+			})
+		}
+	}
+
 	for _, s := range unlinkedSecrets {
-		resolvers := make(map[string]telegraf.ResolveFunc)
-		for _, ref := range s.GetUnlinked() {
-			// Split the reference and lookup the resolver
-			storeID, key := splitLink(ref)
-			store, found := c.SecretStores[storeID]
-			if !found {
-				return fmt.Errorf("unknown secret-store for %q", ref)
+		if err := c.linkSecret(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Config) linkSecret(s *Secret) error {
+	// Skip the secrets linked by a previously loaded configuration
+	if len(s.GetUnlinked()) == 0 {
+		return nil
+	}
+
+	resolvers := make(map[string]telegraf.ResolveFunc)
+	for _, ref := range s.GetUnlinked() {
+		// Split the reference and lookup the resolver
+		storeID, key := splitLink(ref)
+		store, found := c.SecretStores[storeID]
+		if !found {
+			return fmt.Errorf("unknown secret-store for %q", ref)
+		}
+		resolver, err := store.GetResolver(key)
+		if err != nil {
+			return fmt.Errorf("retrieving resolver for %q failed: %w", ref, err)
+		}
+		resolvers[ref] = resolver
+	}
+	// Inject the resolver list into the secret
+	if err := s.Link(resolvers); err != nil {
+		return fmt.Errorf("retrieving resolver failed: %w", err)
+	}
+
+	// Keep track of secrets with dynamic references for change notifications
+	c.secretRefsMu.Lock()
+	for ref := range s.resolvers {
+		c.secretRefs[ref] = append(c.secretRefs[ref], s)
+	}
+	c.secretRefsMu.Unlock()
+
+	return nil
+}
+
+// dropUnusedSecretRefs removes the secret references not used by any of the
+// configured plugins, e.g. references taken over from the running
+// configuration for plugins removed on reload.
+func (c *Config) dropUnusedSecretRefs() {
+	used := make(map[*Secret]bool)
+	for _, p := range c.Inputs {
+		collectSecrets(reflect.ValueOf(p.Input), used)
+	}
+	for _, p := range c.Processors {
+		collectSecrets(reflect.ValueOf(unwrapProcessor(p.Processor)), used)
+	}
+	for _, p := range c.AggProcessors {
+		collectSecrets(reflect.ValueOf(unwrapProcessor(p.Processor)), used)
+	}
+	for _, p := range c.Aggregators {
+		collectSecrets(reflect.ValueOf(p.Aggregator), used)
+	}
+	for _, p := range c.Outputs {
+		collectSecrets(reflect.ValueOf(p.Output), used)
+	}
+	for _, store := range c.SecretStores {
+		collectSecrets(reflect.ValueOf(store), used)
+	}
+
+	c.secretRefsMu.Lock()
+	defer c.secretRefsMu.Unlock()
+	for ref, secrets := range c.secretRefs {
+		secrets = slices.DeleteFunc(secrets, func(s *Secret) bool { return !used[s] })
+		if len(secrets) == 0 {
+			delete(c.secretRefs, ref)
+			continue
+		}
+		c.secretRefs[ref] = secrets
+	}
+}
+
+func unwrapProcessor(p telegraf.StreamingProcessor) interface{} {
+	if unwrapper, ok := p.(processors.HasUnwrap); ok {
+		return unwrapper.Unwrap()
+	}
+	return p
+}
+
+// collectSecrets adds the secrets contained in the exported, i.e. configurable,
+// fields of the given value to the set.
+func collectSecrets(v reflect.Value, secrets map[*Secret]bool) {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		collectSecrets(v.Elem(), secrets)
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(Secret{}) {
+			if v.CanAddr() {
+				secrets[v.Addr().Interface().(*Secret)] = true
 			}
-			resolver, err := store.GetResolver(key)
-			if err != nil {
-				return fmt.Errorf("retrieving resolver for %q failed: %w", ref, err)
+			return
+		}
+		for i := range v.NumField() {
+			if f := v.Type().Field(i); f.IsExported() {
+				collectSecrets(v.Field(i), secrets)
 			}
-			resolvers[ref] = resolver
 		}
-		// Inject the resolver list into the secret
-		if err := s.Link(resolvers); err != nil {
-			return fmt.Errorf("retrieving resolver failed: %w", err)
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			collectSecrets(v.Index(i), secrets)
+		}
+	case reflect.Map:
+		for iter := v.MapRange(); iter.Next(); {
+			collectSecrets(iter.Value(), secrets)
 		}
 	}
-	return nil
+}
+
+// notifySecretChange calls the change callbacks of all secrets referencing
+// the given key of the secret-store
+func (c *Config) notifySecretChange(storeID, key string) {
+	ref := "@{" + storeID + ":" + key + "}"
+
+	c.secretRefsMu.Lock()
+	secrets := slices.Clone(c.secretRefs[ref])
+	c.secretRefsMu.Unlock()
+
+	for _, s := range secrets {
+		s.notifyChange()
+	}
 }
 
 func (c *Config) probeParser(parentCategory, parentName string, table *ast.Table) bool {
@@ -1063,6 +1379,7 @@ func (c *Config) probeParser(parentCategory, parentName string, table *ast.Table
 func (c *Config) addParser(parentcategory, parentname string, table *ast.Table) (*models.RunningParser, error) {
 	conf := &models.ParserConfig{
 		Parent: parentname,
+		Alias:  c.getFieldString(table, "alias"),
 	}
 
 	conf.DataFormat = c.getFieldString(table, "data_format")
@@ -1094,8 +1411,15 @@ func (c *Config) addParser(parentcategory, parentname string, table *ast.Table)
 		return nil, err
 	}
 
+	// The parser shares the ID of the plugin using it
+	id, err := generatePluginID(parentcategory+"."+parentname, table)
+	if err != nil {
+		return nil, err
+	}
+	conf.ID = id
+
 	running := models.NewRunningParser(parser, conf)
-	err := running.Init()
+	err = running.Init()
 	return running, err
 }
 
@@ -1441,6 +1765,7 @@ func (c *Config) buildAggregator(name, source string, tbl *ast.Table) (*models.A
 	conf := &models.AggregatorConfig{
 		Name:   name,
 		Source: source,
+		Line:   tbl.Line,
 		Delay:  time.Millisecond * 100,
 		Period: time.Second * 30,
 		Grace:  time.Second * 0,
@@ -1494,6 +1819,7 @@ func (c *Config) buildProcessor(category, name, source string, tbl *ast.Table) (
 	conf := &models.ProcessorConfig{
 		Name:   name,
 		Source: source,
+		Line:   tbl.Line,
 	}
 
 	conf.Order = c.getFieldInt64(tbl, "order")
@@ -1603,6 +1929,7 @@ func (c *Config) buildInput(name, source string, tbl *ast.Table) (*models.InputC
 	cp := &models.InputConfig{
 		Name:                    name,
 		Source:                  source,
+		Line:                    tbl.Line,
 		AlwaysIncludeLocalTags:  c.Agent.AlwaysIncludeLocalTags,
 		AlwaysIncludeGlobalTags: c.Agent.AlwaysIncludeGlobalTags,
 	}
@@ -1655,9 +1982,16 @@ func (c *Config) buildOutput(name, source string, tbl *ast.Table) (*models.Outpu
 	oc := &models.OutputConfig{
 		Name:            name,
 		Source:          source,
+		Line:            tbl.Line,
 		Filter:          filter,
 		BufferStrategy:  c.Agent.BufferStrategy,
 		BufferDirectory: c.Agent.BufferDirectory,
+		BufferWAL: models.WALConfig{
+			MaxSize:     int64(c.Agent.BufferWALMaxSize),
+			MaxAge:      time.Duration(c.Agent.BufferWALMaxAge),
+			SegmentSize: int64(c.Agent.BufferWALSegmentSize),
+			Compression: c.Agent.BufferWALCompression,
+		},
 	}
 
 	// TODO: support FieldPass/FieldDrop on outputs
@@ -1672,13 +2006,21 @@ func (c *Config) buildOutput(name, source string, tbl *ast.Table) (*models.Outpu
 	oc.NamePrefix = c.getFieldString(tbl, "name_prefix")
 	oc.StartupErrorBehavior = c.getFieldString(tbl, "startup_error_behavior")
 	oc.LogLevel = c.getFieldString(tbl, "log_level")
+	oc.FailoverGroup = c.getFieldString(tbl, "failover_group")
+	oc.FailoverPriority = c.getFieldInt(tbl, "failover_priority")
+	oc.FailoverThreshold = c.getFieldInt(tbl, "failover_threshold")
+	oc.FailoverRecoveryInterval, _ = c.getFieldDuration(tbl, "failover_recovery_interval")
+	oc.MaxMetricsPerSecond = c.getFieldInt64(tbl, "max_metrics_per_second")
+	oc.MaxBytesPerSecond = c.getFieldSize(tbl, "max_bytes_per_second")
+	oc.AdaptiveBatchSize = c.getFieldBool(tbl, "adaptive_batch_size")
+	oc.DeadLetter = c.getFieldString(tbl, "dead_letter")
 
 	if c.hasErrs() {
 		return nil, c.firstErr()
 	}
 
-	if oc.BufferStrategy == "disk" {
-		log.Printf("W! Using disk buffer strategy for plugin outputs.%s, this is an experimental feature", name)
+	if oc.BufferStrategy == "disk" || oc.BufferStrategy == "wal" {
+		log.Printf("W! Using %s buffer strategy for plugin outputs.%s, this is an experimental feature", oc.BufferStrategy, name)
 	}
 
 	// Generate an ID for the plugin
@@ -1689,15 +2031,16 @@ func (c *Config) buildOutput(name, source string, tbl *ast.Table) (*models.Outpu
 func (c *Config) missingTomlField(_ reflect.Type, key string) error {
 	switch key {
 	// General options to ignore
-	case "alias", "always_include_local_tags",
+	case "adaptive_batch_size", "alias", "always_include_local_tags",
 		"buffer_strategy", "buffer_directory",
 		"collection_jitter", "collection_offset",
-		"data_format", "delay", "drop", "drop_original",
+		"data_format", "dead_letter", "delay", "drop", "drop_original",
+		"failover_group", "failover_priority", "failover_recovery_interval", "failover_threshold",
 		"fielddrop", "fieldexclude", "fieldinclude", "fieldpass", "flush_interval", "flush_jitter",
 		"grace",
 		"interval",
 		"log_level", "lvm", // What is this used for?
-		"metric_batch_size", "metric_buffer_limit", "metricpass",
+		"max_bytes_per_second", "max_metrics_per_second", "metric_batch_size", "metric_buffer_limit", "metricpass",
 		"name_override", "name_prefix", "name_suffix", "namedrop", "namedrop_separator", "namepass", "namepass_separator",
 		"order",
 		"pass", "period", "precision",
@@ -1844,6 +2187,33 @@ func (c *Config) getFieldInt64(tbl *ast.Table, fieldName string) int64 {
 	return 0
 }
 
+func (c *Config) getFieldSize(tbl *ast.Table, fieldName string) int64 {
+	if node, ok := tbl.Fields[fieldName]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			switch t := kv.Value.(type) {
+			case *ast.Integer:
+				i, err := t.Int()
+				if err != nil {
+					c.addError(tbl, fmt.Errorf("unexpected int type %q, expecting int", t.Value))
+					return 0
+				}
+				return i
+			case *ast.String:
+				var size Size
+				if err := size.UnmarshalText([]byte(t.Value)); err != nil {
+					c.addError(tbl, fmt.Errorf("error parsing size %q: %w", fieldName, err))
+					return 0
+				}
+				return int64(size)
+			}
+			c.addError(tbl, fmt.Errorf("found unexpected format while parsing %q, expecting size", fieldName))
+			return 0
+		}
+	}
+
+	return 0
+}
+
 func (c *Config) getFieldStringSlice(tbl *ast.Table, fieldName string) []string {
 	var target []string
 	if node, ok := tbl.Fields[fieldName]; ok {
@@ -95,10 +95,11 @@ func TestConfig_LoadSingleInputWithEnvVars(t *testing.T) {
 	}
 	inputConfig.Tags = make(map[string]string)
 
-	// Ignore Log, Parser and ID
+	// Ignore Log, Parser, ID and Line
 	c.Inputs[0].Input.(*MockupInputPlugin).Log = nil
 	c.Inputs[0].Input.(*MockupInputPlugin).parser = nil
 	c.Inputs[0].Config.ID = ""
+	c.Inputs[0].Config.Line = 0
 	require.Equal(t, input, c.Inputs[0].Input, "Testdata did not produce a correct mockup struct.")
 	require.Equal(t, inputConfig, c.Inputs[0].Config, "Testdata did not produce correct input metadata.")
 }
@@ -138,10 +139,11 @@ func TestConfig_LoadSingleInput(t *testing.T) {
 	}
 	inputConfig.Tags = make(map[string]string)
 
-	// Ignore Log, Parser and ID
+	// Ignore Log, Parser, ID and Line
 	c.Inputs[0].Input.(*MockupInputPlugin).Log = nil
 	c.Inputs[0].Input.(*MockupInputPlugin).parser = nil
 	c.Inputs[0].Config.ID = ""
+	c.Inputs[0].Config.Line = 0
 	require.Equal(t, input, c.Inputs[0].Input, "Testdata did not produce a correct memcached struct.")
 	require.Equal(t, inputConfig, c.Inputs[0].Config, "Testdata did not produce correct memcached metadata.")
 }
@@ -183,10 +185,11 @@ func TestConfig_LoadSingleInput_WithSeparators(t *testing.T) {
 	}
 	inputConfig.Tags = make(map[string]string)
 
-	// Ignore Log, Parser and ID
+	// Ignore Log, Parser, ID and Line
 	c.Inputs[0].Input.(*MockupInputPlugin).Log = nil
 	c.Inputs[0].Input.(*MockupInputPlugin).parser = nil
 	c.Inputs[0].Config.ID = ""
+	c.Inputs[0].Config.Line = 0
 	require.Equal(t, input, c.Inputs[0].Input, "Testdata did not produce a correct memcached struct.")
 	require.Equal(t, inputConfig, c.Inputs[0].Config, "Testdata did not produce correct memcached metadata.")
 }
@@ -318,6 +321,7 @@ func TestConfig_LoadDirectory(t *testing.T) {
 			// Prepare parser for comparison
 			require.NoError(t, parser.Init())
 			parser.Log = nil
+			models.SetStatTagsOnPlugin(expectedPlugins[i].parser, map[string]string{"type": "json", "id": plugin.Config.ID})
 
 			// Compare the parser
 			require.Equalf(t, expectedPlugins[i].parser, parser, "Plugin %d: incorrect parser produced", i)
@@ -327,8 +331,9 @@ func TestConfig_LoadDirectory(t *testing.T) {
 		input.parser = nil
 		expectedPlugins[i].parser = nil
 
-		// Ignore the ID
+		// Ignore the ID and line
 		plugin.Config.ID = ""
+		plugin.Config.Line = 0
 
 		require.Equalf(t, expectedPlugins[i], plugin.Input, "Plugin %d: incorrect struct produced", i)
 		require.Equalf(t, expectedConfigs[i], plugin.Config, "Plugin %d: incorrect config produced", i)
@@ -1091,6 +1096,114 @@ func TestConfigPluginIDsSame(t *testing.T) {
 	}
 }
 
+func TestConfigParserAliasAndID(t *testing.T) {
+	c := config.NewConfig()
+	require.NoError(t, c.LoadConfigData([]byte(`
+[[inputs.parser_test_new]]
+  alias = "first"
+  data_format = "json"
+
+[[inputs.parser_test_new]]
+  data_format = "json"
+`), config.EmptySourcePath))
+	require.Len(t, c.Inputs, 2)
+
+	expectedAliases := []string{"first", ""}
+	for i, ri := range c.Inputs {
+		input, ok := ri.Input.(*MockupInputPluginParserNew)
+		require.True(t, ok)
+
+		parser, ok := input.Parser.(*models.RunningParser)
+		require.True(t, ok)
+		require.Equal(t, expectedAliases[i], parser.Config.Alias)
+		require.Equal(t, ri.Config.ID, parser.Config.ID)
+
+		generated, err := input.ParserFunc()
+		require.NoError(t, err)
+		require.Equal(t, ri.Config.ID, generated.(*models.RunningParser).Config.ID)
+	}
+	require.NotEqual(t, c.Inputs[0].Config.ID, c.Inputs[1].Config.ID)
+}
+
+func TestConfig_BufferWAL(t *testing.T) {
+	c := config.NewConfig()
+	require.NoError(t, c.LoadConfigData([]byte(`
+[agent]
+  buffer_strategy = "wal"
+  buffer_directory = "`+filepath.ToSlash(t.TempDir())+`"
+  buffer_wal_max_size = "64MiB"
+  buffer_wal_max_age = "24h"
+  buffer_wal_segment_size = "1MiB"
+  buffer_wal_compression = "zstd"
+[[outputs.http]]
+`), config.EmptySourcePath))
+	require.Len(t, c.Outputs, 1)
+	defer c.Outputs[0].Close()
+
+	expected := models.WALConfig{
+		MaxSize:     64 * 1024 * 1024,
+		MaxAge:      24 * time.Hour,
+		SegmentSize: 1024 * 1024,
+		Compression: "zstd",
+	}
+	require.Equal(t, expected, c.Outputs[0].Config.BufferWAL)
+
+	c = config.NewConfig()
+	err := c.LoadConfigData([]byte(`
+[agent]
+  buffer_wal_compression = "lz4"
+`), config.EmptySourcePath)
+	require.ErrorContains(t, err, "invalid 'buffer_wal_compression'")
+}
+
+func TestConfig_OutputRateLimits(t *testing.T) {
+	c := config.NewConfig()
+	require.NoError(t, c.LoadConfigData([]byte(`
+[[outputs.http]]
+  max_metrics_per_second = 500
+  max_bytes_per_second = "1MiB"
+  adaptive_batch_size = true
+`), config.EmptySourcePath))
+	require.Len(t, c.Outputs, 1)
+	defer c.Outputs[0].Close()
+
+	require.Equal(t, int64(500), c.Outputs[0].Config.MaxMetricsPerSecond)
+	require.Equal(t, int64(1024*1024), c.Outputs[0].Config.MaxBytesPerSecond)
+	require.True(t, c.Outputs[0].Config.AdaptiveBatchSize)
+
+	c = config.NewConfig()
+	err := c.LoadConfigData([]byte(`
+[[outputs.http]]
+  max_bytes_per_second = "lots"
+`), config.EmptySourcePath)
+	require.ErrorContains(t, err, "error parsing size")
+}
+
+func TestConfig_Check(t *testing.T) {
+	c := config.NewConfig()
+	findings := c.Check([]string{"./testdata/check.toml", "./testdata/non_existing.toml"}, false)
+
+	// Ignore the messages and plugin IDs
+	for i := range findings {
+		findings[i].Message = ""
+		findings[i].PluginID = ""
+	}
+
+	expected := []config.Finding{
+		{Rule: "load", Severity: "error", File: "./testdata/check.toml", Line: 5, Plugin: "inputs.memcached"},
+		{Rule: "load", Severity: "error", File: "./testdata/non_existing.toml"},
+		{Rule: "secret", Severity: "error", File: "./testdata/check.toml", Line: 1, Plugin: "inputs.memcached"},
+		{Rule: "duplicate-id", Severity: "warning", File: "./testdata/check.toml", Line: 14, Plugin: "inputs.memcached"},
+		{Rule: "never-matching-filter", Severity: "warning", File: "./testdata/check.toml", Line: 9, Plugin: "inputs.memcached"},
+		{Rule: "never-matching-filter", Severity: "warning", File: "./testdata/check.toml", Line: 14, Plugin: "inputs.memcached"},
+		{Rule: "unrouted-input", Severity: "warning", File: "./testdata/check.toml", Line: 9, Plugin: "inputs.memcached"},
+		{Rule: "unrouted-input", Severity: "warning", File: "./testdata/check.toml", Line: 14, Plugin: "inputs.memcached"},
+	}
+	require.Equal(t, expected, findings)
+	require.Len(t, c.Inputs, 4)
+	require.Equal(t, c.Inputs[2].Config.ID, c.Inputs[3].Config.ID)
+}
+
 func TestPersisterInputStoreLoad(t *testing.T) {
 	// Reserve a temporary state file
 	file, err := os.CreateTemp(t.TempDir(), "telegraf_state-*.json")
@@ -0,0 +1,192 @@
+package config
+
+import (
+	"bytes"
+	"maps"
+	"reflect"
+	"slices"
+
+	"github.com/influxdata/telegraf/models"
+)
+
+// PluginDiff holds the changes of one plugin category between the running and
+// a newly loaded configuration. Plugins are matched by their ID, i.e. a plugin
+// with changed settings is reported as removed and added.
+type PluginDiff[T interface {
+	comparable
+	ID() string
+}] struct {
+	// Added contains the plugins of the loaded configuration not running yet
+	Added []T
+	// Removed contains the running plugins not part of the loaded configuration
+	Removed []T
+	// Kept contains the running plugins also part of the loaded configuration
+	Kept []T
+	// Discarded contains the instances of the loaded configuration superseded
+	// by an identical running plugin
+	Discarded []T
+	// Merged is the plugin list of the loaded configuration in the loaded
+	// order but with the kept, already running, instances
+	Merged []T
+	// Renewed maps the running plugins replaced by an identical instance of
+	// the loaded configuration to their replacement
+	Renewed map[T]T
+
+	reordered bool
+}
+
+// Changed returns true if plugins were added, removed or reordered.
+func (d *PluginDiff[T]) Changed() bool {
+	return len(d.Added) > 0 || len(d.Removed) > 0 || d.reordered
+}
+
+// renew replaces the kept plugins by their identical instances of the loaded
+// configuration, i.e. all running plugins are removed and all loaded plugins
+// are added.
+func (d *PluginDiff[T]) renew() {
+	d.Renewed = make(map[T]T, len(d.Kept))
+	for i, p := range d.Kept {
+		d.Renewed[p] = d.Discarded[i]
+	}
+	d.Removed = append(d.Removed, d.Kept...)
+	for i, p := range d.Merged {
+		if replacement, found := d.Renewed[p]; found {
+			d.Merged[i] = replacement
+		}
+	}
+	d.Added = slices.Clone(d.Merged)
+	d.Kept, d.Discarded = nil, nil
+}
+
+func diffPlugins[T interface {
+	comparable
+	ID() string
+}](running, loaded []T) PluginDiff[T] {
+	available := make(map[string][]T, len(running))
+	for _, p := range running {
+		available[p.ID()] = append(available[p.ID()], p)
+	}
+
+	d := PluginDiff[T]{Merged: make([]T, 0, len(loaded))}
+	kept := make(map[T]bool, len(running))
+	for _, p := range loaded {
+		id := p.ID()
+		if candidates := available[id]; len(candidates) > 0 {
+			available[id] = candidates[1:]
+			kept[candidates[0]] = true
+			d.Kept = append(d.Kept, candidates[0])
+			d.Discarded = append(d.Discarded, p)
+			d.Merged = append(d.Merged, candidates[0])
+			continue
+		}
+		d.Added = append(d.Added, p)
+		d.Merged = append(d.Merged, p)
+	}
+
+	for _, p := range running {
+		if !kept[p] {
+			d.Removed = append(d.Removed, p)
+		}
+	}
+	d.reordered = !slices.Equal(running, d.Merged)
+
+	return d
+}
+
+// Diff describes the differences between a running and a newly loaded
+// configuration to allow restarting only the affected plugins.
+type Diff struct {
+	// RestartReason is non-empty if settings changed that cannot be applied
+	// without restarting the whole agent, e.g. agent settings or global tags.
+	RestartReason string
+
+	Inputs        PluginDiff[*models.RunningInput]
+	Processors    PluginDiff[*models.RunningProcessor]
+	AggProcessors PluginDiff[*models.RunningProcessor]
+	Aggregators   PluginDiff[*models.RunningAggregator]
+	Outputs       PluginDiff[*models.RunningOutput]
+}
+
+// NewDiff compares the running configuration with the loaded one.
+func NewDiff(running, loaded *Config) *Diff {
+	d := &Diff{
+		Inputs:        diffPlugins(running.Inputs, loaded.Inputs),
+		Processors:    diffPlugins(running.Processors, loaded.Processors),
+		AggProcessors: diffPlugins(running.AggProcessors, loaded.AggProcessors),
+		Aggregators:   diffPlugins(running.Aggregators, loaded.Aggregators),
+		Outputs:       diffPlugins(running.Outputs, loaded.Outputs),
+	}
+
+	switch {
+	case agentChanged(running.Agent, loaded.Agent):
+		d.RestartReason = "agent settings changed"
+	case !maps.Equal(running.Tags, loaded.Tags):
+		d.RestartReason = "global tags changed"
+	case !maps.Equal(running.secretStoreIDs, loaded.secretStoreIDs):
+		d.RestartReason = "secret-stores changed"
+	}
+
+	return d
+}
+
+// agentChanged compares the agent settings. Secrets are compared by their
+// value as the protected containers differ for each loaded configuration.
+func agentChanged(running, loaded *AgentConfig) bool {
+	r, l := *running, *loaded
+	r.ControlToken, l.ControlToken = Secret{}, Secret{}
+	if !reflect.DeepEqual(r, l) {
+		return true
+	}
+	return secretChanged(&running.ControlToken, &loaded.ControlToken)
+}
+
+func secretChanged(running, loaded *Secret) bool {
+	if running.Empty() || loaded.Empty() {
+		return running.Empty() != loaded.Empty()
+	}
+
+	r, err := running.Get()
+	if err != nil {
+		return true
+	}
+	defer r.Destroy()
+	l, err := loaded.Get()
+	if err != nil {
+		return true
+	}
+	defer l.Destroy()
+
+	return !bytes.Equal(r.Bytes(), l.Bytes())
+}
+
+// PipelineChanged returns true if the processing stage, i.e. processors or
+// aggregators, differs between the configurations.
+func (d *Diff) PipelineChanged() bool {
+	return d.Processors.Changed() || d.AggProcessors.Changed() || d.Aggregators.Changed()
+}
+
+// RenewPipeline replaces all running processors and aggregators by the
+// instances of the loaded configuration if the pipeline changed. Those
+// plugins cannot be started again after being stopped, so the processing
+// stage has to be built from new instances even for unchanged plugins.
+func (d *Diff) RenewPipeline() {
+	if !d.PipelineChanged() {
+		return
+	}
+	d.Processors.renew()
+	d.AggProcessors.renew()
+	d.Aggregators.renew()
+}
+
+// Apply returns the loaded configuration with its plugin lists replaced by
+// the merged lists containing the still running plugin instances. Secrets of
+// plugins not part of the merged lists are no longer notified about changes.
+func (d *Diff) Apply(loaded *Config) *Config {
+	loaded.Inputs = d.Inputs.Merged
+	loaded.Processors = d.Processors.Merged
+	loaded.AggProcessors = d.AggProcessors.Merged
+	loaded.Aggregators = d.Aggregators.Merged
+	loaded.Outputs = d.Outputs.Merged
+	loaded.dropUnusedSecretRefs()
+	return loaded
+}
@@ -0,0 +1,195 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/models"
+)
+
+func TestDiffPlugins(t *testing.T) {
+	running := config.NewConfig()
+	require.NoError(t, running.LoadConfigData([]byte(`
+[[inputs.memcached]]
+  servers = ["localhost"]
+[[inputs.procstat]]
+  port = 8080
+[[processors.processor]]
+  order = 1
+[[outputs.http]]
+  url = "http://localhost:8080"
+`), config.EmptySourcePath))
+
+	loaded := config.NewConfig()
+	require.NoError(t, loaded.LoadConfigData([]byte(`
+[[inputs.memcached]]
+  servers = ["localhost"]
+[[inputs.procstat]]
+  port = 8081
+[[processors.processor]]
+  order = 1
+[[outputs.http]]
+  url = "http://localhost:8080"
+`), config.EmptySourcePath))
+
+	diff := config.NewDiff(running, loaded)
+	require.Empty(t, diff.RestartReason)
+
+	memcached, procstat := findInput(running, "memcached"), findInput(running, "procstat")
+	newMemcached, newProcstat := findInput(loaded, "memcached"), findInput(loaded, "procstat")
+
+	require.True(t, diff.Inputs.Changed())
+	require.Equal(t, []*models.RunningInput{newProcstat}, diff.Inputs.Added)
+	require.Equal(t, []*models.RunningInput{procstat}, diff.Inputs.Removed)
+	require.Equal(t, []*models.RunningInput{memcached}, diff.Inputs.Kept)
+	require.Equal(t, []*models.RunningInput{newMemcached}, diff.Inputs.Discarded)
+	require.ElementsMatch(t, []*models.RunningInput{memcached, newProcstat}, diff.Inputs.Merged)
+
+	require.False(t, diff.PipelineChanged())
+	require.False(t, diff.Outputs.Changed())
+	require.Same(t, running.Outputs[0], diff.Outputs.Merged[0])
+}
+
+func TestDiffDuplicatePlugins(t *testing.T) {
+	running := config.NewConfig()
+	require.NoError(t, running.LoadConfigData([]byte(`
+[[inputs.memcached]]
+[[inputs.memcached]]
+[[outputs.http]]
+`), config.EmptySourcePath))
+
+	loaded := config.NewConfig()
+	require.NoError(t, loaded.LoadConfigData([]byte(`
+[[inputs.memcached]]
+[[outputs.http]]
+`), config.EmptySourcePath))
+
+	diff := config.NewDiff(running, loaded)
+	require.Empty(t, diff.RestartReason)
+	require.Empty(t, diff.Inputs.Added)
+	require.Len(t, diff.Inputs.Kept, 1)
+	require.Same(t, running.Inputs[0], diff.Inputs.Kept[0])
+	require.Len(t, diff.Inputs.Removed, 1)
+	require.Same(t, running.Inputs[1], diff.Inputs.Removed[0])
+}
+
+func TestDiffProcessorOrder(t *testing.T) {
+	running := config.NewConfig()
+	require.NoError(t, running.LoadConfigData([]byte(`
+[[processors.processor]]
+  order = 1
+[[processors.processor]]
+  order = 2
+[[outputs.http]]
+`), config.EmptySourcePath))
+	running.Processors[0], running.Processors[1] = running.Processors[1], running.Processors[0]
+
+	loaded := config.NewConfig()
+	require.NoError(t, loaded.LoadConfigData([]byte(`
+[[processors.processor]]
+  order = 1
+[[processors.processor]]
+  order = 2
+[[outputs.http]]
+`), config.EmptySourcePath))
+
+	diff := config.NewDiff(running, loaded)
+	require.Empty(t, diff.Processors.Added)
+	require.Empty(t, diff.Processors.Removed)
+	require.True(t, diff.PipelineChanged())
+	require.Same(t, running.Processors[1], diff.Processors.Merged[0])
+}
+
+func TestDiffRenewPipeline(t *testing.T) {
+	cfg := `
+[[processors.processor]]
+  order = 1
+[[processors.processor]]
+  order = 2
+[[outputs.http]]
+`
+	running := config.NewConfig()
+	require.NoError(t, running.LoadConfigData([]byte(cfg), config.EmptySourcePath))
+	loaded := config.NewConfig()
+	require.NoError(t, loaded.LoadConfigData([]byte(cfg+`
+[[processors.processor]]
+  order = 3
+`), config.EmptySourcePath))
+
+	diff := config.NewDiff(running, loaded)
+	require.True(t, diff.PipelineChanged())
+	require.Len(t, diff.Processors.Kept, 2)
+	require.Len(t, diff.Processors.Added, 1)
+
+	diff.RenewPipeline()
+	require.Empty(t, diff.Processors.Kept)
+	require.Empty(t, diff.Processors.Discarded)
+	require.ElementsMatch(t, running.Processors, diff.Processors.Removed)
+	require.Equal(t, []*models.RunningProcessor(loaded.Processors), diff.Processors.Added)
+	require.Equal(t, []*models.RunningProcessor(loaded.Processors), diff.Processors.Merged)
+	require.Len(t, diff.Processors.Renewed, 2)
+	for i, p := range running.Processors {
+		require.Same(t, loaded.Processors[i], diff.Processors.Renewed[p])
+	}
+
+	// Outputs are not affected
+	require.Len(t, diff.Outputs.Kept, 1)
+}
+
+func TestDiffRestartRequired(t *testing.T) {
+	running := config.NewConfig()
+	require.NoError(t, running.LoadConfigData([]byte(`
+[global_tags]
+  dc = "us-east-1"
+[[inputs.memcached]]
+[[outputs.http]]
+`), config.EmptySourcePath))
+
+	loaded := config.NewConfig()
+	require.NoError(t, loaded.LoadConfigData([]byte(`
+[global_tags]
+  dc = "us-west-1"
+[[inputs.memcached]]
+[[outputs.http]]
+`), config.EmptySourcePath))
+	require.Equal(t, "global tags changed", config.NewDiff(running, loaded).RestartReason)
+
+	loaded = config.NewConfig()
+	require.NoError(t, loaded.LoadConfigData([]byte(`
+[agent]
+  interval = "1m"
+[global_tags]
+  dc = "us-east-1"
+[[inputs.memcached]]
+[[outputs.http]]
+`), config.EmptySourcePath))
+	require.Equal(t, "agent settings changed", config.NewDiff(running, loaded).RestartReason)
+}
+
+func TestDiffControlToken(t *testing.T) {
+	load := func(token string) *config.Config {
+		c := config.NewConfig()
+		require.NoError(t, c.LoadConfigData([]byte(`
+[agent]
+  control_token = "`+token+`"
+[[inputs.memcached]]
+[[outputs.http]]
+`), config.EmptySourcePath))
+		return c
+	}
+
+	running := load("secret")
+	require.Empty(t, config.NewDiff(running, load("secret")).RestartReason)
+	require.Equal(t, "agent settings changed", config.NewDiff(running, load("other")).RestartReason)
+}
+
+func findInput(c *config.Config, name string) *models.RunningInput {
+	for _, input := range c.Inputs {
+		if input.Config.Name == name {
+			return input
+		}
+	}
+	return nil
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"log"
 	"regexp"
+	"slices"
 	"strings"
+	"sync"
 	"sync/atomic"
 
 	"github.com/influxdata/telegraf"
@@ -97,6 +99,15 @@ type Secret struct {
 
 	// notempty denotes if the secret is completely empty
 	notempty bool
+
+	// listeners are notified when a dynamic part of the secret changes
+	listeners *secretListeners
+}
+
+// secretListeners holds the callbacks registered for changes of a secret
+type secretListeners struct {
+	sync.Mutex
+	callbacks []func()
 }
 
 // NewSecret creates a new secret from the given bytes
@@ -141,6 +152,7 @@ func (s *Secret) init(secret []byte) {
 		}
 	}
 	s.resolvers = nil
+	s.listeners = &secretListeners{}
 
 	// Setup the container implementation
 	s.container = selectedImpl.Container(secret)
@@ -151,6 +163,7 @@ func (s *Secret) Destroy() {
 	s.resolvers = nil
 	s.unlinked = nil
 	s.notempty = false
+	s.listeners = nil
 
 	if s.container != nil {
 		s.container.Destroy()
@@ -242,6 +255,34 @@ func (s *Secret) Set(value []byte) error {
 	return nil
 }
 
+// OnChange registers a callback called whenever a dynamic secret-store
+// reference of the secret changes, e.g. after the store rotated credentials.
+// Plugins holding long-lived connections can use the callback to reconnect
+// with the new value instead of waiting for authentication errors. The
+// callback is called by the secret-store and must not block.
+func (s *Secret) OnChange(callback func()) {
+	if s.listeners == nil {
+		s.listeners = &secretListeners{}
+	}
+	s.listeners.Lock()
+	s.listeners.callbacks = append(s.listeners.callbacks, callback)
+	s.listeners.Unlock()
+}
+
+func (s *Secret) notifyChange() {
+	listeners := s.listeners
+	if listeners == nil {
+		return
+	}
+	listeners.Lock()
+	callbacks := slices.Clone(listeners.callbacks)
+	listeners.Unlock()
+
+	for _, callback := range callbacks {
+		callback()
+	}
+}
+
 // GetUnlinked return the parts of the secret that is not yet linked to a resolver
 func (s *Secret) GetUnlinked() []string {
 	return s.unlinked
@@ -509,6 +509,71 @@ func TestSecretStoreDeclarationValidID(t *testing.T) {
 	}
 }
 
+func TestSecretStoreReuse(t *testing.T) {
+	defer func() { unlinkedSecrets = make([]*Secret, 0) }()
+
+	running := NewConfig()
+	require.NoError(t, running.LoadConfigData([]byte(`
+[[secretstores.mockup]]
+  id = "mock"
+[[secretstores.mockup]]
+  id = "other"
+[[inputs.mockup]]
+  secret = "@{mock:secret}"
+`), EmptySourcePath))
+	store := running.SecretStores["mock"].(*MockupSecretStore)
+	store.Secrets = map[string][]byte{"secret": []byte("password")}
+	store.Dynamic = true
+	require.NoError(t, running.LinkSecrets())
+
+	var changed int
+	running.Inputs[0].Input.(*MockupSecretPlugin).Secret.OnChange(func() { changed++ })
+
+	loaded := NewConfig()
+	loaded.ReuseSecretStores(running)
+	require.NoError(t, loaded.LoadConfigData([]byte(`
+[[secretstores.mockup]]
+  id = "mock"
+[[secretstores.mockup]]
+  id = "other"
+  dynamic = true
+[[inputs.mockup]]
+  secret = "@{mock:secret}"
+`), EmptySourcePath))
+	require.NoError(t, loaded.LinkSecrets())
+	require.Nil(t, loaded.running)
+
+	// Unchanged stores are reused, changed ones are created
+	require.Same(t, store, loaded.SecretStores["mock"])
+	require.NotSame(t, running.SecretStores["other"], loaded.SecretStores["other"])
+
+	// Changes are notified to the running and the loaded plugins
+	loaded.Inputs[0].Input.(*MockupSecretPlugin).Secret.OnChange(func() { changed++ })
+	store.notify("secret")
+	require.Equal(t, 2, changed)
+
+	// Only the plugins used after applying the reload are notified
+	NewDiff(running, loaded).Apply(loaded)
+	require.Same(t, running.Inputs[0], loaded.Inputs[0])
+	store.notify("secret")
+	require.Equal(t, 3, changed)
+
+	loaded.Inputs = nil
+	loaded.dropUnusedSecretRefs()
+	store.notify("secret")
+	require.Equal(t, 3, changed)
+
+	// Only the stores not reused by the loaded configuration are closed
+	replaced := running.SecretStores["other"].(*MockupSecretStore)
+	running.CloseSecretStores(loaded)
+	require.Zero(t, store.closed)
+	require.Equal(t, 1, replaced.closed)
+
+	loaded.CloseSecretStores(nil)
+	require.Equal(t, 1, store.closed)
+	require.Equal(t, 1, loaded.SecretStores["other"].(*MockupSecretStore).closed)
+}
+
 type SecretImplTestSuite struct {
 	suite.Suite
 	protected bool
@@ -653,6 +718,47 @@ func (tsuite *SecretImplTestSuite) TestSecretStoreDynamic() {
 	}
 }
 
+func (tsuite *SecretImplTestSuite) TestSecretStoreDynamicChange() {
+	t := tsuite.T()
+
+	cfg := []byte(
+		`
+[[inputs.mockup]]
+	secret = "user:@{mock:secret}"
+`)
+
+	c := NewConfig()
+	require.NoError(t, c.LoadConfigData(cfg, EmptySourcePath))
+	require.Len(t, c.Inputs, 1)
+
+	// Create a mockup secretstore
+	store := &MockupSecretStore{
+		Secrets: map[string][]byte{"secret": []byte("Ood Bnar"), "other": []byte("Thon")},
+		Dynamic: true,
+	}
+	require.NoError(t, store.Init())
+	c.SecretStores["mock"] = store
+	require.NoError(t, c.LinkSecrets())
+	require.NotNil(t, store.notify)
+
+	var changed int
+	plugin := c.Inputs[0].Input.(*MockupSecretPlugin)
+	plugin.Secret.OnChange(func() { changed++ })
+
+	// Only changes of referenced secrets are notified
+	store.notify("other")
+	require.Zero(t, changed)
+
+	store.Secrets["secret"] = []byte("Obi-Wan Kenobi")
+	store.notify("secret")
+	require.Equal(t, 1, changed)
+
+	secret, err := plugin.Secret.Get()
+	require.NoError(t, err)
+	defer secret.Destroy()
+	require.EqualValues(t, "user:Obi-Wan Kenobi", secret.TemporaryString())
+}
+
 func (tsuite *SecretImplTestSuite) TestSecretSet() {
 	t := tsuite.T()
 
@@ -799,6 +905,9 @@ func (*MockupSecretPlugin) Gather(_ telegraf.Accumulator) error { return nil }
 type MockupSecretStore struct {
 	Secrets map[string][]byte
 	Dynamic bool
+
+	notify func(key string)
+	closed int
 }
 
 func (*MockupSecretStore) Init() error {
@@ -828,6 +937,15 @@ func (s *MockupSecretStore) List() ([]string, error) {
 	}
 	return keys, nil
 }
+func (s *MockupSecretStore) Close() error {
+	s.closed++
+	return nil
+}
+
+func (s *MockupSecretStore) SetNotifier(notify func(key string)) {
+	s.notify = notify
+}
+
 func (s *MockupSecretStore) GetResolver(key string) (telegraf.ResolveFunc, error) {
 	return func() ([]byte, bool, error) {
 		v, err := s.Get(key)
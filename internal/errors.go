@@ -6,6 +6,7 @@ var (
 	ErrNotConnected     = errors.New("not connected")
 	ErrSerialization    = errors.New("serialization of metric(s) failed")
 	ErrSizeLimitReached = errors.New("size limit reached")
+	ErrThrottled        = errors.New("throttled by the remote service")
 )
 
 // StartupError indicates an error that occurred during startup of a plugin
@@ -22,8 +22,9 @@ type entry struct {
 }
 
 type handler struct {
-	level    telegraf.LogLevel
-	timezone *time.Location
+	level       telegraf.LogLevel
+	timezone    *time.Location
+	suppression *suppression
 
 	impl      sink
 	earlysink *log.Logger
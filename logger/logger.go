@@ -8,6 +8,7 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -28,7 +29,7 @@ type sink interface {
 
 // logger is the actual implementation of the telegraf logger interface
 type logger struct {
-	level    *telegraf.LogLevel
+	level    atomic.Pointer[telegraf.LogLevel]
 	category string
 	name     string
 	alias    string
@@ -36,6 +37,12 @@ type logger struct {
 	prefix     string
 	onError    []func()
 	attributes map[string]interface{}
+	suppressor atomic.Pointer[suppressor]
+
+	// state of a temporary log-level override
+	previous *telegraf.LogLevel
+	revert   *time.Timer
+	sync.Mutex
 }
 
 // New creates a new logging instance to be used in models
@@ -72,8 +79,8 @@ func New(category, name, alias string) *logger {
 
 // Level returns the current log-level of the logger
 func (l *logger) Level() telegraf.LogLevel {
-	if l.level != nil {
-		return *l.level
+	if level := l.level.Load(); level != nil {
+		return *level
 	}
 	return instance.level
 }
@@ -143,9 +150,26 @@ func (l *logger) Print(level telegraf.LogLevel, ts time.Time, args ...interface{
 	}
 
 	// Skip all messages with insufficient log-levels
-	if l.level != nil && !l.level.Includes(level) || l.level == nil && !instance.level.Includes(level) {
+	if !l.Level().Includes(level) {
 		return
 	}
+
+	// Suppress repeated messages and messages exceeding the rate-limit
+	if s := l.getSuppressor(); s != nil {
+		summary := func(level telegraf.LogLevel, ts time.Time, msg string) {
+			l.output(level, ts, msg)
+		}
+		if s.suppressed(level, ts, fmt.Sprint(args...), summary) {
+			return
+		}
+	}
+
+	l.output(level, ts, args...)
+}
+
+func (l *logger) output(level telegraf.LogLevel, ts time.Time, args ...interface{}) {
+	notifyTaps(level, ts, l.attributes, args...)
+
 	if instance.impl != nil {
 		instance.impl.Print(level, ts.In(instance.timezone), l.prefix, l.attributes, args...)
 	} else {
@@ -154,9 +178,66 @@ func (l *logger) Print(level telegraf.LogLevel, ts time.Time, args ...interface{
 	}
 }
 
+// getSuppressor returns the state for suppressing messages of the logger
+// or nil if suppression is disabled
+func (l *logger) getSuppressor() *suppressor {
+	cfg := instance.suppression
+	if cfg == nil {
+		return nil
+	}
+
+	s := l.suppressor.Load()
+	if s != nil && s.cfg == cfg {
+		return s
+	}
+
+	// Create the state on first use or if the settings changed
+	tags := make(map[string]string, 3)
+	for _, k := range []string{"category", "plugin", "alias"} {
+		if v, ok := l.attributes[k].(string); ok && v != "" {
+			tags[k] = v
+		}
+	}
+	if l.suppressor.CompareAndSwap(s, newSuppressor(cfg, tags)) {
+		return l.suppressor.Load()
+	}
+	return l.getSuppressor()
+}
+
 // SetLevel overrides the current log-level of the logger
 func (l *logger) SetLevel(level telegraf.LogLevel) {
-	l.level = &level
+	l.level.Store(&level)
+}
+
+// SetLevelTemporarily overrides the current log-level of the logger and
+// reverts to the previous level after the given duration. Repeated calls
+// before the duration expired restart the duration but keep the level to
+// revert to.
+func (l *logger) SetLevelTemporarily(level telegraf.LogLevel, ttl time.Duration) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.revert == nil {
+		l.previous = l.level.Load()
+	} else {
+		l.revert.Stop()
+	}
+	l.SetLevel(level)
+
+	var revert *time.Timer
+	revert = time.AfterFunc(ttl, func() {
+		l.Lock()
+		defer l.Unlock()
+
+		// Skip if overridden again in the meantime
+		if l.revert != revert {
+			return
+		}
+		l.level.Store(l.previous)
+		l.previous = nil
+		l.revert = nil
+	})
+	l.revert = revert
 }
 
 // SetLevel changes the log-level to the given one
@@ -198,6 +279,14 @@ type Config struct {
 	InstanceName string
 	// Structured logging message key
 	StructuredLogMessageKey string
+	// maximum number of messages per second and plugin, zero disables
+	// rate-limiting
+	RateLimit float64
+	// number of messages allowed to exceed the rate-limit in bursts
+	RateBurst int
+	// time window for collapsing identical messages, zero disables
+	// deduplication
+	DeduplicationWindow time.Duration
 
 	// internal  log-level
 	logLevel telegraf.LogLevel
@@ -262,6 +351,11 @@ func SetupLogging(cfg *Config) error {
 		return fmt.Errorf("setting logging timezone failed: %w", err)
 	}
 
+	suppression, err := newSuppression(cfg)
+	if err != nil {
+		return err
+	}
+
 	// Get the logging factory and create the root instance
 	creator, found := registry[cfg.LogFormat]
 	if !found {
@@ -281,6 +375,7 @@ func SetupLogging(cfg *Config) error {
 	// Update the logging instance
 	skipEarlyLogs := cfg.LogFormat == "text" && cfg.Logfile == ""
 	instance.switchSink(l, cfg.logLevel, tz, skipEarlyLogs)
+	instance.suppression = suppression
 
 	return nil
 }
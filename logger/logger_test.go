@@ -3,9 +3,11 @@ package logger
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/selfstat"
 )
 
@@ -37,3 +39,29 @@ func TestErrorCounting(t *testing.T) {
 
 	require.Equal(t, int64(2), reg.Get())
 }
+
+func TestSetLevelTemporarily(t *testing.T) {
+	instance = defaultHandler()
+
+	l := New("inputs", "test", "")
+	require.Equal(t, telegraf.Info, l.Level())
+
+	l.SetLevelTemporarily(telegraf.Debug, 50*time.Millisecond)
+	require.Equal(t, telegraf.Debug, l.Level())
+
+	// Overriding again keeps the original level
+	l.SetLevelTemporarily(telegraf.Trace, 50*time.Millisecond)
+	require.Equal(t, telegraf.Trace, l.Level())
+
+	require.Eventually(t, func() bool {
+		return l.Level() == telegraf.Info
+	}, time.Second, 10*time.Millisecond)
+
+	// Explicitly configured levels are restored
+	l.SetLevel(telegraf.Error)
+	l.SetLevelTemporarily(telegraf.Debug, 50*time.Millisecond)
+	require.Equal(t, telegraf.Debug, l.Level())
+	require.Eventually(t, func() bool {
+		return l.Level() == telegraf.Error
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,132 @@
+package logger
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// suppression holds the settings for limiting the messages of a logger
+type suppression struct {
+	rate   float64
+	burst  float64
+	window time.Duration
+}
+
+func newSuppression(cfg *Config) (*suppression, error) {
+	if cfg.RateLimit < 0 {
+		return nil, fmt.Errorf("invalid log rate-limit %v, must be positive", cfg.RateLimit)
+	}
+	if cfg.RateBurst < 0 {
+		return nil, fmt.Errorf("invalid log rate-burst %d, must be positive", cfg.RateBurst)
+	}
+	if cfg.DeduplicationWindow < 0 {
+		return nil, fmt.Errorf("invalid log deduplication window %v, must be positive", cfg.DeduplicationWindow)
+	}
+	if cfg.RateLimit == 0 && cfg.DeduplicationWindow == 0 {
+		return nil, nil
+	}
+
+	burst := float64(cfg.RateBurst)
+	if burst == 0 {
+		burst = math.Max(1, math.Ceil(cfg.RateLimit))
+	}
+	return &suppression{
+		rate:   cfg.RateLimit,
+		burst:  burst,
+		window: cfg.DeduplicationWindow,
+	}, nil
+}
+
+// suppressor keeps track of the messages of a single logger to suppress
+// repeated messages and messages exceeding the rate-limit
+type suppressor struct {
+	cfg *suppression
+
+	tokens   float64
+	last     time.Time
+	limited  int64
+	repeated map[string]*repetition
+
+	rateLimited  selfstat.Stat
+	deduplicated selfstat.Stat
+	sync.Mutex
+}
+
+type repetition struct {
+	level telegraf.LogLevel
+	count int64
+}
+
+func newSuppressor(cfg *suppression, tags map[string]string) *suppressor {
+	return &suppressor{
+		cfg:          cfg,
+		tokens:       cfg.burst,
+		repeated:     make(map[string]*repetition),
+		rateLimited:  selfstat.Register("logging", "messages_rate_limited", tags),
+		deduplicated: selfstat.Register("logging", "messages_deduplicated", tags),
+	}
+}
+
+// suppressed checks if the message should be suppressed. The given function
+// is used to output summaries of the suppressed messages.
+func (s *suppressor) suppressed(level telegraf.LogLevel, ts time.Time, msg string, output func(telegraf.LogLevel, time.Time, string)) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	// Collapse identical messages within the window, the first message is
+	// output immediately while the repetitions are summarized when the window
+	// ends.
+	var r *repetition
+	if s.cfg.window > 0 {
+		if r, found := s.repeated[msg]; found {
+			r.count++
+			s.deduplicated.Incr(1)
+			return true
+		}
+		r = &repetition{level: level}
+		s.repeated[msg] = r
+		time.AfterFunc(s.cfg.window, func() {
+			s.Lock()
+			if s.repeated[msg] == r {
+				delete(s.repeated, msg)
+			}
+			count := r.count
+			s.Unlock()
+			if count > 0 {
+				output(r.level, time.Now(), fmt.Sprintf("%s (repeated %d times in the last %s)", msg, count, s.cfg.window))
+			}
+		})
+	}
+
+	// Token bucket rate-limiting
+	if s.cfg.rate > 0 {
+		if ts.After(s.last) {
+			if !s.last.IsZero() {
+				s.tokens = math.Min(s.cfg.burst, s.tokens+ts.Sub(s.last).Seconds()*s.cfg.rate)
+			}
+			s.last = ts
+		}
+		if s.tokens < 1 {
+			// Do not collapse repetitions of a message never output
+			if r != nil {
+				delete(s.repeated, msg)
+			}
+			s.limited++
+			s.rateLimited.Incr(1)
+			return true
+		}
+		s.tokens--
+
+		if s.limited > 0 {
+			output(telegraf.Warn, ts, fmt.Sprintf("Suppressed %d messages exceeding the rate-limit", s.limited))
+			s.limited = 0
+		}
+	}
+
+	return false
+}
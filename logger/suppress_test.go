@@ -0,0 +1,84 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+func TestRateLimit(t *testing.T) {
+	instance = defaultHandler()
+	var buf bytes.Buffer
+	instance.earlysink.SetOutput(&buf)
+	instance.suppression = &suppression{rate: 1, burst: 2}
+	defer func() { instance.suppression = nil }()
+
+	l := New("inputs", "ratelimit", "")
+	ts := time.Now()
+	for i := range 5 {
+		l.Print(telegraf.Info, ts, "message ", i)
+	}
+	// Refill the bucket
+	l.Print(telegraf.Info, ts.Add(time.Second), "message 5")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 4)
+	require.Contains(t, lines[0], "message 0")
+	require.Contains(t, lines[1], "message 1")
+	require.Contains(t, lines[2], "W! [inputs.ratelimit] Suppressed 3 messages exceeding the rate-limit")
+	require.Contains(t, lines[3], "message 5")
+
+	stat := selfstat.Register("logging", "messages_rate_limited", map[string]string{"category": "inputs", "plugin": "ratelimit"})
+	require.Equal(t, int64(3), stat.Get())
+}
+
+func TestDeduplication(t *testing.T) {
+	instance = defaultHandler()
+	var buf syncBuffer
+	instance.earlysink.SetOutput(&buf)
+	instance.suppression = &suppression{window: 100 * time.Millisecond}
+	defer func() { instance.suppression = nil }()
+
+	l := New("inputs", "dedup", "foo")
+	for range 3 {
+		l.Errorf("connection to %q failed", "localhost")
+	}
+	l.Error("another error")
+
+	require.Eventually(t, func() bool {
+		return strings.Contains(buf.String(), "repeated 2 times")
+	}, time.Second, 10*time.Millisecond)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	require.Contains(t, lines[0], `E! [inputs.dedup::foo] connection to "localhost" failed`)
+	require.Contains(t, lines[1], "E! [inputs.dedup::foo] another error")
+	require.Contains(t, lines[2], `E! [inputs.dedup::foo] connection to "localhost" failed (repeated 2 times in the last 100ms)`)
+
+	stat := selfstat.Register("logging", "messages_deduplicated", map[string]string{"category": "inputs", "plugin": "dedup", "alias": "foo"})
+	require.Equal(t, int64(2), stat.Get())
+}
+
+type syncBuffer struct {
+	buf bytes.Buffer
+	mu  sync.Mutex
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,85 @@
+package logger
+
+import (
+	"fmt"
+	"maps"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Entry is a log message passed to taps
+type Entry struct {
+	Time       time.Time
+	Level      telegraf.LogLevel
+	Attributes map[string]interface{}
+	Message    string
+}
+
+type tap struct {
+	level telegraf.LogLevel
+	fn    func(Entry)
+}
+
+var (
+	taps   atomic.Pointer[[]*tap]
+	tapsMu sync.Mutex
+)
+
+// AddTap registers a function receiving all log messages up to the given
+// level in addition to the configured sink. The function is called
+// synchronously by the logging plugin and therefore must not block or log
+// itself. The returned function removes the tap.
+func AddTap(level telegraf.LogLevel, fn func(Entry)) func() {
+	t := &tap{level: level, fn: fn}
+
+	tapsMu.Lock()
+	defer tapsMu.Unlock()
+
+	var current []*tap
+	if p := taps.Load(); p != nil {
+		current = *p
+	}
+	updated := append(make([]*tap, 0, len(current)+1), current...)
+	updated = append(updated, t)
+	taps.Store(&updated)
+
+	return func() {
+		tapsMu.Lock()
+		defer tapsMu.Unlock()
+
+		current := *taps.Load()
+		updated := make([]*tap, 0, len(current))
+		for _, c := range current {
+			if c != t {
+				updated = append(updated, c)
+			}
+		}
+		taps.Store(&updated)
+	}
+}
+
+func notifyTaps(level telegraf.LogLevel, ts time.Time, attr map[string]interface{}, args ...interface{}) {
+	p := taps.Load()
+	if p == nil || len(*p) == 0 {
+		return
+	}
+
+	var e *Entry
+	for _, t := range *p {
+		if !t.level.Includes(level) {
+			continue
+		}
+		if e == nil {
+			e = &Entry{
+				Time:       ts,
+				Level:      level,
+				Attributes: maps.Clone(attr),
+				Message:    fmt.Sprint(args...),
+			}
+		}
+		t.fn(*e)
+	}
+}
@@ -0,0 +1,37 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+)
+
+func TestTap(t *testing.T) {
+	instance = defaultHandler()
+	instance.earlysink.SetOutput(&bytes.Buffer{})
+
+	var entries []Entry
+	remove := AddTap(telegraf.Warn, func(e Entry) { entries = append(entries, e) })
+
+	l := New("inputs", "test", "foo")
+	l.AddAttribute("device", "sda")
+	l.Errorf("failed with %d", 42)
+	l.Info("not collected")
+	l.Debug("suppressed by the log-level")
+
+	remove()
+	l.Error("not collected after removal")
+
+	require.Len(t, entries, 1)
+	require.Equal(t, telegraf.Error, entries[0].Level)
+	require.Equal(t, "failed with 42", entries[0].Message)
+	require.Equal(t, map[string]interface{}{
+		"category": "inputs",
+		"plugin":   "test",
+		"alias":    "foo",
+		"device":   "sda",
+	}, entries[0].Attributes)
+}
@@ -95,7 +95,7 @@ type BufferStats struct {
 }
 
 // NewBuffer returns a new empty Buffer with the given capacity.
-func NewBuffer(name, id, alias string, capacity int, strategy, path string) (Buffer, error) {
+func NewBuffer(name, id, alias string, capacity int, strategy, path string, walCfg WALConfig) (Buffer, error) {
 	registerGob()
 
 	bs := NewBufferStats(name, alias, capacity)
@@ -105,6 +105,10 @@ func NewBuffer(name, id, alias string, capacity int, strategy, path string) (Buf
 		return NewMemoryBuffer(capacity, bs)
 	case "disk":
 		return NewDiskBuffer(name, id, path, bs)
+	case "wal":
+		return NewWALBuffer(name, id, path, walCfg, bs)
+	case "shared":
+		return NewSharedBuffer(capacity, bs)
 	}
 	return nil, fmt.Errorf("invalid buffer strategy %q", strategy)
 }
@@ -136,12 +140,12 @@ func NewBufferStats(name, alias string, capacity int) BufferStats {
 			"metrics_dropped",
 			tags,
 		),
-		BufferSize: selfstat.Register(
+		BufferSize: selfstat.RegisterGauge(
 			"write",
 			"buffer_size",
 			tags,
 		),
-		BufferLimit: selfstat.Register(
+		BufferLimit: selfstat.RegisterGauge(
 			"write",
 			"buffer_limit",
 			tags,
@@ -0,0 +1,314 @@
+package models
+
+import (
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// AgentSharedBufferSize is the number of metrics held by the shared log
+var AgentSharedBufferSize = selfstat.RegisterGauge("agent", "shared_buffer_size", make(map[string]string))
+
+// sharedLog is the reference-counted log of metrics used by all outputs with
+// the "shared" buffer strategy. Each metric is only stored once and released
+// after all referencing outputs accepted, rejected or dropped it.
+var sharedLog = &metricLog{entries: make(map[uint64]*logEntry)}
+
+type logEntry struct {
+	metric   telegraf.Metric
+	refs     int
+	rejected bool
+}
+
+type metricLog struct {
+	sync.Mutex
+
+	entries map[uint64]*logEntry
+	next    uint64
+
+	// Entry of the metric currently being fanned out to the outputs. The
+	// entry holds an additional reference until the fan-out is complete to
+	// prevent releasing the metric before all outputs referenced it.
+	fanout    telegraf.Metric
+	fanoutSeq uint64
+}
+
+// share references the given metric for another output. The metric is
+// appended to the log if it is not already being fanned out.
+func (l *metricLog) share(m telegraf.Metric) uint64 {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.fanout == m {
+		l.entries[l.fanoutSeq].refs++
+		return l.fanoutSeq
+	}
+
+	// Release the previous metric in case the fan-out was never completed
+	if l.fanout != nil {
+		l.unref(l.fanoutSeq, false)
+	}
+
+	seq := l.append(m, 2)
+	l.fanout = m
+	l.fanoutSeq = seq
+	return seq
+}
+
+// add appends a metric owned by a single output to the log
+func (l *metricLog) add(m telegraf.Metric) uint64 {
+	l.Lock()
+	defer l.Unlock()
+
+	return l.append(m, 1)
+}
+
+func (l *metricLog) append(m telegraf.Metric, refs int) uint64 {
+	seq := l.next
+	l.next++
+	l.entries[seq] = &logEntry{metric: m, refs: refs}
+	AgentSharedBufferSize.Set(int64(len(l.entries)))
+	return seq
+}
+
+// get returns the metric for writing it to an output. Outputs may modify
+// the metrics while writing, e.g. by adding tags, so a copy is returned if
+// other outputs still reference the metric. Outputs do not declare whether
+// they modify metrics, so all but the last output writing a metric receive a
+// copy; the strategy saves buffer memory rather than allocations on write.
+// The copy is not tracked as the delivery is reported via the referenced
+// metric.
+func (l *metricLog) get(seq uint64) telegraf.Metric {
+	l.Lock()
+	defer l.Unlock()
+
+	e := l.entries[seq]
+	if e.refs == 1 && l.fanout != e.metric {
+		return e.metric
+	}
+	m := e.metric
+	if um, ok := m.(telegraf.UnwrappableMetric); ok {
+		m = um.Unwrap()
+	}
+	return m.Copy()
+}
+
+// release drops the reference of an output to the given entries. Metrics not
+// referenced anymore are rejected if any output rejected or dropped them and
+// accepted otherwise.
+func (l *metricLog) release(seqs []uint64, rejected bool) {
+	l.Lock()
+	defer l.Unlock()
+
+	for _, seq := range seqs {
+		l.unref(seq, rejected)
+	}
+}
+
+func (l *metricLog) unref(seq uint64, rejected bool) {
+	e := l.entries[seq]
+	e.rejected = e.rejected || rejected
+	e.refs--
+	if e.refs > 0 {
+		return
+	}
+
+	delete(l.entries, seq)
+	AgentSharedBufferSize.Set(int64(len(l.entries)))
+	if e.rejected {
+		e.metric.Reject()
+	} else {
+		e.metric.Accept()
+	}
+}
+
+// ReleaseShared completes the fan-out of the given metric to the outputs
+// using the "shared" buffer strategy. The metric is dropped if no output
+// referenced it.
+func ReleaseShared(m telegraf.Metric) {
+	sharedLog.Lock()
+	defer sharedLog.Unlock()
+
+	if sharedLog.fanout != m {
+		m.Drop()
+		return
+	}
+	sharedLog.fanout = nil
+	sharedLog.unref(sharedLog.fanoutSeq, false)
+}
+
+// SharedBuffer is the view of an output on the shared log of metrics. The
+// buffer only stores the sequence numbers of the referenced metrics, i.e. an
+// independent read cursor into the log.
+type SharedBuffer struct {
+	sync.Mutex
+	BufferStats
+
+	log *metricLog
+	cap int
+
+	pending []uint64 // Sequence numbers of metrics waiting to be written
+	batch   []uint64 // Sequence numbers of metrics in the current transaction
+	closed  bool
+
+	// Number of metrics in the current transaction still occupying space in
+	// the buffer, see MemoryBuffer for details
+	batchSize int
+}
+
+func NewSharedBuffer(capacity int, stats BufferStats) (*SharedBuffer, error) {
+	return &SharedBuffer{
+		BufferStats: stats,
+		log:         sharedLog,
+		cap:         capacity,
+	}, nil
+}
+
+func (b *SharedBuffer) Len() int {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.length()
+}
+
+func (b *SharedBuffer) length() int {
+	return min(len(b.pending)+b.batchSize, b.cap)
+}
+
+// Add adds metrics owned by this output to the buffer.
+func (b *SharedBuffer) Add(metrics ...telegraf.Metric) int {
+	b.Lock()
+	defer b.Unlock()
+
+	var dropped int
+	for _, m := range metrics {
+		dropped += b.makeRoom()
+		b.pending = append(b.pending, b.log.add(m))
+		b.MetricsAdded.Incr(1)
+	}
+
+	b.BufferSize.Set(int64(b.length()))
+	return dropped
+}
+
+// AddShared adds a metric currently fanned out to all outputs without copying
+// it. The metric must not be modified when adding it to the output, outputs
+// receive a copy on writing if the metric is still shared.
+func (b *SharedBuffer) AddShared(m telegraf.Metric) int {
+	b.Lock()
+	defer b.Unlock()
+
+	dropped := b.makeRoom()
+	b.pending = append(b.pending, b.log.share(m))
+	b.MetricsAdded.Incr(1)
+
+	b.BufferSize.Set(int64(b.length()))
+	return dropped
+}
+
+// makeRoom drops the oldest metric if the buffer is full
+func (b *SharedBuffer) makeRoom() int {
+	if len(b.pending) < b.cap {
+		return 0
+	}
+
+	b.log.release(b.pending[:1], true)
+	b.pending = b.pending[1:]
+	if b.batchSize > 0 {
+		b.batchSize--
+	}
+	AgentMetricsDropped.Incr(1)
+	b.MetricsDropped.Incr(1)
+	return 1
+}
+
+func (b *SharedBuffer) BeginTransaction(batchSize int) *Transaction {
+	b.Lock()
+	defer b.Unlock()
+
+	outLen := min(len(b.pending), batchSize)
+	if outLen == 0 {
+		return &Transaction{}
+	}
+
+	seqs := make([]uint64, outLen)
+	copy(seqs, b.pending[:outLen])
+	b.pending = b.pending[outLen:]
+	b.batch = seqs
+	b.batchSize = outLen
+
+	batch := make([]telegraf.Metric, outLen)
+	for i, seq := range seqs {
+		batch[i] = b.log.get(seq)
+	}
+
+	return &Transaction{Batch: batch, valid: true, state: seqs}
+}
+
+func (b *SharedBuffer) EndTransaction(tx *Transaction) {
+	b.Lock()
+	defer b.Unlock()
+
+	// Ignore invalid transactions and make sure they can only be finished once.
+	// Metrics of transactions ending after closing the buffer are already
+	// released.
+	if !tx.valid || b.closed {
+		return
+	}
+	tx.valid = false
+
+	seqs := tx.state.([]uint64)
+	b.batch = nil
+	b.batchSize = 0
+
+	accepted := make([]uint64, 0, len(tx.Accept))
+	for _, idx := range tx.Accept {
+		AgentMetricsWritten.Incr(1)
+		b.MetricsWritten.Incr(1)
+		accepted = append(accepted, seqs[idx])
+	}
+	b.log.release(accepted, false)
+
+	rejected := make([]uint64, 0, len(tx.Reject))
+	for _, idx := range tx.Reject {
+		AgentMetricsRejected.Incr(1)
+		b.MetricsRejected.Incr(1)
+		rejected = append(rejected, seqs[idx])
+	}
+
+	// Restore the kept metrics in front of the pending ones as long as they
+	// fit into the buffer and drop the remaining ones
+	keep := tx.InferKeep()
+	restore := min(len(keep), b.cap-len(b.pending))
+	restored := make([]uint64, 0, restore+len(b.pending))
+	for _, idx := range keep[:restore] {
+		restored = append(restored, seqs[idx])
+	}
+	for _, idx := range keep[restore:] {
+		AgentMetricsDropped.Incr(1)
+		b.MetricsDropped.Incr(1)
+		rejected = append(rejected, seqs[idx])
+	}
+	b.log.release(rejected, true)
+	b.pending = append(restored, b.pending...)
+
+	b.BufferSize.Set(int64(b.length()))
+}
+
+func (b *SharedBuffer) Stats() BufferStats {
+	return b.BufferStats
+}
+
+// Close releases all metrics referenced by the buffer as undelivered.
+func (b *SharedBuffer) Close() error {
+	b.Lock()
+	defer b.Unlock()
+
+	b.log.release(b.pending, true)
+	b.log.release(b.batch, true)
+	b.pending = nil
+	b.batch = nil
+	b.closed = true
+	return nil
+}
@@ -0,0 +1,118 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestSharedBufferReleasesAfterAllOutputs(t *testing.T) {
+	var delivered []bool
+	m := metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 42.0}, time.Unix(0, 0))
+	tm, _ := metric.WithTracking(m, func(info telegraf.DeliveryInfo) {
+		delivered = append(delivered, info.Delivered())
+	})
+
+	first, err := NewBuffer("first", "1", "", 10, "shared", "", WALConfig{})
+	require.NoError(t, err)
+	defer first.Close()
+	second, err := NewBuffer("second", "2", "", 10, "shared", "", WALConfig{})
+	require.NoError(t, err)
+	defer second.Close()
+
+	// Fan-out the metric without copying
+	first.(*SharedBuffer).AddShared(tm)
+	second.(*SharedBuffer).AddShared(tm)
+	ReleaseShared(tm)
+	require.Equal(t, int64(1), AgentSharedBufferSize.Get())
+
+	// The metric is still referenced by the second output so the first one
+	// gets a copy to not interfere with the second output when modifying it
+	tx := first.BeginTransaction(10)
+	require.Len(t, tx.Batch, 1)
+	require.NotSame(t, tm, tx.Batch[0])
+	testutil.RequireMetricEqual(t, m, tx.Batch[0])
+	tx.AcceptAll()
+	first.EndTransaction(tx)
+	require.Empty(t, delivered)
+
+	tx = second.BeginTransaction(10)
+	require.Same(t, tm, tx.Batch[0])
+	tx.AcceptAll()
+	second.EndTransaction(tx)
+	require.Equal(t, []bool{true}, delivered)
+	require.Equal(t, int64(0), AgentSharedBufferSize.Get())
+}
+
+func TestSharedBufferRejectedByOneOutput(t *testing.T) {
+	var delivered []bool
+	m := metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 42.0}, time.Unix(0, 0))
+	tm, _ := metric.WithTracking(m, func(info telegraf.DeliveryInfo) {
+		delivered = append(delivered, info.Delivered())
+	})
+
+	first, err := NewBuffer("first", "1", "", 10, "shared", "", WALConfig{})
+	require.NoError(t, err)
+	defer first.Close()
+	second, err := NewBuffer("second", "2", "", 10, "shared", "", WALConfig{})
+	require.NoError(t, err)
+	defer second.Close()
+
+	first.(*SharedBuffer).AddShared(tm)
+	second.(*SharedBuffer).AddShared(tm)
+	ReleaseShared(tm)
+
+	tx := first.BeginTransaction(10)
+	tx.Reject = []int{0}
+	first.EndTransaction(tx)
+
+	// Keeping the metric must not release it
+	tx = second.BeginTransaction(10)
+	tx.KeepAll()
+	second.EndTransaction(tx)
+	require.Empty(t, delivered)
+	require.Equal(t, 1, second.Len())
+
+	tx = second.BeginTransaction(10)
+	tx.AcceptAll()
+	second.EndTransaction(tx)
+	require.Equal(t, []bool{false}, delivered)
+}
+
+func TestSharedBufferReleaseUnreferenced(t *testing.T) {
+	var delivered []bool
+	m := metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 42.0}, time.Unix(0, 0))
+	tm, _ := metric.WithTracking(m, func(info telegraf.DeliveryInfo) {
+		delivered = append(delivered, info.Delivered())
+	})
+
+	ReleaseShared(tm)
+	require.Equal(t, []bool{true}, delivered)
+}
+
+func TestSharedBufferCloseReleases(t *testing.T) {
+	var delivered []bool
+	m := metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 42.0}, time.Unix(0, 0))
+	tm, _ := metric.WithTracking(m, func(info telegraf.DeliveryInfo) {
+		delivered = append(delivered, info.Delivered())
+	})
+
+	buf, err := NewBuffer("test", "1", "", 10, "shared", "", WALConfig{})
+	require.NoError(t, err)
+	buf.(*SharedBuffer).AddShared(tm)
+	ReleaseShared(tm)
+
+	tx := buf.BeginTransaction(10)
+	require.NoError(t, buf.Close())
+	require.Equal(t, []bool{false}, delivered)
+
+	// Ending the transaction after closing must not release the metric again
+	tx.AcceptAll()
+	buf.EndTransaction(tx)
+	require.Equal(t, []bool{false}, delivered)
+}
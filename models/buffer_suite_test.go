@@ -22,9 +22,9 @@ type BufferSuiteTest struct {
 
 func (s *BufferSuiteTest) SetupTest() {
 	switch s.bufferType {
-	case "", "memory":
+	case "", "memory", "shared":
 		s.hasMaxCapacity = true
-	case "disk":
+	case "disk", "wal":
 		path, err := os.MkdirTemp("", "*-buffer-test")
 		s.Require().NoError(err)
 		s.bufferPath = path
@@ -47,9 +47,17 @@ func TestDiskBufferSuite(t *testing.T) {
 	suite.Run(t, &BufferSuiteTest{bufferType: "disk"})
 }
 
+func TestSharedBufferSuite(t *testing.T) {
+	suite.Run(t, &BufferSuiteTest{bufferType: "shared"})
+}
+
+func TestWALBufferSuite(t *testing.T) {
+	suite.Run(t, &BufferSuiteTest{bufferType: "wal"})
+}
+
 func (s *BufferSuiteTest) newTestBuffer(capacity int) Buffer {
 	s.T().Helper()
-	buf, err := NewBuffer("test", "123", "", capacity, s.bufferType, s.bufferPath)
+	buf, err := NewBuffer("test", "123", "", capacity, s.bufferType, s.bufferPath, WALConfig{})
 	s.Require().NoError(err)
 	buf.Stats().MetricsAdded.Set(0)
 	buf.Stats().MetricsWritten.Set(0)
@@ -0,0 +1,911 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+const (
+	// DefaultWALSegmentSize is the default size after which a new segment of
+	// the WAL buffer is started
+	DefaultWALSegmentSize = 8 * 1024 * 1024
+
+	walSegmentSuffix    = ".wal"
+	walCompressedSuffix = ".wal.zst"
+	walCursorFile       = "cursor"
+	walLockFile         = "lock"
+
+	// Each record is prefixed by the length and the CRC32 checksum of the data
+	walRecordHeaderSize = 8
+)
+
+// ErrWALLocked is returned when accessing a WAL buffer that is in use, e.g. by
+// a running Telegraf instance.
+var ErrWALLocked = errors.New("WAL buffer is in use")
+
+// WALConfig contains the settings of the write-ahead-log buffer.
+type WALConfig struct {
+	// MaxSize limits the size of all segments in bytes, the oldest segments
+	// are dropped when exceeding the limit. Unlimited if zero.
+	MaxSize int64
+	// MaxAge is the age after which segments are dropped. Unlimited if zero.
+	MaxAge time.Duration
+	// SegmentSize is the size in bytes after which a new segment is started
+	SegmentSize int64
+	// Compression used for completed segments, either empty or "zstd"
+	Compression string
+}
+
+// WALSegmentInfo describes a segment of a persisted WAL buffer.
+type WALSegmentInfo struct {
+	Filename   string
+	First      uint64
+	Records    int
+	Size       int64
+	Modified   time.Time
+	Compressed bool
+}
+
+// WALInfo describes the persisted state of a WAL buffer.
+type WALInfo struct {
+	// Cursor is the sequence number of the first unacknowledged record
+	Cursor   uint64
+	Segments []WALSegmentInfo
+}
+
+type walSegment struct {
+	first      uint64 // Sequence number of the first record
+	count      int    // Number of records in the segment
+	size       int64
+	valid      int64 // Size of the valid records when opening the segment
+	modified   time.Time
+	compressed bool
+
+	// Records of the segment, only loaded for the active segment or while
+	// reading the segment
+	records [][]byte
+}
+
+func (s *walSegment) end() uint64 {
+	return s.first + uint64(s.count)
+}
+
+func (s *walSegment) filename() string {
+	if s.compressed {
+		return fmt.Sprintf("%020d%s", s.first, walCompressedSuffix)
+	}
+	return fmt.Sprintf("%020d%s", s.first, walSegmentSuffix)
+}
+
+// WALBuffer stores metrics in a write-ahead-log consisting of segment files.
+// Completed segments can be compressed and are removed once all metrics are
+// written or the retention limits are exceeded.
+type WALBuffer struct {
+	BufferStats
+	sync.Mutex
+
+	cfg  WALConfig
+	path string
+
+	segments []*walSegment // Ordered by sequence, the last one may be active
+	active   *os.File      // File of the last segment if still appended to
+	loaded   *walSegment   // Completed segment with records loaded for reading
+	cursor   uint64        // Sequence number of the first unacknowledged record
+	next     uint64        // Sequence number of the next record to write
+
+	// Acknowledged records after the cursor
+	removed map[uint64]bool
+
+	// Sequence number of the end of records read from disk on telegraf
+	// launch. Used to know whether to discard tracking metrics.
+	originalEnd uint64
+
+	inTransaction bool
+
+	// Lock file held while the buffer is open to prevent modifications by
+	// other processes, e.g. purging the buffer
+	lock *os.File
+
+	encoder     *zstd.Encoder
+	decoder     *zstd.Decoder
+	compressing sync.WaitGroup
+}
+
+func NewWALBuffer(name, id, path string, cfg WALConfig, stats BufferStats) (*WALBuffer, error) {
+	switch cfg.Compression {
+	case "", "none", "zstd":
+	default:
+		return nil, fmt.Errorf("invalid WAL compression %q", cfg.Compression)
+	}
+	if cfg.SegmentSize <= 0 {
+		cfg.SegmentSize = DefaultWALSegmentSize
+	}
+	// Keep segments small compared to the size limit for dropping metrics in
+	// reasonably sized chunks
+	if cfg.MaxSize > 0 && cfg.SegmentSize > cfg.MaxSize/4 {
+		cfg.SegmentSize = max(cfg.MaxSize/4, 1)
+	}
+
+	b := &WALBuffer{
+		BufferStats: stats,
+		cfg:         cfg,
+		path:        filepath.Join(path, id),
+		removed:     make(map[uint64]bool),
+	}
+
+	var err error
+	if b.decoder, err = zstd.NewReader(nil); err != nil {
+		return nil, fmt.Errorf("creating decoder failed: %w", err)
+	}
+	if cfg.Compression == "zstd" {
+		if b.encoder, err = zstd.NewWriter(nil); err != nil {
+			return nil, fmt.Errorf("creating encoder failed: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(b.path, 0750); err != nil {
+		return nil, fmt.Errorf("creating WAL directory failed: %w", err)
+	}
+	if b.lock, err = lockWAL(b.path); err != nil {
+		return nil, err
+	}
+	if err := b.open(); err != nil {
+		b.lock.Close()
+		return nil, err
+	}
+	if len(b.segments) == 0 {
+		log.Printf("I! WAL segments not found for plugin outputs.%s (%s), "+
+			"this can safely be ignored if you added this plugin instance for the first time", name, id)
+	}
+	b.originalEnd = b.next
+	b.BufferSize.Set(int64(b.length()))
+
+	return b, nil
+}
+
+// open restores the segments and the cursor from disk
+func (b *WALBuffer) open() error {
+	info, segments, err := scanWAL(b.path)
+	if err != nil {
+		return err
+	}
+	b.cursor = info.Cursor
+
+	for _, s := range segments {
+		// Remove segments completely acknowledged before shutdown
+		if s.end() <= b.cursor {
+			if err := os.Remove(filepath.Join(b.path, s.filename())); err != nil {
+				return fmt.Errorf("removing segment failed: %w", err)
+			}
+			continue
+		}
+		b.segments = append(b.segments, s)
+	}
+
+	if len(b.segments) == 0 {
+		b.next = b.cursor
+		return nil
+	}
+	b.cursor = max(b.cursor, b.segments[0].first)
+	last := b.segments[len(b.segments)-1]
+	b.next = last.end()
+
+	// Continue appending to the last segment if not completed yet
+	if last.compressed || last.size >= b.cfg.SegmentSize {
+		return b.complete(last)
+	}
+	records, err := readWALSegment(filepath.Join(b.path, last.filename()), false, b.decoder)
+	if err != nil {
+		return err
+	}
+	if len(records) != last.count {
+		return fmt.Errorf("segment %q contains %d instead of %d records", last.filename(), len(records), last.count)
+	}
+	size := last.valid
+	f, err := os.OpenFile(filepath.Join(b.path, last.filename()), os.O_WRONLY, 0640)
+	if err != nil {
+		return fmt.Errorf("opening segment failed: %w", err)
+	}
+	// Cut off incomplete records e.g. from a crash during writing
+	if err := f.Truncate(size); err != nil {
+		f.Close()
+		return fmt.Errorf("truncating segment failed: %w", err)
+	}
+	if _, err := f.Seek(size, io.SeekStart); err != nil {
+		f.Close()
+		return fmt.Errorf("seeking segment failed: %w", err)
+	}
+	last.records = records
+	last.size = size
+	b.active = f
+
+	return nil
+}
+
+func (b *WALBuffer) Len() int {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.length()
+}
+
+func (b *WALBuffer) length() int {
+	return int(b.next-b.cursor) - len(b.removed)
+}
+
+func (b *WALBuffer) Add(metrics ...telegraf.Metric) int {
+	b.Lock()
+	defer b.Unlock()
+
+	for _, m := range metrics {
+		data, err := metric.ToBytes(m)
+		if err != nil {
+			panic(err)
+		}
+		if err := b.write(data); err != nil {
+			log.Printf("E! [buffer] Writing metric to WAL failed: %v", err)
+			b.metricDropped(m)
+			continue
+		}
+		b.metricAdded()
+	}
+	if b.active != nil {
+		if err := b.active.Sync(); err != nil {
+			log.Printf("E! [buffer] Syncing WAL segment failed: %v", err)
+		}
+	}
+
+	dropped := b.applyRetention()
+	b.BufferSize.Set(int64(b.length()))
+	return dropped
+}
+
+func (b *WALBuffer) write(data []byte) error {
+	// Start a new segment if necessary
+	if b.active == nil {
+		s := &walSegment{first: b.next, modified: time.Now()}
+		f, err := os.OpenFile(filepath.Join(b.path, s.filename()), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0640)
+		if err != nil {
+			return fmt.Errorf("creating segment failed: %w", err)
+		}
+		b.active = f
+		b.segments = append(b.segments, s)
+	}
+	s := b.segments[len(b.segments)-1]
+
+	record := make([]byte, walRecordHeaderSize+len(data))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(data)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(data))
+	copy(record[walRecordHeaderSize:], data)
+	if _, err := b.active.Write(record); err != nil {
+		return err
+	}
+
+	s.records = append(s.records, data)
+	s.count++
+	s.size += int64(len(record))
+	s.modified = time.Now()
+	b.next++
+
+	// The record is persisted at this point so failing to complete the
+	// segment must not drop the metric
+	if s.size >= b.cfg.SegmentSize {
+		if err := b.complete(s); err != nil {
+			log.Printf("E! [buffer] Completing WAL segment failed: %v", err)
+		}
+	}
+	return nil
+}
+
+// complete closes the active segment and starts compressing it in the
+// background if configured
+func (b *WALBuffer) complete(s *walSegment) error {
+	if b.active != nil {
+		if err := b.active.Sync(); err != nil {
+			return fmt.Errorf("syncing segment failed: %w", err)
+		}
+		if err := b.active.Close(); err != nil {
+			return fmt.Errorf("closing segment failed: %w", err)
+		}
+		b.active = nil
+	}
+	s.records = nil
+
+	if b.encoder == nil || s.compressed {
+		return nil
+	}
+	b.compressing.Add(1)
+	go b.compress(s, filepath.Join(b.path, s.filename()))
+
+	return nil
+}
+
+// compress replaces the given completed segment by its compressed version.
+// Completed segments are not modified anymore so the file is read and
+// compressed without holding the buffer lock.
+func (b *WALBuffer) compress(s *walSegment, filename string) {
+	defer b.compressing.Done()
+
+	raw, err := os.ReadFile(filename)
+	if err != nil {
+		// The segment might have been removed in the meantime
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("E! [buffer] Reading WAL segment failed: %v", err)
+		}
+		return
+	}
+	compressed := b.encoder.EncodeAll(raw, nil)
+
+	// Write to a temporary file and replace the segment afterwards to always
+	// have a valid segment on disk
+	target := filepath.Join(b.path, fmt.Sprintf("%020d%s", s.first, walCompressedSuffix))
+	if err := writeFileSync(target+".tmp", compressed); err != nil {
+		log.Printf("E! [buffer] Writing compressed WAL segment failed: %v", err)
+		os.Remove(target + ".tmp")
+		return
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	if !slices.Contains(b.segments, s) {
+		os.Remove(target + ".tmp")
+		return
+	}
+	if err := os.Rename(target+".tmp", target); err != nil {
+		log.Printf("E! [buffer] Replacing WAL segment failed: %v", err)
+		os.Remove(target + ".tmp")
+		return
+	}
+	s.compressed = true
+	s.size = int64(len(compressed))
+	if err := os.Remove(filename); err != nil {
+		log.Printf("E! [buffer] Removing uncompressed WAL segment failed: %v", err)
+	}
+}
+
+// record returns the data of the record with the given sequence number
+func (b *WALBuffer) record(seq uint64) ([]byte, error) {
+	idx, found := slices.BinarySearchFunc(b.segments, seq, func(s *walSegment, seq uint64) int {
+		switch {
+		case s.end() <= seq:
+			return -1
+		case s.first > seq:
+			return 1
+		}
+		return 0
+	})
+	if !found {
+		return nil, fmt.Errorf("record %d not found", seq)
+	}
+	s := b.segments[idx]
+
+	// Load the records of completed segments, keeping only one at a time
+	if s.records == nil {
+		records, err := readWALSegment(filepath.Join(b.path, s.filename()), s.compressed, b.decoder)
+		if err != nil {
+			return nil, err
+		}
+		if len(records) != s.count {
+			return nil, fmt.Errorf("segment %q contains %d instead of %d records", s.filename(), len(records), s.count)
+		}
+		if b.loaded != nil {
+			b.loaded.records = nil
+		}
+		s.records = records
+		b.loaded = s
+	}
+
+	return s.records[seq-s.first], nil
+}
+
+func (b *WALBuffer) BeginTransaction(batchSize int) *Transaction {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.length() == 0 {
+		return &Transaction{}
+	}
+
+	metrics := make([]telegraf.Metric, 0, batchSize)
+	seqs := make([]uint64, 0, batchSize)
+	for seq := b.cursor; batchSize > 0 && seq < b.next; seq++ {
+		if b.removed[seq] {
+			continue
+		}
+
+		data, err := b.record(seq)
+		if err != nil {
+			panic(err)
+		}
+
+		// Tracking metrics from older instances cannot be delivered, see the
+		// disk buffer for details, and are removed from the buffer.
+		m, err := metric.FromBytes(data)
+		if err != nil {
+			if errors.Is(err, metric.ErrSkipTracking) {
+				b.removed[seq] = true
+				continue
+			}
+			// non-recoverable error in deserialization, abort
+			log.Printf("E! raw metric data: %v", data)
+			panic(err)
+		}
+		if _, ok := m.(telegraf.TrackingMetric); ok && seq < b.originalEnd {
+			b.removed[seq] = true
+			continue
+		}
+
+		metrics = append(metrics, m)
+		seqs = append(seqs, seq)
+		batchSize--
+	}
+	if len(metrics) == 0 {
+		b.advance()
+		return &Transaction{}
+	}
+	b.inTransaction = true
+
+	return &Transaction{Batch: metrics, valid: true, state: seqs}
+}
+
+func (b *WALBuffer) EndTransaction(tx *Transaction) {
+	if len(tx.Batch) == 0 {
+		return
+	}
+
+	// Ignore invalid transactions and make sure they can only be finished once
+	if !tx.valid {
+		return
+	}
+	tx.valid = false
+
+	seqs := tx.state.([]uint64)
+
+	b.Lock()
+	defer b.Unlock()
+
+	for _, idx := range tx.Accept {
+		b.metricWritten(tx.Batch[idx])
+		b.removed[seqs[idx]] = true
+	}
+	for _, idx := range tx.Reject {
+		b.metricRejected(tx.Batch[idx])
+		b.removed[seqs[idx]] = true
+	}
+	b.inTransaction = false
+
+	b.advance()
+	b.applyRetention()
+	b.BufferSize.Set(int64(b.length()))
+}
+
+// advance moves the cursor past the acknowledged records and removes the
+// segments not containing any unacknowledged records anymore
+func (b *WALBuffer) advance() {
+	start := b.cursor
+	for b.cursor < b.next && b.removed[b.cursor] {
+		delete(b.removed, b.cursor)
+		b.cursor++
+	}
+	if b.cursor == start {
+		return
+	}
+
+	for len(b.segments) > 0 && b.segments[0].end() <= b.cursor {
+		b.removeSegment()
+	}
+	if err := b.storeCursor(); err != nil {
+		log.Printf("E! [buffer] Storing WAL cursor failed: %v", err)
+	}
+}
+
+// applyRetention drops the oldest segments exceeding the size or age limits
+// and returns the number of dropped metrics
+func (b *WALBuffer) applyRetention() int {
+	// Do not drop metrics currently being written
+	if b.inTransaction || (b.cfg.MaxSize <= 0 && b.cfg.MaxAge <= 0) {
+		return 0
+	}
+
+	var size int64
+	for _, s := range b.segments {
+		size += s.size
+	}
+
+	var dropped int
+	for len(b.segments) > 0 {
+		s := b.segments[0]
+		exceeded := b.cfg.MaxSize > 0 && size > b.cfg.MaxSize
+		expired := b.cfg.MaxAge > 0 && time.Since(s.modified) > b.cfg.MaxAge
+		if !exceeded && !expired {
+			break
+		}
+
+		// Reject the dropped metrics to release tracking metrics
+		for seq := max(b.cursor, s.first); seq < s.end(); seq++ {
+			if b.removed[seq] {
+				delete(b.removed, seq)
+				continue
+			}
+			dropped++
+			data, err := b.record(seq)
+			if err != nil {
+				log.Printf("E! [buffer] Reading dropped metric failed: %v", err)
+				continue
+			}
+			if m, err := metric.FromBytes(data); err == nil {
+				b.metricDropped(m)
+			} else {
+				AgentMetricsDropped.Incr(1)
+				b.MetricsDropped.Incr(1)
+			}
+		}
+		size -= s.size
+		b.cursor = max(b.cursor, s.end())
+		b.removeSegment()
+	}
+	if dropped > 0 {
+		if err := b.storeCursor(); err != nil {
+			log.Printf("E! [buffer] Storing WAL cursor failed: %v", err)
+		}
+	}
+
+	return dropped
+}
+
+// removeSegment deletes the oldest segment
+func (b *WALBuffer) removeSegment() {
+	s := b.segments[0]
+	if len(b.segments) == 1 && b.active != nil {
+		if err := b.active.Close(); err != nil {
+			log.Printf("E! [buffer] Closing WAL segment failed: %v", err)
+		}
+		b.active = nil
+	}
+	if err := os.Remove(filepath.Join(b.path, s.filename())); err != nil {
+		log.Printf("E! [buffer] Removing WAL segment failed: %v", err)
+	}
+	if b.loaded == s {
+		b.loaded = nil
+	}
+	b.segments = b.segments[1:]
+}
+
+func (b *WALBuffer) storeCursor() error {
+	filename := filepath.Join(b.path, walCursorFile)
+	if err := writeFileSync(filename+".tmp", []byte(strconv.FormatUint(b.cursor, 10))); err != nil {
+		return err
+	}
+	return os.Rename(filename+".tmp", filename)
+}
+
+func (b *WALBuffer) Stats() BufferStats {
+	return b.BufferStats
+}
+
+func (b *WALBuffer) Close() error {
+	// Finish the pending compressions before releasing the encoder
+	b.compressing.Wait()
+
+	b.Lock()
+	defer b.Unlock()
+	defer b.lock.Close()
+
+	if b.encoder != nil {
+		b.encoder.Close()
+	}
+	b.decoder.Close()
+
+	if err := b.storeCursor(); err != nil {
+		return err
+	}
+	if b.active == nil {
+		return nil
+	}
+	err := b.active.Close()
+	b.active = nil
+	return err
+}
+
+// InspectWAL returns the cursor and the segments of the WAL buffer persisted
+// in the given directory.
+func InspectWAL(path string) (*WALInfo, error) {
+	info, _, err := scanWAL(path)
+	return info, err
+}
+
+// ExportWAL calls the given function for each unacknowledged metric of the
+// WAL buffer persisted in the given directory. Tracking metrics are skipped
+// as they cannot be delivered after a restart.
+func ExportWAL(path string, fn func(telegraf.Metric) error) error {
+	registerGob()
+
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		return err
+	}
+	defer decoder.Close()
+
+	info, segments, err := scanWAL(path)
+	if err != nil {
+		return err
+	}
+
+	// Read one segment at a time to limit the memory usage
+	for _, s := range segments {
+		if s.end() <= info.Cursor {
+			continue
+		}
+		records, err := readWALSegment(filepath.Join(path, s.filename()), s.compressed, decoder)
+		if err != nil {
+			return err
+		}
+		for i, data := range records {
+			if s.first+uint64(i) < info.Cursor {
+				continue
+			}
+			m, err := metric.FromBytes(data)
+			if err != nil {
+				if errors.Is(err, metric.ErrSkipTracking) {
+					continue
+				}
+				return fmt.Errorf("decoding record %d failed: %w", s.first+uint64(i), err)
+			}
+			if err := fn(m); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// PurgeWAL removes all segments and the cursor of the WAL buffer persisted in
+// the given directory. Purging fails with ErrWALLocked if the buffer is in use.
+func PurgeWAL(path string) error {
+	lock, err := lockWAL(path)
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if name != walCursorFile && !strings.HasSuffix(name, walSegmentSuffix) && !strings.HasSuffix(name, walCompressedSuffix) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(path, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanWAL reads the cursor and indexes the segments without keeping their
+// records in memory
+func scanWAL(path string) (*WALInfo, []*walSegment, error) {
+	info := &WALInfo{}
+
+	buf, err := os.ReadFile(filepath.Join(path, walCursorFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, fmt.Errorf("reading cursor failed: %w", err)
+	}
+	if len(buf) > 0 {
+		if info.Cursor, err = strconv.ParseUint(string(bytes.TrimSpace(buf)), 10, 64); err != nil {
+			return nil, nil, fmt.Errorf("parsing cursor failed: %w", err)
+		}
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading WAL directory failed: %w", err)
+	}
+
+	segments := make([]*walSegment, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		var compressed bool
+		var prefix string
+		switch {
+		case strings.HasSuffix(name, walCompressedSuffix):
+			compressed = true
+			prefix = strings.TrimSuffix(name, walCompressedSuffix)
+		case strings.HasSuffix(name, walSegmentSuffix):
+			prefix = strings.TrimSuffix(name, walSegmentSuffix)
+		default:
+			continue
+		}
+		first, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		// An uncompressed and compressed segment might coexist after a crash
+		// during compression, in this case use the uncompressed one
+		if n := len(segments); n > 0 && segments[n-1].first == first {
+			if compressed {
+				continue
+			}
+			segments = segments[:n-1]
+		}
+
+		fi, err := entry.Info()
+		if err != nil {
+			return nil, nil, err
+		}
+		count, valid, err := indexWALSegment(filepath.Join(path, name), compressed)
+		if err != nil {
+			return nil, nil, err
+		}
+		segments = append(segments, &walSegment{
+			first:      first,
+			count:      count,
+			size:       fi.Size(),
+			valid:      valid,
+			modified:   fi.ModTime(),
+			compressed: compressed,
+		})
+	}
+	slices.SortFunc(segments, func(a, b *walSegment) int {
+		switch {
+		case a.first < b.first:
+			return -1
+		case a.first > b.first:
+			return 1
+		}
+		return 0
+	})
+
+	for _, s := range segments {
+		info.Segments = append(info.Segments, WALSegmentInfo{
+			Filename:   s.filename(),
+			First:      s.first,
+			Records:    s.count,
+			Size:       s.size,
+			Modified:   s.modified,
+			Compressed: s.compressed,
+		})
+	}
+
+	return info, segments, nil
+}
+
+// indexWALSegment streams through a segment and returns the number of valid
+// records and their size. Reading stops at the first incomplete or corrupt
+// record.
+func indexWALSegment(filename string, compressed bool) (int, int64, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0, 0, fmt.Errorf("opening segment failed: %w", err)
+	}
+	defer f.Close()
+
+	var r io.Reader = bufio.NewReader(f)
+	if compressed {
+		decoder, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return 0, 0, fmt.Errorf("creating decoder failed: %w", err)
+		}
+		defer decoder.Close()
+		r = decoder
+	}
+	cr := &countingReader{r: r}
+
+	var count int
+	var offset int64
+	header := make([]byte, walRecordHeaderSize)
+	checksum := crc32.NewIEEE()
+	for {
+		if _, err := io.ReadFull(cr, header); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			return 0, 0, fmt.Errorf("reading segment %q failed: %w", filename, err)
+		}
+		length := int64(binary.BigEndian.Uint32(header[0:4]))
+		checksum.Reset()
+		if _, err := io.CopyN(checksum, cr, length); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			return 0, 0, fmt.Errorf("reading segment %q failed: %w", filename, err)
+		}
+		if checksum.Sum32() != binary.BigEndian.Uint32(header[4:8]) {
+			break
+		}
+		count++
+		offset += walRecordHeaderSize + length
+	}
+
+	// Determine the amount of data ignored after the valid records
+	if _, err := io.Copy(io.Discard, cr); err != nil {
+		return 0, 0, fmt.Errorf("reading segment %q failed: %w", filename, err)
+	}
+	if cr.n > offset {
+		log.Printf("W! [buffer] Ignoring %d bytes of incomplete records in segment %q", cr.n-offset, filename)
+	}
+
+	return count, offset, nil
+}
+
+// readWALSegment reads the records of a segment. Reading stops at the first
+// incomplete or corrupt record.
+func readWALSegment(filename string, compressed bool, decoder *zstd.Decoder) ([][]byte, error) {
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading segment failed: %w", err)
+	}
+	if compressed {
+		if buf, err = decoder.DecodeAll(buf, nil); err != nil {
+			return nil, fmt.Errorf("decompressing segment %q failed: %w", filename, err)
+		}
+	}
+
+	var records [][]byte
+	var offset int64
+	for int64(len(buf))-offset >= walRecordHeaderSize {
+		length := int64(binary.BigEndian.Uint32(buf[offset : offset+4]))
+		checksum := binary.BigEndian.Uint32(buf[offset+4 : offset+8])
+		end := offset + walRecordHeaderSize + length
+		if end > int64(len(buf)) {
+			break
+		}
+		data := buf[offset+walRecordHeaderSize : end]
+		if crc32.ChecksumIEEE(data) != checksum {
+			break
+		}
+		records = append(records, data)
+		offset = end
+	}
+
+	return records, nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func writeFileSync(filename string, data []byte) error {
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0640)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,29 @@
+//go:build !windows
+
+package models
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// lockWAL takes an exclusive lock on the WAL buffer in the given directory,
+// closing the returned file releases the lock
+func lockWAL(path string) (*os.File, error) {
+	f, err := os.OpenFile(filepath.Join(path, walLockFile), os.O_CREATE|os.O_RDWR, 0640)
+	if err != nil {
+		return nil, fmt.Errorf("opening lock file failed: %w", err)
+	}
+	if err := unix.Flock(int(f.Fd()), unix.LOCK_EX|unix.LOCK_NB); err != nil {
+		f.Close()
+		if errors.Is(err, unix.EWOULDBLOCK) {
+			return nil, ErrWALLocked
+		}
+		return nil, fmt.Errorf("locking WAL buffer failed: %w", err)
+	}
+	return f, nil
+}
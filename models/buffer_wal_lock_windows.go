@@ -0,0 +1,30 @@
+//go:build windows
+
+package models
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockWAL takes an exclusive lock on the WAL buffer in the given directory,
+// closing the returned file releases the lock
+func lockWAL(path string) (*os.File, error) {
+	f, err := os.OpenFile(filepath.Join(path, walLockFile), os.O_CREATE|os.O_RDWR, 0640)
+	if err != nil {
+		return nil, fmt.Errorf("opening lock file failed: %w", err)
+	}
+	flags := uint32(windows.LOCKFILE_EXCLUSIVE_LOCK | windows.LOCKFILE_FAIL_IMMEDIATELY)
+	if err := windows.LockFileEx(windows.Handle(f.Fd()), flags, 0, 1, 0, &windows.Overlapped{}); err != nil {
+		f.Close()
+		if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+			return nil, ErrWALLocked
+		}
+		return nil, fmt.Errorf("locking WAL buffer failed: %w", err)
+	}
+	return f, nil
+}
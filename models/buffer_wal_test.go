@@ -0,0 +1,239 @@
+package models
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestWALBufferRestoresCursor(t *testing.T) {
+	path := t.TempDir()
+
+	buf, err := NewBuffer("test", "123", "", 0, "wal", path, WALConfig{SegmentSize: 256})
+	require.NoError(t, err)
+
+	expected := make([]telegraf.Metric, 0, 10)
+	for i := range 10 {
+		m := metric.New("test", map[string]string{}, map[string]interface{}{"value": i}, time.Unix(int64(i), 0))
+		buf.Add(m)
+		expected = append(expected, m)
+	}
+
+	// Acknowledge the first metrics
+	tx := buf.BeginTransaction(4)
+	testutil.RequireMetricsEqual(t, expected[:4], tx.Batch)
+	tx.AcceptAll()
+	buf.EndTransaction(tx)
+	require.NoError(t, buf.Close())
+
+	// Reopen the buffer and check the remaining metrics are replayed
+	buf, err = NewBuffer("test", "123", "", 0, "wal", path, WALConfig{SegmentSize: 256})
+	require.NoError(t, err)
+	defer buf.Close()
+	require.Equal(t, 6, buf.Len())
+
+	tx = buf.BeginTransaction(10)
+	testutil.RequireMetricsEqual(t, expected[4:], tx.Batch)
+	tx.AcceptAll()
+	buf.EndTransaction(tx)
+	require.Equal(t, 0, buf.Len())
+}
+
+func TestWALBufferIndexesSegments(t *testing.T) {
+	path := t.TempDir()
+
+	buf, err := NewBuffer("test", "123", "", 0, "wal", path, WALConfig{SegmentSize: 256, Compression: "zstd"})
+	require.NoError(t, err)
+	for i := range 20 {
+		buf.Add(metric.New("test", map[string]string{}, map[string]interface{}{"value": i}, time.Unix(int64(i), 0)))
+	}
+	require.NoError(t, buf.Close())
+
+	// Segments are only indexed when opening the buffer
+	info, segments, err := scanWAL(filepath.Join(path, "123"))
+	require.NoError(t, err)
+	require.Greater(t, len(segments), 1)
+	var records int
+	for i, s := range segments {
+		require.Nil(t, s.records)
+		require.Equal(t, info.Segments[i].Records, s.count)
+		records += s.count
+	}
+	require.Equal(t, 20, records)
+}
+
+func TestWALBufferTruncatesCorruptRecord(t *testing.T) {
+	path := t.TempDir()
+
+	buf, err := NewBuffer("test", "123", "", 0, "wal", path, WALConfig{})
+	require.NoError(t, err)
+	expected := []telegraf.Metric{
+		metric.New("test", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+		metric.New("test", map[string]string{}, map[string]interface{}{"value": 2}, time.Unix(0, 0)),
+	}
+	buf.Add(expected...)
+	require.NoError(t, buf.Close())
+
+	// Simulate a crash while writing the last record
+	filename := filepath.Join(path, "123", "00000000000000000000.wal")
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0640)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{0, 0, 1, 0, 42})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	buf, err = NewBuffer("test", "123", "", 0, "wal", path, WALConfig{})
+	require.NoError(t, err)
+	defer buf.Close()
+	require.Equal(t, 2, buf.Len())
+
+	m := metric.New("test", map[string]string{}, map[string]interface{}{"value": 3}, time.Unix(0, 0))
+	buf.Add(m)
+	expected = append(expected, m)
+
+	tx := buf.BeginTransaction(10)
+	testutil.RequireMetricsEqual(t, expected, tx.Batch)
+}
+
+func TestWALBufferMaxSize(t *testing.T) {
+	buf, err := NewBuffer("test", "123", "", 0, "wal", t.TempDir(), WALConfig{MaxSize: 1024})
+	require.NoError(t, err)
+	defer buf.Close()
+	buf.Stats().MetricsDropped.Set(0)
+
+	var dropped int
+	for i := range 100 {
+		m := metric.New("test", map[string]string{}, map[string]interface{}{"value": i}, time.Unix(int64(i), 0))
+		dropped += buf.Add(m)
+	}
+	require.Positive(t, dropped)
+	require.Equal(t, int64(dropped), buf.Stats().MetricsDropped.Get())
+	require.Equal(t, 100-dropped, buf.Len())
+
+	// The newest metrics must be retained
+	tx := buf.BeginTransaction(100)
+	require.Len(t, tx.Batch, 100-dropped)
+	require.Equal(t, int64(99), tx.Batch[len(tx.Batch)-1].Fields()["value"])
+}
+
+func TestWALBufferMaxAge(t *testing.T) {
+	buf, err := NewBuffer("test", "123", "", 0, "wal", t.TempDir(), WALConfig{MaxAge: 50 * time.Millisecond, SegmentSize: 1})
+	require.NoError(t, err)
+	defer buf.Close()
+
+	m := metric.New("test", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0))
+	require.Equal(t, 0, buf.Add(m, m))
+	time.Sleep(100 * time.Millisecond)
+
+	// Adding a new metric expires the old segments
+	require.Equal(t, 2, buf.Add(m))
+	require.Equal(t, 1, buf.Len())
+}
+
+func TestWALBufferTrackingDroppedOnReopen(t *testing.T) {
+	path := t.TempDir()
+
+	buf, err := NewBuffer("test", "123", "", 0, "wal", path, WALConfig{})
+	require.NoError(t, err)
+	m := metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 42.0}, time.Unix(0, 0))
+	tm, _ := metric.WithTracking(m, func(telegraf.DeliveryInfo) {})
+	buf.Add(m, tm, m)
+	require.NoError(t, buf.Close())
+
+	buf, err = NewBuffer("test", "123", "", 0, "wal", path, WALConfig{})
+	require.NoError(t, err)
+	defer buf.Close()
+
+	tx := buf.BeginTransaction(3)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{m, m}, tx.Batch)
+	tx.AcceptAll()
+	buf.EndTransaction(tx)
+	require.Equal(t, 0, buf.Len())
+}
+
+func TestWALBufferCompression(t *testing.T) {
+	path := t.TempDir()
+
+	buf, err := NewBuffer("test", "123", "", 0, "wal", path, WALConfig{SegmentSize: 512, Compression: "zstd"})
+	require.NoError(t, err)
+
+	expected := make([]telegraf.Metric, 0, 20)
+	for i := range 20 {
+		m := metric.New("test", map[string]string{}, map[string]interface{}{"value": i}, time.Unix(int64(i), 0))
+		buf.Add(m)
+		expected = append(expected, m)
+	}
+	require.NoError(t, buf.Close())
+
+	info, err := InspectWAL(filepath.Join(path, "123"))
+	require.NoError(t, err)
+	require.Greater(t, len(info.Segments), 1)
+	require.True(t, info.Segments[0].Compressed)
+
+	var records int
+	for _, s := range info.Segments {
+		records += s.Records
+	}
+	require.Equal(t, 20, records)
+
+	// Read back the metrics offline and via the buffer
+	var exported []telegraf.Metric
+	require.NoError(t, ExportWAL(filepath.Join(path, "123"), func(m telegraf.Metric) error {
+		exported = append(exported, m)
+		return nil
+	}))
+	testutil.RequireMetricsEqual(t, expected, exported)
+
+	buf, err = NewBuffer("test", "123", "", 0, "wal", path, WALConfig{SegmentSize: 512, Compression: "zstd"})
+	require.NoError(t, err)
+	defer buf.Close()
+	tx := buf.BeginTransaction(20)
+	testutil.RequireMetricsEqual(t, expected, tx.Batch)
+}
+
+func TestWALBufferPurge(t *testing.T) {
+	path := t.TempDir()
+
+	buf, err := NewBuffer("test", "123", "", 0, "wal", path, WALConfig{SegmentSize: 256})
+	require.NoError(t, err)
+	m := metric.New("test", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0))
+	buf.Add(m, m, m, m, m)
+	require.NoError(t, buf.Close())
+
+	require.NoError(t, PurgeWAL(filepath.Join(path, "123")))
+	entries, err := os.ReadDir(filepath.Join(path, "123"))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, walLockFile, entries[0].Name())
+}
+
+func TestWALBufferLocked(t *testing.T) {
+	path := t.TempDir()
+
+	buf, err := NewBuffer("test", "123", "", 0, "wal", path, WALConfig{SegmentSize: 256})
+	require.NoError(t, err)
+	m := metric.New("test", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0))
+	buf.Add(m, m, m)
+
+	// Neither purging nor opening the buffer a second time is possible while
+	// the buffer is in use
+	require.ErrorIs(t, PurgeWAL(filepath.Join(path, "123")), ErrWALLocked)
+	_, err = NewBuffer("test", "123", "", 0, "wal", path, WALConfig{SegmentSize: 256})
+	require.ErrorIs(t, err, ErrWALLocked)
+	require.Equal(t, 3, buf.Len())
+
+	require.NoError(t, buf.Close())
+	require.NoError(t, PurgeWAL(filepath.Join(path, "123")))
+}
+
+func TestWALBufferInvalidCompression(t *testing.T) {
+	_, err := NewBuffer("test", "123", "", 0, "wal", t.TempDir(), WALConfig{Compression: "lz4"})
+	require.ErrorContains(t, err, "invalid WAL compression")
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+const (
+	// DefaultFailoverThreshold is the default number of consecutive write
+	// failures after which the circuit of an output is opened
+	DefaultFailoverThreshold = 3
+	// DefaultFailoverRecoveryInterval is the default time after which an open
+	// circuit allows probing the output again
+	DefaultFailoverRecoveryInterval = 30 * time.Second
+)
+
+// CircuitState is the state of the circuit breaker of an output.
+type CircuitState int
+
+const (
+	// CircuitClosed denotes a healthy output receiving metrics
+	CircuitClosed CircuitState = iota
+	// CircuitOpen denotes a failing output not receiving metrics
+	CircuitOpen
+	// CircuitHalfOpen denotes a failing output probing for recovery
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+type circuitBreaker struct {
+	sync.Mutex
+
+	threshold int
+	recovery  time.Duration
+
+	state    CircuitState
+	failures int
+	openedAt time.Time
+
+	stateStat selfstat.Stat
+	opens     selfstat.Stat
+}
+
+func newCircuitBreaker(threshold int, recovery time.Duration, tags map[string]string) *circuitBreaker {
+	if threshold <= 0 {
+		threshold = DefaultFailoverThreshold
+	}
+	if recovery <= 0 {
+		recovery = DefaultFailoverRecoveryInterval
+	}
+
+	return &circuitBreaker{
+		threshold: threshold,
+		recovery:  recovery,
+		stateStat: selfstat.RegisterGauge("write", "circuit_state", tags),
+		opens:     selfstat.Register("write", "circuit_opens", tags),
+	}
+}
+
+// allow returns true if writing is permitted. An open circuit switches to
+// half-open after the recovery interval to allow a probing write.
+func (b *circuitBreaker) allow(now time.Time) bool {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.state == CircuitOpen && now.Sub(b.openedAt) >= b.recovery {
+		b.setState(CircuitHalfOpen)
+	}
+	return b.state != CircuitOpen
+}
+
+// record updates the circuit with the result of a write. Partial writes count
+// as success as the output is reachable.
+func (b *circuitBreaker) record(now time.Time, err error) {
+	b.Lock()
+	defer b.Unlock()
+
+	var writeErr *internal.PartialWriteError
+	if err == nil || errors.As(err, &writeErr) {
+		b.failures = 0
+		b.setState(CircuitClosed)
+		return
+	}
+
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.threshold {
+		b.openedAt = now
+		if b.state != CircuitOpen {
+			b.opens.Incr(1)
+		}
+		b.setState(CircuitOpen)
+	}
+}
+
+func (b *circuitBreaker) current() CircuitState {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.state
+}
+
+func (b *circuitBreaker) setState(state CircuitState) {
+	b.state = state
+	b.stateStat.Set(int64(state))
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"maps"
 	"reflect"
 
 	"github.com/influxdata/telegraf"
@@ -36,3 +37,18 @@ func SetLoggerOnPlugin(i interface{}, logger telegraf.Logger) {
 			valI.Type().Name(), field.Type().String())
 	}
 }
+
+// PluginWithStatTags is implemented by plugins registering their own internal
+// statistics to receive the tags identifying the plugin instance, i.e. the
+// plugin name, alias and ID. The tags are set before calling Init.
+type PluginWithStatTags interface {
+	SetStatTags(tags map[string]string)
+}
+
+// SetStatTagsOnPlugin passes a copy of the given tags to the plugin if it
+// implements PluginWithStatTags.
+func SetStatTagsOnPlugin(i interface{}, tags map[string]string) {
+	if p, ok := i.(PluginWithStatTags); ok {
+		p.SetStatTags(maps.Clone(tags))
+	}
+}
@@ -0,0 +1,234 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+const (
+	// DeadLetterReasonTag is the tag holding the reason of the rejection of
+	// a dead-lettered metric
+	DeadLetterReasonTag = "dead_letter_reason"
+	// DeadLetterOutputTag is the tag holding the output rejecting a
+	// dead-lettered metric
+	DeadLetterOutputTag = "dead_letter_output"
+	// DeadLetterMarkerTag marks metrics to be rejected by all outputs and sent
+	// to their dead-letter route, e.g. by processors detecting invalid
+	// metrics. The value is used as the rejection reason and the tag is
+	// removed when routing the metric.
+	DeadLetterMarkerTag = "_dead_letter"
+)
+
+// deadLetter routes the metrics rejected by an output either to another
+// output or to a file.
+type deadLetter struct {
+	sync.Mutex
+
+	kind   string
+	target string
+	route  func([]telegraf.Metric) error
+
+	Routed selfstat.Stat
+}
+
+func newDeadLetter(setting string, tags map[string]string) (*deadLetter, error) {
+	kind, target, found := strings.Cut(setting, ":")
+	if !found || target == "" {
+		return nil, fmt.Errorf("invalid 'dead_letter' setting %q, expecting 'output:<name>' or 'file:<path>'", setting)
+	}
+
+	d := &deadLetter{
+		kind:   kind,
+		target: target,
+		Routed: selfstat.Register("write", "metrics_dead_lettered", tags),
+	}
+	switch kind {
+	case "output":
+		// The route is set when linking the outputs
+	case "file":
+		f := &deadLetterFile{path: target}
+		if err := f.serializer.Init(); err != nil {
+			return nil, err
+		}
+		d.route = f.write
+	default:
+		return nil, fmt.Errorf("invalid 'dead_letter' type %q, expecting 'output' or 'file'", kind)
+	}
+
+	return d, nil
+}
+
+// send routes the rejected metrics of the transaction tagged with the reason
+// of the rejection.
+func (d *deadLetter) send(source string, tx *Transaction, err error) error {
+	if len(tx.Reject) == 0 {
+		return nil
+	}
+
+	var writeErr *internal.PartialWriteError
+	errors.As(err, &writeErr)
+
+	metrics := make([]telegraf.Metric, 0, len(tx.Reject))
+	reasons := make([]string, 0, len(tx.Reject))
+	for i, idx := range tx.Reject {
+		reason := err.Error()
+		if writeErr != nil && i < len(writeErr.MetricsRejectErrors) && writeErr.MetricsRejectErrors[i] != nil {
+			reason = writeErr.MetricsRejectErrors[i].Error()
+		}
+		metrics = append(metrics, tx.Batch[idx])
+		reasons = append(reasons, reason)
+	}
+	return d.forward(source, metrics, reasons)
+}
+
+// forward routes copies of the metrics tagged with the reason of the
+// rejection and the rejecting output. The given metrics are not modified.
+func (d *deadLetter) forward(source string, metrics []telegraf.Metric, reasons []string) error {
+	d.Lock()
+	route := d.route
+	d.Unlock()
+	if route == nil {
+		return nil
+	}
+
+	copies := make([]telegraf.Metric, 0, len(metrics))
+	for i, m := range metrics {
+		// Create an untracked copy as the original is rejected when the
+		// transaction ends
+		dm := metric.New(m.Name(), m.Tags(), m.Fields(), m.Time(), m.Type())
+		dm.RemoveTag(DeadLetterMarkerTag)
+		dm.AddTag(DeadLetterReasonTag, reasons[i])
+		dm.AddTag(DeadLetterOutputTag, source)
+		copies = append(copies, dm)
+	}
+
+	if err := route(copies); err != nil {
+		return err
+	}
+	d.Routed.Incr(int64(len(copies)))
+	return nil
+}
+
+// deadLetterFile appends dead-lettered metrics to a file in line-protocol
+type deadLetterFile struct {
+	sync.Mutex
+
+	path       string
+	serializer influx.Serializer
+}
+
+func (f *deadLetterFile) write(metrics []telegraf.Metric) error {
+	f.Lock()
+	defer f.Unlock()
+
+	buf, err := f.serializer.SerializeBatch(metrics)
+	if err != nil {
+		return fmt.Errorf("serializing dead-letter metrics failed: %w", err)
+	}
+
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return fmt.Errorf("opening dead-letter file failed: %w", err)
+	}
+	if _, err := file.Write(buf); err != nil {
+		file.Close()
+		return fmt.Errorf("writing dead-letter file failed: %w", err)
+	}
+	return file.Close()
+}
+
+// LinkDeadLetters connects the outputs with an output dead-letter route to
+// their target output. The target is referenced by its alias or, if unique,
+// by the plugin name. Routes with an invalid target or forming a loop, which
+// would pass rejected metrics around endlessly, are disabled.
+func LinkDeadLetters(outputs []*RunningOutput) error {
+	var errs []error
+	targets := make(map[*RunningOutput]*RunningOutput)
+	for _, output := range outputs {
+		d := output.deadLetter
+		if d == nil || d.kind != "output" {
+			continue
+		}
+
+		target, err := findDeadLetterTarget(outputs, d.target)
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("dead-letter route of %s: %w", output.LogName(), err))
+		case target == output:
+			errs = append(errs, fmt.Errorf("dead-letter route of %s references the output itself", output.LogName()))
+		default:
+			targets[output] = target
+		}
+	}
+
+	for _, output := range outputs {
+		d := output.deadLetter
+		if d == nil || d.kind != "output" {
+			continue
+		}
+
+		var route func([]telegraf.Metric) error
+		if target, found := targets[output]; found {
+			if deadLetterLoop(targets, output) {
+				errs = append(errs, fmt.Errorf("dead-letter route of %s forms a loop", output.LogName()))
+			} else {
+				route = func(metrics []telegraf.Metric) error {
+					for _, m := range metrics {
+						target.AddMetricNoCopy(m)
+					}
+					return nil
+				}
+			}
+		}
+
+		d.Lock()
+		d.route = route
+		d.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// deadLetterLoop returns true if following the dead-letter routes starting at
+// the given output leads back to the output
+func deadLetterLoop(targets map[*RunningOutput]*RunningOutput, start *RunningOutput) bool {
+	seen := map[*RunningOutput]bool{start: true}
+	for next := targets[start]; next != nil; next = targets[next] {
+		if seen[next] {
+			return next == start
+		}
+		seen[next] = true
+	}
+	return false
+}
+
+func findDeadLetterTarget(outputs []*RunningOutput, name string) (*RunningOutput, error) {
+	for _, output := range outputs {
+		if output.Config.Alias == name {
+			return output, nil
+		}
+	}
+
+	var found *RunningOutput
+	for _, output := range outputs {
+		if output.Config.Name != name {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("output %q is ambiguous, use an alias", name)
+		}
+		found = output
+	}
+	if found == nil {
+		return nil, fmt.Errorf("output %q not found", name)
+	}
+	return found, nil
+}
@@ -0,0 +1,186 @@
+package models
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestDeadLetterInvalidSetting(t *testing.T) {
+	for _, setting := range []string{"foo", "output:", "queue:dlq"} {
+		ro := NewRunningOutput(&mockOutput{}, &OutputConfig{Name: "invalid", DeadLetter: setting}, 1000, 10000)
+		require.ErrorContains(t, ro.Init(), "invalid 'dead_letter'", setting)
+	}
+}
+
+func TestDeadLetterOutput(t *testing.T) {
+	m := &rejectingOutput{reject: []int{1}}
+	ro := NewRunningOutput(m, &OutputConfig{Name: "rejecting", DeadLetter: "output:dlq"}, 1000, 10000)
+	require.NoError(t, ro.Init())
+	dlq := &mockOutput{}
+	roDLQ := NewRunningOutput(dlq, &OutputConfig{Name: "file", Alias: "dlq"}, 1000, 10000)
+	require.NoError(t, roDLQ.Init())
+	require.NoError(t, LinkDeadLetters([]*RunningOutput{ro, roDLQ}))
+
+	ro.AddMetric(testutil.TestMetric(1, "metric1"))
+	ro.AddMetric(testutil.TestMetric(2, "metric2"))
+	require.ErrorContains(t, ro.Write(), "invalid field value")
+	require.NoError(t, roDLQ.Write())
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"metric2",
+			map[string]string{
+				"tag1":               "value1",
+				"dead_letter_reason": "invalid field value",
+				"dead_letter_output": "outputs.rejecting",
+			},
+			map[string]interface{}{"value": 2},
+			time.Unix(1257894000, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, dlq.Metrics())
+	require.Equal(t, int64(1), ro.deadLetter.Routed.Get())
+	require.Equal(t, 0, ro.buffer.Len())
+}
+
+func TestDeadLetterFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "dead_letter.influx")
+	m := &rejectingOutput{reject: []int{0, 1}}
+	ro := NewRunningOutput(m, &OutputConfig{Name: "rejecting_file", DeadLetter: "file:" + filename}, 1000, 10000)
+	require.NoError(t, ro.Init())
+
+	ro.AddMetric(testutil.TestMetric(1, "metric1"))
+	ro.AddMetric(testutil.TestMetric(2, "metric2"))
+	require.ErrorContains(t, ro.Write(), "invalid field value")
+
+	buf, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	expected := "metric1,dead_letter_output=outputs.rejecting_file,dead_letter_reason=invalid\\ field\\ value,tag1=value1 value=1i 1257894000000000000\n" +
+		"metric2,dead_letter_output=outputs.rejecting_file,dead_letter_reason=invalid\\ field\\ value,tag1=value1 value=2i 1257894000000000000\n"
+	require.Equal(t, expected, string(buf))
+}
+
+func TestDeadLetterLoop(t *testing.T) {
+	a := NewRunningOutput(&rejectingOutput{reject: []int{0}}, &OutputConfig{Name: "loop", Alias: "a", DeadLetter: "output:b"}, 1000, 10000)
+	require.NoError(t, a.Init())
+	b := NewRunningOutput(&rejectingOutput{reject: []int{0}}, &OutputConfig{Name: "loop", Alias: "b", DeadLetter: "output:a"}, 1000, 10000)
+	require.NoError(t, b.Init())
+	require.ErrorContains(t, LinkDeadLetters([]*RunningOutput{a, b}), "forms a loop")
+
+	// Routes forming a loop are disabled
+	a.AddMetric(testutil.TestMetric(1, "metric1"))
+	require.ErrorContains(t, a.Write(), "invalid field value")
+	require.Equal(t, 0, a.buffer.Len())
+	require.Equal(t, 0, b.buffer.Len())
+}
+
+func TestDeadLetterChain(t *testing.T) {
+	a := NewRunningOutput(&rejectingOutput{reject: []int{0}}, &OutputConfig{Name: "chain", Alias: "a", DeadLetter: "output:b"}, 1000, 10000)
+	require.NoError(t, a.Init())
+	b := NewRunningOutput(&rejectingOutput{reject: []int{0}}, &OutputConfig{Name: "chain", Alias: "b", DeadLetter: "output:c"}, 1000, 10000)
+	require.NoError(t, b.Init())
+	dlq := &mockOutput{}
+	c := NewRunningOutput(dlq, &OutputConfig{Name: "chain", Alias: "c"}, 1000, 10000)
+	require.NoError(t, c.Init())
+	require.NoError(t, LinkDeadLetters([]*RunningOutput{a, b, c}))
+
+	// Metrics rejected by a dead-letter output follow its route
+	a.AddMetric(testutil.TestMetric(1, "metric1"))
+	require.ErrorContains(t, a.Write(), "invalid field value")
+	require.ErrorContains(t, b.Write(), "invalid field value")
+	require.NoError(t, c.Write())
+	require.Len(t, dlq.Metrics(), 1)
+	require.Equal(t, "outputs.chain::b", dlq.Metrics()[0].Tags()[DeadLetterOutputTag])
+}
+
+func TestDeadLetterMarker(t *testing.T) {
+	m := &mockOutput{}
+	ro := NewRunningOutput(m, &OutputConfig{Name: "marked", DeadLetter: "output:dlq"}, 1000, 10000)
+	require.NoError(t, ro.Init())
+	dlq := &mockOutput{}
+	roDLQ := NewRunningOutput(dlq, &OutputConfig{Name: "file", Alias: "dlq"}, 1000, 10000)
+	require.NoError(t, roDLQ.Init())
+	require.NoError(t, LinkDeadLetters([]*RunningOutput{ro, roDLQ}))
+
+	marked := testutil.TestMetric(2, "metric2")
+	marked.AddTag(DeadLetterMarkerTag, "schema violation")
+	ro.AddMetric(testutil.TestMetric(1, "metric1"))
+	ro.AddMetric(marked)
+	require.NoError(t, ro.Write())
+	require.NoError(t, roDLQ.Write())
+
+	// The marked metric is rejected and routed without the marker
+	require.Len(t, m.Metrics(), 1)
+	require.Equal(t, "metric1", m.Metrics()[0].Name())
+	expected := []telegraf.Metric{
+		metric.New(
+			"metric2",
+			map[string]string{
+				"tag1":               "value1",
+				"dead_letter_reason": "schema violation",
+				"dead_letter_output": "outputs.marked",
+			},
+			map[string]interface{}{"value": 2},
+			time.Unix(1257894000, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, dlq.Metrics())
+	require.True(t, marked.HasTag(DeadLetterMarkerTag))
+	require.Equal(t, int64(1), ro.BufferStats().MetricsRejected.Get())
+	require.Equal(t, int64(1), ro.deadLetter.Routed.Get())
+}
+
+func TestDeadLetterLinkErrors(t *testing.T) {
+	self := NewRunningOutput(&mockOutput{}, &OutputConfig{Name: "self", DeadLetter: "output:self"}, 1000, 10000)
+	require.NoError(t, self.Init())
+	require.ErrorContains(t, LinkDeadLetters([]*RunningOutput{self}), "references the output itself")
+
+	missing := NewRunningOutput(&mockOutput{}, &OutputConfig{Name: "missing", DeadLetter: "output:dlq"}, 1000, 10000)
+	require.NoError(t, missing.Init())
+	require.ErrorContains(t, LinkDeadLetters([]*RunningOutput{missing}), `output "dlq" not found`)
+
+	dup1 := NewRunningOutput(&mockOutput{}, &OutputConfig{Name: "dup"}, 1000, 10000)
+	dup2 := NewRunningOutput(&mockOutput{}, &OutputConfig{Name: "dup"}, 1000, 10000)
+	ambiguous := NewRunningOutput(&mockOutput{}, &OutputConfig{Name: "ambiguous", DeadLetter: "output:dup"}, 1000, 10000)
+	require.NoError(t, ambiguous.Init())
+	require.ErrorContains(t, LinkDeadLetters([]*RunningOutput{ambiguous, dup1, dup2}), "is ambiguous")
+}
+
+type rejectingOutput struct {
+	reject []int
+}
+
+func (*rejectingOutput) Connect() error {
+	return nil
+}
+
+func (*rejectingOutput) Close() error {
+	return nil
+}
+
+func (*rejectingOutput) SampleConfig() string {
+	return ""
+}
+
+func (o *rejectingOutput) Write(metrics []telegraf.Metric) error {
+	werr := &internal.PartialWriteError{Err: errors.New("invalid field value")}
+	for i := range metrics {
+		if slices.Contains(o.reject, i) {
+			werr.MetricsReject = append(werr.MetricsReject, i)
+		} else {
+			werr.MetricsAccept = append(werr.MetricsAccept, i)
+		}
+	}
+	return werr
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"log"
+	"slices"
+	"sync"
+
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// FailoverGroup is a set of outputs of which only one, the active output,
+// receives metrics. The active output is the output with the highest priority,
+// i.e. the lowest priority value, whose circuit is not open. An output probing
+// for recovery thus receives the metrics to write as probe.
+type FailoverGroup struct {
+	Name string
+
+	Failovers selfstat.Stat
+
+	mu      sync.Mutex
+	members []*RunningOutput
+	active  *RunningOutput
+}
+
+// NewFailoverGroups creates the failover groups for the given outputs and
+// returns the group of each output being member of a group.
+func NewFailoverGroups(outputs []*RunningOutput) map[*RunningOutput]*FailoverGroup {
+	groups := make(map[string]*FailoverGroup)
+	membership := make(map[*RunningOutput]*FailoverGroup)
+	for _, output := range outputs {
+		name := output.Config.FailoverGroup
+		if name == "" {
+			continue
+		}
+		group, found := groups[name]
+		if !found {
+			group = &FailoverGroup{
+				Name:      name,
+				Failovers: selfstat.Register("failover", "failovers", map[string]string{"group": name}),
+			}
+			groups[name] = group
+		}
+		group.members = append(group.members, output)
+		membership[output] = group
+		output.group.Store(group)
+	}
+
+	for _, group := range groups {
+		slices.SortStableFunc(group.members, func(a, b *RunningOutput) int {
+			return a.Config.FailoverPriority - b.Config.FailoverPriority
+		})
+		group.active = group.members[0]
+	}
+
+	return membership
+}
+
+// Active returns the output currently receiving the metrics of the group.
+func (g *FailoverGroup) Active() *RunningOutput {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// Use the first output not failing or probing for recovery and fall back
+	// to the primary output if all circuits are open
+	active := g.members[0]
+	for _, output := range g.members {
+		if output.CircuitState() != CircuitOpen {
+			active = output
+			break
+		}
+	}
+
+	if active != g.active {
+		log.Printf("I! [agent] Failover group %q switched from %s to %s", g.Name, g.active.LogName(), active.LogName())
+		g.Failovers.Incr(1)
+		g.active = active
+	}
+	return active
+}
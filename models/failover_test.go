@@ -0,0 +1,137 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	b := newCircuitBreaker(2, time.Minute, map[string]string{"output": "test"})
+	now := time.Now()
+	require.True(t, b.allow(now))
+
+	// Open the circuit after the threshold of consecutive failures
+	b.record(now, errors.New("failed write"))
+	require.Equal(t, CircuitClosed, b.current())
+	b.record(now, errors.New("failed write"))
+	require.Equal(t, CircuitOpen, b.current())
+	require.Equal(t, int64(1), b.opens.Get())
+	require.False(t, b.allow(now.Add(30*time.Second)))
+
+	// A failing probe opens the circuit again
+	require.True(t, b.allow(now.Add(time.Minute)))
+	require.Equal(t, CircuitHalfOpen, b.current())
+	b.record(now.Add(time.Minute), errors.New("failed write"))
+	require.Equal(t, CircuitOpen, b.current())
+	require.False(t, b.allow(now.Add(90*time.Second)))
+
+	// A successful probe closes the circuit
+	require.True(t, b.allow(now.Add(2*time.Minute)))
+	b.record(now.Add(2*time.Minute), nil)
+	require.Equal(t, CircuitClosed, b.current())
+	require.Equal(t, int64(2), b.opens.Get())
+}
+
+func TestFailoverGroup(t *testing.T) {
+	primary := &mockOutput{}
+	roPrimary := NewRunningOutput(primary, &OutputConfig{
+		Name:              "primary",
+		FailoverGroup:     "test",
+		FailoverPriority:  1,
+		FailoverThreshold: 1,
+	}, 1000, 10000)
+	secondary := &mockOutput{}
+	roSecondary := NewRunningOutput(secondary, &OutputConfig{
+		Name:              "secondary",
+		FailoverGroup:     "test",
+		FailoverPriority:  2,
+		FailoverThreshold: 1,
+	}, 1000, 10000)
+	standalone := NewRunningOutput(&mockOutput{}, &OutputConfig{Name: "standalone"}, 1000, 10000)
+
+	groups := NewFailoverGroups([]*RunningOutput{roSecondary, standalone, roPrimary})
+	require.Len(t, groups, 2)
+	group := groups[roPrimary]
+	require.Same(t, group, groups[roSecondary])
+	require.Same(t, roPrimary, group.Active())
+
+	// Fail over to the secondary output after the primary failed and hand
+	// over the buffered metrics
+	primary.batchAcceptSize = -1
+	roPrimary.AddMetric(testutil.TestMetric(1, "metric1"))
+	require.Error(t, roPrimary.Write())
+	require.Equal(t, CircuitOpen, roPrimary.CircuitState())
+	require.Same(t, roSecondary, group.Active())
+	require.Equal(t, int64(1), group.Failovers.Get())
+	require.Equal(t, 0, roPrimary.BufferLength())
+	require.NoError(t, roSecondary.Write())
+	require.Len(t, secondary.Metrics(), 1)
+
+	// Writes are skipped while the circuit is open
+	require.NoError(t, roPrimary.Write())
+	require.Equal(t, 1, primary.writes)
+
+	// Probing without metrics must not close the circuit but makes the
+	// primary output receive metrics again
+	primary.batchAcceptSize = 0
+	roPrimary.breaker.openedAt = time.Now().Add(-DefaultFailoverRecoveryInterval)
+	require.NoError(t, roPrimary.Write())
+	require.Equal(t, CircuitHalfOpen, roPrimary.CircuitState())
+	require.Equal(t, 1, primary.writes)
+	require.Same(t, roPrimary, group.Active())
+	require.Equal(t, int64(2), group.Failovers.Get())
+
+	// Close the circuit after a successful probe
+	roPrimary.AddMetric(testutil.TestMetric(2, "metric2"))
+	require.NoError(t, roPrimary.Write())
+	require.Equal(t, CircuitClosed, roPrimary.CircuitState())
+	require.Len(t, primary.Metrics(), 1)
+	require.Same(t, roPrimary, group.Active())
+	require.Equal(t, int64(2), group.Failovers.Get())
+}
+
+func TestFailoverGroupFailedProbe(t *testing.T) {
+	primary := &mockOutput{}
+	roPrimary := NewRunningOutput(primary, &OutputConfig{
+		Name:              "primary",
+		FailoverGroup:     "test",
+		FailoverPriority:  1,
+		FailoverThreshold: 1,
+	}, 1000, 10000)
+	secondary := &mockOutput{}
+	roSecondary := NewRunningOutput(secondary, &OutputConfig{
+		Name:              "secondary",
+		FailoverGroup:     "test",
+		FailoverPriority:  2,
+		FailoverThreshold: 1,
+	}, 1000, 10000)
+	group := NewFailoverGroups([]*RunningOutput{roPrimary, roSecondary})[roPrimary]
+
+	primary.batchAcceptSize = -1
+	roPrimary.AddMetric(testutil.TestMetric(1, "metric1"))
+	require.Error(t, roPrimary.Write())
+	require.Same(t, roSecondary, group.Active())
+
+	// A failing probe opens the circuit again and hands the probe metrics over
+	// to the secondary output
+	roPrimary.breaker.openedAt = time.Now().Add(-DefaultFailoverRecoveryInterval)
+	require.NoError(t, roPrimary.Write())
+	require.Same(t, roPrimary, group.Active())
+	roPrimary.AddMetric(testutil.TestMetric(2, "metric2"))
+	require.Error(t, roPrimary.Write())
+	require.Equal(t, CircuitOpen, roPrimary.CircuitState())
+	require.Same(t, roSecondary, group.Active())
+	require.Equal(t, 0, roPrimary.BufferLength())
+
+	require.NoError(t, roSecondary.Write())
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{testutil.TestMetric(1, "metric1"), testutil.TestMetric(2, "metric2")},
+		secondary.Metrics(),
+	)
+}
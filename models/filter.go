@@ -3,6 +3,7 @@ package models
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/cel-go/cel"
@@ -160,6 +161,39 @@ func (f *Filter) Select(metric telegraf.Metric) (bool, error) {
 	return true, nil
 }
 
+// Explain works like Select but additionally returns the rule rejecting the
+// metric if it does not match. The metric is not modified.
+func (f *Filter) Explain(metric telegraf.Metric) (bool, string, error) {
+	ok, err := f.Select(metric)
+	if ok || err != nil {
+		return ok, "", err
+	}
+
+	name := metric.Name()
+	if f.namePassFilter != nil && !f.namePassFilter.Match(name) {
+		return false, fmt.Sprintf("namepass %q", f.NamePass), nil
+	}
+	if f.nameDropFilter != nil && f.nameDropFilter.Match(name) {
+		return false, fmt.Sprintf("namedrop %q", f.NameDrop), nil
+	}
+
+	tags := metric.TagList()
+	if f.TagPassFilters != nil && !ShouldTagsPass(f.TagPassFilters, nil, tags) {
+		rules := make([]string, 0, len(f.TagPassFilters))
+		for _, pat := range f.TagPassFilters {
+			rules = append(rules, fmt.Sprintf("%s=%q", pat.Name, pat.Values))
+		}
+		return false, "tagpass " + strings.Join(rules, " "), nil
+	}
+	for _, pat := range f.TagDropFilters {
+		if !ShouldTagsPass(nil, []TagFilter{pat}, tags) {
+			return false, fmt.Sprintf("tagdrop %s=%q", pat.Name, pat.Values), nil
+		}
+	}
+
+	return false, fmt.Sprintf("metricpass %q", f.MetricPass), nil
+}
+
 // Modify removes any tags and fields from the metric according to the
 // fieldinclude/fieldexclude and taginclude/tagexclude filters.
 func (f *Filter) Modify(metric telegraf.Metric) {
@@ -646,6 +646,67 @@ func TestFilterMetricPass(t *testing.T) {
 	}
 }
 
+func TestFilterExplain(t *testing.T) {
+	m := testutil.MustMetric("cpu",
+		map[string]string{"cpu": "cpu0", "host": "localhost"},
+		map[string]interface{}{"value": 42},
+		time.Unix(0, 0),
+	)
+
+	var tests = []struct {
+		name     string
+		filter   Filter
+		expected string
+	}{
+		{
+			name:   "selected",
+			filter: Filter{NamePass: []string{"cpu"}},
+		},
+		{
+			name:     "namepass",
+			filter:   Filter{NamePass: []string{"mem", "disk"}},
+			expected: `namepass ["mem" "disk"]`,
+		},
+		{
+			name:     "namedrop",
+			filter:   Filter{NamePass: []string{"c*"}, NameDrop: []string{"cpu"}},
+			expected: `namedrop ["cpu"]`,
+		},
+		{
+			name: "tagpass",
+			filter: Filter{
+				TagPassFilters: []TagFilter{{Name: "cpu", Values: []string{"cpu-total"}}},
+			},
+			expected: `tagpass cpu=["cpu-total"]`,
+		},
+		{
+			name: "tagdrop",
+			filter: Filter{
+				TagDropFilters: []TagFilter{
+					{Name: "cpu", Values: []string{"cpu-total"}},
+					{Name: "host", Values: []string{"local*"}},
+				},
+			},
+			expected: `tagdrop host=["local*"]`,
+		},
+		{
+			name:     "metricpass",
+			filter:   Filter{MetricPass: `fields.value > 100`},
+			expected: `metricpass "fields.value > 100"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, tt.filter.Compile())
+			selected, reason, err := tt.filter.Explain(m)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected == "", selected)
+			require.Equal(t, tt.expected, reason)
+		})
+	}
+}
+
 func BenchmarkFilter(b *testing.B) {
 	tests := []struct {
 		name   string
@@ -71,6 +71,7 @@ func NewRunningAggregator(aggregator telegraf.Aggregator, config *AggregatorConf
 type AggregatorConfig struct {
 	Name         string
 	Source       string
+	Line         int
 	Alias        string
 	ID           string
 	DropOriginal bool
@@ -3,6 +3,7 @@ package models
 import (
 	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -30,8 +31,13 @@ type RunningInput struct {
 	gatherStart time.Time
 	gatherEnd   time.Time
 
+	status          statusTracker
+	paused          atomic.Bool
+	gatherRequested chan struct{}
+
 	MetricsGathered selfstat.Stat
 	GatherTime      selfstat.Stat
+	GatherDuration  *selfstat.Histogram
 	GatherTimeouts  selfstat.Stat
 	StartupErrors   selfstat.Stat
 }
@@ -66,6 +72,11 @@ func NewRunningInput(input telegraf.Input, config *InputConfig) *RunningInput {
 			"gather_time_ns",
 			tags,
 		),
+		GatherDuration: selfstat.RegisterDurationHistogram(
+			"gather",
+			"gather_duration_seconds",
+			tags,
+		),
 		GatherTimeouts: selfstat.Register(
 			"gather",
 			"gather_timeouts",
@@ -76,7 +87,8 @@ func NewRunningInput(input telegraf.Input, config *InputConfig) *RunningInput {
 			"startup_errors",
 			tags,
 		),
-		log: logger,
+		log:             logger,
+		gatherRequested: make(chan struct{}, 1),
 	}
 }
 
@@ -84,6 +96,7 @@ func NewRunningInput(input telegraf.Input, config *InputConfig) *RunningInput {
 type InputConfig struct {
 	Name                 string
 	Source               string
+	Line                 int
 	Alias                string
 	ID                   string
 	Interval             time.Duration
@@ -193,6 +206,12 @@ func (r *RunningInput) ID() string {
 }
 
 func (r *RunningInput) MakeMetric(metric telegraf.Metric) telegraf.Metric {
+	// Discard metrics of paused service inputs
+	if r.paused.Load() {
+		metric.Drop()
+		return nil
+	}
+
 	ok, err := r.Config.Filter.Select(metric)
 	if err != nil {
 		r.log.Errorf("filtering failed: %v", err)
@@ -247,6 +266,7 @@ func (r *RunningInput) Gather(acc telegraf.Accumulator) error {
 			var serr *internal.StartupError
 			if !errors.As(err, &serr) || !serr.Retry || !serr.Partial {
 				r.StartupErrors.Incr(1)
+				r.status.update(time.Now(), err)
 				return internal.ErrNotConnected
 			}
 			r.log.Debugf("Partially connected after %d attempts", r.retries)
@@ -261,9 +281,47 @@ func (r *RunningInput) Gather(acc telegraf.Accumulator) error {
 	r.gatherEnd = time.Now()
 
 	r.GatherTime.Incr(r.gatherEnd.Sub(r.gatherStart).Nanoseconds())
+	r.GatherDuration.ObserveDuration(r.gatherEnd.Sub(r.gatherStart))
+	r.status.update(r.gatherEnd, err)
 	return err
 }
 
+// Status returns the time of the last gather and the last error.
+func (r *RunningInput) Status() Status {
+	return r.status.get()
+}
+
+// Pause stops gathering the input on schedule and discards the metrics of
+// service inputs until the input is resumed.
+func (r *RunningInput) Pause() {
+	r.paused.Store(true)
+}
+
+// Resume continues gathering a paused input.
+func (r *RunningInput) Resume() {
+	r.paused.Store(false)
+}
+
+// Paused returns true if the input is paused.
+func (r *RunningInput) Paused() bool {
+	return r.paused.Load()
+}
+
+// RequestGather requests to gather the input immediately. Requests made while
+// a previous request is pending are ignored.
+func (r *RunningInput) RequestGather() {
+	select {
+	case r.gatherRequested <- struct{}{}:
+	default:
+	}
+}
+
+// GatherRequested returns a channel receiving the requests for gathering the
+// input immediately.
+func (r *RunningInput) GatherRequested() <-chan struct{} {
+	return r.gatherRequested
+}
+
 func (r *RunningInput) SetDefaultTags(tags map[string]string) {
 	r.defaultTags = tags
 }
@@ -273,6 +273,37 @@ func TestRunningInputMetricErrorCounters(t *testing.T) {
 	require.GreaterOrEqual(t, int64(1), GlobalGatherErrors.Get())
 }
 
+func TestRunningInputPaused(t *testing.T) {
+	ri := NewRunningInput(&mockInput{}, &InputConfig{
+		Name: "TestRunningInput",
+	})
+	m := testutil.MustMetric("RITest",
+		map[string]string{},
+		map[string]interface{}{"value": int64(101)},
+		time.Now(),
+	)
+
+	ri.Pause()
+	require.True(t, ri.Paused())
+	require.Nil(t, ri.MakeMetric(m))
+
+	ri.Resume()
+	require.False(t, ri.Paused())
+	require.NotNil(t, ri.MakeMetric(m))
+}
+
+func TestRunningInputStatus(t *testing.T) {
+	ri := NewRunningInput(&mockInput{}, &InputConfig{
+		Name: "TestRunningInput",
+	})
+	require.Zero(t, ri.Status())
+
+	require.NoError(t, ri.Gather(&testutil.Accumulator{}))
+	status := ri.Status()
+	require.False(t, status.LastRun.IsZero())
+	require.Empty(t, status.LastError)
+}
+
 func TestRunningInputMakeMetricWithAlwaysKeepingPluginTagsDisabled(t *testing.T) {
 	now := time.Now()
 	ri := NewRunningInput(&mockInput{}, &InputConfig{
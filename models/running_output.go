@@ -1,12 +1,18 @@
 package models
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	logging "github.com/influxdata/telegraf/logger"
@@ -25,6 +31,7 @@ const (
 type OutputConfig struct {
 	Name                 string
 	Source               string
+	Line                 int
 	Alias                string
 	ID                   string
 	StartupErrorBehavior string
@@ -41,6 +48,18 @@ type OutputConfig struct {
 
 	BufferStrategy  string
 	BufferDirectory string
+	BufferWAL       WALConfig
+
+	FailoverGroup            string
+	FailoverPriority         int
+	FailoverThreshold        int
+	FailoverRecoveryInterval time.Duration
+
+	MaxMetricsPerSecond int64
+	MaxBytesPerSecond   int64
+	AdaptiveBatchSize   bool
+
+	DeadLetter string
 
 	LogLevel string
 }
@@ -58,10 +77,19 @@ type RunningOutput struct {
 
 	MetricsFiltered selfstat.Stat
 	WriteTime       selfstat.Stat
+	WriteDuration   *selfstat.Histogram
 	StartupErrors   selfstat.Stat
 
 	BatchReady chan time.Time
 
+	status         statusTracker
+	flushRequested chan struct{}
+	breaker        *circuitBreaker
+	group          atomic.Pointer[FailoverGroup]
+	limits         *writeLimits
+	deadLetter     *deadLetter
+	tracer         trace.Tracer
+
 	buffer Buffer
 	log    telegraf.Logger
 
@@ -100,14 +128,9 @@ func NewRunningOutput(output telegraf.Output, config *OutputConfig, batchSize, b
 		batchSize = DefaultMetricBatchSize
 	}
 
-	b, err := NewBuffer(config.Name, config.ID, config.Alias, bufferLimit, config.BufferStrategy, config.BufferDirectory)
-	if err != nil {
-		panic(err)
-	}
-
 	ro := &RunningOutput{
-		buffer:            b,
 		BatchReady:        make(chan time.Time, 1),
+		flushRequested:    make(chan struct{}, 1),
 		Output:            output,
 		Config:            config,
 		MetricBufferLimit: bufferLimit,
@@ -122,17 +145,47 @@ func NewRunningOutput(output telegraf.Output, config *OutputConfig, batchSize, b
 			"write_time_ns",
 			tags,
 		),
+		WriteDuration: selfstat.RegisterDurationHistogram(
+			"write",
+			"write_duration_seconds",
+			tags,
+		),
 		StartupErrors: selfstat.Register(
 			"write",
 			"startup_errors",
 			tags,
 		),
-		log: logger,
+		tracer: noop.NewTracerProvider().Tracer(""),
+		log:    logger,
+	}
+	ro.limits = newWriteLimits(config, batchSize, tags)
+
+	// Persistent buffers are opened on initialization to not access the
+	// buffer directory for outputs never started, e.g. the instances
+	// superseded by already running outputs when reloading the configuration.
+	if !ro.persistentBuffer() {
+		b, err := ro.newBuffer()
+		if err != nil {
+			panic(err)
+		}
+		ro.buffer = b
+	}
+	if config.FailoverGroup != "" {
+		ro.breaker = newCircuitBreaker(config.FailoverThreshold, config.FailoverRecoveryInterval, tags)
 	}
 
 	return ro
 }
 
+func (r *RunningOutput) persistentBuffer() bool {
+	return r.Config.BufferStrategy == "disk" || r.Config.BufferStrategy == "wal"
+}
+
+func (r *RunningOutput) newBuffer() (Buffer, error) {
+	c := r.Config
+	return NewBuffer(c.Name, c.ID, c.Alias, r.MetricBufferLimit, c.BufferStrategy, c.BufferDirectory, c.BufferWAL)
+}
+
 func (r *RunningOutput) LogName() string {
 	return logName("outputs", r.Config.Name, r.Config.Alias)
 }
@@ -156,6 +209,26 @@ func (r *RunningOutput) Init() error {
 		return fmt.Errorf("invalid 'startup_error_behavior' setting %q", r.Config.StartupErrorBehavior)
 	}
 
+	if r.buffer == nil {
+		b, err := r.newBuffer()
+		if err != nil {
+			return fmt.Errorf("creating buffer failed: %w", err)
+		}
+		r.buffer = b
+	}
+
+	if r.Config.DeadLetter != "" {
+		tags := map[string]string{"output": r.Config.Name}
+		if r.Config.Alias != "" {
+			tags["alias"] = r.Config.Alias
+		}
+		d, err := newDeadLetter(r.Config.DeadLetter, tags)
+		if err != nil {
+			return err
+		}
+		r.deadLetter = d
+	}
+
 	if p, ok := r.Output.(telegraf.Initializer); ok {
 		err := p.Init()
 		if err != nil {
@@ -200,14 +273,29 @@ func (r *RunningOutput) Close() {
 	if err := r.Output.Close(); err != nil {
 		r.log.Errorf("Error closing output: %v", err)
 	}
+	r.closeBuffer()
+}
 
+// Discard releases the resources of an output that was never connected, such
+// as its buffer, without closing the output plugin itself.
+func (r *RunningOutput) Discard() {
+	r.closeBuffer()
+}
+
+func (r *RunningOutput) closeBuffer() {
+	// Persistent buffers are not opened before initialization
+	if r.buffer == nil {
+		return
+	}
 	if err := r.buffer.Close(); err != nil {
 		r.log.Errorf("Error closing output buffer: %v", err)
 	}
 }
 
 // AddMetric adds a metric to the output.
-// The given metric will be copied if the output selects the metric.
+// The given metric will be copied if the output selects the metric. Outputs
+// using a shared buffer only reference the metric if they do not modify it,
+// the fan-out must be completed using ReleaseShared in this case.
 func (r *RunningOutput) AddMetric(metric telegraf.Metric) {
 	ok, err := r.Config.Filter.Select(metric)
 	if err != nil {
@@ -216,6 +304,19 @@ func (r *RunningOutput) AddMetric(metric telegraf.Metric) {
 		r.MetricsFiltered.Incr(1)
 		return
 	}
+	if r.deadLetterMarked(metric) {
+		return
+	}
+
+	if b, ok := r.buffer.(*SharedBuffer); ok && !r.modifiesMetrics() {
+		if len(metric.FieldList()) == 0 {
+			r.MetricsFiltered.Incr(1)
+			return
+		}
+		dropped := b.AddShared(metric)
+		r.added(dropped)
+		return
+	}
 
 	r.add(metric.Copy())
 }
@@ -230,10 +331,33 @@ func (r *RunningOutput) AddMetricNoCopy(metric telegraf.Metric) {
 		r.metricFiltered(metric)
 		return
 	}
+	if r.deadLetterMarked(metric) {
+		metric.Reject()
+		return
+	}
 
 	r.add(metric)
 }
 
+// deadLetterMarked rejects metrics marked for dead-lettering and routes them
+// to the dead-letter route of the output if any. It returns false for all
+// other metrics. The given metric is not modified.
+func (r *RunningOutput) deadLetterMarked(metric telegraf.Metric) bool {
+	reason, found := metric.GetTag(DeadLetterMarkerTag)
+	if !found {
+		return false
+	}
+
+	AgentMetricsRejected.Incr(1)
+	r.buffer.Stats().MetricsRejected.Incr(1)
+	if r.deadLetter != nil {
+		if err := r.deadLetter.forward(r.LogName(), []telegraf.Metric{metric}, []string{reason}); err != nil {
+			r.log.Errorf("Routing rejected metrics to dead-letter failed: %v", err)
+		}
+	}
+	return true
+}
+
 func (r *RunningOutput) add(metric telegraf.Metric) {
 	r.Config.Filter.Modify(metric)
 	if len(metric.FieldList()) == 0 {
@@ -260,7 +384,18 @@ func (r *RunningOutput) add(metric telegraf.Metric) {
 		metric.AddSuffix(r.Config.NameSuffix)
 	}
 
-	dropped := r.buffer.Add(metric)
+	r.added(r.buffer.Add(metric))
+}
+
+// modifiesMetrics returns true if adding a metric to the output changes it
+func (r *RunningOutput) modifiesMetrics() bool {
+	if _, ok := r.Output.(telegraf.AggregatingOutput); ok {
+		return true
+	}
+	return r.Config.Filter.modifyActive || r.Config.NameOverride != "" || r.Config.NamePrefix != "" || r.Config.NameSuffix != ""
+}
+
+func (r *RunningOutput) added(dropped int) {
 	atomic.AddInt64(&r.droppedMetrics, int64(dropped))
 
 	count := atomic.AddInt64(&r.newMetricsCount, 1)
@@ -273,9 +408,20 @@ func (r *RunningOutput) add(metric telegraf.Metric) {
 	}
 }
 
+// SetTracer sets the tracer for recording the written batches as spans.
+func (r *RunningOutput) SetTracer(tracer trace.Tracer) {
+	r.tracer = tracer
+}
+
 // Write writes all metrics to the output, stopping when all have been sent on
 // or error.
 func (r *RunningOutput) Write() error {
+	return r.WriteContext(context.Background())
+}
+
+// WriteContext is like Write but records the written batches as children of
+// the span in the given context.
+func (r *RunningOutput) WriteContext(ctx context.Context) error {
 	// Try to connect if we are not yet started up
 	if !r.started {
 		r.retries++
@@ -283,6 +429,7 @@ func (r *RunningOutput) Write() error {
 			var serr *internal.StartupError
 			if !errors.As(err, &serr) || !serr.Retry || !serr.Partial {
 				r.StartupErrors.Incr(1)
+				r.status.update(time.Now(), err)
 				return internal.ErrNotConnected
 			}
 			r.log.Debugf("Partially connected after %d attempts", r.retries)
@@ -302,47 +449,164 @@ func (r *RunningOutput) Write() error {
 
 	atomic.StoreInt64(&r.newMetricsCount, 0)
 
+	if !r.allowWrite() {
+		r.handOver()
+		return nil
+	}
+
 	// Only process the metrics in the buffer now. Metrics added while we are
 	// writing will be sent on the next call.
 	nBuffer := r.buffer.Len()
-	nBatches := nBuffer/r.MetricBatchSize + 1
-	for i := 0; i < nBatches; i++ {
-		tx := r.buffer.BeginTransaction(r.MetricBatchSize)
-		if len(tx.Batch) == 0 {
-			return nil
-		}
-		err := r.writeMetrics(tx.Batch)
-		r.updateTransaction(tx, err)
-		r.buffer.EndTransaction(tx)
+	for written := 0; written < nBuffer; {
+		n, err := r.writeBatch(ctx)
 		if err != nil {
+			r.handOver()
 			return err
 		}
+		if n == 0 {
+			return nil
+		}
+		written += n
 	}
 	return nil
 }
 
 // WriteBatch writes a single batch of metrics to the output.
 func (r *RunningOutput) WriteBatch() error {
+	return r.WriteBatchContext(context.Background())
+}
+
+// WriteBatchContext is like WriteBatch but records the written batch as child
+// of the span in the given context.
+func (r *RunningOutput) WriteBatchContext(ctx context.Context) error {
 	// Try to connect if we are not yet started up
 	if !r.started {
 		r.retries++
 		if err := r.Output.Connect(); err != nil {
 			r.StartupErrors.Incr(1)
+			r.status.update(time.Now(), err)
 			return internal.ErrNotConnected
 		}
 		r.started = true
 		r.log.Debugf("Successfully connected after %d attempts", r.retries)
 	}
 
-	tx := r.buffer.BeginTransaction(r.MetricBatchSize)
-	if len(tx.Batch) == 0 {
+	if !r.allowWrite() {
+		r.handOver()
 		return nil
 	}
-	err := r.writeMetrics(tx.Batch)
+
+	if _, err := r.writeBatch(ctx); err != nil {
+		r.handOver()
+		return err
+	}
+	return nil
+}
+
+// writeBatch writes a single batch of metrics restricted by the rate-limits
+// and the adaptive batch size. The number of written metrics is returned.
+func (r *RunningOutput) writeBatch(ctx context.Context) (int, error) {
+	now := time.Now()
+	size := r.limits.size(now)
+	if size <= 0 {
+		r.limits.limited()
+		return 0, nil
+	}
+
+	tx := r.buffer.BeginTransaction(size)
+	if len(tx.Batch) == 0 {
+		return 0, nil
+	}
+
+	// Only write the metrics fitting into the byte rate-limit and keep the
+	// remaining ones for the next period
+	n, used := r.limits.limit(now, tx.Batch)
+	if n == 0 {
+		r.limits.limited()
+		tx.KeepAll()
+		r.buffer.EndTransaction(tx)
+		return 0, nil
+	}
+
+	_, span := r.tracer.Start(ctx, "write batch", trace.WithAttributes(
+		attribute.String("telegraf.output", r.LogName()),
+		attribute.Int("telegraf.batch_size", n),
+	))
+	err := r.writeMetrics(tx.Batch[:n])
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+	r.limits.record(now, n, used, err)
 	r.updateTransaction(tx, err)
+	if r.deadLetter != nil {
+		if derr := r.deadLetter.send(r.LogName(), tx, err); derr != nil {
+			r.log.Errorf("Routing rejected metrics to dead-letter failed: %v", derr)
+		}
+	}
+	if n < len(tx.Batch) {
+		r.limits.limited()
+		accept := tx.Accept[:0]
+		for _, idx := range tx.Accept {
+			if idx < n {
+				accept = append(accept, idx)
+			}
+		}
+		tx.Accept = accept
+	}
 	r.buffer.EndTransaction(tx)
 
-	return err
+	return n, err
+}
+
+// SetRateLimiters sets the limiters for the number of metrics and the number
+// of bytes written per period. The size of metrics is determined in
+// line-protocol format. A nil limiter disables the respective limit.
+func (r *RunningOutput) SetRateLimiters(metrics, bytes WriteLimiter) {
+	r.limits.setLimiters(metrics, bytes)
+}
+
+// allowWrite checks the circuit breaker of outputs in a failover group. A
+// half-open circuit is only closed by a successful write of the metrics
+// received as active output of the group.
+func (r *RunningOutput) allowWrite() bool {
+	if r.breaker == nil {
+		return true
+	}
+
+	if !r.breaker.allow(time.Now()) {
+		r.log.Trace("Circuit open, skipping write")
+		return false
+	}
+	return true
+}
+
+// handOver moves the buffered metrics of an output with an open circuit to
+// the active output of its failover group. The metrics are accepted by the
+// failing output once added to the active one. This must only be called from
+// the flush loop of the output to not interfere with running transactions.
+func (r *RunningOutput) handOver() {
+	group := r.group.Load()
+	if group == nil || r.CircuitState() != CircuitOpen {
+		return
+	}
+	target := group.Active()
+	if target == r || target.CircuitState() == CircuitOpen {
+		return
+	}
+
+	n := r.buffer.Len()
+	if n == 0 {
+		return
+	}
+	tx := r.buffer.BeginTransaction(n)
+	for _, m := range tx.Batch {
+		target.AddMetricNoCopy(m.Copy())
+	}
+	tx.AcceptAll()
+	r.buffer.EndTransaction(tx)
+	r.log.Infof("Handed over %d buffered metrics to %s", len(tx.Batch), target.LogName())
 }
 
 func (r *RunningOutput) writeMetrics(metrics []telegraf.Metric) error {
@@ -356,6 +620,11 @@ func (r *RunningOutput) writeMetrics(metrics []telegraf.Metric) error {
 	err := r.Output.Write(metrics)
 	elapsed := time.Since(start)
 	r.WriteTime.Incr(elapsed.Nanoseconds())
+	r.WriteDuration.ObserveDuration(elapsed)
+	r.status.update(start.Add(elapsed), err)
+	if r.breaker != nil {
+		r.breaker.record(start.Add(elapsed), err)
+	}
 
 	if err == nil {
 		r.log.Debugf("Wrote batch of %d metrics in %s", len(metrics), elapsed)
@@ -385,7 +654,7 @@ func (*RunningOutput) updateTransaction(tx *Transaction, err error) {
 
 func (r *RunningOutput) LogBufferStatus() {
 	nBuffer := r.buffer.Len()
-	if r.Config.BufferStrategy == "disk" {
+	if r.persistentBuffer() {
 		r.log.Debugf("Buffer fullness: %d metrics", nBuffer)
 	} else {
 		r.log.Debugf("Buffer fullness: %d / %d metrics", nBuffer, r.MetricBufferLimit)
@@ -399,3 +668,37 @@ func (r *RunningOutput) Log() telegraf.Logger {
 func (r *RunningOutput) BufferLength() int {
 	return r.buffer.Len()
 }
+
+// BufferStats returns the statistics of the output's buffer.
+func (r *RunningOutput) BufferStats() BufferStats {
+	return r.buffer.Stats()
+}
+
+// CircuitState returns the state of the circuit breaker of outputs in a
+// failover group. Outputs not being member of a group are always closed.
+func (r *RunningOutput) CircuitState() CircuitState {
+	if r.breaker == nil {
+		return CircuitClosed
+	}
+	return r.breaker.current()
+}
+
+// Status returns the time of the last write and the last error.
+func (r *RunningOutput) Status() Status {
+	return r.status.get()
+}
+
+// RequestFlush requests to write the buffered metrics immediately. Requests
+// made while a previous request is pending are ignored.
+func (r *RunningOutput) RequestFlush() {
+	select {
+	case r.flushRequested <- struct{}{}:
+	default:
+	}
+}
+
+// FlushRequested returns a channel receiving the requests for writing the
+// buffered metrics immediately.
+func (r *RunningOutput) FlushRequested() <-chan struct{} {
+	return r.flushRequested
+}
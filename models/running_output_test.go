@@ -2,6 +2,8 @@ package models
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
@@ -218,6 +220,89 @@ func TestRunningOutputNameSuffix(t *testing.T) {
 	require.Equal(t, "metric1_suffix", m.Metrics()[0].Name())
 }
 
+// Test that outputs with a shared buffer only copy metrics they modify
+func TestRunningOutputSharedBuffer(t *testing.T) {
+	plain := &mockOutput{}
+	roPlain := NewRunningOutput(plain, &OutputConfig{Name: "plain", BufferStrategy: "shared"}, 1000, 10000)
+	defer roPlain.Close()
+	prefixed := &mockOutput{}
+	roPrefixed := NewRunningOutput(prefixed, &OutputConfig{Name: "prefixed", BufferStrategy: "shared", NamePrefix: "prefix_"}, 1000, 10000)
+	defer roPrefixed.Close()
+
+	metric := testutil.TestMetric(101, "metric1")
+	roPlain.AddMetric(metric)
+	roPrefixed.AddMetric(metric)
+	ReleaseShared(metric)
+	require.Equal(t, int64(2), AgentSharedBufferSize.Get())
+
+	require.NoError(t, roPlain.Write())
+	require.NoError(t, roPrefixed.Write())
+	require.Len(t, plain.Metrics(), 1)
+	require.Same(t, metric, plain.Metrics()[0])
+	require.Equal(t, "metric1", plain.Metrics()[0].Name())
+	require.Len(t, prefixed.Metrics(), 1)
+	require.Equal(t, "prefix_metric1", prefixed.Metrics()[0].Name())
+	require.Equal(t, int64(0), AgentSharedBufferSize.Get())
+}
+
+// Test that outputs modifying metrics on write do not affect other outputs
+func TestRunningOutputSharedBufferMutatingOutput(t *testing.T) {
+	plain := &mockOutput{}
+	roPlain := NewRunningOutput(plain, &OutputConfig{Name: "plain", BufferStrategy: "shared"}, 1000, 10000)
+	defer roPlain.Close()
+	tagging := &taggingOutput{}
+	roTagging := NewRunningOutput(tagging, &OutputConfig{Name: "tagging", BufferStrategy: "shared"}, 1000, 10000)
+	defer roTagging.Close()
+
+	for i := range 100 {
+		m := testutil.TestMetric(i, "metric1")
+		roPlain.AddMetric(m)
+		roTagging.AddMetric(m)
+		ReleaseShared(m)
+	}
+
+	var wg sync.WaitGroup
+	for _, ro := range []*RunningOutput{roPlain, roTagging} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 10 {
+				if err := ro.WriteBatch(); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Len(t, plain.Metrics(), 100)
+	for _, m := range plain.Metrics() {
+		require.False(t, m.HasTag("written"))
+	}
+	require.Len(t, tagging.Metrics(), 100)
+	for _, m := range tagging.Metrics() {
+		require.True(t, m.HasTag("written"))
+	}
+	require.Equal(t, int64(0), AgentSharedBufferSize.Get())
+}
+
+func TestRunningOutputPersistentBufferOnInit(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &OutputConfig{Name: "test", ID: "persistent", BufferStrategy: "wal", BufferDirectory: dir}
+
+	// Discarding an output never initialized must not access the directory
+	discarded := NewRunningOutput(&mockOutput{}, cfg, 1000, 10000)
+	discarded.Discard()
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	ro := NewRunningOutput(&mockOutput{}, cfg, 1000, 10000)
+	require.NoError(t, ro.Init())
+	defer ro.Close()
+	require.DirExists(t, filepath.Join(dir, "persistent"))
+}
+
 // Test that we can write metrics with simple default setup.
 func TestRunningOutputDefault(t *testing.T) {
 	conf := &OutputConfig{
@@ -975,6 +1060,19 @@ func (m *mockOutput) Metrics() []telegraf.Metric {
 	return m.metrics
 }
 
+// taggingOutput modifies the metrics on write like e.g. outputs adding tags
+// for routing.
+type taggingOutput struct {
+	mockOutput
+}
+
+func (m *taggingOutput) Write(metrics []telegraf.Metric) error {
+	for _, x := range metrics {
+		x.AddTag("written", "true")
+	}
+	return m.mockOutput.Write(metrics)
+}
+
 type perfOutput struct {
 	// if true, mock write failure
 	failWrite bool
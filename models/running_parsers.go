@@ -33,6 +33,15 @@ func NewRunningParser(parser telegraf.Parser, config *ParserConfig) *RunningPars
 	}
 	SetLoggerOnPlugin(parser, logger)
 
+	statTags := map[string]string{"type": config.DataFormat}
+	if config.Alias != "" {
+		statTags["alias"] = config.Alias
+	}
+	if config.ID != "" {
+		statTags["id"] = config.ID
+	}
+	SetStatTagsOnPlugin(parser, statTags)
+
 	return &RunningParser{
 		Parser: parser,
 		Config: config,
@@ -54,6 +63,7 @@ func NewRunningParser(parser telegraf.Parser, config *ParserConfig) *RunningPars
 type ParserConfig struct {
 	Parent      string
 	Alias       string
+	ID          string
 	DataFormat  string
 	DefaultTags map[string]string
 	LogLevel    string
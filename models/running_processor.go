@@ -2,6 +2,7 @@ package models
 
 import (
 	"sync"
+	"time"
 
 	"github.com/influxdata/telegraf"
 	logging "github.com/influxdata/telegraf/logger"
@@ -13,6 +14,8 @@ type RunningProcessor struct {
 	log       telegraf.Logger
 	Processor telegraf.StreamingProcessor
 	Config    *ProcessorConfig
+
+	ProcessDuration *selfstat.Histogram
 }
 
 type RunningProcessors []*RunningProcessor
@@ -25,6 +28,7 @@ func (rp RunningProcessors) Less(i, j int) bool { return rp[i].Config.Order < rp
 type ProcessorConfig struct {
 	Name     string
 	Source   string
+	Line     int
 	Alias    string
 	ID       string
 	Order    int64
@@ -48,10 +52,24 @@ func NewRunningProcessor(processor telegraf.StreamingProcessor, config *Processo
 	}
 	SetLoggerOnPlugin(processor, logger)
 
+	statTags := map[string]string{"processor": config.Name}
+	if config.Alias != "" {
+		statTags["alias"] = config.Alias
+	}
+	if config.ID != "" {
+		statTags["id"] = config.ID
+	}
+	SetStatTagsOnPlugin(processor, statTags)
+
 	return &RunningProcessor{
 		Processor: processor,
 		Config:    config,
-		log:       logger,
+		ProcessDuration: selfstat.RegisterDurationHistogram(
+			"process",
+			"process_duration_seconds",
+			tags,
+		),
+		log: logger,
 	}
 }
 
@@ -109,7 +127,14 @@ func (rp *RunningProcessor) Add(m telegraf.Metric, acc telegraf.Accumulator) err
 		return nil
 	}
 
-	return rp.Processor.Add(m, acc)
+	if rp.ProcessDuration == nil {
+		return rp.Processor.Add(m, acc)
+	}
+
+	start := time.Now()
+	err = rp.Processor.Add(m, acc)
+	rp.ProcessDuration.ObserveDuration(time.Since(start))
+	return err
 }
 
 func (rp *RunningProcessor) Stop() {
@@ -22,9 +22,10 @@ type RunningSerializer struct {
 	Config     *SerializerConfig
 	log        telegraf.Logger
 
-	MetricsSerialized selfstat.Stat
-	BytesSerialized   selfstat.Stat
-	SerializationTime selfstat.Stat
+	MetricsSerialized     selfstat.Stat
+	BytesSerialized       selfstat.Stat
+	SerializationTime     selfstat.Stat
+	SerializationDuration *selfstat.Histogram
 }
 
 func NewRunningSerializer(serializer telegraf.Serializer, config *SerializerConfig) *RunningSerializer {
@@ -61,6 +62,11 @@ func NewRunningSerializer(serializer telegraf.Serializer, config *SerializerConf
 			"serialization_time_ns",
 			tags,
 		),
+		SerializationDuration: selfstat.RegisterDurationHistogram(
+			"serializer",
+			"serialization_duration_seconds",
+			tags,
+		),
 		log: logger,
 	}
 }
@@ -84,6 +90,7 @@ func (r *RunningSerializer) Serialize(metric telegraf.Metric) ([]byte, error) {
 	buf, err := r.Serializer.Serialize(metric)
 	elapsed := time.Since(start)
 	r.SerializationTime.Incr(elapsed.Nanoseconds())
+	r.SerializationDuration.ObserveDuration(elapsed)
 	r.MetricsSerialized.Incr(1)
 	r.BytesSerialized.Incr(int64(len(buf)))
 
@@ -95,6 +102,7 @@ func (r *RunningSerializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, e
 	buf, err := r.Serializer.SerializeBatch(metrics)
 	elapsed := time.Since(start)
 	r.SerializationTime.Incr(elapsed.Nanoseconds())
+	r.SerializationDuration.ObserveDuration(elapsed)
 	r.MetricsSerialized.Incr(int64(len(metrics)))
 	r.BytesSerialized.Incr(int64(len(buf)))
 
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"sync"
+	"time"
+)
+
+// Status is a snapshot of the runtime state of a plugin.
+type Status struct {
+	// LastRun is the time the plugin last finished gathering or writing
+	LastRun time.Time
+	// LastError is the error of the most recent failed run, if any
+	LastError string
+	// LastErrorTime is the time LastError occurred
+	LastErrorTime time.Time
+}
+
+type statusTracker struct {
+	status Status
+	sync.Mutex
+}
+
+func (s *statusTracker) update(t time.Time, err error) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.status.LastRun = t
+	if err != nil {
+		s.status.LastError = err.Error()
+		s.status.LastErrorTime = t
+	}
+}
+
+func (s *statusTracker) get() Status {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.status
+}
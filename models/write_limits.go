@@ -0,0 +1,166 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// WriteLimiter limits the amount written by an output within a period.
+type WriteLimiter interface {
+	// Remaining returns the amount still available in the current period
+	Remaining(t time.Time) int64
+	// Accept consumes the given amount in the current period
+	Accept(t time.Time, used int64)
+}
+
+// writeLimits restricts the batches written by an output by the configured
+// rate-limits and the adaptive batch size.
+type writeLimits struct {
+	sync.Mutex
+
+	metrics WriteLimiter
+	bytes   WriteLimiter
+	sizer   *influx.Serializer
+
+	adaptive  bool
+	maxBatch  int
+	minBatch  int
+	batchSize int
+
+	BatchSize           selfstat.Stat
+	MaxMetricsPerSecond selfstat.Stat
+	MaxBytesPerSecond   selfstat.Stat
+	RateLimited         selfstat.Stat
+}
+
+func newWriteLimits(config *OutputConfig, batchSize int, tags map[string]string) *writeLimits {
+	l := &writeLimits{
+		adaptive:  config.AdaptiveBatchSize,
+		maxBatch:  batchSize,
+		minBatch:  max(batchSize/100, 1),
+		batchSize: batchSize,
+	}
+
+	// Only report the statistics for outputs actually being limited
+	if config.MaxMetricsPerSecond <= 0 && config.MaxBytesPerSecond <= 0 && !config.AdaptiveBatchSize {
+		return l
+	}
+	l.BatchSize = selfstat.RegisterGauge("write", "batch_size", tags)
+	l.MaxMetricsPerSecond = selfstat.RegisterGauge("write", "max_metrics_per_second", tags)
+	l.MaxBytesPerSecond = selfstat.RegisterGauge("write", "max_bytes_per_second", tags)
+	l.RateLimited = selfstat.Register("write", "rate_limited", tags)
+	l.BatchSize.Set(int64(batchSize))
+	l.MaxMetricsPerSecond.Set(config.MaxMetricsPerSecond)
+	l.MaxBytesPerSecond.Set(config.MaxBytesPerSecond)
+
+	return l
+}
+
+// limited records a write being restricted by the rate-limits
+func (l *writeLimits) limited() {
+	if l.RateLimited != nil {
+		l.RateLimited.Incr(1)
+	}
+}
+
+func (l *writeLimits) setLimiters(metrics, bytes WriteLimiter) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.metrics = metrics
+	l.bytes = bytes
+	if bytes != nil && l.sizer == nil {
+		l.sizer = &influx.Serializer{}
+		if err := l.sizer.Init(); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// size returns the number of metrics to request for the next batch
+func (l *writeLimits) size(now time.Time) int {
+	l.Lock()
+	defer l.Unlock()
+
+	n := l.batchSize
+	if l.metrics != nil {
+		n = int(min(int64(n), l.metrics.Remaining(now)))
+	}
+	return n
+}
+
+// limit returns the number of metrics of the batch fitting into the byte
+// rate-limit as well as their size. At least one metric is returned if any
+// bytes are remaining to allow writing metrics exceeding the limit.
+func (l *writeLimits) limit(now time.Time, metrics []telegraf.Metric) (int, int64) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.bytes == nil {
+		return len(metrics), 0
+	}
+
+	remaining := l.bytes.Remaining(now)
+	var used int64
+	for i, m := range metrics {
+		buf, err := l.sizer.Serialize(m)
+		if err != nil {
+			continue
+		}
+		if used+int64(len(buf)) > remaining && (i > 0 || remaining <= 0) {
+			return i, used
+		}
+		used += int64(len(buf))
+	}
+	return len(metrics), used
+}
+
+// record consumes the rate-limits for the written metrics and adapts the
+// batch size depending on the write result.
+func (l *writeLimits) record(now time.Time, count int, size int64, err error) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.metrics != nil {
+		l.metrics.Accept(now, int64(count))
+	}
+	if l.bytes != nil {
+		l.bytes.Accept(now, size)
+	}
+
+	if !l.adaptive {
+		return
+	}
+
+	// Decrease the batch size multiplicatively on throttling and increase it
+	// additively on success
+	switch {
+	case err == nil:
+		l.batchSize = min(l.batchSize+max(l.maxBatch/10, 1), l.maxBatch)
+	case isThrottlingError(err):
+		l.batchSize = max(l.batchSize/2, l.minBatch)
+	}
+	if l.BatchSize != nil {
+		l.BatchSize.Set(int64(l.batchSize))
+	}
+}
+
+// isThrottlingError returns true if the error indicates the remote service
+// being overloaded, i.e. the output reporting throttled requests or the write
+// timing out.
+func isThrottlingError(err error) bool {
+	if errors.Is(err, internal.ErrThrottled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
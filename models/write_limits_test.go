@@ -0,0 +1,143 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+type mockLimiter struct {
+	remaining int64
+}
+
+func (l *mockLimiter) Remaining(time.Time) int64 {
+	return l.remaining
+}
+
+func (l *mockLimiter) Accept(_ time.Time, used int64) {
+	l.remaining -= used
+}
+
+func TestRunningOutputMetricRateLimit(t *testing.T) {
+	m := &mockOutput{}
+	ro := NewRunningOutput(m, &OutputConfig{Name: "metric_limit", MaxMetricsPerSecond: 3}, 10, 100)
+	limiter := &mockLimiter{remaining: 3}
+	ro.SetRateLimiters(limiter, nil)
+
+	for i := range 5 {
+		ro.AddMetric(testutil.TestMetric(i))
+	}
+
+	// Only the metrics within the limit are written, the remaining ones are
+	// kept in the buffer
+	require.NoError(t, ro.Write())
+	require.Len(t, m.Metrics(), 3)
+	require.Equal(t, 2, ro.buffer.Len())
+	require.Equal(t, int64(1), ro.limits.RateLimited.Get())
+
+	// Continue writing in the next period
+	limiter.remaining = 3
+	require.NoError(t, ro.Write())
+	require.Len(t, m.Metrics(), 5)
+	require.Equal(t, 0, ro.buffer.Len())
+}
+
+func TestRunningOutputByteRateLimit(t *testing.T) {
+	m := &mockOutput{}
+	ro := NewRunningOutput(m, &OutputConfig{Name: "byte_limit", MaxBytesPerSecond: 1}, 10, 100)
+	limiter := &mockLimiter{remaining: 1}
+	ro.SetRateLimiters(nil, limiter)
+
+	for i := range 3 {
+		ro.AddMetric(testutil.TestMetric(i))
+	}
+
+	// A single metric exceeding the limit is written to make progress
+	require.NoError(t, ro.WriteBatch())
+	require.Len(t, m.Metrics(), 1)
+	require.Equal(t, 2, ro.buffer.Len())
+	require.Negative(t, limiter.remaining)
+
+	// Nothing is written with the limit exhausted
+	require.NoError(t, ro.WriteBatch())
+	require.Len(t, m.Metrics(), 1)
+	require.Equal(t, 2, ro.buffer.Len())
+	require.Equal(t, int64(2), ro.limits.RateLimited.Get())
+}
+
+func TestAdaptiveBatchSize(t *testing.T) {
+	l := newWriteLimits(&OutputConfig{AdaptiveBatchSize: true}, 100, map[string]string{"output": "test"})
+	now := time.Now()
+	require.Equal(t, 100, l.size(now))
+
+	// Throttling halves the batch size down to the minimum
+	l.record(now, 100, 0, fmt.Errorf("received status 429: %w", internal.ErrThrottled))
+	require.Equal(t, 50, l.size(now))
+	l.record(now, 50, 0, fmt.Errorf("posting metrics failed: %w", context.DeadlineExceeded))
+	require.Equal(t, 25, l.size(now))
+	for range 10 {
+		l.record(now, 1, 0, internal.ErrThrottled)
+	}
+	require.Equal(t, 1, l.size(now))
+	require.Equal(t, int64(1), l.BatchSize.Get())
+
+	// Other errors do not change the batch size
+	l.record(now, 1, 0, errors.New("invalid field"))
+	require.Equal(t, 1, l.size(now))
+	l.record(now, 1, 0, errors.New("received status 429"))
+	require.Equal(t, 1, l.size(now))
+
+	// Successful writes grow the batch size up to the configured one
+	l.record(now, 1, 0, nil)
+	require.Equal(t, 11, l.size(now))
+	for range 20 {
+		l.record(now, 1, 0, nil)
+	}
+	require.Equal(t, 100, l.size(now))
+}
+
+func TestRunningOutputAdaptiveBatchSize(t *testing.T) {
+	m := &throttlingOutput{}
+	ro := NewRunningOutput(m, &OutputConfig{Name: "adaptive", AdaptiveBatchSize: true}, 8, 100)
+	for i := range 8 {
+		ro.AddMetric(testutil.TestMetric(i))
+	}
+
+	// The output throttles batches larger than four metrics
+	require.ErrorIs(t, ro.Write(), internal.ErrThrottled)
+	require.NoError(t, ro.Write())
+	require.Len(t, m.metrics, 8)
+	require.Equal(t, 6, ro.limits.size(time.Now()))
+}
+
+type throttlingOutput struct {
+	metrics []telegraf.Metric
+}
+
+func (*throttlingOutput) Connect() error {
+	return nil
+}
+
+func (*throttlingOutput) Close() error {
+	return nil
+}
+
+func (*throttlingOutput) SampleConfig() string {
+	return ""
+}
+
+func (o *throttlingOutput) Write(metrics []telegraf.Metric) error {
+	if len(metrics) > 4 {
+		return internal.ErrThrottled
+	}
+	o.metrics = append(o.metrics, metrics...)
+	return nil
+}
@@ -1,18 +1,36 @@
 package persister
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"os"
+	"path/filepath"
 	"reflect"
+	"sync"
 
 	"github.com/influxdata/telegraf"
 )
 
+// version of the state file format written by the persister
+const version = 1
+
+// snapshot is the on-disk representation of the plugin states
+type snapshot struct {
+	Version  int               `json:"version"`
+	Checksum string            `json:"checksum"`
+	States   map[string][]byte `json:"states"`
+}
+
 type Persister struct {
 	Filename string
 
 	register map[string]telegraf.StatefulPlugin
+	mu       sync.Mutex
 }
 
 func (p *Persister) Init() error {
@@ -22,6 +40,9 @@ func (p *Persister) Init() error {
 }
 
 func (p *Persister) Register(id string, plugin telegraf.StatefulPlugin) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	if _, found := p.register[id]; found {
 		return fmt.Errorf("plugin with ID %q already registered", id)
 	}
@@ -30,18 +51,39 @@ func (p *Persister) Register(id string, plugin telegraf.StatefulPlugin) error {
 	return nil
 }
 
+func (p *Persister) Unregister(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.register, id)
+}
+
+// PreviousFilename returns the name of the file holding the snapshot written
+// before the current one.
+func (p *Persister) PreviousFilename() string {
+	return p.Filename + ".previous"
+}
+
 func (p *Persister) Load() error {
-	// Read the states from disk
-	in, err := os.ReadFile(p.Filename)
+	// Read the states from disk and fall back to the previous snapshot if the
+	// current one is missing or corrupt, e.g. due to a crash during writing.
+	states, err := readStates(p.Filename)
 	if err != nil {
-		return fmt.Errorf("reading states file failed: %w", err)
+		var errPrev error
+		states, errPrev = readStates(p.PreviousFilename())
+		if errPrev != nil {
+			if errors.Is(errPrev, os.ErrNotExist) {
+				return err
+			}
+			return fmt.Errorf("%w; reading previous states failed: %w", err, errPrev)
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("W! [agent] %v; restoring previous states from %q", err, p.PreviousFilename())
+		}
 	}
 
-	// Unmarshal the id to serialized states map
-	var states map[string][]byte
-	if err := json.Unmarshal(in, &states); err != nil {
-		return fmt.Errorf("unmarshalling states failed: %w", err)
-	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
 	// Get the initialized state as blueprint for unmarshalling
 	for id, serialized := range states {
@@ -51,25 +93,47 @@ func (p *Persister) Load() error {
 			continue
 		}
 
-		// Create a new empty state of the "state"-type. As we need a pointer
-		// of the state, we cannot dereference it here due to the unknown
-		// nature of the state-type.
-		nstate := reflect.New(reflect.TypeOf(plugin.GetState())).Interface()
-		if err := json.Unmarshal(serialized, &nstate); err != nil {
-			return fmt.Errorf("unmarshalling state for %q failed: %w", id, err)
+		if err := restore(plugin, serialized); err != nil {
+			return fmt.Errorf("restoring state of %q failed: %w", id, err)
 		}
-		state := reflect.ValueOf(nstate).Elem().Interface()
+	}
 
-		// Set the state in the plugin
-		if err := plugin.SetState(state); err != nil {
-			return fmt.Errorf("setting state of %q failed: %w", id, err)
-		}
+	return nil
+}
+
+// Transfer copies the state of a plugin to another instance of the same
+// plugin, e.g. when replacing the plugin on configuration reload. The state is
+// serialized to not share any data between the two instances.
+func Transfer(from, to telegraf.StatefulPlugin) error {
+	serialized, err := json.Marshal(from.GetState())
+	if err != nil {
+		return fmt.Errorf("marshalling state failed: %w", err)
+	}
+	return restore(to, serialized)
+}
+
+// restore sets the serialized state in the plugin
+func restore(plugin telegraf.StatefulPlugin, serialized []byte) error {
+	// Create a new empty state of the "state"-type. As we need a pointer
+	// of the state, we cannot dereference it here due to the unknown
+	// nature of the state-type.
+	nstate := reflect.New(reflect.TypeOf(plugin.GetState())).Interface()
+	if err := json.Unmarshal(serialized, &nstate); err != nil {
+		return fmt.Errorf("unmarshalling state failed: %w", err)
 	}
+	state := reflect.ValueOf(nstate).Elem().Interface()
 
+	// Set the state in the plugin
+	if err := plugin.SetState(state); err != nil {
+		return fmt.Errorf("setting state failed: %w", err)
+	}
 	return nil
 }
 
 func (p *Persister) Store() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	states := make(map[string][]byte)
 
 	// Collect the states and serialize the individual data chunks
@@ -83,21 +147,112 @@ func (p *Persister) Store() error {
 	}
 
 	// Serialize the states
-	serialized, err := json.Marshal(states)
+	checksum, err := checksumStates(states)
+	if err != nil {
+		return err
+	}
+	serialized, err := json.Marshal(snapshot{
+		Version:  version,
+		Checksum: checksum,
+		States:   states,
+	})
 	if err != nil {
 		return fmt.Errorf("marshalling states failed: %w", err)
 	}
 
-	// Write the states to disk
-	f, err := os.Create(p.Filename)
+	// Write the states to a temporary file in the same directory first, so
+	// the state file is never left in a partially written state
+	dir, base := filepath.Split(p.Filename)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := os.CreateTemp(dir, base+".*.tmp")
 	if err != nil {
-		return fmt.Errorf("creating states file %q failed: %w", p.Filename, err)
+		return fmt.Errorf("creating temporary states file for %q failed: %w", p.Filename, err)
 	}
-	defer f.Close()
+	tmpname := f.Name()
+	defer os.Remove(tmpname)
 
 	if _, err := f.Write(serialized); err != nil {
+		f.Close()
 		return fmt.Errorf("writing states failed: %w", err)
 	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("syncing states failed: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("closing states file failed: %w", err)
+	}
+
+	// Keep the current snapshot as fallback and replace it with the new one
+	if err := os.Rename(p.Filename, p.PreviousFilename()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("keeping previous states failed: %w", err)
+	}
+	if err := os.Rename(tmpname, p.Filename); err != nil {
+		return fmt.Errorf("replacing states file %q failed: %w", p.Filename, err)
+	}
+	syncDir(dir)
 
 	return nil
 }
+
+func readStates(filename string) (map[string][]byte, error) {
+	in, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading states file failed: %w", err)
+	}
+
+	// Distinguish the versioned format from the legacy plain id to
+	// serialized states map
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(in, &raw); err != nil {
+		return nil, fmt.Errorf("unmarshalling states failed: %w", err)
+	}
+	if _, found := raw["version"]; !found {
+		var states map[string][]byte
+		if err := json.Unmarshal(in, &states); err != nil {
+			return nil, fmt.Errorf("unmarshalling states failed: %w", err)
+		}
+		return states, nil
+	}
+
+	var s snapshot
+	if err := json.Unmarshal(in, &s); err != nil {
+		return nil, fmt.Errorf("unmarshalling states failed: %w", err)
+	}
+	if s.Version > version {
+		return nil, fmt.Errorf("unsupported states file version %d", s.Version)
+	}
+	checksum, err := checksumStates(s.States)
+	if err != nil {
+		return nil, err
+	}
+	if s.Checksum != checksum {
+		return nil, fmt.Errorf("checksum mismatch in states file %q", filename)
+	}
+
+	return s.States, nil
+}
+
+func checksumStates(states map[string][]byte) (string, error) {
+	// The JSON encoding of maps is sorted by key and thus stable
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(states); err != nil {
+		return "", fmt.Errorf("marshalling states for checksum failed: %w", err)
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// syncDir flushes the directory entry to make the rename durable. This is not
+// supported on all platforms, so errors are ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	defer d.Close()
+	//nolint:errcheck // best effort, directories cannot be synced everywhere
+	d.Sync()
+}
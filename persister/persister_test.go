@@ -0,0 +1,96 @@
+package persister
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreLoad(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "states.json")
+
+	store := &Persister{Filename: filename}
+	require.NoError(t, store.Init())
+	require.NoError(t, store.Register("id", &mockupState{state: map[string]int64{"offset": 42}}))
+	require.NoError(t, store.Store())
+
+	// Only the state file must be left in the directory
+	entries, err := os.ReadDir(filepath.Dir(filename))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	plugin := &mockupState{}
+	load := &Persister{Filename: filename}
+	require.NoError(t, load.Init())
+	require.NoError(t, load.Register("id", plugin))
+	require.NoError(t, load.Load())
+	require.Equal(t, map[string]int64{"offset": 42}, plugin.state)
+}
+
+func TestLoadLegacy(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "states.json")
+	require.NoError(t, os.WriteFile(filename, []byte(`{"id":"eyJvZmZzZXQiOjEwfQ=="}`), 0640))
+
+	plugin := &mockupState{}
+	p := &Persister{Filename: filename}
+	require.NoError(t, p.Init())
+	require.NoError(t, p.Register("id", plugin))
+	require.NoError(t, p.Load())
+	require.Equal(t, map[string]int64{"offset": 10}, plugin.state)
+}
+
+func TestLoadFallbackToPrevious(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "states.json")
+
+	source := &mockupState{state: map[string]int64{"offset": 1}}
+	store := &Persister{Filename: filename}
+	require.NoError(t, store.Init())
+	require.NoError(t, store.Register("id", source))
+	require.NoError(t, store.Store())
+	source.state = map[string]int64{"offset": 2}
+	require.NoError(t, store.Store())
+
+	// Corrupt the current snapshot
+	buf, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filename, buf[:len(buf)/2], 0640))
+
+	plugin := &mockupState{}
+	load := &Persister{Filename: filename}
+	require.NoError(t, load.Init())
+	require.NoError(t, load.Register("id", plugin))
+	require.NoError(t, load.Load())
+	require.Equal(t, map[string]int64{"offset": 1}, plugin.state)
+}
+
+func TestLoadChecksumMismatch(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "states.json")
+	content := `{"version":1,"checksum":"invalid","states":{"id":"eyJvZmZzZXQiOjEwfQ=="}}`
+	require.NoError(t, os.WriteFile(filename, []byte(content), 0640))
+
+	p := &Persister{Filename: filename}
+	require.NoError(t, p.Init())
+	require.NoError(t, p.Register("id", &mockupState{}))
+	require.ErrorContains(t, p.Load(), "checksum mismatch")
+}
+
+func TestLoadNotExist(t *testing.T) {
+	p := &Persister{Filename: filepath.Join(t.TempDir(), "states.json")}
+	require.NoError(t, p.Init())
+	require.ErrorIs(t, p.Load(), os.ErrNotExist)
+}
+
+type mockupState struct {
+	state map[string]int64
+}
+
+func (m *mockupState) GetState() interface{} {
+	return m.state
+}
+
+func (m *mockupState) SetState(state interface{}) error {
+	m.state = state.(map[string]int64)
+	return nil
+}
@@ -0,0 +1,177 @@
+package columnar
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+)
+
+// MeasurementKey is the schema metadata key containing the measurement name of
+// the records
+const MeasurementKey = "measurement"
+
+// RoleKey is the field metadata key containing the role of a column, i.e.
+// whether the column holds a tag, a field or the timestamp of the metrics
+const RoleKey = "role"
+
+// Column roles stored in the field metadata
+const (
+	RoleTag       = "tag"
+	RoleField     = "field"
+	RoleTimestamp = "timestamp"
+)
+
+// Role returns the role stored in the metadata of the given column or an empty
+// string if the column has no role
+func Role(field arrow.Field) string {
+	if idx := field.Metadata.FindKey(RoleKey); idx >= 0 {
+		return field.Metadata.Values()[idx]
+	}
+	return ""
+}
+
+// ColumnMapping assigns the column values of a row to the name, tags, fields
+// and timestamp of a metric
+type ColumnMapping struct {
+	MeasurementColumn string
+	TagColumns        []string
+	TimestampColumn   string
+	TimestampFormat   string
+	Location          *time.Location
+}
+
+// NewColumnMapping creates a mapping for the given columns. Timestamps without
+// zone information are parsed in the given timezone, UTC if empty.
+func NewColumnMapping(measurementColumn string, tagColumns []string, timestampColumn, timestampFormat, timezone string) (*ColumnMapping, error) {
+	location := time.UTC
+	if timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid location %s: %w", timezone, err)
+		}
+		location = loc
+	}
+
+	return &ColumnMapping{
+		MeasurementColumn: measurementColumn,
+		TagColumns:        tagColumns,
+		TimestampColumn:   timestampColumn,
+		TimestampFormat:   timestampFormat,
+		Location:          location,
+	}, nil
+}
+
+// Set assigns the value of the given column to the metric
+func (c *ColumnMapping) Set(m telegraf.Metric, column string, value interface{}) error {
+	switch {
+	case c.MeasurementColumn != "" && column == c.MeasurementColumn:
+		valStr, err := internal.ToString(value)
+		if err != nil {
+			return fmt.Errorf("could not convert value to string: %w", err)
+		}
+		m.SetName(valStr)
+	case slices.Contains(c.TagColumns, column):
+		valStr, err := internal.ToString(value)
+		if err != nil {
+			return fmt.Errorf("could not convert value to string: %w", err)
+		}
+		m.AddTag(column, valStr)
+	case c.TimestampColumn != "" && column == c.TimestampColumn:
+		if t, ok := value.(time.Time); ok {
+			m.SetTime(t)
+			return nil
+		}
+		valStr, err := internal.ToString(value)
+		if err != nil {
+			return fmt.Errorf("could not convert value to string: %w", err)
+		}
+		timestamp, err := internal.ParseTimestamp(c.TimestampFormat, valStr, c.Location)
+		if err != nil {
+			return fmt.Errorf("could not parse '%s' to '%s'", valStr, c.TimestampFormat)
+		}
+		m.SetTime(timestamp)
+	default:
+		m.AddField(column, value)
+	}
+	return nil
+}
+
+// SetWithRole assigns the value of the given column to the metric according to
+// the role of the column. Explicitly configured columns and columns without
+// role are assigned as done by Set.
+func (c *ColumnMapping) SetWithRole(m telegraf.Metric, column, role string, value interface{}) error {
+	configured := column == c.MeasurementColumn || column == c.TimestampColumn || slices.Contains(c.TagColumns, column)
+	if configured || role == "" {
+		return c.Set(m, column, value)
+	}
+
+	switch role {
+	case RoleTag:
+		valStr, err := internal.ToString(value)
+		if err != nil {
+			return fmt.Errorf("could not convert value to string: %w", err)
+		}
+		m.AddTag(column, valStr)
+	case RoleTimestamp:
+		switch v := value.(type) {
+		case time.Time:
+			m.SetTime(v)
+		case int64:
+			m.SetTime(time.Unix(0, v))
+		default:
+			return fmt.Errorf("invalid timestamp %v of type %T in column %q", value, value, column)
+		}
+	default:
+		m.AddField(column, value)
+	}
+	return nil
+}
+
+// Value returns the value of the array at the given index or nil if the value
+// is null or of unsupported type. Timestamps are returned as time.Time.
+func Value(arr arrow.Array, index int) interface{} {
+	if arr.IsNull(index) {
+		return nil
+	}
+
+	switch a := arr.(type) {
+	case *array.Int8:
+		return a.Value(index)
+	case *array.Int16:
+		return a.Value(index)
+	case *array.Int32:
+		return a.Value(index)
+	case *array.Int64:
+		return a.Value(index)
+	case *array.Uint8:
+		return a.Value(index)
+	case *array.Uint16:
+		return a.Value(index)
+	case *array.Uint32:
+		return a.Value(index)
+	case *array.Uint64:
+		return a.Value(index)
+	case *array.Float32:
+		return a.Value(index)
+	case *array.Float64:
+		return a.Value(index)
+	case *array.String:
+		return a.Value(index)
+	case *array.LargeString:
+		return a.Value(index)
+	case *array.Binary:
+		return string(a.Value(index))
+	case *array.Boolean:
+		return a.Value(index)
+	case *array.Timestamp:
+		unit := a.DataType().(*arrow.TimestampType).Unit
+		return a.Value(index).ToTime(unit)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+// Package columnar contains the conversion between metrics and Apache Arrow
+// records shared by the columnar data formats like Parquet and Arrow IPC.
+package columnar
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+)
+
+// InferSchema creates a schema with a nullable column for each tag and field
+// of the given metrics. Tags are strings and the type of a field column is
+// determined by the first occurrence of the field. Columns are sorted by name
+// followed by the timestamp column, containing nanoseconds since epoch, if a
+// name is given. The role of each column is stored in the field metadata.
+func InferSchema(metrics []telegraf.Metric, timestampColumn string) (*arrow.Schema, error) {
+	columns := make(map[string]arrow.DataType)
+	roles := make(map[string]string)
+	for _, m := range metrics {
+		for _, field := range m.FieldList() {
+			// Fields take precedence over tags of the same name when
+			// appending the metrics
+			roles[field.Key] = RoleField
+			if _, found := columns[field.Key]; !found {
+				dataType, err := DataType(field.Value)
+				if err != nil {
+					return nil, fmt.Errorf("error converting '%s=%v' field to arrow type: %w", field.Key, field.Value, err)
+				}
+				columns[field.Key] = dataType
+			}
+		}
+		for _, tag := range m.TagList() {
+			if _, found := columns[tag.Key]; !found {
+				columns[tag.Key] = arrow.BinaryTypes.String
+				roles[tag.Key] = RoleTag
+			}
+		}
+	}
+
+	fields := make([]arrow.Field, 0, len(columns)+1)
+	for name, dataType := range columns {
+		if name == timestampColumn {
+			continue
+		}
+		fields = append(fields, arrow.Field{
+			Name:     name,
+			Type:     dataType,
+			Nullable: true,
+			Metadata: arrow.NewMetadata([]string{RoleKey}, []string{roles[name]}),
+		})
+	}
+	slices.SortFunc(fields, func(a, b arrow.Field) int { return strings.Compare(a.Name, b.Name) })
+
+	if timestampColumn != "" {
+		fields = append(fields, arrow.Field{
+			Name:     timestampColumn,
+			Type:     arrow.PrimitiveTypes.Int64,
+			Metadata: arrow.NewMetadata([]string{RoleKey}, []string{RoleTimestamp}),
+		})
+	}
+
+	return arrow.NewSchema(fields, nil), nil
+}
+
+// DataType returns the Arrow type of the given field value
+func DataType(value interface{}) (arrow.DataType, error) {
+	switch value.(type) {
+	case int8:
+		return arrow.PrimitiveTypes.Int8, nil
+	case int16:
+		return arrow.PrimitiveTypes.Int16, nil
+	case int32:
+		return arrow.PrimitiveTypes.Int32, nil
+	case int64, int:
+		return arrow.PrimitiveTypes.Int64, nil
+	case uint8:
+		return arrow.PrimitiveTypes.Uint8, nil
+	case uint16:
+		return arrow.PrimitiveTypes.Uint16, nil
+	case uint32:
+		return arrow.PrimitiveTypes.Uint32, nil
+	case uint64, uint:
+		return arrow.PrimitiveTypes.Uint64, nil
+	case float32:
+		return arrow.PrimitiveTypes.Float32, nil
+	case float64:
+		return arrow.PrimitiveTypes.Float64, nil
+	case string:
+		return arrow.BinaryTypes.String, nil
+	case bool:
+		return arrow.FixedWidthTypes.Boolean, nil
+	default:
+		return nil, fmt.Errorf("unsupported type: %T", value)
+	}
+}
+
+// AppendMetrics adds the metrics as rows to the builder of a schema created by
+// InferSchema. Values are taken from the field of the column name, or from
+// the tag if no such field exists, and converted to the column type. Columns
+// without value in a metric are null.
+func AppendMetrics(builder *array.RecordBuilder, metrics []telegraf.Metric, timestampColumn string) error {
+	for index, col := range builder.Schema().Fields() {
+		fb := builder.Field(index)
+		for _, m := range metrics {
+			if timestampColumn != "" && col.Name == timestampColumn {
+				fb.(*array.Int64Builder).Append(m.Time().UnixNano())
+				continue
+			}
+
+			value, found := m.GetField(col.Name)
+			if !found {
+				value, found = m.GetTag(col.Name)
+			}
+			if !found {
+				fb.AppendNull()
+				continue
+			}
+
+			if err := appendValue(fb, value); err != nil {
+				return fmt.Errorf("column %q: %w", col.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func appendValue(builder array.Builder, value interface{}) error {
+	switch b := builder.(type) {
+	case *array.Int8Builder:
+		v, err := internal.ToInt8(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Int16Builder:
+		v, err := internal.ToInt16(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Int32Builder:
+		v, err := internal.ToInt32(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Int64Builder:
+		v, err := internal.ToInt64(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Uint8Builder:
+		v, err := internal.ToUint8(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Uint16Builder:
+		v, err := internal.ToUint16(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Uint32Builder:
+		v, err := internal.ToUint32(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Uint64Builder:
+		v, err := internal.ToUint64(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Float32Builder:
+		v, err := internal.ToFloat32(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Float64Builder:
+		v, err := internal.ToFloat64(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.StringBuilder:
+		v, err := internal.ToString(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.BooleanBuilder:
+		v, err := internal.ToBool(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	default:
+		return fmt.Errorf("unsupported column type %s", builder.Type())
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package columnar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+func TestInferSchema(t *testing.T) {
+	metrics := []telegraf.Metric{
+		metric.New("test", map[string]string{"value": "tag", "b": "x"}, map[string]interface{}{"value": int64(1)}, time.Unix(0, 0)),
+		metric.New("test", map[string]string{}, map[string]interface{}{"a": true, "value": 2.5}, time.Unix(1, 0)),
+	}
+
+	schema, err := InferSchema(metrics, "time")
+	require.NoError(t, err)
+	expected := arrow.NewSchema(
+		[]arrow.Field{
+			{Name: "a", Type: arrow.FixedWidthTypes.Boolean, Nullable: true, Metadata: roleMetadata(RoleField)},
+			{Name: "b", Type: arrow.BinaryTypes.String, Nullable: true, Metadata: roleMetadata(RoleTag)},
+			{Name: "value", Type: arrow.PrimitiveTypes.Int64, Nullable: true, Metadata: roleMetadata(RoleField)},
+			{Name: "time", Type: arrow.PrimitiveTypes.Int64, Metadata: roleMetadata(RoleTimestamp)},
+		},
+		nil,
+	)
+	require.True(t, expected.Equal(schema), schema.String())
+
+	// Values are converted to the type of the first occurrence
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer builder.Release()
+	require.NoError(t, AppendMetrics(builder, metrics, "time"))
+	record := builder.NewRecord()
+	defer record.Release()
+
+	require.Equal(t, int64(2), record.NumRows())
+	require.Nil(t, Value(record.Column(0), 0))
+	require.Equal(t, true, Value(record.Column(0), 1))
+	require.Equal(t, "x", Value(record.Column(1), 0))
+	require.Equal(t, []int64{1, 2}, record.Column(2).(*array.Int64).Int64Values())
+	require.Equal(t, []int64{0, 1000000000}, record.Column(3).(*array.Int64).Int64Values())
+}
+
+func TestAppendMetricsConversionFail(t *testing.T) {
+	metrics := []telegraf.Metric{
+		metric.New("test", map[string]string{}, map[string]interface{}{"value": int64(1)}, time.Unix(0, 0)),
+		metric.New("test", map[string]string{}, map[string]interface{}{"value": "foo"}, time.Unix(0, 0)),
+	}
+	schema, err := InferSchema(metrics, "")
+	require.NoError(t, err)
+
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer builder.Release()
+	require.ErrorContains(t, AppendMetrics(builder, metrics, ""), `column "value"`)
+}
+
+func roleMetadata(role string) arrow.Metadata {
+	return arrow.NewMetadata([]string{RoleKey}, []string{role})
+}
@@ -0,0 +1,119 @@
+// Package jsonschema validates JSON documents of parsers against a JSON schema
+// or a schema component of an OpenAPI specification.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"strings"
+
+	jsv "github.com/santhosh-tekuri/jsonschema/v5"
+	"gopkg.in/yaml.v3"
+
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// Validator checks documents against a schema and counts the invalid ones
+type Validator struct {
+	schema  *jsv.Schema
+	invalid selfstat.Stat
+}
+
+// New compiles the schema at the given location. The location is a path to a
+// JSON or YAML file, optionally followed by a JSON pointer to the schema
+// within the document, e.g. "openapi.yaml#/components/schemas/Reading".
+// Invalid documents are counted in the "invalid_documents" statistic of the
+// "parser" measurement tagged with the given tags and the schema location.
+func New(location string, tags map[string]string) (*Validator, error) {
+	compiler := jsv.NewCompiler()
+	compiler.LoadURL = loadURL
+
+	schema, err := compiler.Compile(location)
+	if err != nil {
+		return nil, fmt.Errorf("compiling schema %q failed: %w", location, err)
+	}
+
+	statTags := maps.Clone(tags)
+	if statTags == nil {
+		statTags = make(map[string]string, 1)
+	}
+	statTags["schema"] = location
+
+	return &Validator{
+		schema:  schema,
+		invalid: selfstat.Register("parser", "invalid_documents", statTags),
+	}, nil
+}
+
+// Validate checks the JSON document against the schema. The returned error
+// names the location and the violated rule of each violation.
+func (v *Validator) Validate(buf []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(buf))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		v.invalid.Incr(1)
+		return fmt.Errorf("decoding document failed: %w", err)
+	}
+
+	if err := v.schema.Validate(doc); err != nil {
+		v.invalid.Incr(1)
+		var verr *jsv.ValidationError
+		if errors.As(err, &verr) {
+			return fmt.Errorf("document does not match schema: %s", describe(verr))
+		}
+		return err
+	}
+	return nil
+}
+
+// describe lists the innermost causes of the validation error as those
+// contain the actual violations
+func describe(err *jsv.ValidationError) string {
+	var violations []string
+	var walk func(e *jsv.ValidationError)
+	walk = func(e *jsv.ValidationError) {
+		if len(e.Causes) > 0 {
+			for _, cause := range e.Causes {
+				walk(cause)
+			}
+			return
+		}
+		location := e.InstanceLocation
+		if location == "" {
+			location = "/"
+		}
+		violations = append(violations, fmt.Sprintf("at %q: %s (rule %q)", location, e.Message, e.KeywordLocation))
+	}
+	walk(err)
+	return strings.Join(violations, "; ")
+}
+
+// loadURL loads JSON or YAML schema documents from the file-system
+func loadURL(s string) (io.ReadCloser, error) {
+	ext := strings.ToLower(filepath.Ext(strings.TrimPrefix(s, "file://")))
+	if ext != ".yaml" && ext != ".yml" {
+		return jsv.LoadURL(s)
+	}
+
+	r, err := jsv.LoadURL(s)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var doc interface{}
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decoding YAML document %q failed: %w", s, err)
+	}
+	buf, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("converting YAML document %q failed: %w", s, err)
+	}
+	return io.NopCloser(bytes.NewReader(buf)), nil
+}
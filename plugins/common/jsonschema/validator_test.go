@@ -0,0 +1,89 @@
+package jsonschema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+func TestNewFail(t *testing.T) {
+	_, err := New("testdata/missing.json", nil)
+	require.ErrorContains(t, err, `compiling schema "testdata/missing.json" failed`)
+
+	_, err = New("testdata/openapi.yaml#/components/schemas/Missing", nil)
+	require.ErrorContains(t, err, "compiling schema")
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		document string
+		expected []string
+	}{
+		{
+			name:     "valid",
+			schema:   "testdata/reading.json",
+			document: `{"name": "sensor", "temperature": 21.5, "tags": {"room": "kitchen"}}`,
+		},
+		{
+			name:     "wrong type",
+			schema:   "testdata/reading.json",
+			document: `{"name": "sensor", "temperature": "warm"}`,
+			expected: []string{`at "/temperature": expected number, but got string (rule "/properties/temperature/type")`},
+		},
+		{
+			name:     "multiple violations",
+			schema:   "testdata/reading.json",
+			document: `{"name": "", "tags": {"room": 1}}`,
+			expected: []string{
+				`at "/": missing properties: 'temperature' (rule "/required")`,
+				`at "/name": length must be >= 1, but got 0 (rule "/properties/name/minLength")`,
+				`at "/tags/room": expected string, but got number (rule "/properties/tags/additionalProperties/type")`,
+			},
+		},
+		{
+			name:     "malformed",
+			schema:   "testdata/reading.json",
+			document: `{"name": "sensor",`,
+			expected: []string{"decoding document failed"},
+		},
+		{
+			name:     "openapi valid",
+			schema:   "testdata/openapi.yaml#/components/schemas/Reading",
+			document: `{"sensor": "s1", "value": 1, "unit": "celsius"}`,
+		},
+		{
+			name:     "openapi reference",
+			schema:   "testdata/openapi.yaml#/components/schemas/Reading",
+			document: `{"sensor": "s1", "value": 1, "unit": "kelvin"}`,
+			expected: []string{`at "/unit": value must be one of "celsius", "fahrenheit" (rule "/properties/unit/$ref/enum")`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator, err := New(tt.schema, map[string]string{"type": "test", "id": tt.name})
+			require.NoError(t, err)
+			invalid := selfstat.Register("parser", "invalid_documents", map[string]string{
+				"type":   "test",
+				"schema": tt.schema,
+				"id":     tt.name,
+			})
+
+			err = validator.Validate([]byte(tt.document))
+			if len(tt.expected) == 0 {
+				require.NoError(t, err)
+				require.Zero(t, invalid.Get())
+				return
+			}
+			require.Error(t, err)
+			for _, e := range tt.expected {
+				require.Contains(t, err.Error(), e)
+			}
+			require.Equal(t, int64(1), invalid.Get())
+		})
+	}
+}
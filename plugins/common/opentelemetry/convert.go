@@ -0,0 +1,40 @@
+package opentelemetry
+
+import (
+	"fmt"
+
+	"github.com/influxdata/influxdb-observability/common"
+	"github.com/influxdata/influxdb-observability/influx2otel"
+
+	"github.com/influxdata/telegraf"
+)
+
+// NewConverter creates a converter from Telegraf metrics to OpenTelemetry
+// metrics logging to the given logger
+func NewConverter(log telegraf.Logger) (*influx2otel.LineProtocolToOtelMetrics, error) {
+	return influx2otel.NewLineProtocolToOtelMetrics(&Logger{log})
+}
+
+// AddMetric converts the metric with the given tags and adds it to the batch
+func AddMetric(batch *influx2otel.MetricsBatch, m telegraf.Metric, tags map[string]string) error {
+	var vType common.InfluxMetricValueType
+	switch m.Type() {
+	case telegraf.Gauge:
+		vType = common.InfluxMetricValueTypeGauge
+	case telegraf.Untyped:
+		vType = common.InfluxMetricValueTypeUntyped
+	case telegraf.Counter:
+		vType = common.InfluxMetricValueTypeSum
+	case telegraf.Histogram:
+		vType = common.InfluxMetricValueTypeHistogram
+	case telegraf.Summary:
+		vType = common.InfluxMetricValueTypeSummary
+	default:
+		return fmt.Errorf("unrecognized metric type %v", m.Type())
+	}
+
+	if err := batch.AddPoint(m.Name(), tags, m.Fields(), m.Time(), vType); err != nil {
+		return fmt.Errorf("conversion failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package opentelemetry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestAddMetric(t *testing.T) {
+	tests := []struct {
+		name     string
+		vtype    telegraf.ValueType
+		fields   map[string]interface{}
+		expected pmetric.MetricType
+	}{
+		{
+			name:     "gauge",
+			vtype:    telegraf.Gauge,
+			fields:   map[string]interface{}{"gauge": 42.0},
+			expected: pmetric.MetricTypeGauge,
+		},
+		{
+			name:     "untyped",
+			vtype:    telegraf.Untyped,
+			fields:   map[string]interface{}{"value": 42.0},
+			expected: pmetric.MetricTypeGauge,
+		},
+		{
+			name:     "counter",
+			vtype:    telegraf.Counter,
+			fields:   map[string]interface{}{"counter": 42.0},
+			expected: pmetric.MetricTypeSum,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter, err := NewConverter(testutil.Logger{})
+			require.NoError(t, err)
+
+			batch := converter.NewBatch()
+			m := metric.New("test", map[string]string{"host": "localhost"}, tt.fields, time.Unix(0, 0), tt.vtype)
+			require.NoError(t, AddMetric(batch, m, m.Tags()))
+
+			rm := batch.GetMetrics().ResourceMetrics()
+			require.Equal(t, 1, rm.Len())
+			metrics := rm.At(0).ScopeMetrics().At(0).Metrics()
+			require.Equal(t, 1, metrics.Len())
+			require.Equal(t, tt.expected, metrics.At(0).Type())
+		})
+	}
+}
+
+func TestAddMetricInvalidFields(t *testing.T) {
+	converter, err := NewConverter(testutil.Logger{})
+	require.NoError(t, err)
+
+	m := metric.New("test", map[string]string{}, map[string]interface{}{"bucket": "invalid"}, time.Unix(0, 0), telegraf.Histogram)
+	require.ErrorContains(t, AddMetric(converter.NewBatch(), m, m.Tags()), "conversion failed")
+}
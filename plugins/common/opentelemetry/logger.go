@@ -6,11 +6,13 @@ import (
 	"github.com/influxdata/telegraf"
 )
 
-type otelLogger struct {
+// Logger adapts the Telegraf logger to the logger used by the influx2otel and
+// otel2influx converters
+type Logger struct {
 	telegraf.Logger
 }
 
-func (l otelLogger) Debug(msg string, kv ...interface{}) {
+func (l Logger) Debug(msg string, kv ...interface{}) {
 	format := msg + strings.Repeat(" %s=%q", len(kv)/2)
 	l.Logger.Debugf(format, kv...)
 }
@@ -0,0 +1,140 @@
+// Package schemaregistry contains the client of Confluent-compatible schema
+// registries shared by the Avro parser and serializer.
+package schemaregistry
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+const mimeType = "application/vnd.schemaregistry.v1+json"
+
+// Client accesses a schema registry using the credentials given in the
+// registry URL
+type Client struct {
+	url      string
+	username string
+	password string
+	client   *http.Client
+}
+
+// NewClient creates a client for the registry at the given address. The
+// certificate of the registry is verified using the CA certificate at the
+// given path if any.
+func NewClient(addr, caCertPath string) (*Client, error) {
+	var tlsCfg *tls.Config
+	if caCertPath != "" {
+		caCert, err := os.ReadFile(caCertPath)
+		if err != nil {
+			return nil, err
+		}
+		caCertPool := x509.NewCertPool()
+		caCertPool.AppendCertsFromPEM(caCert)
+		tlsCfg = &tls.Config{
+			RootCAs: caCertPool,
+		}
+	}
+
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing registry URL failed: %w", err)
+	}
+
+	var username, password string
+	if u.User != nil {
+		username = u.User.Username()
+		password, _ = u.User.Password()
+		u.User = nil
+	}
+
+	return &Client{
+		url:      u.String(),
+		username: username,
+		password: password,
+		client: &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: tlsCfg,
+				MaxIdleConns:    10,
+				IdleConnTimeout: 90 * time.Second,
+			},
+			Timeout: 10 * time.Second,
+		},
+	}, nil
+}
+
+// SchemaByID returns the schema registered with the given ID
+func (c *Client) SchemaByID(id int) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/schemas/ids/%d", c.url, id), nil)
+	if err != nil {
+		return "", err
+	}
+
+	var response struct {
+		Schema *string `json:"schema"`
+	}
+	if err := c.do(req, &response); err != nil {
+		return "", fmt.Errorf("getting schema %d failed: %w", id, err)
+	}
+	if response.Schema == nil {
+		return "", errors.New("malformed response from schema registry: no 'schema' key")
+	}
+	return *response.Schema, nil
+}
+
+// ID returns the ID of the schema under the given subject. The schema is
+// registered if requested, otherwise it must already exist in the registry.
+func (c *Client) ID(subject, schema string, register bool) (int, error) {
+	endpoint := "%s/subjects/%s"
+	if register {
+		endpoint = "%s/subjects/%s/versions"
+	}
+	body, err := json.Marshal(map[string]string{"schema": schema})
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf(endpoint, c.url, url.PathEscape(subject)), bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", mimeType)
+
+	var response struct {
+		ID int `json:"id"`
+	}
+	if err := c.do(req, &response); err != nil {
+		return 0, fmt.Errorf("subject %q: %w", subject, err)
+	}
+	return response.ID, nil
+}
+
+// do sends the request and decodes the JSON response. Responses with a status
+// other than OK are returned as error.
+func (c *Client) do(req *http.Request, v interface{}) error {
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("schema registry returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("malformed response from schema registry: %w", err)
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 
 	"go.starlark.net/lib/json"
 	"go.starlark.net/lib/math"
@@ -30,9 +31,14 @@ type Common struct {
 	functions  map[string]*starlark.Function
 	parameters map[string]starlark.Tuple
 	state      *starlark.Dict
+	stateMu    sync.Mutex
 }
 
 func (s *Common) GetState() interface{} {
+	// Protect the state from being modified by a concurrently running script
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
 	// Return the actual byte-type instead of nil allowing the persister
 	// to guess instantiate variable of the appropriate type
 	if s.state == nil {
@@ -244,6 +250,9 @@ func (s *Common) Call(name string) (starlark.Value, error) {
 	if !ok {
 		return nil, fmt.Errorf("params for function %q do not exist", name)
 	}
+
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
 	return starlark.Call(s.thread, fn, args, nil)
 }
 
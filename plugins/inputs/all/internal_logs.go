@@ -0,0 +1,5 @@
+//go:build !custom || inputs || inputs.internal_logs
+
+package all
+
+import _ "github.com/influxdata/telegraf/plugins/inputs/internal_logs" // register plugin
@@ -104,7 +104,7 @@ func (monitor *DirectoryMonitor) Init() error {
 	monitor.filesDroppedDir = selfstat.Register("directory_monitor", "files_dropped_per_dir", tags)
 	monitor.filesProcessed = selfstat.Register("directory_monitor", "files_processed", make(map[string]string))
 	monitor.filesProcessedDir = selfstat.Register("directory_monitor", "files_processed_per_dir", tags)
-	monitor.filesQueuedDir = selfstat.Register("directory_monitor", "files_queue_per_dir", tags)
+	monitor.filesQueuedDir = selfstat.RegisterGauge("directory_monitor", "files_queue_per_dir", tags)
 
 	// If an error directory should be used but has not been configured yet, create one ourselves.
 	if monitor.ErrorDirectory != "" {
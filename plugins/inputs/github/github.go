@@ -59,8 +59,8 @@ func (g *GitHub) Gather(acc telegraf.Accumulator) error {
 		}
 
 		g.rateLimitErrors = selfstat.Register("github", "rate_limit_blocks", tokenTags)
-		g.rateLimit = selfstat.Register("github", "rate_limit_limit", tokenTags)
-		g.rateRemaining = selfstat.Register("github", "rate_limit_remaining", tokenTags)
+		g.rateLimit = selfstat.RegisterGauge("github", "rate_limit_limit", tokenTags)
+		g.rateRemaining = selfstat.RegisterGauge("github", "rate_limit_remaining", tokenTags)
 	}
 
 	var wg sync.WaitGroup
@@ -77,7 +77,7 @@ func (h *handler) subscribeGNMI(ctx context.Context, acc telegraf.Accumulator, t
 	// Used to report the status of the TCP connection to the device. If the
 	// GNMI connection goes down, but TCP is still up this will still report
 	// connected until the TCP connection times out.
-	connectStat := selfstat.Register("gnmi", "grpc_connection_status", map[string]string{"source": h.host})
+	connectStat := selfstat.RegisterGauge("gnmi", "grpc_connection_status", map[string]string{"source": h.host})
 	defer connectStat.Set(0)
 
 	address := net.JoinHostPort(h.host, h.port)
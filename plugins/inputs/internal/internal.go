@@ -4,8 +4,11 @@ package internal
 import (
 	_ "embed"
 	"fmt"
+	"maps"
 	"runtime"
 	"runtime/metrics"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/influxdata/telegraf"
@@ -18,8 +21,9 @@ import (
 var sampleConfig string
 
 type Internal struct {
-	CollectMemstats bool `toml:"collect_memstats"`
-	CollectGostats  bool `toml:"collect_gostats"`
+	CollectMemstats   bool `toml:"collect_memstats"`
+	CollectGostats    bool `toml:"collect_gostats"`
+	CollectHistograms bool `toml:"collect_histograms"`
 }
 
 func (*Internal) SampleConfig() string {
@@ -43,9 +47,37 @@ func (s *Internal) Gather(acc telegraf.Accumulator) error {
 		collectGoStat(acc)
 	}
 
+	if s.CollectHistograms {
+		collectHistograms(acc)
+	}
+
 	return nil
 }
 
+// collectHistograms adds the selfstat histograms in the format of the
+// prometheus input, i.e. one metric per cumulative bucket tagged with the
+// upper bound in "le" and one metric with the sum and count.
+func collectHistograms(acc telegraf.Accumulator) {
+	for _, h := range selfstat.Histograms() {
+		h.Tags["version"] = inter.Version
+
+		for _, upper := range slices.Sorted(maps.Keys(h.Buckets)) {
+			tags := maps.Clone(h.Tags)
+			tags["le"] = strconv.FormatFloat(upper, 'f', -1, 64)
+			acc.AddHistogram(h.Measurement, map[string]any{h.Field + "_bucket": h.Buckets[upper]}, tags)
+		}
+		tags := maps.Clone(h.Tags)
+		tags["le"] = "+Inf"
+		acc.AddHistogram(h.Measurement, map[string]any{h.Field + "_bucket": h.Count}, tags)
+
+		fields := map[string]any{
+			h.Field + "_sum":   h.Sum,
+			h.Field + "_count": h.Count,
+		}
+		acc.AddHistogram(h.Measurement, fields, h.Tags)
+	}
+}
+
 func collectMemStat(acc telegraf.Accumulator) {
 	m := &runtime.MemStats{}
 	runtime.ReadMemStats(m)
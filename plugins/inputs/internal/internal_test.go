@@ -2,9 +2,12 @@ package internal
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/selfstat"
 	"github.com/influxdata/telegraf/testutil"
 )
@@ -111,3 +114,52 @@ func TestGostats(t *testing.T) {
 		}
 	}
 }
+
+func TestHistograms(t *testing.T) {
+	h := selfstat.RegisterHistogram("myhistogram", "duration_seconds", map[string]string{"test": "foo"}, []float64{0.1, 1})
+	h.Observe(0.05)
+	h.Observe(0.5)
+	h.Observe(2)
+
+	s := Internal{CollectHistograms: true}
+	acc := &testutil.Accumulator{}
+	require.NoError(t, s.Gather(acc))
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"internal_myhistogram",
+			map[string]string{"test": "foo", "le": "0.1", "version": "unknown"},
+			map[string]interface{}{"duration_seconds_bucket": uint64(1)},
+			time.Unix(0, 0),
+			telegraf.Histogram,
+		),
+		metric.New(
+			"internal_myhistogram",
+			map[string]string{"test": "foo", "le": "1", "version": "unknown"},
+			map[string]interface{}{"duration_seconds_bucket": uint64(2)},
+			time.Unix(0, 0),
+			telegraf.Histogram,
+		),
+		metric.New(
+			"internal_myhistogram",
+			map[string]string{"test": "foo", "le": "+Inf", "version": "unknown"},
+			map[string]interface{}{"duration_seconds_bucket": uint64(3)},
+			time.Unix(0, 0),
+			telegraf.Histogram,
+		),
+		metric.New(
+			"internal_myhistogram",
+			map[string]string{"test": "foo", "version": "unknown"},
+			map[string]interface{}{"duration_seconds_sum": float64(2.55), "duration_seconds_count": uint64(3)},
+			time.Unix(0, 0),
+			telegraf.Histogram,
+		),
+	}
+	var actual []telegraf.Metric
+	for _, m := range acc.GetTelegrafMetrics() {
+		if m.Name() == "internal_myhistogram" {
+			actual = append(actual, m)
+		}
+	}
+	testutil.RequireMetricsEqual(t, expected, actual, testutil.IgnoreTime(), testutil.SortMetrics())
+}
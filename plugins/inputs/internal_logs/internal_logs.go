@@ -0,0 +1,187 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package internal_logs
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/logger"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type InternalLogs struct {
+	Level      string          `toml:"level"`
+	Categories []string        `toml:"categories"`
+	QueueSize  int             `toml:"queue_size"`
+	Log        telegraf.Logger `toml:"-"`
+
+	level      telegraf.LogLevel
+	categories map[string]bool
+	queue      chan logger.Entry
+	done       chan struct{}
+	remove     func()
+	dropped    selfstat.Stat
+	wg         sync.WaitGroup
+}
+
+func (*InternalLogs) SampleConfig() string {
+	return sampleConfig
+}
+
+func (l *InternalLogs) Init() error {
+	if l.Level == "" {
+		l.Level = "info"
+	}
+	l.level = telegraf.LogLevelFromString(l.Level)
+	if l.level == telegraf.None {
+		return fmt.Errorf("invalid 'level' %q", l.Level)
+	}
+
+	if len(l.Categories) == 0 {
+		l.Categories = []string{"agent", "inputs"}
+	}
+	l.categories = make(map[string]bool, len(l.Categories))
+	for _, c := range l.Categories {
+		if c == "outputs" {
+			return errors.New("collecting messages of outputs is not supported as it causes a feedback loop")
+		}
+		l.categories[c] = true
+	}
+
+	if l.QueueSize == 0 {
+		l.QueueSize = 1000
+	}
+	if l.QueueSize < 0 {
+		return errors.New("'queue_size' must be positive")
+	}
+
+	l.dropped = selfstat.Register("internal_logs", "entries_dropped", map[string]string{})
+
+	return nil
+}
+
+func (l *InternalLogs) Start(acc telegraf.Accumulator) error {
+	l.queue = make(chan logger.Entry, l.QueueSize)
+	l.done = make(chan struct{})
+
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		for {
+			select {
+			case <-l.done:
+				return
+			case e := <-l.queue:
+				l.add(acc, e)
+			}
+		}
+	}()
+	l.remove = logger.AddTap(l.level, l.tap)
+
+	return nil
+}
+
+func (*InternalLogs) Gather(telegraf.Accumulator) error {
+	return nil
+}
+
+func (l *InternalLogs) Stop() {
+	if l.remove != nil {
+		l.remove()
+	}
+	close(l.done)
+	l.wg.Wait()
+}
+
+// tap receives the log entries from the logging framework. To never block
+// the logging plugin, entries are dropped if the queue is full.
+func (l *InternalLogs) tap(e logger.Entry) {
+	if !l.collect(e) {
+		return
+	}
+	select {
+	case l.queue <- e:
+	default:
+		l.dropped.Incr(1)
+	}
+}
+
+func (*InternalLogs) add(acc telegraf.Accumulator, e logger.Entry) {
+	tags := make(map[string]string, len(e.Attributes)+1)
+	for k, v := range e.Attributes {
+		if s := fmt.Sprint(v); s != "" {
+			tags[k] = s
+		}
+	}
+	tags["level"] = strings.ToLower(e.Level.String())
+
+	msg := e.Message
+	if tags["category"] == "" {
+		source, remainder, found := splitSource(msg)
+		if found {
+			msg = remainder
+			category, plugin, _ := strings.Cut(source, ".")
+			plugin, alias, _ := strings.Cut(plugin, "::")
+			tags["category"] = category
+			if plugin != "" {
+				tags["plugin"] = plugin
+			}
+			if alias != "" {
+				tags["alias"] = alias
+			}
+		}
+	}
+
+	acc.AddFields("internal_log", map[string]interface{}{"message": msg}, tags, e.Time)
+}
+
+// collect checks if the entry can be added to the pipeline without causing
+// a feedback loop. Only messages of the configured categories are collected.
+// Messages of this plugin and messages about outputs are always skipped as
+// writing the resulting metrics might produce the same messages again, e.g.
+// if the output failed to write. The same applies to processors, aggregators
+// and parsers logging for every metric passing through them.
+func (l *InternalLogs) collect(e logger.Entry) bool {
+	category, _ := e.Attributes["category"].(string)
+	plugin, _ := e.Attributes["plugin"].(string)
+	if category == "" {
+		// Messages logged via the standard logger like the ones of the agent
+		// only contain the source as prefix
+		source, msg, _ := splitSource(e.Message)
+		if strings.Contains(msg, "outputs.") {
+			return false
+		}
+		category, plugin, _ = strings.Cut(source, ".")
+		plugin, _, _ = strings.Cut(plugin, "::")
+	}
+	if category == "inputs" && plugin == "internal_logs" {
+		return false
+	}
+	return category != "outputs" && l.categories[category]
+}
+
+// splitSource splits messages of the form "[source] message"
+func splitSource(msg string) (source, remainder string, found bool) {
+	if !strings.HasPrefix(msg, "[") {
+		return "", msg, false
+	}
+	source, remainder, found = strings.Cut(msg[1:], "] ")
+	if !found || strings.ContainsAny(source, " []") {
+		return "", msg, false
+	}
+	return source, remainder, true
+}
+
+func init() {
+	inputs.Add("internal_logs", func() telegraf.Input {
+		return &InternalLogs{}
+	})
+}
@@ -0,0 +1,207 @@
+package internal_logs
+
+import (
+	"log"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/logger"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestSampleConfig(t *testing.T) {
+	plugin := &InternalLogs{}
+	require.NotEmpty(t, plugin.SampleConfig())
+}
+
+func TestInitFail(t *testing.T) {
+	plugin := &InternalLogs{Level: "foo"}
+	require.ErrorContains(t, plugin.Init(), `invalid 'level' "foo"`)
+
+	plugin = &InternalLogs{QueueSize: -1}
+	require.ErrorContains(t, plugin.Init(), "'queue_size' must be positive")
+
+	plugin = &InternalLogs{Categories: []string{"inputs", "outputs"}}
+	require.ErrorContains(t, plugin.Init(), "collecting messages of outputs is not supported")
+}
+
+func TestCollect(t *testing.T) {
+	plugin := &InternalLogs{Level: "warn", Log: testutil.Logger{}}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	input := logger.New("inputs", "cpu", "host1")
+	input.AddAttribute("core", 3)
+	input.Error("reading failed")
+	input.Info("below the level")
+	log.Printf("W! [agent] Collection took longer than expected")
+
+	// Messages of categories not collected by default
+	logger.New("processors", "starlark", "").Warn("slow script")
+	log.Printf("W! [aggregators.basicstats] Metric is outside aggregation window")
+
+	// Messages which could cause a feedback loop
+	logger.New("outputs", "loki", "").Error("writing failed")
+	logger.New("inputs", "internal_logs", "").Error("own message")
+	log.Printf("E! [agent] Error writing to outputs.loki: timeout")
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"internal_log",
+			map[string]string{
+				"level":    "error",
+				"category": "inputs",
+				"plugin":   "cpu",
+				"alias":    "host1",
+				"core":     "3",
+			},
+			map[string]interface{}{"message": "reading failed"},
+			time.Unix(0, 0),
+		),
+		metric.New(
+			"internal_log",
+			map[string]string{
+				"level":    "warn",
+				"category": "agent",
+			},
+			map[string]interface{}{"message": "Collection took longer than expected"},
+			time.Unix(0, 0),
+		),
+	}
+	require.Eventually(t, func() bool {
+		return acc.NMetrics() >= uint64(len(expected))
+	}, 3*time.Second, 10*time.Millisecond)
+
+	// Give dropped messages a chance to show up
+	time.Sleep(50 * time.Millisecond)
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestCollectCategories(t *testing.T) {
+	plugin := &InternalLogs{
+		Level:      "warn",
+		Categories: []string{"processors", "aggregators"},
+		Log:        testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	logger.New("inputs", "cpu", "").Error("reading failed")
+	log.Printf("W! [agent] Collection took longer than expected")
+	logger.New("processors", "starlark", "").Warn("slow script")
+	log.Printf("W! [aggregators.basicstats] Metric is outside aggregation window")
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"internal_log",
+			map[string]string{
+				"level":    "warn",
+				"category": "processors",
+				"plugin":   "starlark",
+			},
+			map[string]interface{}{"message": "slow script"},
+			time.Unix(0, 0),
+		),
+		metric.New(
+			"internal_log",
+			map[string]string{
+				"level":    "warn",
+				"category": "aggregators",
+				"plugin":   "basicstats",
+			},
+			map[string]interface{}{"message": "Metric is outside aggregation window"},
+			time.Unix(0, 0),
+		),
+	}
+	require.Eventually(t, func() bool {
+		return acc.NMetrics() >= uint64(len(expected))
+	}, 3*time.Second, 10*time.Millisecond)
+
+	// Give dropped messages a chance to show up
+	time.Sleep(50 * time.Millisecond)
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestProcessorFeedbackLoop(t *testing.T) {
+	plugin := &InternalLogs{Log: testutil.Logger{}}
+	require.NoError(t, plugin.Init())
+
+	// Pass the collected metrics through a processor logging for every metric
+	// like it would happen in the pipeline
+	var acc testutil.Accumulator
+	processor := &loggingProcessor{Log: logger.New("processors", "logging", "")}
+	require.NoError(t, plugin.Start(&processingAccumulator{Accumulator: &acc, processor: processor}))
+	defer plugin.Stop()
+
+	logger.New("inputs", "cpu", "").Error("reading failed")
+
+	require.Eventually(t, func() bool {
+		return acc.NMetrics() >= 1
+	}, 3*time.Second, 10*time.Millisecond)
+
+	// Give messages caused by the processor a chance to show up
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(t, uint64(1), acc.NMetrics())
+	require.Equal(t, int64(1), processor.processed.Load())
+}
+
+func TestQueueFull(t *testing.T) {
+	plugin := &InternalLogs{QueueSize: 1, Log: testutil.Logger{}}
+	require.NoError(t, plugin.Init())
+	plugin.queue = make(chan logger.Entry, plugin.QueueSize)
+
+	before := plugin.dropped.Get()
+	for range 3 {
+		plugin.tap(logger.Entry{
+			Level:      telegraf.Error,
+			Attributes: map[string]interface{}{"category": "inputs", "plugin": "cpu"},
+			Message:    "reading failed",
+		})
+	}
+	require.Len(t, plugin.queue, 1)
+	require.Equal(t, before+2, plugin.dropped.Get())
+}
+
+type loggingProcessor struct {
+	Log       telegraf.Logger
+	processed atomic.Int64
+}
+
+func (*loggingProcessor) SampleConfig() string {
+	return ""
+}
+
+func (*loggingProcessor) Start(telegraf.Accumulator) error {
+	return nil
+}
+
+func (p *loggingProcessor) Add(m telegraf.Metric, acc telegraf.Accumulator) error {
+	p.processed.Add(1)
+	p.Log.Infof("processing metric %q", m.Name())
+	acc.AddMetric(m)
+	return nil
+}
+
+func (*loggingProcessor) Stop() {}
+
+type processingAccumulator struct {
+	*testutil.Accumulator
+	processor telegraf.StreamingProcessor
+}
+
+func (a *processingAccumulator) AddFields(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	if err := a.processor.Add(metric.New(measurement, tags, fields, t[0]), a.Accumulator); err != nil {
+		a.AddError(err)
+	}
+}
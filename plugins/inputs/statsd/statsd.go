@@ -253,18 +253,18 @@ func (s *Statsd) Start(ac telegraf.Accumulator) error {
 	tags := map[string]string{
 		"address": s.ServiceAddress,
 	}
-	s.Stats.MaxConnections = selfstat.Register("statsd", "tcp_max_connections", tags)
+	s.Stats.MaxConnections = selfstat.RegisterGauge("statsd", "tcp_max_connections", tags)
 	s.Stats.MaxConnections.Set(int64(s.MaxTCPConnections))
-	s.Stats.CurrentConnections = selfstat.Register("statsd", "tcp_current_connections", tags)
+	s.Stats.CurrentConnections = selfstat.RegisterGauge("statsd", "tcp_current_connections", tags)
 	s.Stats.TotalConnections = selfstat.Register("statsd", "tcp_total_connections", tags)
 	s.Stats.TCPPacketsRecv = selfstat.Register("statsd", "tcp_packets_received", tags)
 	s.Stats.TCPBytesRecv = selfstat.Register("statsd", "tcp_bytes_received", tags)
 	s.Stats.UDPPacketsRecv = selfstat.Register("statsd", "udp_packets_received", tags)
 	s.Stats.UDPPacketsDrop = selfstat.Register("statsd", "udp_packets_dropped", tags)
 	s.Stats.UDPBytesRecv = selfstat.Register("statsd", "udp_bytes_received", tags)
-	s.Stats.ParseTimeNS = selfstat.Register("statsd", "parse_time_ns", tags)
-	s.Stats.PendingMessages = selfstat.Register("statsd", "pending_messages", tags)
-	s.Stats.MaxPendingMessages = selfstat.Register("statsd", "max_pending_messages", tags)
+	s.Stats.ParseTimeNS = selfstat.RegisterGauge("statsd", "parse_time_ns", tags)
+	s.Stats.PendingMessages = selfstat.RegisterGauge("statsd", "pending_messages", tags)
+	s.Stats.MaxPendingMessages = selfstat.RegisterGauge("statsd", "max_pending_messages", tags)
 	s.Stats.MaxPendingMessages.Set(int64(s.AllowedPendingMessages))
 
 	s.in = make(chan input, s.AllowedPendingMessages)
@@ -52,6 +52,7 @@ type Tail struct {
 	Log        telegraf.Logger `toml:"-"`
 	tailers    map[string]*tail.Tail
 	offsets    map[string]int64
+	tailersMu  sync.Mutex // protects tailers and offsets
 	parserFunc telegraf.ParserFunc
 	wg         sync.WaitGroup
 
@@ -189,7 +190,23 @@ func (t *Tail) getSeekInfo(file string) (*tail.SeekInfo, error) {
 }
 
 func (t *Tail) GetState() interface{} {
-	return t.offsets
+	t.tailersMu.Lock()
+	defer t.tailersMu.Unlock()
+
+	// Use the current position of running tailers to allow storing the state
+	// while the plugin is running
+	offsets := make(map[string]int64, len(t.offsets))
+	for k, v := range t.offsets {
+		offsets[k] = v
+	}
+	if !t.Pipe {
+		for _, tailer := range t.tailers {
+			if offset, err := tailer.Tell(); err == nil {
+				offsets[tailer.Filename] = offset
+			}
+		}
+	}
+	return offsets
 }
 
 func (t *Tail) SetState(state interface{}) error {
@@ -197,6 +214,9 @@ func (t *Tail) SetState(state interface{}) error {
 	if !ok {
 		return errors.New("state has to be of type 'map[string]int64'")
 	}
+
+	t.tailersMu.Lock()
+	defer t.tailersMu.Unlock()
 	for k, v := range offsetsState {
 		t.offsets[k] = v
 	}
@@ -208,6 +228,7 @@ func (t *Tail) Gather(_ telegraf.Accumulator) error {
 }
 
 func (t *Tail) Stop() {
+	t.tailersMu.Lock()
 	for _, tailer := range t.tailers {
 		if !t.Pipe {
 			// store offset for resume
@@ -224,16 +245,20 @@ func (t *Tail) Stop() {
 			t.Log.Errorf("Stopping tail on %q: %s", tailer.Filename, err.Error())
 		}
 	}
+	t.tailers = nil
+	t.tailersMu.Unlock()
 
 	t.cancel()
 	t.wg.Wait()
 
 	// persist offsets
+	t.tailersMu.Lock()
 	offsetsMutex.Lock()
 	for k, v := range t.offsets {
 		offsets[k] = v
 	}
 	offsetsMutex.Unlock()
+	t.tailersMu.Unlock()
 }
 
 func (t *Tail) tailNewFiles() error {
@@ -242,6 +267,9 @@ func (t *Tail) tailNewFiles() error {
 		poll = true
 	}
 
+	t.tailersMu.Lock()
+	defer t.tailersMu.Unlock()
+
 	// Create a "tailer" for each file
 	for _, filepath := range t.Files {
 		g, err := globpath.Compile(filepath)
@@ -299,7 +327,9 @@ func (t *Tail) tailNewFiles() error {
 				if err := tailer.Err(); err != nil {
 					if strings.HasSuffix(err.Error(), "permission denied") {
 						t.Log.Errorf("Deleting tailer for %q due to: %v", tailer.Filename, err)
+						t.tailersMu.Lock()
 						delete(t.tailers, tailer.Filename)
+						t.tailersMu.Unlock()
 					} else {
 						t.Log.Errorf("Tailing %q: %s", tailer.Filename, err.Error())
 					}
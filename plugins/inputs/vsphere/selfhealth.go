@@ -37,6 +37,6 @@ func (s *stopwatch) stop() {
 // sendInternalCounterWithTags is a convenience method for sending non-timing internal metrics. Allows additional tags
 func sendInternalCounterWithTags(name, vCenter string, tags map[string]string, value int64) {
 	tags["vcenter"] = vCenter
-	s := selfstat.Register("vsphere", name, tags)
+	s := selfstat.RegisterGauge("vsphere", name, tags)
 	s.Set(value)
 }
@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -47,6 +48,7 @@ type WinEventLog struct {
 	subscription     evtHandle
 	subscriptionFlag evtSubscribeFlag
 	bookmark         evtHandle
+	bookmarkMu       sync.Mutex
 	tagFilter        filter.Filter
 	fieldFilter      filter.Filter
 	fieldEmptyFilter filter.Filter
@@ -104,6 +106,10 @@ func (w *WinEventLog) Start(telegraf.Accumulator) error {
 }
 
 func (w *WinEventLog) GetState() interface{} {
+	// The state is persisted periodically, concurrently to gathering events
+	w.bookmarkMu.Lock()
+	defer w.bookmarkMu.Unlock()
+
 	bookmarkXML, err := renderBookmark(w.bookmark)
 	if err != nil {
 		w.Log.Errorf("State-persistence failed, cannot render bookmark: %v", err)
@@ -127,7 +133,9 @@ func (w *WinEventLog) SetState(state interface{}) error {
 	if err != nil {
 		return fmt.Errorf("creating bookmark failed: %w", err)
 	}
+	w.bookmarkMu.Lock()
 	w.bookmark = bookmark
+	w.bookmarkMu.Unlock()
 	w.subscriptionFlag = evtSubscribeStartAfterBookmark
 
 	return nil
@@ -325,7 +333,9 @@ func (w *WinEventLog) evtSubscribe() (evtHandle, error) {
 
 	var bookmark evtHandle
 	if w.subscriptionFlag == evtSubscribeStartAfterBookmark {
+		w.bookmarkMu.Lock()
 		bookmark = w.bookmark
+		w.bookmarkMu.Unlock()
 	}
 	subsHandle, err := evtSubscribe(0, uintptr(sigEvent), logNamePtr, xqueryPtr, bookmark, 0, 0, w.subscriptionFlag)
 	if err != nil {
@@ -365,7 +375,10 @@ func (w *WinEventLog) fetchEvents(subsHandle evtHandle) ([]event, error) {
 		if event, err := w.renderEvent(eventHandle); err == nil {
 			events = append(events, event)
 		}
-		if err := evtUpdateBookmark(w.bookmark, eventHandle); err != nil && evterr == nil {
+		w.bookmarkMu.Lock()
+		err := evtUpdateBookmark(w.bookmark, eventHandle)
+		w.bookmarkMu.Unlock()
+		if err != nil && evterr == nil {
 			evterr = err
 		}
 
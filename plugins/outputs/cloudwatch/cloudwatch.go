@@ -4,6 +4,7 @@ package cloudwatch
 import (
 	"context"
 	_ "embed"
+	"fmt"
 	"math"
 	"net/http"
 	"sort"
@@ -11,10 +12,12 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	common_aws "github.com/influxdata/telegraf/plugins/common/aws"
 	common_http "github.com/influxdata/telegraf/plugins/common/http"
 	"github.com/influxdata/telegraf/plugins/outputs"
@@ -220,6 +223,9 @@ func (c *CloudWatch) WriteToCloudWatch(datums []types.MetricDatum) error {
 
 	if err != nil {
 		c.Log.Errorf("Unable to write to CloudWatch : %+v", err.Error())
+		if retry.IsErrorThrottles(retry.DefaultThrottles).IsErrorThrottle(err) == aws.TrueTernary {
+			return fmt.Errorf("%w: %w", internal.ErrThrottled, err)
+		}
 	}
 
 	return err
@@ -189,6 +189,9 @@ func (d *Datadog) Write(metrics []telegraf.Metric) error {
 	if resp.StatusCode < 200 || resp.StatusCode > 209 {
 		//nolint:errcheck // err can be ignored since it is just for logging
 		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return fmt.Errorf("received bad status code, %d: %s: %w", resp.StatusCode, string(body), internal.ErrThrottled)
+		}
 		return fmt.Errorf("received bad status code, %d: %s", resp.StatusCode, string(body))
 	}
 
@@ -14,6 +14,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/testutil"
 )
 
@@ -86,6 +87,18 @@ func TestBadStatusCode(t *testing.T) {
 	}
 }
 
+func TestThrottled(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer ts.Close()
+
+	d := NewDatadog(ts.URL)
+	d.Apikey = "123456"
+	require.NoError(t, d.Connect())
+	require.ErrorIs(t, d.Write(testutil.MockMetrics()), internal.ErrThrottled)
+}
+
 func TestAuthenticatedUrl(t *testing.T) {
 	d := fakeDatadog()
 
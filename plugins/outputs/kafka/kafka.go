@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/IBM/sarama"
@@ -55,6 +56,8 @@ type Kafka struct {
 	producerFunc func(addrs []string, config *sarama.Config) (sarama.SyncProducer, error)
 	producer     sarama.SyncProducer
 
+	credentialsChanged atomic.Bool
+
 	serializer telegraf.Serializer
 }
 
@@ -155,6 +158,10 @@ func (k *Kafka) Init() error {
 		return fmt.Errorf("unknown producer_timestamp option: %s", k.ProducerTimestamp)
 	}
 
+	// Reconnect on the next write if the SASL credentials are rotated
+	k.SASLUsername.OnChange(func() { k.credentialsChanged.Store(true) })
+	k.SASLPassword.OnChange(func() { k.credentialsChanged.Store(true) })
+
 	return nil
 }
 
@@ -167,6 +174,25 @@ func (k *Kafka) Connect() error {
 	return nil
 }
 
+// reconnect replaces the producer by a new one using the changed SASL
+// credentials. The current producer is kept if connecting fails.
+func (k *Kafka) reconnect() error {
+	cfg := *k.saramaConfig
+	if err := k.SetSASLConfig(&cfg); err != nil {
+		return err
+	}
+	producer, err := k.producerFunc(k.Brokers, &cfg)
+	if err != nil {
+		return err
+	}
+	if err := k.producer.Close(); err != nil {
+		k.Log.Debugf("Closing previous producer failed: %v", err)
+	}
+	k.producer = producer
+	k.saramaConfig = &cfg
+	return nil
+}
+
 func (k *Kafka) Close() error {
 	if k.producer == nil {
 		return nil
@@ -194,6 +220,14 @@ func (k *Kafka) routingKey(metric telegraf.Metric) (string, error) {
 }
 
 func (k *Kafka) Write(metrics []telegraf.Metric) error {
+	if k.credentialsChanged.Swap(false) {
+		k.Log.Info("SASL credentials changed, reconnecting")
+		if err := k.reconnect(); err != nil {
+			k.credentialsChanged.Store(true)
+			k.Log.Errorf("Reconnecting with changed credentials failed: %v", err)
+		}
+	}
+
 	msgs := make([]*sarama.ProducerMessage, 0, len(metrics))
 	for _, metric := range metrics {
 		metric, topic := k.GetTopicName(metric)
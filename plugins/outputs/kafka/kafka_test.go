@@ -9,7 +9,9 @@ import (
 	kafkacontainer "github.com/testcontainers/testcontainers-go/modules/kafka"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/secretstores"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 	"github.com/influxdata/telegraf/testutil"
 )
@@ -310,3 +312,47 @@ func TestTopicTag(t *testing.T) {
 		})
 	}
 }
+
+func TestCredentialsRotation(t *testing.T) {
+	store := testutil.NewRotatingSecretStore(map[string]string{"username": "old", "password": "old"})
+	secretstores.Add("rotating", func(string) telegraf.SecretStore { return store })
+
+	cfg := config.NewConfig()
+	require.NoError(t, cfg.LoadConfigData([]byte(`
+[[secretstores.rotating]]
+  id = "kafka"
+[[outputs.kafka]]
+  brokers = ["localhost:9092"]
+  sasl_mechanism = "PLAIN"
+  sasl_username = "@{kafka:username}"
+  sasl_password = "@{kafka:password}"
+`), config.EmptySourcePath))
+	require.NoError(t, cfg.LinkSecrets())
+	require.Len(t, cfg.Outputs, 1)
+	plugin, ok := cfg.Outputs[0].Output.(*Kafka)
+	require.True(t, ok)
+
+	var producers []*MockProducer
+	var users []string
+	plugin.producerFunc = func(_ []string, cfg *sarama.Config) (sarama.SyncProducer, error) {
+		p := &MockProducer{}
+		producers = append(producers, p)
+		users = append(users, cfg.Net.SASL.User+":"+cfg.Net.SASL.Password)
+		return p, nil
+	}
+	plugin.Log = testutil.Logger{}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+
+	m := metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 42.0}, time.Unix(0, 0))
+	require.NoError(t, plugin.Write([]telegraf.Metric{m}))
+	require.Equal(t, []string{"old:old"}, users)
+
+	// The next write uses a new producer with the rotated credentials
+	require.NoError(t, store.Set("password", "new"))
+	require.NoError(t, plugin.Write([]telegraf.Metric{m}))
+	require.Equal(t, []string{"old:old", "old:new"}, users)
+	require.Len(t, producers[0].sent, 1)
+	require.Len(t, producers[1].sent, 1)
+	require.NoError(t, plugin.Close())
+}
@@ -245,3 +245,44 @@ func TestTimestampDifferentName(t *testing.T) {
 	require.Equal(t, 1, int(metadata.NumRows))
 	require.Equal(t, 2, metadata.Schema.NumColumns())
 }
+
+func TestWriteAfterConversionError(t *testing.T) {
+	valid := []telegraf.Metric{
+		testutil.MustMetric(
+			"test",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"value": 1.0},
+			time.Now(),
+		),
+	}
+	invalid := []telegraf.Metric{
+		testutil.MustMetric(
+			"test",
+			map[string]string{"host": "b"},
+			map[string]interface{}{"value": "not a number"},
+			time.Now(),
+		),
+	}
+
+	testDir := t.TempDir()
+	plugin := &Parquet{
+		Directory:          testDir,
+		TimestampFieldName: defaultTimestampFieldName,
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	require.NoError(t, plugin.Write(valid))
+	require.ErrorContains(t, plugin.Write(invalid), `column "value"`)
+	require.NoError(t, plugin.Write(valid))
+	require.NoError(t, plugin.Close())
+
+	files, err := os.ReadDir(testDir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	reader, err := file.OpenParquetFile(filepath.Join(testDir, files[0].Name()), false)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	metadata := reader.MetaData()
+	require.Equal(t, 2, int(metadata.NumRows))
+}
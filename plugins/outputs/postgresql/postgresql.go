@@ -3,10 +3,12 @@ package postgresql
 
 import (
 	"context"
+	"crypto/sha256"
 	_ "embed"
 	"errors"
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/coocood/freecache"
@@ -64,6 +66,9 @@ type Postgresql struct {
 
 	pguint8 *pgtype.DataType
 
+	credentialsChanged atomic.Bool
+	credentials        atomic.Pointer[[sha256.Size]byte] // hash of the current connection settings
+
 	writeChan      chan *TableSource
 	writeWaitGroup *utils.WaitGroup
 
@@ -107,36 +112,10 @@ func (p *Postgresql) Init() error {
 	p.fieldsJSONColumn = utils.Column{Name: "fields", Type: PgJSONb, Role: utils.FieldColType}
 	p.tagsJSONColumn = utils.Column{Name: "tags", Type: PgJSONb, Role: utils.TagColType}
 
-	connectionSecret, err := p.Connection.Get()
-	if err != nil {
-		return fmt.Errorf("getting address failed: %w", err)
-	}
-	connection := connectionSecret.String()
-	defer connectionSecret.Destroy()
-
-	if p.dbConfig, err = pgxpool.ParseConfig(connection); err != nil {
-		return err
-	}
-	parsedConfig, err := pgx.ParseConfig(connection)
-	if err != nil {
+	var err error
+	if p.dbConfig, err = p.parseConnection(); err != nil {
 		return err
 	}
-	if _, ok := parsedConfig.Config.RuntimeParams["pool_max_conns"]; !ok {
-		// The pgx default for pool_max_conns is 4. However we want to default to 1.
-		p.dbConfig.MaxConns = 1
-	}
-
-	if _, ok := p.dbConfig.ConnConfig.RuntimeParams["application_name"]; !ok {
-		p.dbConfig.ConnConfig.RuntimeParams["application_name"] = "telegraf"
-	}
-
-	if p.LogLevel != "" {
-		p.dbConfig.ConnConfig.Logger = utils.PGXLogger{Logger: p.Logger}
-		p.dbConfig.ConnConfig.LogLevel, err = pgx.LogLevelFromString(p.LogLevel)
-		if err != nil {
-			return errors.New("invalid log level")
-		}
-	}
 
 	switch p.Uint64Type {
 	case PgNumeric:
@@ -146,6 +125,15 @@ func (p *Postgresql) Init() error {
 		return errors.New("invalid uint64_type")
 	}
 
+	// Use the current credentials for new connections and renew the
+	// connections on the next write if the secret is rotated. Connections
+	// using outdated credentials are closed instead of being reused, i.e.
+	// when acquired while idle or when released after being in use.
+	p.dbConfig.BeforeConnect = p.applyCredentials
+	p.dbConfig.BeforeAcquire = func(_ context.Context, conn *pgx.Conn) bool { return p.currentCredentials(conn.Config()) }
+	p.dbConfig.AfterRelease = func(conn *pgx.Conn) bool { return p.currentCredentials(conn.Config()) }
+	p.Connection.OnChange(func() { p.credentialsChanged.Store(true) })
+
 	return nil
 }
 
@@ -201,6 +189,94 @@ func (p *Postgresql) registerUint8(_ context.Context, conn *pgx.Conn) error {
 	return nil
 }
 
+// parseConnection parses the connection secret into the pool configuration
+// including the connection settings of the plugin
+func (p *Postgresql) parseConnection() (*pgxpool.Config, error) {
+	connectionSecret, err := p.Connection.Get()
+	if err != nil {
+		return nil, fmt.Errorf("getting address failed: %w", err)
+	}
+	connection := connectionSecret.String()
+	defer connectionSecret.Destroy()
+
+	cfg, err := pgxpool.ParseConfig(connection)
+	if err != nil {
+		return nil, err
+	}
+	parsedConfig, err := pgx.ParseConfig(connection)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := parsedConfig.Config.RuntimeParams["pool_max_conns"]; !ok {
+		// The pgx default for pool_max_conns is 4. However we want to default to 1.
+		cfg.MaxConns = 1
+	}
+
+	if _, ok := cfg.ConnConfig.RuntimeParams["application_name"]; !ok {
+		cfg.ConnConfig.RuntimeParams["application_name"] = "telegraf"
+	}
+
+	if p.LogLevel != "" {
+		cfg.ConnConfig.Logger = utils.PGXLogger{Logger: p.Logger}
+		cfg.ConnConfig.LogLevel, err = pgx.LogLevelFromString(p.LogLevel)
+		if err != nil {
+			return nil, errors.New("invalid log level")
+		}
+	}
+
+	return cfg, nil
+}
+
+// applyCredentials replaces the config of a new connection by the one parsed
+// from the connection secret as the secret might have been rotated since
+// initialization
+func (p *Postgresql) applyCredentials(_ context.Context, cfg *pgx.ConnConfig) error {
+	parsed, err := p.parseConnection()
+	if err != nil {
+		return err
+	}
+	*cfg = *parsed.ConnConfig
+	return nil
+}
+
+// renewConnections records the rotated connection settings and closes all idle
+// connections so the pool reconnects using the changed credentials.
+// Connections in use are closed once released.
+func (p *Postgresql) renewConnections() {
+	p.Logger.Info("Connection credentials changed, renewing connections")
+	if err := p.storeCredentials(); err != nil {
+		p.Logger.Errorf("Parsing rotated connection failed: %v", err)
+		return
+	}
+
+	// Acquiring the idle connections destroys the outdated ones
+	for _, c := range p.db.AcquireAllIdle(p.dbContext) {
+		c.Release()
+	}
+}
+
+// storeCredentials records the current connection settings to be used by all
+// connections
+func (p *Postgresql) storeCredentials() error {
+	cfg, err := p.parseConnection()
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256([]byte(cfg.ConnConfig.ConnString()))
+	p.credentials.Store(&hash)
+	return nil
+}
+
+// currentCredentials returns false if the given connection settings differ
+// from the rotated ones
+func (p *Postgresql) currentCredentials(cfg *pgx.ConnConfig) bool {
+	expected := p.credentials.Load()
+	if expected == nil {
+		return true
+	}
+	return sha256.Sum256([]byte(cfg.ConnString())) == *expected
+}
+
 // Close closes the connection(s) to the database.
 func (p *Postgresql) Close() error {
 	if p.writeChan != nil {
@@ -223,6 +299,10 @@ func (p *Postgresql) Close() error {
 }
 
 func (p *Postgresql) Write(metrics []telegraf.Metric) error {
+	if p.credentialsChanged.Swap(false) {
+		p.renewConnections()
+	}
+
 	if p.tagsCache != nil {
 		// gather at the start of write so there's less chance of any async operations ongoing
 		p.Logger.Debugf("cache: size=%d hit=%d miss=%d full=%d\n",
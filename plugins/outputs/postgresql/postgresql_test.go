@@ -22,6 +22,7 @@ import (
 	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/models"
 	"github.com/influxdata/telegraf/plugins/outputs/postgresql/utils"
+	"github.com/influxdata/telegraf/plugins/secretstores"
 	"github.com/influxdata/telegraf/testutil"
 )
 
@@ -307,6 +308,93 @@ func TestPostgresqlConnectIntegration(t *testing.T) {
 	require.EqualValues(t, 2, p.db.Stat().MaxConns())
 }
 
+// newRotatingPostgresql returns a plugin with the connection referencing a
+// secret of the returned store
+func newRotatingPostgresql(t *testing.T, connection string) (*Postgresql, *testutil.RotatingSecretStore) {
+	store := testutil.NewRotatingSecretStore(map[string]string{"connection": connection})
+	secretstores.Add("rotating", func(string) telegraf.SecretStore { return store })
+
+	cfg := config.NewConfig()
+	require.NoError(t, cfg.LoadConfigData([]byte(`
+[[secretstores.rotating]]
+  id = "db"
+[[outputs.postgresql]]
+  connection = "@{db:connection}"
+`), config.EmptySourcePath))
+	require.NoError(t, cfg.LinkSecrets())
+	require.Len(t, cfg.Outputs, 1)
+
+	p, ok := cfg.Outputs[0].Output.(*Postgresql)
+	require.True(t, ok)
+	return p, store
+}
+
+func TestCredentialsRotation(t *testing.T) {
+	p, store := newRotatingPostgresql(t, "host=db.example.com user=old password=old dbname=telegraf")
+	require.NoError(t, p.Init())
+	require.False(t, p.credentialsChanged.Load())
+
+	conn := p.dbConfig.ConnConfig.Copy()
+	require.NoError(t, p.dbConfig.BeforeConnect(ctx, conn))
+	require.Equal(t, "old", conn.User)
+
+	// New connections use the complete rotated connection settings
+	oldConn := conn
+	require.NoError(t, store.Set("connection", "host=other.example.com user=new password=new dbname=metrics"))
+	require.True(t, p.credentialsChanged.Load())
+
+	conn = p.dbConfig.ConnConfig.Copy()
+	require.NoError(t, p.dbConfig.BeforeConnect(ctx, conn))
+	require.Equal(t, "other.example.com", conn.Host)
+	require.Equal(t, "new", conn.User)
+	require.Equal(t, "new", conn.Password)
+	require.Equal(t, "metrics", conn.Database)
+	require.Equal(t, "telegraf", conn.RuntimeParams["application_name"])
+
+	// Connections using the old credentials are not reused once the rotated
+	// credentials are in effect, including connections in use
+	require.True(t, p.currentCredentials(oldConn))
+	require.NoError(t, p.storeCredentials())
+	require.False(t, p.currentCredentials(oldConn))
+	require.True(t, p.currentCredentials(conn))
+}
+
+func TestCredentialsRotationIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	pt, err := newPostgresqlTest(t)
+	require.NoError(t, err)
+	connection, err := pt.Connection.Get()
+	require.NoError(t, err)
+	original := connection.String()
+	connection.Destroy()
+
+	p, store := newRotatingPostgresql(t, original)
+	p.Logger = pt.Logger
+	require.NoError(t, p.Init())
+	require.NoError(t, p.Connect())
+	defer p.Close()
+
+	_, err = p.db.Exec(ctx, "CREATE ROLE rotated LOGIN SUPERUSER PASSWORD 'rotated'")
+	require.NoError(t, err)
+
+	var user string
+	require.NoError(t, p.db.QueryRow(ctx, "SELECT current_user").Scan(&user))
+	require.Equal(t, "postgres", user)
+
+	// The idle connection is renewed with the rotated credentials on write
+	rotated := strings.Replace(original, "user=postgres password=postgres", "user=rotated password=rotated", 1)
+	require.NoError(t, store.Set("connection", rotated))
+	metrics := []telegraf.Metric{
+		metric.New("rotation", nil, map[string]interface{}{"value": 1}, time.Now()),
+	}
+	require.NoError(t, p.Write(metrics))
+	require.NoError(t, p.db.QueryRow(ctx, "SELECT current_user").Scan(&user))
+	require.Equal(t, "rotated", user)
+}
+
 func TestConnectionIssueAtStartup(t *testing.T) {
 	// Test case for https://github.com/influxdata/telegraf/issues/14365
 	if testing.Short() {
@@ -0,0 +1,132 @@
+package arrow
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/common/columnar"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+type Parser struct {
+	MeasurementColumn string   `toml:"measurement_column"`
+	TagColumns        []string `toml:"tag_columns"`
+	TimestampColumn   string   `toml:"timestamp_column"`
+	TimestampFormat   string   `toml:"timestamp_format"`
+	TimestampTimezone string   `toml:"timestamp_timezone"`
+
+	defaultTags map[string]string
+	mapping     *columnar.ColumnMapping
+	metricName  string
+}
+
+func (p *Parser) Init() error {
+	if p.TimestampFormat == "" {
+		p.TimestampFormat = "unix_ns"
+	}
+
+	mapping, err := columnar.NewColumnMapping(p.MeasurementColumn, p.TagColumns, p.TimestampColumn, p.TimestampFormat, p.TimestampTimezone)
+	if err != nil {
+		return err
+	}
+	p.mapping = mapping
+
+	return nil
+}
+
+// Parse reads all concatenated IPC streams of the buffer. Columns not
+// configured explicitly are assigned according to the role stored in their
+// metadata, e.g. by the arrow serializer.
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	// Initialize the parser if used without calling Init
+	if p.mapping == nil {
+		if err := p.Init(); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+
+	var metrics []telegraf.Metric
+	r := bytes.NewReader(buf)
+	for r.Len() > 0 {
+		m, err := p.parseStream(r, now)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m...)
+	}
+
+	return metrics, nil
+}
+
+func (p *Parser) parseStream(r *bytes.Reader, now time.Time) ([]telegraf.Metric, error) {
+	reader, err := ipc.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create arrow reader: %w", err)
+	}
+	defer reader.Release()
+
+	schema := reader.Schema()
+	name := p.metricName
+	if idx := schema.Metadata().FindKey(columnar.MeasurementKey); idx >= 0 {
+		name = schema.Metadata().Values()[idx]
+	}
+
+	var metrics []telegraf.Metric
+	for reader.Next() {
+		record := reader.Record()
+		for row := range int(record.NumRows()) {
+			m := metric.New(name, p.defaultTags, nil, now)
+			for col, field := range schema.Fields() {
+				value := columnar.Value(record.Column(col), row)
+				if value == nil {
+					continue
+				}
+				if err := p.mapping.SetWithRole(m, field.Name, columnar.Role(field), value); err != nil {
+					return nil, err
+				}
+			}
+			metrics = append(metrics, m)
+		}
+	}
+	if err := reader.Err(); err != nil {
+		return nil, fmt.Errorf("reading record batch failed: %w", err)
+	}
+
+	return metrics, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, nil
+	}
+	if len(metrics) > 1 {
+		return nil, errors.New("line contains multiple metrics")
+	}
+
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.defaultTags = tags
+}
+
+func init() {
+	parsers.Add("arrow",
+		func(defaultMetricName string) telegraf.Parser {
+			return &Parser{metricName: defaultMetricName}
+		},
+	)
+}
@@ -0,0 +1,118 @@
+package arrow
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestParse(t *testing.T) {
+	schema := arrow.NewSchema(
+		[]arrow.Field{
+			{Name: "name", Type: arrow.BinaryTypes.String},
+			{Name: "time", Type: &arrow.TimestampType{Unit: arrow.Millisecond}},
+			{Name: "host", Type: arrow.BinaryTypes.String},
+			{Name: "value", Type: arrow.PrimitiveTypes.Float32, Nullable: true},
+			{Name: "count", Type: arrow.PrimitiveTypes.Int32, Nullable: true},
+		},
+		nil,
+	)
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer builder.Release()
+	builder.Field(0).(*array.StringBuilder).AppendValues([]string{"cpu", "mem"}, nil)
+	builder.Field(1).(*array.TimestampBuilder).AppendValues([]arrow.Timestamp{1700000000123, 1700000001000}, nil)
+	builder.Field(2).(*array.StringBuilder).AppendValues([]string{"a", "b"}, nil)
+	builder.Field(3).(*array.Float32Builder).AppendValues([]float32{1.5, 0}, []bool{true, false})
+	builder.Field(4).(*array.Int32Builder).AppendValues([]int32{0, 42}, []bool{false, true})
+	record := builder.NewRecord()
+	defer record.Release()
+
+	var buf bytes.Buffer
+	writer := ipc.NewWriter(&buf, ipc.WithSchema(schema))
+	require.NoError(t, writer.Write(record))
+	require.NoError(t, writer.Close())
+
+	parser := &Parser{
+		MeasurementColumn: "name",
+		TagColumns:        []string{"host"},
+		TimestampColumn:   "time",
+		metricName:        "arrow",
+	}
+	require.NoError(t, parser.Init())
+	parser.SetDefaultTags(map[string]string{"source": "test"})
+
+	actual, err := parser.Parse(buf.Bytes())
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"host": "a", "source": "test"},
+			map[string]interface{}{"value": 1.5},
+			time.UnixMilli(1700000000123),
+		),
+		metric.New(
+			"mem",
+			map[string]string{"host": "b", "source": "test"},
+			map[string]interface{}{"count": int64(42)},
+			time.UnixMilli(1700000001000),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestParseInvalid(t *testing.T) {
+	parser := &Parser{}
+	require.NoError(t, parser.Init())
+
+	_, err := parser.Parse([]byte("cpu value=42"))
+	require.ErrorContains(t, err, "unable to create arrow reader")
+}
+
+func TestParseWithoutInit(t *testing.T) {
+	schema := arrow.NewSchema(
+		[]arrow.Field{
+			{Name: "time", Type: arrow.BinaryTypes.String},
+			{Name: "value", Type: arrow.PrimitiveTypes.Int64},
+		},
+		nil,
+	)
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer builder.Release()
+	builder.Field(0).(*array.StringBuilder).Append("2024-01-02 03:04:05")
+	builder.Field(1).(*array.Int64Builder).Append(42)
+	record := builder.NewRecord()
+	defer record.Release()
+
+	var buf bytes.Buffer
+	writer := ipc.NewWriter(&buf, ipc.WithSchema(schema))
+	require.NoError(t, writer.Write(record))
+	require.NoError(t, writer.Close())
+
+	// The timezone setting must be respected even if Init was not called
+	parser := &Parser{
+		TimestampColumn:   "time",
+		TimestampFormat:   "2006-01-02 15:04:05",
+		TimestampTimezone: "Europe/Berlin",
+		metricName:        "arrow",
+	}
+	actual, err := parser.Parse(buf.Bytes())
+	require.NoError(t, err)
+
+	loc, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	expected := []telegraf.Metric{
+		metric.New("arrow", map[string]string{}, map[string]interface{}{"value": int64(42)}, time.Date(2024, 1, 2, 3, 4, 5, 0, loc)),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/influxdata/telegraf/filter"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/common/jsonschema"
 	"github.com/influxdata/telegraf/plugins/parsers"
 )
 
@@ -32,6 +33,7 @@ type Parser struct {
 	TimeFormat   string   `toml:"json_time_format"`
 	Timezone     string   `toml:"json_timezone"`
 	Strict       bool     `toml:"json_strict"`
+	Schema       string   `toml:"json_schema"`
 
 	DefaultTags map[string]string `toml:"-"`
 	Log         telegraf.Logger   `toml:"-"`
@@ -39,6 +41,8 @@ type Parser struct {
 	location     *time.Location
 	tagFilter    filter.Filter
 	stringFilter filter.Filter
+	validator    *jsonschema.Validator
+	statTags     map[string]string
 }
 
 func (p *Parser) parseArray(data []interface{}, timestamp time.Time) ([]telegraf.Metric, error) {
@@ -178,10 +182,34 @@ func (p *Parser) Init() error {
 		p.location = loc
 	}
 
+	if p.Schema != "" {
+		if p.statTags == nil {
+			p.statTags = map[string]string{"type": "json"}
+		}
+		p.validator, err = jsonschema.New(p.Schema, p.statTags)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// SetStatTags sets the tags identifying the plugin instance in the statistics
+func (p *Parser) SetStatTags(tags map[string]string) {
+	p.statTags = tags
+}
+
 func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	// Validate the whole document before applying the query
+	if p.validator != nil {
+		if doc := bytes.TrimPrefix(bytes.TrimSpace(buf), utf8BOM); len(doc) > 0 {
+			if err := p.validator.Validate(doc); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	if p.Query != "" {
 		result := gjson.GetBytes(buf, p.Query)
 		buf = []byte(result.Raw)
@@ -2,6 +2,8 @@ package json
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -10,6 +12,7 @@ import (
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal/fuzz"
 	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/selfstat"
 	"github.com/influxdata/telegraf/testutil"
 )
 
@@ -1471,6 +1474,45 @@ func BenchmarkParsingParallel(b *testing.B) {
 	})
 }
 
+func TestParseSchema(t *testing.T) {
+	schema := filepath.Join(t.TempDir(), "schema.json")
+	content := `{
+		"type": "object",
+		"required": ["a"],
+		"properties": {"a": {"type": "integer", "minimum": 0}}
+	}`
+	require.NoError(t, os.WriteFile(schema, []byte(content), 0600))
+
+	parser := &Parser{MetricName: "json_test", Schema: schema}
+	parser.SetStatTags(map[string]string{"type": "json", "alias": "schema_test", "id": "json_test"})
+	require.NoError(t, parser.Init())
+	invalid := selfstat.Register("parser", "invalid_documents", map[string]string{
+		"type":   "json",
+		"schema": schema,
+		"alias":  "schema_test",
+		"id":     "json_test",
+	})
+
+	actual, err := parser.Parse([]byte(validJSON))
+	require.NoError(t, err)
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"json_test",
+			map[string]string{},
+			map[string]interface{}{"a": float64(5), "b_c": float64(6)},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual, testutil.IgnoreTime())
+
+	_, err = parser.Parse([]byte(`{"a": -1}`))
+	require.ErrorContains(t, err, `at "/a"`)
+	require.ErrorContains(t, err, "/properties/a/minimum")
+	_, err = parser.Parse([]byte(`{"b": 1}`))
+	require.ErrorContains(t, err, "/required")
+	require.Equal(t, int64(2), invalid.Get())
+}
+
 func FuzzParserJSON(f *testing.F) {
 	for _, value := range fuzz.JSONDictionary {
 		f.Add([]byte(value))
@@ -15,12 +15,14 @@ import (
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/common/jsonschema"
 	"github.com/influxdata/telegraf/plugins/parsers"
 )
 
 // Parser adheres to the parser interface, contains the parser configuration, and data required to parse JSON
 type Parser struct {
 	Configs           []Config          `toml:"json_v2"`
+	Schema            string            `toml:"json_schema"`
 	DefaultMetricName string            `toml:"-"`
 	DefaultTags       map[string]string `toml:"-"`
 	Log               telegraf.Logger   `toml:"-"`
@@ -37,6 +39,10 @@ type Parser struct {
 	iterateObjects bool
 	// objectConfig contains the config for an object, some info is needed while iterating over the gjson results
 	objectConfig Object
+	// validator checks the documents against the schema if configured
+	validator *jsonschema.Validator
+	// statTags identify the plugin instance in the statistics
+	statTags map[string]string
 	// parseMutex is here because Parse() is not threadsafe.  If it is made threadsafe at some point, then we won't need it anymore.
 	parseMutex sync.Mutex
 }
@@ -117,9 +123,26 @@ func (p *Parser) Init() error {
 			p.Configs[i].Location = loc
 		}
 	}
+
+	if p.Schema != "" {
+		if p.statTags == nil {
+			p.statTags = map[string]string{"type": "json_v2"}
+		}
+		validator, err := jsonschema.New(p.Schema, p.statTags)
+		if err != nil {
+			return err
+		}
+		p.validator = validator
+	}
+
 	return nil
 }
 
+// SetStatTags sets the tags identifying the plugin instance in the statistics
+func (p *Parser) SetStatTags(tags map[string]string) {
+	p.statTags = tags
+}
+
 func (p *Parser) Parse(input []byte) ([]telegraf.Metric, error) {
 	// What we've done here is to put the entire former contents of Parse()
 	// into parseCriticalPath().
@@ -149,6 +172,11 @@ func (p *Parser) parseCriticalPath(input []byte) ([]telegraf.Metric, error) {
 	if !gjson.Valid(string(input)) {
 		return nil, fmt.Errorf("invalid JSON provided, unable to parse: %s", string(input))
 	}
+	if p.validator != nil {
+		if err := p.validator.Validate(input); err != nil {
+			return nil, err
+		}
+	}
 
 	var metrics []telegraf.Metric
 	// timestamp defaults to current time
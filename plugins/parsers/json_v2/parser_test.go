@@ -12,10 +12,12 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/inputs/file"
 	"github.com/influxdata/telegraf/plugins/parsers/influx"
 	"github.com/influxdata/telegraf/plugins/parsers/json_v2"
+	"github.com/influxdata/telegraf/selfstat"
 	"github.com/influxdata/telegraf/testutil"
 )
 
@@ -105,6 +107,50 @@ func TestParserEmptyConfig(t *testing.T) {
 	require.ErrorContains(t, plugin.Init(), "no configuration provided")
 }
 
+func TestParserSchema(t *testing.T) {
+	schema := filepath.Join(t.TempDir(), "schema.yaml")
+	content := `
+components:
+  schemas:
+    Reading:
+      type: object
+      required: [name, value]
+      properties:
+        name: {type: string}
+        value: {type: number}
+`
+	require.NoError(t, os.WriteFile(schema, []byte(content), 0600))
+
+	plugin := &json_v2.Parser{
+		Configs: []json_v2.Config{
+			{
+				MeasurementName: "reading",
+				Fields:          []json_v2.DataSet{{Path: "value"}},
+				Tags:            []json_v2.DataSet{{Path: "name"}},
+			},
+		},
+		Schema: schema + "#/components/schemas/Reading",
+	}
+	plugin.SetStatTags(map[string]string{"type": "json_v2", "id": "json_v2_test"})
+	require.NoError(t, plugin.Init())
+	invalid := selfstat.Register("parser", "invalid_documents", map[string]string{
+		"type":   "json_v2",
+		"schema": schema + "#/components/schemas/Reading",
+		"id":     "json_v2_test",
+	})
+
+	actual, err := plugin.Parse([]byte(`{"name": "cpu", "value": 42.5}`))
+	require.NoError(t, err)
+	expected := []telegraf.Metric{
+		metric.New("reading", map[string]string{"name": "cpu"}, map[string]interface{}{"value": 42.5}, time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual, testutil.IgnoreTime())
+
+	_, err = plugin.Parse([]byte(`{"name": "cpu", "value": "high"}`))
+	require.ErrorContains(t, err, `at "/value": expected number, but got string`)
+	require.Equal(t, int64(1), invalid.Get())
+}
+
 func BenchmarkParsingSequential(b *testing.B) {
 	inputFilename := filepath.Join("testdata", "benchmark", "input.json")
 
@@ -4,14 +4,13 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"slices"
 	"time"
 
 	"github.com/apache/arrow-go/v18/parquet/file"
 
 	"github.com/influxdata/telegraf"
-	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/common/columnar"
 	"github.com/influxdata/telegraf/plugins/parsers"
 )
 
@@ -23,7 +22,7 @@ type Parser struct {
 	TimestampTimezone string   `toml:"timestamp_timezone"`
 
 	defaultTags map[string]string
-	location    *time.Location
+	mapping     *columnar.ColumnMapping
 	metricName  string
 }
 
@@ -31,20 +30,24 @@ func (p *Parser) Init() error {
 	if p.TimestampFormat == "" {
 		p.TimestampFormat = "unix"
 	}
-	if p.TimestampTimezone == "" {
-		p.location = time.UTC
-	} else {
-		loc, err := time.LoadLocation(p.TimestampTimezone)
-		if err != nil {
-			return fmt.Errorf("invalid location %s: %w", p.TimestampTimezone, err)
-		}
-		p.location = loc
+
+	mapping, err := columnar.NewColumnMapping(p.MeasurementColumn, p.TagColumns, p.TimestampColumn, p.TimestampFormat, p.TimestampTimezone)
+	if err != nil {
+		return err
 	}
+	p.mapping = mapping
 
 	return nil
 }
 
 func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	// Initialize the parser if used without calling Init
+	if p.mapping == nil {
+		if err := p.Init(); err != nil {
+			return nil, err
+		}
+	}
+
 	reader := bytes.NewReader(buf)
 	parquetReader, err := file.NewParquetReader(reader)
 	if err != nil {
@@ -84,30 +87,8 @@ func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
 					rowGroupMetrics[rowIndex] = metric.New(p.metricName, p.defaultTags, nil, now)
 				}
 
-				if p.MeasurementColumn != "" && s.name == p.MeasurementColumn {
-					valStr, err := internal.ToString(val)
-					if err != nil {
-						return nil, fmt.Errorf("could not convert value to string: %w", err)
-					}
-					rowGroupMetrics[rowIndex].SetName(valStr)
-				} else if p.TagColumns != nil && slices.Contains(p.TagColumns, s.name) {
-					valStr, err := internal.ToString(val)
-					if err != nil {
-						return nil, fmt.Errorf("could not convert value to string: %w", err)
-					}
-					rowGroupMetrics[rowIndex].AddTag(s.name, valStr)
-				} else if p.TimestampColumn != "" && s.name == p.TimestampColumn {
-					valStr, err := internal.ToString(val)
-					if err != nil {
-						return nil, fmt.Errorf("could not convert value to string: %w", err)
-					}
-					timestamp, err := internal.ParseTimestamp(p.TimestampFormat, valStr, p.location)
-					if err != nil {
-						return nil, fmt.Errorf("could not parse '%s' to '%s'", valStr, p.TimestampFormat)
-					}
-					rowGroupMetrics[rowIndex].SetTime(timestamp)
-				} else {
-					rowGroupMetrics[rowIndex].AddField(s.name, val)
+				if err := p.mapping.Set(rowGroupMetrics[rowIndex], s.name, val); err != nil {
+					return nil, err
 				}
 
 				rowIndex++
@@ -0,0 +1,357 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package schema
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+// Kinds of schema violations
+const (
+	unknownMeasurement = "unknown_measurement"
+	missingTag         = "missing_tag"
+	unknownTag         = "unknown_tag"
+	missingField       = "missing_field"
+	unknownField       = "unknown_field"
+	invalidType        = "invalid_type"
+	outOfRange         = "out_of_range"
+)
+
+var violationKinds = []string{
+	unknownMeasurement, missingTag, unknownTag, missingField, unknownField, invalidType, outOfRange,
+}
+
+type Schema struct {
+	File                string          `toml:"file"`
+	CoerceTypes         bool            `toml:"coerce_types"`
+	UnknownMeasurements string          `toml:"unknown_measurements"`
+	Action              string          `toml:"action"`
+	ViolationTag        string          `toml:"violation_tag"`
+	Log                 telegraf.Logger `toml:"-"`
+
+	metrics    []*metricSchema
+	statTags   map[string]string
+	violations map[string]selfstat.Stat
+	coerced    selfstat.Stat
+}
+
+type schemaFile struct {
+	Metrics []*metricSchema `json:"metrics" yaml:"metrics"`
+}
+
+type metricSchema struct {
+	Measurement        string         `json:"measurement" yaml:"measurement"`
+	RequiredTags       []string       `json:"required_tags" yaml:"required_tags"`
+	OptionalTags       []string       `json:"optional_tags" yaml:"optional_tags"`
+	AllowUnknownTags   bool           `json:"allow_unknown_tags" yaml:"allow_unknown_tags"`
+	Fields             []*fieldSchema `json:"fields" yaml:"fields"`
+	AllowUnknownFields bool           `json:"allow_unknown_fields" yaml:"allow_unknown_fields"`
+
+	filter filter.Filter
+	fields map[string]*fieldSchema
+}
+
+type fieldSchema struct {
+	Name     string   `json:"name" yaml:"name"`
+	Type     string   `json:"type" yaml:"type"`
+	Unit     string   `json:"unit" yaml:"unit"`
+	Required bool     `json:"required" yaml:"required"`
+	Min      *float64 `json:"min" yaml:"min"`
+	Max      *float64 `json:"max" yaml:"max"`
+}
+
+type violation struct {
+	kind    string
+	message string
+}
+
+func (*Schema) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Schema) Init() error {
+	if s.File == "" {
+		return errors.New("'file' required")
+	}
+
+	switch s.UnknownMeasurements {
+	case "":
+		s.UnknownMeasurements = "pass"
+	case "pass", "violate":
+	default:
+		return fmt.Errorf("invalid 'unknown_measurements' %q", s.UnknownMeasurements)
+	}
+
+	switch s.Action {
+	case "":
+		s.Action = "drop"
+	case "drop", "tag", "dead_letter":
+	default:
+		return fmt.Errorf("invalid 'action' %q", s.Action)
+	}
+	if s.ViolationTag == "" {
+		s.ViolationTag = "schema_violation"
+	}
+
+	if err := s.load(); err != nil {
+		return fmt.Errorf("loading schema %q failed: %w", s.File, err)
+	}
+
+	s.violations = make(map[string]selfstat.Stat, len(violationKinds))
+	for _, kind := range violationKinds {
+		s.violations[kind] = selfstat.Register("schema", "violations_"+kind, s.statTags)
+	}
+	s.coerced = selfstat.Register("schema", "fields_coerced", s.statTags)
+
+	return nil
+}
+
+// SetStatTags sets the tags identifying the plugin instance in the statistics
+func (s *Schema) SetStatTags(tags map[string]string) {
+	s.statTags = tags
+}
+
+func (s *Schema) load() error {
+	buf, err := os.ReadFile(s.File)
+	if err != nil {
+		return err
+	}
+
+	var file schemaFile
+	switch strings.ToLower(filepath.Ext(s.File)) {
+	case ".json":
+		err = json.Unmarshal(buf, &file)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(buf, &file)
+	default:
+		return errors.New("unknown format, expecting a '.json', '.yaml' or '.yml' file")
+	}
+	if err != nil {
+		return err
+	}
+	if len(file.Metrics) == 0 {
+		return errors.New("no metrics defined")
+	}
+
+	for i, m := range file.Metrics {
+		if m.Measurement == "" {
+			return fmt.Errorf("metric %d: 'measurement' required", i+1)
+		}
+		if m.filter, err = filter.Compile([]string{m.Measurement}); err != nil {
+			return fmt.Errorf("metric %q: %w", m.Measurement, err)
+		}
+
+		m.fields = make(map[string]*fieldSchema, len(m.Fields))
+		for _, f := range m.Fields {
+			if f.Name == "" {
+				return fmt.Errorf("metric %q: field 'name' required", m.Measurement)
+			}
+			switch f.Type {
+			case "float", "integer", "unsigned", "string", "boolean":
+			case "":
+				return fmt.Errorf("metric %q: 'type' of field %q required", m.Measurement, f.Name)
+			default:
+				return fmt.Errorf("metric %q: invalid 'type' %q of field %q", m.Measurement, f.Type, f.Name)
+			}
+			if f.Min != nil && f.Max != nil && *f.Min > *f.Max {
+				return fmt.Errorf("metric %q: 'min' of field %q exceeds 'max'", m.Measurement, f.Name)
+			}
+			if _, found := m.fields[f.Name]; found {
+				return fmt.Errorf("metric %q: field %q defined multiple times", m.Measurement, f.Name)
+			}
+			m.fields[f.Name] = f
+		}
+	}
+	s.metrics = file.Metrics
+
+	return nil
+}
+
+func (s *Schema) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := make([]telegraf.Metric, 0, len(in))
+	for _, m := range in {
+		violations := s.validate(m)
+		if len(violations) == 0 {
+			out = append(out, m)
+			continue
+		}
+
+		kinds := make([]string, 0, len(violations))
+		messages := make([]string, 0, len(violations))
+		for _, v := range violations {
+			s.violations[v.kind].Incr(1)
+			messages = append(messages, v.message)
+		}
+		for _, kind := range violationKinds {
+			if slices.ContainsFunc(violations, func(v violation) bool { return v.kind == kind }) {
+				kinds = append(kinds, kind)
+			}
+		}
+
+		switch s.Action {
+		case "drop":
+			s.Log.Debugf("Dropping metric %q: %s", m.Name(), strings.Join(messages, "; "))
+			m.Drop()
+			continue
+		case "tag":
+			m.AddTag(s.ViolationTag, strings.Join(kinds, ","))
+		case "dead_letter":
+			s.Log.Debugf("Dead-lettering metric %q: %s", m.Name(), strings.Join(messages, "; "))
+			m.AddTag(models.DeadLetterMarkerTag, "schema violation: "+strings.Join(kinds, ","))
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+// validate checks the metric against the first matching schema and coerces
+// the field types if enabled
+func (s *Schema) validate(m telegraf.Metric) []violation {
+	var schema *metricSchema
+	for _, candidate := range s.metrics {
+		if candidate.filter.Match(m.Name()) {
+			schema = candidate
+			break
+		}
+	}
+	if schema == nil {
+		if s.UnknownMeasurements == "violate" {
+			return []violation{{unknownMeasurement, fmt.Sprintf("measurement %q not declared", m.Name())}}
+		}
+		return nil
+	}
+
+	var violations []violation
+	for _, key := range schema.RequiredTags {
+		if !m.HasTag(key) {
+			violations = append(violations, violation{missingTag, fmt.Sprintf("tag %q missing", key)})
+		}
+	}
+	if !schema.AllowUnknownTags {
+		for _, tag := range m.TagList() {
+			if !slices.Contains(schema.RequiredTags, tag.Key) && !slices.Contains(schema.OptionalTags, tag.Key) {
+				violations = append(violations, violation{unknownTag, fmt.Sprintf("tag %q not declared", tag.Key)})
+			}
+		}
+	}
+
+	for _, f := range schema.Fields {
+		if f.Required && !m.HasField(f.Name) {
+			violations = append(violations, violation{missingField, fmt.Sprintf("field %q missing", f.Name)})
+		}
+	}
+	// Check the fields in a stable order
+	fields := slices.SortedFunc(slices.Values(m.FieldList()), func(a, b *telegraf.Field) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	for _, field := range fields {
+		f, found := schema.fields[field.Key]
+		if !found {
+			if !schema.AllowUnknownFields {
+				violations = append(violations, violation{unknownField, fmt.Sprintf("field %q not declared", field.Key)})
+			}
+			continue
+		}
+
+		value := field.Value
+		if !hasType(value, f.Type) {
+			if !s.CoerceTypes {
+				violations = append(violations, violation{invalidType, fmt.Sprintf("field %q is %T instead of %s", f.Name, value, f.Type)})
+				continue
+			}
+			v, err := convert(value, f.Type)
+			if err != nil {
+				violations = append(violations, violation{invalidType, fmt.Sprintf("converting field %q to %s failed: %v", f.Name, f.Type, err)})
+				continue
+			}
+			m.AddField(field.Key, v)
+			value = v
+			s.coerced.Incr(1)
+		}
+
+		if f.Min == nil && f.Max == nil {
+			continue
+		}
+		// Non-numeric types cannot be out of range
+		if f.Type == "string" || f.Type == "boolean" {
+			continue
+		}
+		v, err := internal.ToFloat64(value)
+		if err != nil {
+			continue
+		}
+		if f.Min != nil && v < *f.Min || f.Max != nil && v > *f.Max {
+			msg := fmt.Sprintf("field %q value %v", f.Name, value)
+			if f.Unit != "" {
+				msg += " " + f.Unit
+			}
+			violations = append(violations, violation{outOfRange, msg + " out of range"})
+		}
+	}
+
+	return violations
+}
+
+func hasType(value interface{}, typ string) bool {
+	switch value.(type) {
+	case float64:
+		return typ == "float"
+	case int64:
+		return typ == "integer"
+	case uint64:
+		return typ == "unsigned"
+	case string:
+		return typ == "string"
+	case bool:
+		return typ == "boolean"
+	}
+	return false
+}
+
+func convert(value interface{}, typ string) (interface{}, error) {
+	// Do not silently truncate fractional values
+	if typ == "integer" || typ == "unsigned" {
+		if v, ok := value.(float64); ok && v != math.Trunc(v) {
+			return nil, fmt.Errorf("fractional value %v", v)
+		}
+	}
+
+	switch typ {
+	case "float":
+		return internal.ToFloat64(value)
+	case "integer":
+		return internal.ToInt64(value)
+	case "unsigned":
+		return internal.ToUint64(value)
+	case "string":
+		return internal.ToString(value)
+	case "boolean":
+		return internal.ToBool(value)
+	}
+	return nil, fmt.Errorf("invalid type %q", typ)
+}
+
+func init() {
+	processors.Add("schema", func() telegraf.Processor {
+		return &Schema{CoerceTypes: true}
+	})
+}
@@ -0,0 +1,342 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/selfstat"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestSampleConfig(t *testing.T) {
+	plugin := &Schema{}
+	require.NotEmpty(t, plugin.SampleConfig())
+}
+
+func TestInitFail(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+		return path
+	}
+
+	tests := []struct {
+		name     string
+		plugin   *Schema
+		expected string
+	}{
+		{
+			name:     "no file",
+			plugin:   &Schema{},
+			expected: "'file' required",
+		},
+		{
+			name:     "invalid action",
+			plugin:   &Schema{File: "testdata/schema.yaml", Action: "foo"},
+			expected: `invalid 'action' "foo"`,
+		},
+		{
+			name:     "invalid unknown measurements",
+			plugin:   &Schema{File: "testdata/schema.yaml", UnknownMeasurements: "foo"},
+			expected: `invalid 'unknown_measurements' "foo"`,
+		},
+		{
+			name:     "unknown format",
+			plugin:   &Schema{File: write("schema.txt", "")},
+			expected: "unknown format",
+		},
+		{
+			name:     "invalid type",
+			plugin:   &Schema{File: write("type.json", `{"metrics": [{"measurement": "cpu", "fields": [{"name": "a", "type": "int"}]}]}`)},
+			expected: `metric "cpu": invalid 'type' "int" of field "a"`,
+		},
+		{
+			name:     "invalid range",
+			plugin:   &Schema{File: write("range.json", `{"metrics": [{"measurement": "cpu", "fields": [{"name": "a", "type": "float", "min": 1, "max": 0}]}]}`)},
+			expected: `metric "cpu": 'min' of field "a" exceeds 'max'`,
+		},
+		{
+			name:     "empty",
+			plugin:   &Schema{File: write("empty.yaml", "metrics: []")},
+			expected: "no metrics defined",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.plugin.Log = testutil.Logger{}
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestCoercion(t *testing.T) {
+	plugin := &Schema{File: "testdata/schema.yaml", CoerceTypes: true, Log: testutil.Logger{}}
+	require.NoError(t, plugin.Init())
+	before := plugin.coerced.Get()
+
+	input := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"host": "a", "cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": int64(42), "cores": "8"},
+			time.Unix(0, 0),
+		),
+		metric.New(
+			"disk",
+			map[string]string{"path": "/", "fstype": "ext4"},
+			map[string]interface{}{"used": int64(1024), "free": 512.0},
+			time.Unix(0, 0),
+		),
+		metric.New(
+			"mem",
+			map[string]string{},
+			map[string]interface{}{"used": "a lot"},
+			time.Unix(0, 0),
+		),
+	}
+	expected := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"host": "a", "cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": 42.0, "cores": int64(8)},
+			time.Unix(0, 0),
+		),
+		metric.New(
+			"disk",
+			map[string]string{"path": "/", "fstype": "ext4"},
+			map[string]interface{}{"used": uint64(1024), "free": 512.0},
+			time.Unix(0, 0),
+		),
+		metric.New(
+			"mem",
+			map[string]string{},
+			map[string]interface{}{"used": "a lot"},
+			time.Unix(0, 0),
+		),
+	}
+
+	actual := plugin.Apply(input...)
+	testutil.RequireMetricsEqual(t, expected, actual)
+	require.Equal(t, int64(3), plugin.coerced.Get()-before)
+}
+
+func TestViolations(t *testing.T) {
+	input := []telegraf.Metric{
+		// Missing tag, unknown tag and out of range
+		metric.New(
+			"cpu",
+			map[string]string{"host": "a", "zone": "b"},
+			map[string]interface{}{"usage_idle": 142.0},
+			time.Unix(0, 0),
+		),
+		// Missing field, fractional integer and unknown field
+		metric.New(
+			"cpu",
+			map[string]string{"host": "a", "cpu": "cpu0"},
+			map[string]interface{}{"cores": 1.5, "usage_user": 1.0},
+			time.Unix(0, 0),
+		),
+		// Valid metric
+		metric.New(
+			"cpu",
+			map[string]string{"host": "a", "cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": 12.0},
+			time.Unix(0, 0),
+		),
+		// Unknown measurement
+		metric.New(
+			"mem",
+			map[string]string{},
+			map[string]interface{}{"used": int64(1)},
+			time.Unix(0, 0),
+		),
+	}
+
+	tests := []struct {
+		name     string
+		action   string
+		expected []telegraf.Metric
+	}{
+		{
+			name:     "drop",
+			action:   "drop",
+			expected: []telegraf.Metric{input[2]},
+		},
+		{
+			name:   "tag",
+			action: "tag",
+			expected: []telegraf.Metric{
+				metric.New(
+					"cpu",
+					map[string]string{"host": "a", "zone": "b", "schema_violation": "missing_tag,unknown_tag,out_of_range"},
+					map[string]interface{}{"usage_idle": 142.0},
+					time.Unix(0, 0),
+				),
+				metric.New(
+					"cpu",
+					map[string]string{"host": "a", "cpu": "cpu0", "schema_violation": "missing_field,unknown_field,invalid_type"},
+					map[string]interface{}{"cores": 1.5, "usage_user": 1.0},
+					time.Unix(0, 0),
+				),
+				input[2],
+				metric.New(
+					"mem",
+					map[string]string{"schema_violation": "unknown_measurement"},
+					map[string]interface{}{"used": int64(1)},
+					time.Unix(0, 0),
+				),
+			},
+		},
+		{
+			name:   "dead_letter",
+			action: "dead_letter",
+			expected: []telegraf.Metric{
+				metric.New(
+					"cpu",
+					map[string]string{
+						"host":                     "a",
+						"zone":                     "b",
+						models.DeadLetterMarkerTag: "schema violation: missing_tag,unknown_tag,out_of_range",
+					},
+					map[string]interface{}{"usage_idle": 142.0},
+					time.Unix(0, 0),
+				),
+				metric.New(
+					"cpu",
+					map[string]string{
+						"host":                     "a",
+						"cpu":                      "cpu0",
+						models.DeadLetterMarkerTag: "schema violation: missing_field,unknown_field,invalid_type",
+					},
+					map[string]interface{}{"cores": 1.5, "usage_user": 1.0},
+					time.Unix(0, 0),
+				),
+				input[2],
+				metric.New(
+					"mem",
+					map[string]string{models.DeadLetterMarkerTag: "schema violation: unknown_measurement"},
+					map[string]interface{}{"used": int64(1)},
+					time.Unix(0, 0),
+				),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &Schema{
+				File:                "testdata/schema.yaml",
+				CoerceTypes:         true,
+				UnknownMeasurements: "violate",
+				Action:              tt.action,
+				Log:                 testutil.Logger{},
+			}
+			require.NoError(t, plugin.Init())
+			before := make(map[string]int64, len(plugin.violations))
+			for kind, stat := range plugin.violations {
+				before[kind] = stat.Get()
+			}
+
+			metrics := make([]telegraf.Metric, 0, len(input))
+			for _, m := range input {
+				metrics = append(metrics, m.Copy())
+			}
+			actual := plugin.Apply(metrics...)
+			testutil.RequireMetricsEqual(t, tt.expected, actual, testutil.SortMetrics())
+
+			for kind, count := range map[string]int64{
+				unknownMeasurement: 1,
+				missingTag:         1,
+				unknownTag:         1,
+				missingField:       1,
+				unknownField:       1,
+				invalidType:        1,
+				outOfRange:         1,
+			} {
+				require.Equal(t, count, plugin.violations[kind].Get()-before[kind], kind)
+			}
+		})
+	}
+}
+
+func TestStatisticsPerInstance(t *testing.T) {
+	input := metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"usage_idle": 42.0}, time.Unix(0, 0))
+
+	// Instances sharing the schema file must report their violations separately
+	for i, alias := range []string{"first", "second"} {
+		plugin := &Schema{File: "testdata/schema.yaml", Log: testutil.Logger{}}
+		rp := models.NewRunningProcessor(
+			processors.NewStreamingProcessorFromProcessor(plugin),
+			&models.ProcessorConfig{Name: "schema", Alias: alias, ID: alias + "-id"},
+		)
+		require.NoError(t, rp.Init())
+
+		for range i + 1 {
+			plugin.Apply(input.Copy())
+		}
+		stat := selfstat.Register("schema", "violations_missing_tag", map[string]string{
+			"processor": "schema",
+			"alias":     alias,
+			"id":        alias + "-id",
+		})
+		require.Equal(t, int64(i+1), stat.Get())
+	}
+}
+
+func TestNoCoercion(t *testing.T) {
+	plugin := &Schema{File: "testdata/schema.yaml", Action: "tag", Log: testutil.Logger{}}
+	require.NoError(t, plugin.Init())
+
+	input := metric.New(
+		"cpu",
+		map[string]string{"host": "a", "cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": int64(42)},
+		time.Unix(0, 0),
+	)
+	expected := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"host": "a", "cpu": "cpu0", "schema_violation": "invalid_type"},
+			map[string]interface{}{"usage_idle": int64(42)},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, plugin.Apply(input))
+}
+
+func TestTracking(t *testing.T) {
+	var delivered int
+	notify := func(telegraf.DeliveryInfo) { delivered++ }
+
+	plugin := &Schema{File: "testdata/schema.yaml", CoerceTypes: true, Action: "drop", Log: testutil.Logger{}}
+	require.NoError(t, plugin.Init())
+
+	valid, _ := metric.WithTracking(metric.New(
+		"cpu",
+		map[string]string{"host": "a", "cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": 42.0},
+		time.Unix(0, 0),
+	), notify)
+	invalid, _ := metric.WithTracking(metric.New(
+		"cpu",
+		map[string]string{"host": "a"},
+		map[string]interface{}{"usage_idle": 42.0},
+		time.Unix(0, 0),
+	), notify)
+
+	actual := plugin.Apply(valid, invalid)
+	require.Len(t, actual, 1)
+	require.Equal(t, 1, delivered)
+	actual[0].Accept()
+	require.Equal(t, 2, delivered)
+}
@@ -53,6 +53,12 @@ func (sp *streamingProcessor) Init() error {
 	return nil
 }
 
+// SetStatTags passes the tags identifying the plugin instance to the wrapped
+// processor if it registers own statistics
+func (sp *streamingProcessor) SetStatTags(tags map[string]string) {
+	models.SetStatTagsOnPlugin(sp.processor, tags)
+}
+
 // Unwrap lets you retrieve the original telegraf.Processor from the
 // StreamingProcessor. This is necessary because the toml Unmarshaller won't
 // look inside composed types.
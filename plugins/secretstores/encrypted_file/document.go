@@ -0,0 +1,488 @@
+package encrypted_file
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// document is a YAML, JSON or dotenv document holding the secrets as
+// top-level keys
+type document interface {
+	// entries returns the top-level values of the document excluding the
+	// SOPS metadata
+	entries() map[string]string
+	// sops returns the SOPS metadata of the document or nil if the document
+	// is not encrypted by SOPS
+	sops() (*sopsMetadata, error)
+	// sopsLeaves returns the values and comments of the document excluding
+	// the SOPS metadata in the order used by SOPS for computing the MAC
+	sopsLeaves() ([]sopsLeaf, error)
+	// set sets the value of the given top-level key
+	set(key, value string)
+	// marshal serializes the document
+	marshal() ([]byte, error)
+}
+
+func parseDocument(format string, buf []byte) (document, error) {
+	switch format {
+	case "yaml":
+		return parseYAML(buf)
+	case "json":
+		return parseJSON(buf)
+	case "dotenv":
+		return parseDotenv(buf)
+	}
+	return nil, fmt.Errorf("invalid format %q", format)
+}
+
+type yamlDocument struct {
+	root yaml.Node
+}
+
+func parseYAML(buf []byte) (*yamlDocument, error) {
+	d := &yamlDocument{}
+	if err := yaml.Unmarshal(buf, &d.root); err != nil {
+		return nil, err
+	}
+
+	// Create the top-level mapping for empty documents
+	if d.root.Kind == 0 {
+		d.root = yaml.Node{
+			Kind:    yaml.DocumentNode,
+			Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}},
+		}
+	}
+	if len(d.root.Content) != 1 || d.root.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("document is not a mapping")
+	}
+	return d, nil
+}
+
+func (d *yamlDocument) mapping() *yaml.Node {
+	return d.root.Content[0]
+}
+
+func (d *yamlDocument) entries() map[string]string {
+	content := d.mapping().Content
+	entries := make(map[string]string, len(content)/2)
+	for i := 0; i+1 < len(content); i += 2 {
+		key, value := content[i], content[i+1]
+		if key.Value == "sops" || value.Kind != yaml.ScalarNode {
+			continue
+		}
+		entries[key.Value] = value.Value
+	}
+	return entries
+}
+
+func (d *yamlDocument) sops() (*sopsMetadata, error) {
+	content := d.mapping().Content
+	for i := 0; i+1 < len(content); i += 2 {
+		if content[i].Value != "sops" {
+			continue
+		}
+		var metadata sopsMetadata
+		if err := content[i+1].Decode(&metadata); err != nil {
+			return nil, fmt.Errorf("decoding SOPS metadata failed: %w", err)
+		}
+		return &metadata, nil
+	}
+	return nil, nil
+}
+
+func (d *yamlDocument) sopsLeaves() ([]sopsLeaf, error) {
+	w := &yamlWalker{}
+	if err := w.walk(&d.root, nil, false, false); err != nil {
+		return nil, err
+	}
+	return w.leaves, nil
+}
+
+// yamlWalker collects the leaves of a YAML document attaching the comments
+// the same way as the SOPS YAML store does
+type yamlWalker struct {
+	leaves []sopsLeaf
+}
+
+func (w *yamlWalker) comments(path []string, comment string) {
+	for _, line := range strings.Split(comment, "\n") {
+		if line != "" {
+			w.leaves = append(w.leaves, sopsLeaf{path: path, value: line[1:], comment: true})
+		}
+	}
+}
+
+func (w *yamlWalker) walk(node *yaml.Node, path []string, commentsHandled, root bool) error {
+	if !commentsHandled {
+		w.comments(path, node.HeadComment)
+		w.comments(path, node.LineComment)
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, n := range node.Content {
+			if err := w.walk(n, path, false, true); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			w.comments(path, key.HeadComment)
+			w.comments(path, key.LineComment)
+			scalar := value.Kind == yaml.ScalarNode || value.Kind == yaml.AliasNode
+			if scalar {
+				w.comments(path, value.HeadComment)
+				w.comments(path, value.LineComment)
+			}
+			if !root || key.Value != "sops" {
+				if err := w.walk(value, append(slices.Clone(path), key.Value), scalar, false); err != nil {
+					return err
+				}
+			}
+			if scalar {
+				w.comments(path, value.FootComment)
+			}
+			w.comments(path, key.FootComment)
+		}
+	case yaml.SequenceNode:
+		for _, n := range node.Content {
+			if err := w.walk(n, path, false, false); err != nil {
+				return err
+			}
+		}
+	case yaml.AliasNode:
+		if err := w.walk(node.Alias, path, true, false); err != nil {
+			return err
+		}
+	case yaml.ScalarNode:
+		var v interface{}
+		if err := node.Decode(&v); err != nil {
+			return fmt.Errorf("decoding %q failed: %w", strings.Join(path, ":"), err)
+		}
+		if value, ok := sopsScalar(v); ok {
+			w.leaves = append(w.leaves, sopsLeaf{path: path, value: value})
+		}
+	}
+
+	if !commentsHandled {
+		w.comments(path, node.FootComment)
+	}
+	return nil
+}
+
+func (d *yamlDocument) set(key, value string) {
+	node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+	if strings.Contains(value, "\n") {
+		node.Style = yaml.LiteralStyle
+	}
+
+	m := d.mapping()
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = node
+			return
+		}
+	}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, node)
+}
+
+func (d *yamlDocument) marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&d.root); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// jsonDocument keeps the raw document to walk the values in document order
+// for SOPS
+type jsonDocument struct {
+	data map[string]interface{}
+	raw  []byte
+}
+
+func parseJSON(buf []byte) (*jsonDocument, error) {
+	d := &jsonDocument{data: make(map[string]interface{}), raw: buf}
+	if len(bytes.TrimSpace(buf)) == 0 {
+		return d, nil
+	}
+	if err := json.Unmarshal(buf, &d.data); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *jsonDocument) entries() map[string]string {
+	entries := make(map[string]string, len(d.data))
+	for key, value := range d.data {
+		switch v := value.(type) {
+		case string:
+			entries[key] = v
+		case float64, bool:
+			entries[key] = fmt.Sprint(v)
+		}
+	}
+	delete(entries, "sops")
+	return entries
+}
+
+func (d *jsonDocument) sops() (*sopsMetadata, error) {
+	raw, found := d.data["sops"]
+	if !found {
+		return nil, nil
+	}
+	buf, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var metadata sopsMetadata
+	if err := json.Unmarshal(buf, &metadata); err != nil {
+		return nil, fmt.Errorf("decoding SOPS metadata failed: %w", err)
+	}
+	return &metadata, nil
+}
+
+func (d *jsonDocument) sopsLeaves() ([]sopsLeaf, error) {
+	decoder := json.NewDecoder(bytes.NewReader(d.raw))
+	decoder.UseNumber()
+
+	var leaves []sopsLeaf
+	var walk func(path []string, root bool) error
+	walk = func(path []string, root bool) error {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case json.Delim:
+			switch t {
+			case '{':
+				for decoder.More() {
+					token, err := decoder.Token()
+					if err != nil {
+						return err
+					}
+					key, ok := token.(string)
+					if !ok {
+						return fmt.Errorf("unexpected key %v", token)
+					}
+					if root && key == "sops" {
+						var skip json.RawMessage
+						if err := decoder.Decode(&skip); err != nil {
+							return err
+						}
+						continue
+					}
+					if err := walk(append(slices.Clone(path), key), false); err != nil {
+						return err
+					}
+				}
+			case '[':
+				for decoder.More() {
+					if err := walk(path, false); err != nil {
+						return err
+					}
+				}
+			}
+			// Consume the closing delimiter
+			_, err := decoder.Token()
+			return err
+		case json.Number:
+			var v interface{} = t.String()
+			if i, err := t.Int64(); err == nil {
+				v = i
+			} else if f, err := t.Float64(); err == nil {
+				v = f
+			}
+			value, _ := sopsScalar(v)
+			leaves = append(leaves, sopsLeaf{path: path, value: value})
+		default:
+			if value, ok := sopsScalar(t); ok {
+				leaves = append(leaves, sopsLeaf{path: path, value: value})
+			}
+		}
+		return nil
+	}
+
+	if len(bytes.TrimSpace(d.raw)) == 0 {
+		return nil, nil
+	}
+	if err := walk(nil, true); err != nil {
+		return nil, fmt.Errorf("walking document failed: %w", err)
+	}
+	return leaves, nil
+}
+
+func (d *jsonDocument) set(key, value string) {
+	d.data[key] = value
+}
+
+func (d *jsonDocument) marshal() ([]byte, error) {
+	buf, err := json.MarshalIndent(d.data, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, '\n'), nil
+}
+
+// dotenvDocument keeps the lines of the document to preserve comments and
+// the order of the keys when writing the document
+type dotenvDocument struct {
+	lines  []string
+	keys   []string
+	values map[string]string
+}
+
+func parseDotenv(buf []byte) (*dotenvDocument, error) {
+	d := &dotenvDocument{values: make(map[string]string)}
+
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		line := scanner.Text()
+		d.lines = append(d.lines, line)
+
+		key, value, err := parseDotenvLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", len(d.lines), err)
+		}
+		d.keys = append(d.keys, key)
+		if key != "" {
+			d.values[key] = value
+		}
+	}
+	return d, scanner.Err()
+}
+
+// parseDotenvLine returns the key and value of the line or an empty key for
+// empty lines and comments
+func parseDotenvLine(line string) (key, value string, err error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", "", nil
+	}
+	line = strings.TrimPrefix(line, "export ")
+
+	key, value, found := strings.Cut(line, "=")
+	if !found {
+		return "", "", fmt.Errorf("missing '=' in %q", line)
+	}
+	key = strings.TrimSpace(key)
+	value = strings.TrimSpace(value)
+
+	switch {
+	case strings.HasPrefix(value, `"`):
+		value, err = strconv.Unquote(value)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid quoted value for %q: %w", key, err)
+		}
+	case strings.HasPrefix(value, "'"):
+		if len(value) < 2 || !strings.HasSuffix(value, "'") {
+			return "", "", fmt.Errorf("invalid quoted value for %q", key)
+		}
+		value = value[1 : len(value)-1]
+	default:
+		if idx := strings.Index(value, " #"); idx >= 0 {
+			value = strings.TrimSpace(value[:idx])
+		}
+	}
+	return key, value, nil
+}
+
+func (d *dotenvDocument) entries() map[string]string {
+	entries := make(map[string]string, len(d.values))
+	for key, value := range d.values {
+		if !strings.HasPrefix(key, "sops_") {
+			entries[key] = value
+		}
+	}
+	return entries
+}
+
+func (d *dotenvDocument) sops() (*sopsMetadata, error) {
+	if _, found := d.values["sops_version"]; !found {
+		return nil, nil
+	}
+
+	// SOPS flattens the metadata into keys like "sops_age__list_0__map_enc"
+	// and escapes the newlines of the values
+	metadata := &sopsMetadata{
+		LastModified:      d.values["sops_lastmodified"],
+		MAC:               d.values["sops_mac"],
+		MACOnlyEncrypted:  d.values["sops_mac_only_encrypted"] == "true",
+		UnencryptedSuffix: d.values["sops_unencrypted_suffix"],
+		EncryptedSuffix:   d.values["sops_encrypted_suffix"],
+		UnencryptedRegex:  d.values["sops_unencrypted_regex"],
+		EncryptedRegex:    d.values["sops_encrypted_regex"],
+		Version:           d.values["sops_version"],
+	}
+	for i := 0; ; i++ {
+		prefix := fmt.Sprintf("sops_age__list_%d__map_", i)
+		enc, found := d.values[prefix+"enc"]
+		if !found {
+			break
+		}
+		metadata.Age = append(metadata.Age, sopsAgeKey{
+			Recipient: d.values[prefix+"recipient"],
+			Enc:       strings.ReplaceAll(enc, `\n`, "\n"),
+		})
+	}
+	return metadata, nil
+}
+
+func (d *dotenvDocument) sopsLeaves() ([]sopsLeaf, error) {
+	var leaves []sopsLeaf
+	for i, line := range d.lines {
+		key := d.keys[i]
+		switch {
+		case key == "":
+			if line = strings.TrimSpace(line); strings.HasPrefix(line, "#") {
+				leaves = append(leaves, sopsLeaf{value: line[1:], comment: true})
+			}
+		case !strings.HasPrefix(key, "sops_"):
+			_, value, err := parseDotenvLine(line)
+			if err != nil {
+				return nil, err
+			}
+			leaves = append(leaves, sopsLeaf{path: []string{key}, value: value})
+		}
+	}
+	return leaves, nil
+}
+
+func (d *dotenvDocument) set(key, value string) {
+	line := key + "=" + value
+	if strings.ContainsAny(value, " \t\n\"'#\\") {
+		line = key + "=" + strconv.Quote(value)
+	}
+	d.values[key] = value
+
+	for i, k := range d.keys {
+		if k == key {
+			d.lines[i] = line
+			return
+		}
+	}
+	d.lines = append(d.lines, line)
+	d.keys = append(d.keys, key)
+}
+
+func (d *dotenvDocument) marshal() ([]byte, error) {
+	if len(d.lines) == 0 {
+		return nil, nil
+	}
+	return []byte(strings.Join(d.lines, "\n") + "\n"), nil
+}
@@ -0,0 +1,399 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package encrypted_file
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type EncryptedFile struct {
+	Path          string          `toml:"path"`
+	Format        string          `toml:"format"`
+	KeyFile       string          `toml:"key_file"`
+	Passphrase    config.Secret   `toml:"passphrase"`
+	Recipients    []string        `toml:"recipients"`
+	WatchInterval config.Duration `toml:"watch_interval"`
+	Log           telegraf.Logger `toml:"-"`
+
+	identities []age.Identity
+	recipients []age.Recipient
+
+	doc     document
+	values  map[string]string
+	sops    *sopsMetadata
+	dataKey []byte
+	modTime time.Time
+	notify  func(key string)
+	sync.Mutex
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func (*EncryptedFile) SampleConfig() string {
+	return sampleConfig
+}
+
+// Init initializes all internals of the secret-store
+func (e *EncryptedFile) Init() error {
+	if e.Path == "" {
+		return errors.New("'path' required")
+	}
+
+	// Determine the format from the file extension if not given
+	if e.Format == "" {
+		switch strings.ToLower(filepath.Ext(e.Path)) {
+		case ".yaml", ".yml":
+			e.Format = "yaml"
+		case ".json":
+			e.Format = "json"
+		case ".env":
+			e.Format = "dotenv"
+		default:
+			return fmt.Errorf("cannot determine format of %q, please set 'format'", e.Path)
+		}
+	}
+	switch e.Format {
+	case "yaml", "json", "dotenv":
+	default:
+		return fmt.Errorf("invalid 'format' %q", e.Format)
+	}
+
+	// Setup the identities for decryption and the recipients for encryption
+	if err := e.setupKeys(); err != nil {
+		return err
+	}
+
+	if err := e.load(); err != nil {
+		return err
+	}
+
+	if e.WatchInterval > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		e.cancel = cancel
+		e.wg.Add(1)
+		go func() {
+			defer e.wg.Done()
+			e.watch(ctx)
+		}()
+	}
+
+	return nil
+}
+
+// Close stops watching the file for changes
+func (e *EncryptedFile) Close() error {
+	if e.cancel != nil {
+		e.cancel()
+		e.wg.Wait()
+	}
+	return nil
+}
+
+func (e *EncryptedFile) setupKeys() error {
+	switch {
+	case e.KeyFile != "" && !e.Passphrase.Empty():
+		return errors.New("'key_file' and 'passphrase' are mutually exclusive")
+	case e.KeyFile != "":
+		f, err := os.Open(e.KeyFile)
+		if err != nil {
+			return fmt.Errorf("opening key file failed: %w", err)
+		}
+		defer f.Close()
+		identities, err := age.ParseIdentities(f)
+		if err != nil {
+			return fmt.Errorf("parsing key file failed: %w", err)
+		}
+		e.identities = identities
+		for _, id := range identities {
+			if x, ok := id.(*age.X25519Identity); ok {
+				e.recipients = append(e.recipients, x.Recipient())
+			}
+		}
+	case !e.Passphrase.Empty():
+		passphrase, err := e.Passphrase.Get()
+		if err != nil {
+			return fmt.Errorf("getting passphrase failed: %w", err)
+		}
+		defer passphrase.Destroy()
+		identity, err := age.NewScryptIdentity(passphrase.String())
+		if err != nil {
+			return err
+		}
+		recipient, err := age.NewScryptRecipient(passphrase.String())
+		if err != nil {
+			return err
+		}
+		e.identities = []age.Identity{identity}
+		e.recipients = []age.Recipient{recipient}
+	default:
+		return errors.New("either 'key_file' or 'passphrase' required")
+	}
+
+	// Explicitly configured recipients replace the ones of the identities
+	if len(e.Recipients) > 0 {
+		recipients, err := age.ParseRecipients(strings.NewReader(strings.Join(e.Recipients, "\n")))
+		if err != nil {
+			return fmt.Errorf("parsing recipients failed: %w", err)
+		}
+		e.recipients = recipients
+	}
+
+	return nil
+}
+
+// Get searches for the given key and return the secret
+func (e *EncryptedFile) Get(key string) ([]byte, error) {
+	e.Lock()
+	defer e.Unlock()
+
+	value, found := e.values[key]
+	if !found {
+		return nil, errors.New("not found")
+	}
+
+	if e.sops != nil {
+		if !e.sops.encrypted([]string{key}) {
+			return []byte(value), nil
+		}
+		return decryptSOPSValue(e.dataKey, key, value)
+	}
+	if !strings.HasPrefix(value, armor.Header) {
+		return []byte(value), nil
+	}
+
+	r, err := age.Decrypt(armor.NewReader(strings.NewReader(value)), e.identities...)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting %q failed: %w", key, err)
+	}
+	return io.ReadAll(r)
+}
+
+// Set sets the given secret for the given key
+func (e *EncryptedFile) Set(key, value string) error {
+	e.Lock()
+	defer e.Unlock()
+
+	if e.sops != nil {
+		return errors.New("setting secrets in SOPS documents not supported, use the 'sops' tool instead")
+	}
+	if len(e.recipients) == 0 {
+		return errors.New("no recipients for encrypting the secret")
+	}
+
+	var buf bytes.Buffer
+	aw := armor.NewWriter(&buf)
+	w, err := age.Encrypt(aw, e.recipients...)
+	if err != nil {
+		return fmt.Errorf("encrypting secret failed: %w", err)
+	}
+	if _, err := io.WriteString(w, value); err != nil {
+		return fmt.Errorf("encrypting secret failed: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("encrypting secret failed: %w", err)
+	}
+	if err := aw.Close(); err != nil {
+		return fmt.Errorf("encrypting secret failed: %w", err)
+	}
+	encrypted := buf.String()
+
+	e.doc.set(key, encrypted)
+	content, err := e.doc.marshal()
+	if err != nil {
+		return fmt.Errorf("serializing document failed: %w", err)
+	}
+	if err := writeFile(e.Path, content); err != nil {
+		return err
+	}
+	e.values[key] = encrypted
+
+	if info, err := os.Stat(e.Path); err == nil {
+		e.modTime = info.ModTime()
+	}
+	return nil
+}
+
+// List lists all known secret keys
+func (e *EncryptedFile) List() ([]string, error) {
+	e.Lock()
+	defer e.Unlock()
+
+	keys := make([]string, 0, len(e.values))
+	for k := range e.values {
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// GetResolver returns a function to resolve the given key.
+func (e *EncryptedFile) GetResolver(key string) (telegraf.ResolveFunc, error) {
+	// Secrets may change if the file is watched
+	dynamic := e.WatchInterval > 0
+	resolver := func() ([]byte, bool, error) {
+		s, err := e.Get(key)
+		return s, dynamic, err
+	}
+	return resolver, nil
+}
+
+// SetNotifier sets the function to call for secrets changed in the watched
+// file
+func (e *EncryptedFile) SetNotifier(notify func(key string)) {
+	e.Lock()
+	e.notify = notify
+	e.Unlock()
+}
+
+// load reads and parses the document. A non-existing file is treated as an
+// empty document to allow adding secrets.
+func (e *EncryptedFile) load() error {
+	content, err := os.ReadFile(e.Path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("reading file failed: %w", err)
+	}
+	var modTime time.Time
+	if err == nil {
+		if info, err := os.Stat(e.Path); err == nil {
+			modTime = info.ModTime()
+		}
+	}
+
+	doc, err := parseDocument(e.Format, content)
+	if err != nil {
+		return fmt.Errorf("parsing %q failed: %w", e.Path, err)
+	}
+
+	var dataKey []byte
+	metadata, err := doc.sops()
+	if err != nil {
+		return err
+	}
+	if metadata != nil {
+		if err := metadata.init(); err != nil {
+			return err
+		}
+		dataKey, err = metadata.dataKey(e.identities)
+		if err != nil {
+			return err
+		}
+		leaves, err := doc.sopsLeaves()
+		if err != nil {
+			return fmt.Errorf("parsing %q failed: %w", e.Path, err)
+		}
+		if err := metadata.verify(dataKey, leaves); err != nil {
+			return fmt.Errorf("verifying SOPS document failed: %w", err)
+		}
+	}
+
+	e.Lock()
+	defer e.Unlock()
+	e.doc = doc
+	e.values = doc.entries()
+	e.sops = metadata
+	e.dataKey = dataKey
+	e.modTime = modTime
+
+	return nil
+}
+
+// watch reloads the document whenever the file changes
+func (e *EncryptedFile) watch(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(e.WatchInterval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(e.Path)
+		if err != nil {
+			e.Log.Errorf("Checking file failed: %v", err)
+			continue
+		}
+		e.Lock()
+		changed := !info.ModTime().Equal(e.modTime)
+		previous := e.values
+		e.Unlock()
+		if !changed {
+			continue
+		}
+
+		if err := e.load(); err != nil {
+			e.Log.Errorf("Reloading file failed: %v", err)
+			continue
+		}
+		e.Log.Debugf("Reloaded secrets from %q", e.Path)
+
+		e.Lock()
+		current, notify := e.values, e.notify
+		e.Unlock()
+		if notify == nil {
+			continue
+		}
+		for key, value := range current {
+			if v, found := previous[key]; !found || v != value {
+				notify(key)
+			}
+		}
+		for key := range previous {
+			if _, found := current[key]; !found {
+				notify(key)
+			}
+		}
+	}
+}
+
+// writeFile replaces the file with the given content by renaming a temporary
+// file to avoid leaving a partially written document behind
+func writeFile(path string, content []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("creating temporary file failed: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if info, err := os.Stat(path); err == nil {
+		if err := f.Chmod(info.Mode().Perm()); err != nil {
+			f.Close()
+			return fmt.Errorf("setting permissions failed: %w", err)
+		}
+	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return fmt.Errorf("writing file failed: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writing file failed: %w", err)
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// Register the secret-store on load.
+func init() {
+	secretstores.Add("encrypted_file", func(string) telegraf.SecretStore {
+		return &EncryptedFile{}
+	})
+}
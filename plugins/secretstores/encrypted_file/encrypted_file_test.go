@@ -0,0 +1,403 @@
+package encrypted_file
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestSampleConfig(t *testing.T) {
+	plugin := &EncryptedFile{}
+	require.NotEmpty(t, plugin.SampleConfig())
+}
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *EncryptedFile
+		expected string
+	}{
+		{
+			name:     "no path",
+			plugin:   &EncryptedFile{},
+			expected: "'path' required",
+		},
+		{
+			name:     "unknown extension",
+			plugin:   &EncryptedFile{Path: "secrets.txt"},
+			expected: `cannot determine format of "secrets.txt"`,
+		},
+		{
+			name:     "invalid format",
+			plugin:   &EncryptedFile{Path: "secrets.txt", Format: "xml"},
+			expected: `invalid 'format' "xml"`,
+		},
+		{
+			name:     "no key",
+			plugin:   &EncryptedFile{Path: "secrets.yaml"},
+			expected: "either 'key_file' or 'passphrase' required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.plugin.Log = testutil.Logger{}
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestSetGet(t *testing.T) {
+	tests := []struct {
+		filename string
+		content  string
+		expected []string
+	}{
+		{
+			filename: "secrets.yaml",
+			content:  "# credentials of the host\nusername: telegraf\n",
+			expected: []string{"# credentials of the host", "username: telegraf", "password: |"},
+		},
+		{
+			filename: "secrets.json",
+			content:  `{"username": "telegraf"}`,
+			expected: []string{`"username": "telegraf"`, `"password": "-----BEGIN AGE ENCRYPTED FILE-----\n`},
+		},
+		{
+			filename: "secrets.env",
+			content:  "# credentials of the host\nusername=telegraf\n",
+			expected: []string{"# credentials of the host", "username=telegraf", `password="-----BEGIN AGE ENCRYPTED FILE-----\n`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			dir := t.TempDir()
+			keyFile := createKeyFile(t, dir)
+			path := filepath.Join(dir, tt.filename)
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0600))
+
+			plugin := &EncryptedFile{Path: path, KeyFile: keyFile, Log: testutil.Logger{}}
+			require.NoError(t, plugin.Init())
+			require.NoError(t, plugin.Set("password", "pa$$word"))
+
+			// Check the document is updated in place
+			buf, err := os.ReadFile(path)
+			require.NoError(t, err)
+			for _, line := range tt.expected {
+				require.Contains(t, string(buf), line)
+			}
+			require.NotContains(t, string(buf), "pa$$word")
+
+			// Read the document with a new instance
+			plugin = &EncryptedFile{Path: path, KeyFile: keyFile, Log: testutil.Logger{}}
+			require.NoError(t, plugin.Init())
+			keys, err := plugin.List()
+			require.NoError(t, err)
+			require.ElementsMatch(t, []string{"username", "password"}, keys)
+
+			secret, err := plugin.Get("password")
+			require.NoError(t, err)
+			require.Equal(t, "pa$$word", string(secret))
+			secret, err = plugin.Get("username")
+			require.NoError(t, err)
+			require.Equal(t, "telegraf", string(secret))
+		})
+	}
+}
+
+func TestPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.yaml")
+	plugin := &EncryptedFile{
+		Path:       path,
+		Passphrase: config.NewSecret([]byte("my passphrase")),
+		Log:        testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Set("token", "abc"))
+
+	plugin = &EncryptedFile{
+		Path:       path,
+		Passphrase: config.NewSecret([]byte("my passphrase")),
+		Log:        testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	secret, err := plugin.Get("token")
+	require.NoError(t, err)
+	require.Equal(t, "abc", string(secret))
+
+	plugin = &EncryptedFile{
+		Path:       path,
+		Passphrase: config.NewSecret([]byte("wrong passphrase")),
+		Log:        testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	_, err = plugin.Get("token")
+	require.ErrorContains(t, err, `decrypting "token" failed`)
+}
+
+func TestSOPS(t *testing.T) {
+	dir := t.TempDir()
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	keyFile := filepath.Join(dir, "age.key")
+	require.NoError(t, os.WriteFile(keyFile, []byte(identity.String()+"\n"), 0600))
+	dataKey, enc := createSOPSDataKey(t, identity.Recipient())
+
+	lastModified := "2024-01-01T00:00:00Z"
+	comment := encryptSOPS(t, dataKey, "credentials of the host", ":")
+	password := encryptSOPS(t, dataKey, "pa$$word", "password:")
+	mac := sopsMAC(t, dataKey, lastModified, "credentials of the host", "telegraf", "pa$$word")
+	tests := []struct {
+		filename string
+		content  string
+	}{
+		{
+			filename: "secrets.yaml",
+			content: "#" + comment + "\n" +
+				"username_unencrypted: telegraf\n" +
+				"password: " + password + "\n" +
+				"sops:\n" +
+				"  age:\n" +
+				"    - recipient: " + identity.Recipient().String() + "\n" +
+				"      enc: |\n" +
+				"        " + strings.ReplaceAll(strings.TrimSpace(enc), "\n", "\n        ") + "\n" +
+				"  lastmodified: \"" + lastModified + "\"\n" +
+				"  mac: " + mac + "\n" +
+				"  unencrypted_suffix: _unencrypted\n" +
+				"  version: 3.8.1\n",
+		},
+		{
+			filename: "secrets.json",
+			content: `{"username_unencrypted": "telegraf", "password": "` + password + `", "sops": {` +
+				`"age": [{"recipient": "` + identity.Recipient().String() + `", "enc": ` + strconv.Quote(enc) + `}], ` +
+				`"lastmodified": "` + lastModified + `", "mac": "` + sopsMAC(t, dataKey, lastModified, "telegraf", "pa$$word") + `", ` +
+				`"unencrypted_suffix": "_unencrypted", "version": "3.8.1"}}`,
+		},
+		{
+			filename: "secrets.env",
+			content: "#credentials of the host\n" +
+				"username_unencrypted=telegraf\n" +
+				"password=" + password + "\n" +
+				"sops_age__list_0__map_enc=" + strings.ReplaceAll(enc, "\n", `\n`) + "\n" +
+				"sops_age__list_0__map_recipient=" + identity.Recipient().String() + "\n" +
+				"sops_lastmodified=" + lastModified + "\n" +
+				"sops_mac=" + mac + "\n" +
+				"sops_unencrypted_suffix=_unencrypted\n" +
+				"sops_version=3.8.1\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			path := filepath.Join(dir, tt.filename)
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0600))
+
+			plugin := &EncryptedFile{Path: path, KeyFile: keyFile, Log: testutil.Logger{}}
+			require.NoError(t, plugin.Init())
+
+			keys, err := plugin.List()
+			require.NoError(t, err)
+			require.ElementsMatch(t, []string{"username_unencrypted", "password"}, keys)
+
+			secret, err := plugin.Get("password")
+			require.NoError(t, err)
+			require.Equal(t, "pa$$word", string(secret))
+			secret, err = plugin.Get("username_unencrypted")
+			require.NoError(t, err)
+			require.Equal(t, "telegraf", string(secret))
+
+			require.ErrorContains(t, plugin.Set("password", "foo"), "not supported")
+		})
+	}
+}
+
+func TestSOPSInvalid(t *testing.T) {
+	dir := t.TempDir()
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	keyFile := filepath.Join(dir, "age.key")
+	require.NoError(t, os.WriteFile(keyFile, []byte(identity.String()+"\n"), 0600))
+	dataKey, enc := createSOPSDataKey(t, identity.Recipient())
+
+	lastModified := "2024-01-01T00:00:00Z"
+	password := encryptSOPS(t, dataKey, "pa$$word", "password:")
+	metadata := "sops_age__list_0__map_enc=" + strings.ReplaceAll(enc, "\n", `\n`) + "\n" +
+		"sops_age__list_0__map_recipient=" + identity.Recipient().String() + "\n" +
+		"sops_lastmodified=" + lastModified + "\n" +
+		"sops_unencrypted_suffix=_unencrypted\n" +
+		"sops_version=3.8.1\n"
+
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			name:     "missing MAC",
+			content:  "password=" + password + "\n" + metadata,
+			expected: "missing MAC",
+		},
+		{
+			name: "modified value",
+			content: "password=" + password + "\n" +
+				"sops_mac=" + sopsMAC(t, dataKey, lastModified, "other") + "\n" + metadata,
+			expected: "MAC mismatch",
+		},
+		{
+			name: "added value",
+			content: "password=" + password + "\n" + "user_unencrypted=root\n" +
+				"sops_mac=" + sopsMAC(t, dataKey, lastModified, "pa$$word") + "\n" + metadata,
+			expected: "MAC mismatch",
+		},
+		{
+			name: "modified lastmodified",
+			content: "password=" + password + "\n" +
+				"sops_mac=" + sopsMAC(t, dataKey, "2023-01-01T00:00:00Z", "pa$$word") + "\n" + metadata,
+			expected: "decrypting MAC failed",
+		},
+		{
+			name: "plaintext value",
+			content: "password=" + password + "\n" + "username=telegraf\n" +
+				"sops_mac=" + sopsMAC(t, dataKey, lastModified, "pa$$word", "telegraf") + "\n" + metadata,
+			expected: `value of "username": not encrypted`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "secrets.env")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0600))
+
+			plugin := &EncryptedFile{Path: path, KeyFile: keyFile, Log: testutil.Logger{}}
+			require.ErrorContains(t, plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := createKeyFile(t, dir)
+	path := filepath.Join(dir, "secrets.env")
+	require.NoError(t, os.WriteFile(path, []byte("username=telegraf\n"), 0600))
+
+	plugin := &EncryptedFile{
+		Path:          path,
+		KeyFile:       keyFile,
+		WatchInterval: config.Duration(50 * time.Millisecond),
+		Log:           testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	defer plugin.Close()
+
+	changed := make(chan string, 10)
+	plugin.SetNotifier(func(key string) { changed <- key })
+
+	resolver, err := plugin.GetResolver("username")
+	require.NoError(t, err)
+	secret, dynamic, err := resolver()
+	require.NoError(t, err)
+	require.True(t, dynamic)
+	require.Equal(t, "telegraf", string(secret))
+
+	// Set the modification time explicitly as writing the file within the
+	// timestamp resolution of the filesystem does not change it
+	modified := time.Now().Add(time.Minute)
+	require.NoError(t, os.WriteFile(path, []byte("username=admin\n"), 0600))
+	require.NoError(t, os.Chtimes(path, modified, modified))
+	require.Eventually(t, func() bool {
+		secret, _, err := resolver()
+		return err == nil && string(secret) == "admin"
+	}, 3*time.Second, 50*time.Millisecond)
+	require.Equal(t, "username", <-changed)
+
+	// Changes are not picked up after closing the store
+	require.NoError(t, plugin.Close())
+	modified = modified.Add(time.Minute)
+	require.NoError(t, os.WriteFile(path, []byte("username=root\n"), 0600))
+	require.NoError(t, os.Chtimes(path, modified, modified))
+	require.Never(t, func() bool {
+		secret, _, err := resolver()
+		return err != nil || string(secret) != "admin"
+	}, 200*time.Millisecond, 50*time.Millisecond)
+}
+
+func createKeyFile(t *testing.T, dir string) string {
+	t.Helper()
+
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	path := filepath.Join(dir, "age.key")
+	require.NoError(t, os.WriteFile(path, []byte(identity.String()+"\n"), 0600))
+	return path
+}
+
+// createSOPSDataKey creates a random data key and returns it together with
+// the armored age ciphertext for the given recipient
+func createSOPSDataKey(t *testing.T, recipient age.Recipient) (dataKey []byte, enc string) {
+	t.Helper()
+
+	dataKey = make([]byte, 32)
+	_, err := rand.Read(dataKey)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	aw := armor.NewWriter(&buf)
+	w, err := age.Encrypt(aw, recipient)
+	require.NoError(t, err)
+	_, err = w.Write(dataKey)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, aw.Close())
+	return dataKey, buf.String()
+}
+
+func encryptSOPS(t *testing.T, dataKey []byte, value, aad string) string {
+	t.Helper()
+
+	iv := make([]byte, 32)
+	_, err := rand.Read(iv)
+	require.NoError(t, err)
+	block, err := aes.NewCipher(dataKey)
+	require.NoError(t, err)
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	require.NoError(t, err)
+
+	sealed := gcm.Seal(nil, iv, []byte(value), []byte(aad))
+	data, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:str]",
+		base64.StdEncoding.EncodeToString(data),
+		base64.StdEncoding.EncodeToString(iv),
+		base64.StdEncoding.EncodeToString(tag),
+	)
+}
+
+// sopsMAC computes the encrypted MAC of a SOPS document with the given
+// plaintext values and comments in document order
+func sopsMAC(t *testing.T, dataKey []byte, lastModified string, values ...string) string {
+	t.Helper()
+
+	hash := sha512.New()
+	for _, v := range values {
+		hash.Write([]byte(v))
+	}
+	return encryptSOPS(t, dataKey, strings.ToUpper(hex.EncodeToString(hash.Sum(nil))), lastModified)
+}
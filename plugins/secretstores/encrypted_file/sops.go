@@ -0,0 +1,210 @@
+package encrypted_file
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// Pattern of values encrypted by SOPS
+var sopsValuePattern = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.*),iv:(.*),tag:(.*),type:(.*)\]$`)
+
+// sopsMetadata is the metadata of a SOPS document. Only age is supported for
+// protecting the data key.
+type sopsMetadata struct {
+	Age               []sopsAgeKey `yaml:"age" json:"age"`
+	LastModified      string       `yaml:"lastmodified" json:"lastmodified"`
+	MAC               string       `yaml:"mac" json:"mac"`
+	MACOnlyEncrypted  bool         `yaml:"mac_only_encrypted" json:"mac_only_encrypted"`
+	UnencryptedSuffix string       `yaml:"unencrypted_suffix" json:"unencrypted_suffix"`
+	EncryptedSuffix   string       `yaml:"encrypted_suffix" json:"encrypted_suffix"`
+	UnencryptedRegex  string       `yaml:"unencrypted_regex" json:"unencrypted_regex"`
+	EncryptedRegex    string       `yaml:"encrypted_regex" json:"encrypted_regex"`
+	Version           string       `yaml:"version" json:"version"`
+
+	unencryptedRegex *regexp.Regexp
+	encryptedRegex   *regexp.Regexp
+}
+
+type sopsAgeKey struct {
+	Recipient string `yaml:"recipient" json:"recipient"`
+	Enc       string `yaml:"enc" json:"enc"`
+}
+
+// sopsLeaf is a value or comment of a SOPS document with the path of keys
+// leading to it
+type sopsLeaf struct {
+	path    []string
+	value   string
+	comment bool
+}
+
+// init compiles the regular expressions of the encryption rules
+func (m *sopsMetadata) init() error {
+	var err error
+	if m.UnencryptedRegex != "" {
+		if m.unencryptedRegex, err = regexp.Compile(m.UnencryptedRegex); err != nil {
+			return fmt.Errorf("invalid unencrypted_regex: %w", err)
+		}
+	}
+	if m.EncryptedRegex != "" {
+		if m.encryptedRegex, err = regexp.Compile(m.EncryptedRegex); err != nil {
+			return fmt.Errorf("invalid encrypted_regex: %w", err)
+		}
+	}
+	return nil
+}
+
+// encrypted returns true if SOPS encrypts the value at the given path
+// according to the suffix or regular expression rules of the document
+func (m *sopsMetadata) encrypted(path []string) bool {
+	switch {
+	case m.UnencryptedSuffix != "":
+		return !slices.ContainsFunc(path, func(k string) bool { return strings.HasSuffix(k, m.UnencryptedSuffix) })
+	case m.EncryptedSuffix != "":
+		return slices.ContainsFunc(path, func(k string) bool { return strings.HasSuffix(k, m.EncryptedSuffix) })
+	case m.unencryptedRegex != nil:
+		return !slices.ContainsFunc(path, m.unencryptedRegex.MatchString)
+	case m.encryptedRegex != nil:
+		return slices.ContainsFunc(path, m.encryptedRegex.MatchString)
+	}
+	return true
+}
+
+// dataKey decrypts the data key of the document with the first matching
+// identity
+func (m *sopsMetadata) dataKey(identities []age.Identity) ([]byte, error) {
+	if len(m.Age) == 0 {
+		return nil, errors.New("no age recipients in SOPS metadata")
+	}
+
+	var errs []error
+	for _, a := range m.Age {
+		r, err := age.Decrypt(armor.NewReader(strings.NewReader(a.Enc)), identities...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("recipient %q: %w", a.Recipient, err))
+			continue
+		}
+		return io.ReadAll(r)
+	}
+	return nil, fmt.Errorf("decrypting SOPS data key failed: %w", errors.Join(errs...))
+}
+
+// verify checks that all values are encrypted according to the rules of the
+// document and compares the MAC of the document with the SHA-512 hash of the
+// decrypted values and comments in document order as done by SOPS.
+func (m *sopsMetadata) verify(dataKey []byte, leaves []sopsLeaf) error {
+	hash := sha512.New()
+	for _, leaf := range leaves {
+		encrypted := m.encrypted(leaf.path)
+		value := []byte(leaf.value)
+		// SOPS accepts unencrypted comments created by older versions
+		if encrypted && (!leaf.comment || sopsValuePattern.MatchString(leaf.value)) {
+			aad := strings.Join(leaf.path, ":") + ":"
+			plaintext, err := decryptSOPS(dataKey, leaf.value, aad)
+			if err != nil {
+				return fmt.Errorf("value of %q: %w", strings.Join(leaf.path, ":"), err)
+			}
+			value = plaintext
+		}
+		if !m.MACOnlyEncrypted || encrypted {
+			hash.Write(value)
+		}
+	}
+
+	if m.MAC == "" {
+		return errors.New("missing MAC")
+	}
+	lastModified, err := time.Parse(time.RFC3339, m.LastModified)
+	if err != nil {
+		return fmt.Errorf("parsing lastmodified failed: %w", err)
+	}
+	mac, err := decryptSOPS(dataKey, m.MAC, lastModified.Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("decrypting MAC failed: %w", err)
+	}
+	expected := strings.ToUpper(hex.EncodeToString(hash.Sum(nil)))
+	if subtle.ConstantTimeCompare(mac, []byte(expected)) != 1 {
+		return errors.New("MAC mismatch, the document was modified")
+	}
+	return nil
+}
+
+// decryptSOPSValue decrypts the value of the given top-level key with the
+// data key.
+func decryptSOPSValue(dataKey []byte, key, value string) ([]byte, error) {
+	plaintext, err := decryptSOPS(dataKey, value, key+":")
+	if err != nil {
+		return nil, fmt.Errorf("decrypting %q failed: %w", key, err)
+	}
+	return plaintext, nil
+}
+
+// decryptSOPS decrypts the given SOPS value authenticating the additional
+// data, i.e. the path of the value or the modification time for the MAC
+func decryptSOPS(dataKey []byte, value, aad string) ([]byte, error) {
+	match := sopsValuePattern.FindStringSubmatch(value)
+	if match == nil {
+		return nil, errors.New("not encrypted")
+	}
+
+	var parts [3][]byte
+	for i, name := range []string{"data", "iv", "tag"} {
+		buf, err := base64.StdEncoding.DecodeString(match[i+1])
+		if err != nil {
+			return nil, fmt.Errorf("decoding %s failed: %w", name, err)
+		}
+		parts[i] = buf
+	}
+	data, iv, tag := parts[0], parts[1], parts[2]
+
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return nil, err
+	}
+	return gcm.Open(nil, iv, append(data, tag...), []byte(aad))
+}
+
+// sopsScalar returns the representation of a value used by SOPS for
+// computing the MAC. Null values are not part of the MAC.
+func sopsScalar(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, true
+	case bool:
+		// SOPS follows the Python implementation for booleans
+		if v {
+			return "True", true
+		}
+		return "False", true
+	case int:
+		return strconv.Itoa(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case time.Time:
+		return v.Format(time.RFC3339), true
+	}
+	return fmt.Sprint(v), true
+}
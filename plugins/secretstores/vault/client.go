@@ -0,0 +1,113 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var errNotFound = errors.New("not found")
+
+// response is the generic response of the Vault HTTP API
+type response struct {
+	LeaseID       string                 `json:"lease_id"`
+	LeaseDuration int64                  `json:"lease_duration"`
+	Renewable     bool                   `json:"renewable"`
+	Data          map[string]interface{} `json:"data"`
+	Auth          *authInfo              `json:"auth"`
+	Errors        []string               `json:"errors"`
+}
+
+type authInfo struct {
+	ClientToken   string `json:"client_token"`
+	LeaseDuration int64  `json:"lease_duration"`
+	Renewable     bool   `json:"renewable"`
+}
+
+// request sends a request to the given API path of the server, authenticated
+// with the token if not empty
+func (v *Vault) request(method, path string, query url.Values, body interface{}, token string) (*response, error) {
+	u := v.address.JoinPath("v1", path)
+	u.RawQuery = query.Encode()
+
+	var reader io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("encoding request failed: %w", err)
+		}
+		reader = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequest(method, u.String(), reader)
+	if err != nil {
+		return nil, fmt.Errorf("creating request failed: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if v.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.Namespace)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("executing request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	buf, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response failed: %w", err)
+	}
+
+	// Check the status first as error responses, e.g. of proxies, might not
+	// contain JSON
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%s %w", path, errNotFound)
+	case resp.StatusCode >= 400:
+		msg := http.StatusText(resp.StatusCode)
+		var r response
+		if err := json.Unmarshal(buf, &r); err == nil && len(r.Errors) > 0 {
+			msg = strings.Join(r.Errors, "; ")
+		}
+		return nil, fmt.Errorf("request to %s failed with status %d: %s", path, resp.StatusCode, msg)
+	}
+
+	var r response
+	if len(buf) > 0 {
+		if err := json.Unmarshal(buf, &r); err != nil {
+			return nil, fmt.Errorf("decoding response failed: %w", err)
+		}
+	}
+
+	return &r, nil
+}
+
+// lease is a dynamic secret with the credentials issued for its lifetime
+type lease struct {
+	id        string
+	duration  time.Duration
+	renewable bool
+	increment time.Duration
+	data      map[string]interface{}
+}
+
+func newLease(r *response) *lease {
+	return &lease{
+		id:        r.LeaseID,
+		duration:  time.Duration(r.LeaseDuration) * time.Second,
+		renewable: r.Renewable,
+		data:      r.Data,
+	}
+}
@@ -0,0 +1,527 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package vault
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/common/tls"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+// Interval for retrying to fetch credentials of an expiring lease
+const retryInterval = 5 * time.Second
+
+type SecretConfig struct {
+	Key     string `toml:"key"`
+	Path    string `toml:"path"`
+	Engine  string `toml:"engine"`
+	Field   string `toml:"field"`
+	Version int    `toml:"version"`
+}
+
+type Vault struct {
+	Address             string          `toml:"address"`
+	Namespace           string          `toml:"namespace"`
+	AuthMethod          string          `toml:"auth_method"`
+	AuthMount           string          `toml:"auth_mount"`
+	Token               config.Secret   `toml:"token"`
+	RoleID              config.Secret   `toml:"role_id"`
+	SecretID            config.Secret   `toml:"secret_id"`
+	KubernetesRole      string          `toml:"kubernetes_role"`
+	KubernetesTokenFile string          `toml:"kubernetes_token_file"`
+	RenewIncrement      config.Duration `toml:"renew_increment"`
+	Timeout             config.Duration `toml:"timeout"`
+	Secrets             []SecretConfig  `toml:"secret"`
+	Log                 telegraf.Logger `toml:"-"`
+	tls.ClientConfig
+
+	address *url.URL
+	client  *http.Client
+	secrets map[string]*SecretConfig
+
+	// Authentication token of the login and its lifetime, zero if the token
+	// does not expire
+	token          string
+	tokenExpiry    time.Time
+	tokenTTL       time.Duration
+	tokenRenewable bool
+	authMu         sync.Mutex
+
+	// Leases of the dynamic secrets by path
+	leases map[string]*lease
+	notify func(key string)
+	sync.Mutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func (*Vault) SampleConfig() string {
+	return sampleConfig
+}
+
+// Init initializes all internals of the secret-store
+func (v *Vault) Init() error {
+	if v.Address == "" {
+		return errors.New("'address' required")
+	}
+	u, err := url.Parse(v.Address)
+	if err != nil {
+		return fmt.Errorf("parsing address failed: %w", err)
+	}
+	v.address = u
+
+	// Check the authentication settings
+	switch v.AuthMethod {
+	case "", "token":
+		v.AuthMethod = "token"
+		if v.Token.Empty() {
+			return errors.New("'token' required for token authentication")
+		}
+	case "approle":
+		if v.RoleID.Empty() {
+			return errors.New("'role_id' required for AppRole authentication")
+		}
+		if v.AuthMount == "" {
+			v.AuthMount = "approle"
+		}
+	case "kubernetes":
+		if v.KubernetesRole == "" {
+			return errors.New("'kubernetes_role' required for Kubernetes authentication")
+		}
+		if v.KubernetesTokenFile == "" {
+			v.KubernetesTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+		}
+		if v.AuthMount == "" {
+			v.AuthMount = "kubernetes"
+		}
+	default:
+		return fmt.Errorf("invalid 'auth_method' %q", v.AuthMethod)
+	}
+
+	// Check the secrets
+	v.secrets = make(map[string]*SecretConfig, len(v.Secrets))
+	for i := range v.Secrets {
+		s := &v.Secrets[i]
+		if s.Key == "" {
+			return errors.New("'key' not specified")
+		}
+		if _, found := v.secrets[s.Key]; found {
+			return fmt.Errorf("secret with key %q already defined", s.Key)
+		}
+		if s.Path == "" {
+			return fmt.Errorf("'path' not specified for key %q", s.Key)
+		}
+		if s.Field == "" {
+			return fmt.Errorf("'field' not specified for key %q", s.Key)
+		}
+		s.Path = strings.Trim(s.Path, "/")
+
+		switch s.Engine {
+		case "":
+			s.Engine = "kv2"
+		case "kv1", "kv2", "dynamic":
+		default:
+			return fmt.Errorf("invalid 'engine' %q for key %q", s.Engine, s.Key)
+		}
+		if s.Engine == "kv2" && !strings.Contains(s.Path, "/") {
+			return fmt.Errorf("'path' of key %q must contain the mount and the secret path", s.Key)
+		}
+		if s.Engine != "kv2" && s.Version != 0 {
+			return fmt.Errorf("'version' of key %q only supported for the 'kv2' engine", s.Key)
+		}
+		v.secrets[s.Key] = s
+	}
+
+	// Setup the client
+	tlsCfg, err := v.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+	v.client = &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsCfg,
+		},
+		Timeout: time.Duration(v.Timeout),
+	}
+
+	v.leases = make(map[string]*lease)
+	v.ctx, v.cancel = context.WithCancel(context.Background())
+
+	return nil
+}
+
+// Close stops renewing the leases of dynamic secrets and revokes them
+func (v *Vault) Close() error {
+	if v.cancel == nil {
+		return nil
+	}
+	v.cancel()
+	v.wg.Wait()
+
+	v.Lock()
+	leases := v.leases
+	v.leases = make(map[string]*lease)
+	v.Unlock()
+
+	for path, l := range leases {
+		if l.id == "" {
+			continue
+		}
+		if err := v.revokeLease(l); err != nil {
+			v.Log.Warnf("Revoking lease of %q failed: %v", path, err)
+		}
+	}
+	return nil
+}
+
+// Get searches for the given key and return the secret
+func (v *Vault) Get(key string) ([]byte, error) {
+	s, found := v.secrets[key]
+	if !found {
+		return nil, fmt.Errorf("secret %q not found", key)
+	}
+
+	var data map[string]interface{}
+	var err error
+	switch s.Engine {
+	case "kv1":
+		data, err = v.readKV1(s.Path)
+	case "kv2":
+		data, err = v.readKV2(s.Path, s.Version)
+	case "dynamic":
+		data, err = v.readDynamic(s.Path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	value, found := data[s.Field]
+	if !found {
+		return nil, fmt.Errorf("field %q not found in secret %q", s.Field, s.Path)
+	}
+	if str, ok := value.(string); ok {
+		return []byte(str), nil
+	}
+	return json.Marshal(value)
+}
+
+// Set sets the given secret for the given key
+func (*Vault) Set(_, _ string) error {
+	return errors.New("setting secrets not supported")
+}
+
+// List lists all known secret keys
+func (v *Vault) List() ([]string, error) {
+	keys := make([]string, 0, len(v.secrets))
+	for k := range v.secrets {
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// GetResolver returns a function to resolve the given key.
+func (v *Vault) GetResolver(key string) (telegraf.ResolveFunc, error) {
+	s, found := v.secrets[key]
+	if !found {
+		return nil, fmt.Errorf("secret %q not found", key)
+	}
+
+	// Dynamic secrets change when the lease cannot be renewed anymore
+	dynamic := s.Engine == "dynamic"
+	resolver := func() ([]byte, bool, error) {
+		s, err := v.Get(key)
+		return s, dynamic, err
+	}
+	return resolver, nil
+}
+
+// SetNotifier sets the function to call when credentials of dynamic secrets
+// were rotated
+func (v *Vault) SetNotifier(notify func(key string)) {
+	v.Lock()
+	v.notify = notify
+	v.Unlock()
+}
+
+func (v *Vault) readKV1(path string) (map[string]interface{}, error) {
+	token, err := v.authenticate()
+	if err != nil {
+		return nil, err
+	}
+	r, err := v.request(http.MethodGet, path, nil, nil, token)
+	if err != nil {
+		return nil, err
+	}
+	return r.Data, nil
+}
+
+func (v *Vault) readKV2(path string, version int) (map[string]interface{}, error) {
+	token, err := v.authenticate()
+	if err != nil {
+		return nil, err
+	}
+
+	mount, name, _ := strings.Cut(path, "/")
+	query := url.Values{}
+	if version > 0 {
+		query.Set("version", strconv.Itoa(version))
+	}
+	r, err := v.request(http.MethodGet, mount+"/data/"+name, query, nil, token)
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := r.Data["data"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("secret %q has no data, maybe deleted", path)
+	}
+	return data, nil
+}
+
+// readDynamic returns the credentials of the lease for the given path,
+// requesting a new lease if none exists. Leases are shared by all secrets
+// referencing the same path and renewed in the background.
+func (v *Vault) readDynamic(path string) (map[string]interface{}, error) {
+	v.Lock()
+	defer v.Unlock()
+
+	if l, found := v.leases[path]; found {
+		return l.data, nil
+	}
+
+	l, err := v.fetchLease(path)
+	if err != nil {
+		return nil, err
+	}
+	v.leases[path] = l
+	v.wg.Add(1)
+	go func() {
+		defer v.wg.Done()
+		v.maintain(path, l)
+	}()
+
+	return l.data, nil
+}
+
+func (v *Vault) fetchLease(path string) (*lease, error) {
+	token, err := v.authenticate()
+	if err != nil {
+		return nil, err
+	}
+	r, err := v.request(http.MethodGet, path, nil, nil, token)
+	if err != nil {
+		return nil, err
+	}
+
+	l := newLease(r)
+	l.increment = time.Duration(v.RenewIncrement)
+	if l.increment == 0 {
+		l.increment = l.duration
+	}
+	return l, nil
+}
+
+// maintain renews the lease of the given path in the background. If the lease
+// cannot be extended any further, new credentials are requested in time.
+func (v *Vault) maintain(path string, l *lease) {
+	if l.duration == 0 {
+		// The lease does not expire
+		return
+	}
+
+	wait := l.duration * 2 / 3
+	for {
+		select {
+		case <-v.ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		if l.renewable {
+			d, err := v.renewLease(l)
+			if err == nil {
+				renewed := *l
+				renewed.duration = d
+				// Renewals are capped by the maximum TTL of the lease, so
+				// request new credentials before the lease finally expires
+				renewed.renewable = d >= l.increment
+				l = &renewed
+				v.Lock()
+				v.leases[path] = l
+				v.Unlock()
+				wait = d * 2 / 3
+				continue
+			}
+			v.Log.Warnf("Renewing lease of %q failed: %v", path, err)
+		}
+
+		nl, err := v.fetchLease(path)
+		if err != nil {
+			v.Log.Errorf("Requesting new credentials for %q failed: %v", path, err)
+			wait = retryInterval
+			continue
+		}
+		v.Log.Debugf("Rotated credentials of %q", path)
+		previous := l
+		l = nl
+		v.Lock()
+		v.leases[path] = l
+		notify := v.notify
+		v.Unlock()
+
+		if notify != nil {
+			for _, s := range v.Secrets {
+				if s.Engine == "dynamic" && s.Path == path {
+					notify(s.Key)
+				}
+			}
+		}
+
+		// The new credentials are in use now, so the previous ones can be
+		// revoked instead of waiting for their expiry
+		if previous.id != "" && previous.id != l.id {
+			if err := v.revokeLease(previous); err != nil {
+				v.Log.Warnf("Revoking previous lease of %q failed: %v", path, err)
+			}
+		}
+		if l.duration == 0 {
+			return
+		}
+		wait = l.duration * 2 / 3
+	}
+}
+
+func (v *Vault) renewLease(l *lease) (time.Duration, error) {
+	token, err := v.authenticate()
+	if err != nil {
+		return 0, err
+	}
+	body := map[string]interface{}{
+		"lease_id":  l.id,
+		"increment": int64(l.increment.Seconds()),
+	}
+	r, err := v.request(http.MethodPut, "sys/leases/renew", nil, body, token)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(r.LeaseDuration) * time.Second, nil
+}
+
+func (v *Vault) revokeLease(l *lease) error {
+	token, err := v.authenticate()
+	if err != nil {
+		return err
+	}
+	_, err = v.request(http.MethodPut, "sys/leases/revoke", nil, map[string]interface{}{"lease_id": l.id}, token)
+	return err
+}
+
+// authenticate returns a valid token, renewing the token or logging in again
+// if the token is about to expire
+func (v *Vault) authenticate() (string, error) {
+	v.authMu.Lock()
+	defer v.authMu.Unlock()
+
+	if v.AuthMethod == "token" {
+		token, err := v.Token.Get()
+		if err != nil {
+			return "", fmt.Errorf("getting token failed: %w", err)
+		}
+		defer token.Destroy()
+		return token.String(), nil
+	}
+
+	if v.token != "" && (v.tokenTTL == 0 || time.Now().Add(v.tokenTTL/3).Before(v.tokenExpiry)) {
+		return v.token, nil
+	}
+
+	// Try to extend the current token before logging in again
+	if v.token != "" && v.tokenRenewable {
+		r, err := v.request(http.MethodPut, "auth/token/renew-self", nil, nil, v.token)
+		if err == nil && r.Auth != nil {
+			v.setToken(r.Auth)
+			return v.token, nil
+		}
+		v.Log.Debugf("Renewing token failed, logging in again: %v", err)
+	}
+
+	body, err := v.loginData()
+	if err != nil {
+		return "", err
+	}
+	r, err := v.request(http.MethodPost, "auth/"+v.AuthMount+"/login", nil, body, "")
+	if err != nil {
+		return "", fmt.Errorf("login failed: %w", err)
+	}
+	if r.Auth == nil || r.Auth.ClientToken == "" {
+		return "", errors.New("login failed: no token received")
+	}
+	v.setToken(r.Auth)
+
+	return v.token, nil
+}
+
+func (v *Vault) setToken(auth *authInfo) {
+	v.token = auth.ClientToken
+	v.tokenTTL = time.Duration(auth.LeaseDuration) * time.Second
+	v.tokenExpiry = time.Now().Add(v.tokenTTL)
+	v.tokenRenewable = auth.Renewable
+}
+
+func (v *Vault) loginData() (map[string]string, error) {
+	switch v.AuthMethod {
+	case "approle":
+		roleID, err := v.RoleID.Get()
+		if err != nil {
+			return nil, fmt.Errorf("getting role ID failed: %w", err)
+		}
+		defer roleID.Destroy()
+		data := map[string]string{"role_id": roleID.String()}
+
+		if !v.SecretID.Empty() {
+			secretID, err := v.SecretID.Get()
+			if err != nil {
+				return nil, fmt.Errorf("getting secret ID failed: %w", err)
+			}
+			defer secretID.Destroy()
+			data["secret_id"] = secretID.String()
+		}
+		return data, nil
+	case "kubernetes":
+		jwt, err := os.ReadFile(v.KubernetesTokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading service account token failed: %w", err)
+		}
+		return map[string]string{
+			"role": v.KubernetesRole,
+			"jwt":  strings.TrimSpace(string(jwt)),
+		}, nil
+	}
+	return nil, fmt.Errorf("login not supported for %q authentication", v.AuthMethod)
+}
+
+// Register the secret-store on load.
+func init() {
+	secretstores.Add("vault", func(string) telegraf.SecretStore {
+		return &Vault{Timeout: config.Duration(5 * time.Second)}
+	})
+}
@@ -0,0 +1,324 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestSampleConfig(t *testing.T) {
+	plugin := &Vault{}
+	require.NotEmpty(t, plugin.SampleConfig())
+}
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *Vault
+		expected string
+	}{
+		{
+			name:     "no address",
+			plugin:   &Vault{},
+			expected: "'address' required",
+		},
+		{
+			name:     "no token",
+			plugin:   &Vault{Address: "http://localhost:8200"},
+			expected: "'token' required for token authentication",
+		},
+		{
+			name:     "invalid auth method",
+			plugin:   &Vault{Address: "http://localhost:8200", AuthMethod: "foo"},
+			expected: `invalid 'auth_method' "foo"`,
+		},
+		{
+			name:     "approle without role",
+			plugin:   &Vault{Address: "http://localhost:8200", AuthMethod: "approle"},
+			expected: "'role_id' required for AppRole authentication",
+		},
+		{
+			name: "invalid engine",
+			plugin: &Vault{
+				Address: "http://localhost:8200",
+				Token:   config.NewSecret([]byte("token")),
+				Secrets: []SecretConfig{{Key: "a", Path: "secret/a", Field: "value", Engine: "foo"}},
+			},
+			expected: `invalid 'engine' "foo" for key "a"`,
+		},
+		{
+			name: "kv2 without mount",
+			plugin: &Vault{
+				Address: "http://localhost:8200",
+				Token:   config.NewSecret([]byte("token")),
+				Secrets: []SecretConfig{{Key: "a", Path: "secret", Field: "value"}},
+			},
+			expected: `'path' of key "a" must contain the mount and the secret path`,
+		},
+		{
+			name: "duplicate key",
+			plugin: &Vault{
+				Address: "http://localhost:8200",
+				Token:   config.NewSecret([]byte("token")),
+				Secrets: []SecretConfig{
+					{Key: "a", Path: "secret/a", Field: "value"},
+					{Key: "a", Path: "secret/b", Field: "value"},
+				},
+			},
+			expected: `secret with key "a" already defined`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.plugin.Log = testutil.Logger{}
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestKeyValue(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" || r.Header.Get("X-Vault-Namespace") != "telegraf" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/kv/app":
+			fmt.Fprint(w, `{"data": {"password": "kv1secret", "port": 5432}}`)
+		case "/v1/secret/data/app":
+			password := "latest"
+			if r.URL.Query().Get("version") == "1" {
+				password = "first"
+			}
+			fmt.Fprintf(w, `{"data": {"data": {"password": %q}, "metadata": {"version": 2}}}`, password)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	plugin := &Vault{
+		Address:   server.URL,
+		Namespace: "telegraf",
+		Token:     config.NewSecret([]byte("root")),
+		Secrets: []SecretConfig{
+			{Key: "kv1", Path: "kv/app", Engine: "kv1", Field: "password"},
+			{Key: "port", Path: "kv/app", Engine: "kv1", Field: "port"},
+			{Key: "kv2", Path: "secret/app", Field: "password"},
+			{Key: "kv2_v1", Path: "secret/app", Field: "password", Version: 1},
+			{Key: "missing", Path: "secret/missing", Field: "password"},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	expected := map[string]string{
+		"kv1":    "kv1secret",
+		"port":   "5432",
+		"kv2":    "latest",
+		"kv2_v1": "first",
+	}
+	for key, value := range expected {
+		resolver, err := plugin.GetResolver(key)
+		require.NoError(t, err)
+		secret, dynamic, err := resolver()
+		require.NoError(t, err)
+		require.False(t, dynamic)
+		require.Equal(t, value, string(secret), key)
+	}
+
+	_, err := plugin.Get("missing")
+	require.ErrorContains(t, err, "not found")
+}
+
+func TestDynamicLease(t *testing.T) {
+	var logins, leases, renewals atomic.Int32
+	var revoked []string
+	var revokedMu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/approle/login":
+			var body map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body["role_id"] != "role" || body["secret_id"] != "secret" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			n := logins.Add(1)
+			fmt.Fprintf(w, `{"auth": {"client_token": "token%d", "lease_duration": 3600, "renewable": true}}`, n)
+			return
+		case "/v1/database/creds/readonly":
+			if r.Header.Get("X-Vault-Token") != "token1" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			n := leases.Add(1)
+			fmt.Fprintf(w, `{
+				"lease_id": "database/creds/readonly/%d",
+				"lease_duration": 1,
+				"renewable": true,
+				"data": {"username": "user%d", "password": "password%d"}
+			}`, n, n, n)
+		case "/v1/sys/leases/renew":
+			// Cap the first renewal to mimic reaching the maximum TTL
+			renewals.Add(1)
+			fmt.Fprint(w, `{"lease_duration": 0, "renewable": true}`)
+		case "/v1/sys/leases/revoke":
+			var body map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			revokedMu.Lock()
+			revoked = append(revoked, body["lease_id"])
+			revokedMu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	plugin := &Vault{
+		Address:    server.URL,
+		AuthMethod: "approle",
+		RoleID:     config.NewSecret([]byte("role")),
+		SecretID:   config.NewSecret([]byte("secret")),
+		Secrets: []SecretConfig{
+			{Key: "username", Path: "database/creds/readonly", Engine: "dynamic", Field: "username"},
+			{Key: "password", Path: "database/creds/readonly", Engine: "dynamic", Field: "password"},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	defer plugin.Close()
+
+	var changed []string
+	var mu sync.Mutex
+	plugin.SetNotifier(func(key string) {
+		mu.Lock()
+		changed = append(changed, key)
+		mu.Unlock()
+	})
+
+	resolver, err := plugin.GetResolver("password")
+	require.NoError(t, err)
+	secret, dynamic, err := resolver()
+	require.NoError(t, err)
+	require.True(t, dynamic)
+	require.Equal(t, "password1", string(secret))
+
+	// Secrets of the same path share the lease
+	username, err := plugin.Get("username")
+	require.NoError(t, err)
+	require.Equal(t, "user1", string(username))
+	require.Equal(t, int32(1), leases.Load())
+
+	// The lease cannot be extended so new credentials are requested
+	require.Eventually(t, func() bool {
+		secret, _, err := resolver()
+		return err == nil && string(secret) == "password2"
+	}, 3*time.Second, 100*time.Millisecond)
+	require.Equal(t, int32(1), renewals.Load())
+	require.Equal(t, int32(1), logins.Load())
+
+	// Both secrets of the rotated lease are notified
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(changed) >= 2
+	}, time.Second, 10*time.Millisecond)
+	mu.Lock()
+	require.Equal(t, []string{"username", "password"}, changed[:2])
+	mu.Unlock()
+
+	// The previous lease is revoked after the rotation
+	require.Eventually(t, func() bool {
+		revokedMu.Lock()
+		defer revokedMu.Unlock()
+		return len(revoked) > 0
+	}, time.Second, 10*time.Millisecond)
+	revokedMu.Lock()
+	require.Equal(t, "database/creds/readonly/1", revoked[0])
+	revokedMu.Unlock()
+}
+
+func TestRequestFailNonJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		fmt.Fprint(w, "<html><body>502 Bad Gateway</body></html>")
+	}))
+	defer server.Close()
+
+	plugin := &Vault{
+		Address: server.URL,
+		Token:   config.NewSecret([]byte("token")),
+		Secrets: []SecretConfig{{Key: "password", Path: "secret/data/db", Field: "password"}},
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	defer plugin.Close()
+
+	_, err := plugin.Get("password")
+	require.ErrorContains(t, err, "failed with status 502: Bad Gateway")
+}
+
+func TestCloseRevokesLeases(t *testing.T) {
+	var revoked []string
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/database/creds/readonly":
+			fmt.Fprint(w, `{
+				"lease_id": "database/creds/readonly/1",
+				"lease_duration": 3600,
+				"renewable": true,
+				"data": {"username": "user1"}
+			}`)
+		case "/v1/sys/leases/revoke":
+			var body map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			mu.Lock()
+			revoked = append(revoked, body["lease_id"])
+			mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	plugin := &Vault{
+		Address: server.URL,
+		Token:   config.NewSecret([]byte("token")),
+		Secrets: []SecretConfig{
+			{Key: "username", Path: "database/creds/readonly", Engine: "dynamic", Field: "username"},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	username, err := plugin.Get("username")
+	require.NoError(t, err)
+	require.Equal(t, "user1", string(username))
+
+	// Closing stops the renewal and revokes the lease exactly once
+	require.NoError(t, plugin.Close())
+	require.NoError(t, plugin.Close())
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, []string{"database/creds/readonly/1"}, revoked)
+}
@@ -0,0 +1,94 @@
+package arrow
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/columnar"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+type Serializer struct {
+	TimestampColumn string          `toml:"arrow_timestamp_column"`
+	Compression     string          `toml:"arrow_compression"`
+	Log             telegraf.Logger `toml:"-"`
+
+	options []ipc.Option
+}
+
+func (s *Serializer) Init() error {
+	switch s.Compression {
+	case "", "none":
+	case "lz4":
+		s.options = append(s.options, ipc.WithLZ4())
+	case "zstd":
+		s.options = append(s.options, ipc.WithZstd())
+	default:
+		return fmt.Errorf("invalid 'arrow_compression' %q", s.Compression)
+	}
+	return nil
+}
+
+func (s *Serializer) Serialize(m telegraf.Metric) ([]byte, error) {
+	return s.SerializeBatch([]telegraf.Metric{m})
+}
+
+// SerializeBatch outputs one IPC stream per measurement, in order of the first
+// occurrence of the measurement, containing all metrics of the measurement in
+// a single record batch.
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	groups := make(map[string][]telegraf.Metric)
+	var order []string
+	for _, m := range metrics {
+		if _, found := groups[m.Name()]; !found {
+			order = append(order, m.Name())
+		}
+		groups[m.Name()] = append(groups[m.Name()], m)
+	}
+
+	var buf bytes.Buffer
+	for _, name := range order {
+		if err := s.writeStream(&buf, name, groups[name]); err != nil {
+			return nil, fmt.Errorf("serializing measurement %q failed: %w", name, err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *Serializer) writeStream(buf *bytes.Buffer, name string, metrics []telegraf.Metric) error {
+	inferred, err := columnar.InferSchema(metrics, s.TimestampColumn)
+	if err != nil {
+		return err
+	}
+	metadata := arrow.NewMetadata([]string{columnar.MeasurementKey}, []string{name})
+	schema := arrow.NewSchema(inferred.Fields(), &metadata)
+
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer builder.Release()
+	if err := columnar.AppendMetrics(builder, metrics, s.TimestampColumn); err != nil {
+		return err
+	}
+	record := builder.NewRecord()
+	defer record.Release()
+
+	writer := ipc.NewWriter(buf, append([]ipc.Option{ipc.WithSchema(schema)}, s.options...)...)
+	if err := writer.Write(record); err != nil {
+		writer.Close()
+		return err
+	}
+	return writer.Close()
+}
+
+func init() {
+	serializers.Add("arrow",
+		func() telegraf.Serializer {
+			return &Serializer{TimestampColumn: "timestamp"}
+		},
+	)
+}
@@ -0,0 +1,141 @@
+package arrow
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/common/columnar"
+	parsers_arrow "github.com/influxdata/telegraf/plugins/parsers/arrow"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestInitFail(t *testing.T) {
+	serializer := &Serializer{Compression: "gzip"}
+	require.ErrorContains(t, serializer.Init(), `invalid 'arrow_compression' "gzip"`)
+}
+
+func TestSchema(t *testing.T) {
+	serializer := &Serializer{TimestampColumn: "timestamp"}
+	require.NoError(t, serializer.Init())
+
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"usage": 1.5}, time.Unix(1, 0)),
+		metric.New("cpu", map[string]string{"host": "b"}, map[string]interface{}{"count": int64(2)}, time.Unix(2, 0)),
+	}
+	buf, err := serializer.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	reader, err := ipc.NewReader(bytes.NewReader(buf))
+	require.NoError(t, err)
+	defer reader.Release()
+
+	expected := arrow.NewSchema(
+		[]arrow.Field{
+			{Name: "count", Type: arrow.PrimitiveTypes.Int64, Nullable: true, Metadata: roleMetadata(columnar.RoleField)},
+			{Name: "host", Type: arrow.BinaryTypes.String, Nullable: true, Metadata: roleMetadata(columnar.RoleTag)},
+			{Name: "usage", Type: arrow.PrimitiveTypes.Float64, Nullable: true, Metadata: roleMetadata(columnar.RoleField)},
+			{Name: "timestamp", Type: arrow.PrimitiveTypes.Int64, Metadata: roleMetadata(columnar.RoleTimestamp)},
+		},
+		&arrow.Metadata{},
+	)
+	require.True(t, expected.Equal(reader.Schema()), reader.Schema().String())
+	require.Equal(t, []string{"cpu"}, reader.Schema().Metadata().Values())
+
+	require.True(t, reader.Next())
+	record := reader.Record()
+	require.Equal(t, int64(2), record.NumRows())
+	require.True(t, record.Column(0).IsNull(0))
+	require.True(t, record.Column(2).IsNull(1))
+	require.False(t, reader.Next())
+}
+
+func TestRoundtrip(t *testing.T) {
+	metrics := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"host": "server01", "cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": 42.5, "online": true},
+			time.Unix(1700000000, 123456789),
+		),
+		metric.New(
+			"mem",
+			map[string]string{"host": "server01"},
+			map[string]interface{}{"used": int64(1024), "total": uint64(4096), "state": "ok"},
+			time.Unix(1700000000, 0),
+		),
+		metric.New(
+			"cpu",
+			map[string]string{"host": "server02"},
+			map[string]interface{}{"usage_idle": 12.0},
+			time.Unix(1700000001, 0),
+		),
+	}
+
+	for _, compression := range []string{"none", "lz4", "zstd"} {
+		t.Run(compression, func(t *testing.T) {
+			serializer := &Serializer{TimestampColumn: "timestamp", Compression: compression}
+			require.NoError(t, serializer.Init())
+
+			parser := &parsers_arrow.Parser{
+				TagColumns:      []string{"host", "cpu"},
+				TimestampColumn: "timestamp",
+			}
+			require.NoError(t, parser.Init())
+
+			// Metrics are grouped by measurement in batches
+			buf, err := serializer.SerializeBatch(metrics)
+			require.NoError(t, err)
+			actual, err := parser.Parse(buf)
+			require.NoError(t, err)
+			expected := []telegraf.Metric{metrics[0], metrics[2], metrics[1]}
+			testutil.RequireMetricsEqual(t, expected, actual)
+
+			// Single metrics
+			buf, err = serializer.Serialize(metrics[1])
+			require.NoError(t, err)
+			actual, err = parser.Parse(buf)
+			require.NoError(t, err)
+			testutil.RequireMetricsEqual(t, metrics[1:2], actual)
+		})
+	}
+}
+
+func TestRoundtripRoles(t *testing.T) {
+	metrics := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"host": "server01", "cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": 42.5, "state": "ok"},
+			time.Unix(1700000000, 123456789),
+		),
+		metric.New(
+			"cpu",
+			map[string]string{"host": "server02"},
+			map[string]interface{}{"usage_idle": 12.0},
+			time.Unix(1700000001, 0),
+		),
+	}
+
+	serializer := &Serializer{TimestampColumn: "timestamp"}
+	require.NoError(t, serializer.Init())
+	buf, err := serializer.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	// Tags, fields and the timestamp are restored from the column roles
+	// without configuring the parser
+	parser := &parsers_arrow.Parser{}
+	actual, err := parser.Parse(buf)
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t, metrics, actual)
+}
+
+func roleMetadata(role string) arrow.Metadata {
+	return arrow.NewMetadata([]string{columnar.RoleKey}, []string{role})
+}
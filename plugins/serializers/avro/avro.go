@@ -0,0 +1,441 @@
+package avro
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/linkedin/goavro/v2"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+// maxCachedSchemas limits the number of derived schemas and of schema IDs kept
+// in memory. Least recently used entries are evicted and derived or looked up
+// again when needed.
+const maxCachedSchemas = 1000
+
+// If SchemaRegistry is set, the output will be in Confluent Wire Format
+// (https://docs.confluent.io/platform/current/schema-registry/serdes-develop/index.html#wire-format)
+// with the schema registered in or looked up from the registry. Otherwise,
+// the output is plain Avro binary and the reader must know the schema.
+
+type Serializer struct {
+	SchemaRegistry   string            `toml:"avro_schema_registry"`
+	CaCertPath       string            `toml:"avro_schema_registry_cert"`
+	AutoRegister     bool              `toml:"avro_schema_registry_auto_register"`
+	Subject          string            `toml:"avro_subject"`
+	Schema           string            `toml:"avro_schema"`
+	Schemas          map[string]string `toml:"avro_schemas"`
+	Namespace        string            `toml:"avro_namespace"`
+	MeasurementField string            `toml:"avro_measurement_field"`
+	Timestamp        string            `toml:"avro_timestamp"`
+	TimestampFormat  string            `toml:"avro_timestamp_format"`
+	Log              telegraf.Logger   `toml:"-"`
+
+	registry   *schemaRegistry
+	configured map[string]*encoder
+	derived    *lru.Cache[string, *encoder]
+	mu         sync.Mutex
+}
+
+// encoder converts metrics to records of a schema
+type encoder struct {
+	schema string
+	codec  *goavro.Codec
+	fields []recordField
+}
+
+type recordField struct {
+	name string
+	key  string
+	typ  interface{}
+}
+
+func (s *Serializer) Init() error {
+	switch s.TimestampFormat {
+	case "":
+		s.TimestampFormat = "unix_ms"
+	case "unix", "unix_ms", "unix_us", "unix_ns":
+	default:
+		return fmt.Errorf("invalid 'avro_timestamp_format' %q", s.TimestampFormat)
+	}
+	if s.Timestamp == "" {
+		s.Timestamp = "timestamp"
+	}
+	if s.Subject != "" && s.SchemaRegistry == "" {
+		return errors.New("'avro_subject' requires 'avro_schema_registry'")
+	}
+
+	// Parse the configured schemas, the default one uses the empty key
+	s.configured = make(map[string]*encoder, len(s.Schemas)+1)
+	for name, schema := range s.Schemas {
+		e, err := newConfiguredEncoder(schema)
+		if err != nil {
+			return fmt.Errorf("invalid schema for %q: %w", name, err)
+		}
+		s.configured[name] = e
+	}
+	if s.Schema != "" {
+		e, err := newConfiguredEncoder(s.Schema)
+		if err != nil {
+			return fmt.Errorf("invalid 'avro_schema': %w", err)
+		}
+		s.configured[""] = e
+	}
+	derived, err := lru.New[string, *encoder](maxCachedSchemas)
+	if err != nil {
+		return err
+	}
+	s.derived = derived
+
+	if s.SchemaRegistry != "" {
+		registry, err := newSchemaRegistry(s.SchemaRegistry, s.CaCertPath)
+		if err != nil {
+			return fmt.Errorf("creating schema registry client for %q failed: %w", s.SchemaRegistry, err)
+		}
+		s.registry = registry
+	}
+
+	return nil
+}
+
+func (s *Serializer) Serialize(m telegraf.Metric) ([]byte, error) {
+	return s.serialize(nil, m)
+}
+
+// SerializeBatch concatenates the serialized metrics. With a schema registry
+// each record is prefixed by its schema ID so the batch can be decoded
+// sequentially.
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	var buf []byte
+	for _, m := range metrics {
+		var err error
+		if buf, err = s.serialize(buf, m); err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+func (s *Serializer) serialize(buf []byte, m telegraf.Metric) ([]byte, error) {
+	e, err := s.encoder(m)
+	if err != nil {
+		return nil, err
+	}
+	record, err := s.record(e, m)
+	if err != nil {
+		return nil, fmt.Errorf("converting metric %q failed: %w", m.Name(), err)
+	}
+
+	if s.registry == nil {
+		return e.codec.BinaryFromNative(buf, record)
+	}
+
+	subject := s.Subject
+	if subject == "" {
+		subject = m.Name() + "-value"
+	}
+	id, err := s.registry.id(subject, e.schema, s.AutoRegister)
+	if err != nil {
+		return nil, err
+	}
+	buf = append(buf, 0)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(id))
+	return e.codec.BinaryFromNative(buf, record)
+}
+
+// encoder returns the configured schema of the metric or derives a schema
+// from the tags and fields of the metric
+func (s *Serializer) encoder(m telegraf.Metric) (*encoder, error) {
+	if e, found := s.configured[m.Name()]; found {
+		return e, nil
+	}
+	if e, found := s.configured[""]; found {
+		return e, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	signature := s.signature(m)
+	if e, found := s.derived.Get(signature); found {
+		return e, nil
+	}
+	e, err := s.deriveEncoder(m)
+	if err != nil {
+		return nil, fmt.Errorf("deriving schema for %q failed: %w", m.Name(), err)
+	}
+	s.derived.Add(signature, e)
+	return e, nil
+}
+
+func (*Serializer) signature(m telegraf.Metric) string {
+	var b strings.Builder
+	b.WriteString(m.Name())
+	for _, tag := range m.TagList() {
+		b.WriteString("\x00t")
+		b.WriteString(tag.Key)
+	}
+	for _, field := range m.FieldList() {
+		b.WriteString("\x00f")
+		b.WriteString(field.Key)
+		b.WriteString(fieldType(field.Value))
+	}
+	return b.String()
+}
+
+// deriveEncoder creates a record schema with the timestamp, the tags and the
+// fields of the metric. Tags and fields are nullable to allow evolving the
+// schema when tags or fields are added or removed.
+func (s *Serializer) deriveEncoder(m telegraf.Metric) (*encoder, error) {
+	type avroField struct {
+		Name    string          `json:"name"`
+		Type    interface{}     `json:"type"`
+		Default json.RawMessage `json:"default,omitempty"`
+	}
+	schemaFields := make([]avroField, 0, len(m.TagList())+len(m.FieldList())+2)
+	fields := make([]recordField, 0, cap(schemaFields))
+	seen := make(map[string]bool, cap(schemaFields))
+	add := func(name, key string, typ interface{}, nullable bool) error {
+		name = sanitize(name)
+		if seen[name] {
+			return fmt.Errorf("duplicate record field %q", name)
+		}
+		seen[name] = true
+
+		f := avroField{Name: name, Type: typ}
+		if nullable {
+			f.Type = []interface{}{"null", typ}
+			f.Default = json.RawMessage("null")
+		}
+		schemaFields = append(schemaFields, f)
+		fields = append(fields, recordField{name: name, key: key, typ: f.Type})
+		return nil
+	}
+
+	if err := add(s.Timestamp, s.Timestamp, "long", false); err != nil {
+		return nil, err
+	}
+	if s.MeasurementField != "" {
+		if err := add(s.MeasurementField, s.MeasurementField, "string", false); err != nil {
+			return nil, err
+		}
+	}
+	for _, tag := range m.TagList() {
+		if err := add(tag.Key, tag.Key, "string", true); err != nil {
+			return nil, err
+		}
+	}
+	for _, field := range m.FieldList() {
+		typ := fieldType(field.Value)
+		if typ == "" {
+			return nil, fmt.Errorf("unsupported type %T of field %q", field.Value, field.Key)
+		}
+		if err := add(field.Key, field.Key, typ, true); err != nil {
+			return nil, err
+		}
+	}
+
+	schema := map[string]interface{}{
+		"type":   "record",
+		"name":   sanitize(m.Name()),
+		"fields": schemaFields,
+	}
+	if s.Namespace != "" {
+		schema["namespace"] = s.Namespace
+	}
+	buf, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+	codec, err := goavro.NewCodec(string(buf))
+	if err != nil {
+		return nil, err
+	}
+
+	return &encoder{schema: string(buf), codec: codec, fields: fields}, nil
+}
+
+func newConfiguredEncoder(schema string) (*encoder, error) {
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	var definition struct {
+		Type   string `json:"type"`
+		Fields []struct {
+			Name string      `json:"name"`
+			Type interface{} `json:"type"`
+		} `json:"fields"`
+	}
+	if err := json.Unmarshal([]byte(schema), &definition); err != nil {
+		return nil, err
+	}
+	if definition.Type != "record" {
+		return nil, fmt.Errorf("expected a record schema but got %q", definition.Type)
+	}
+
+	fields := make([]recordField, 0, len(definition.Fields))
+	for _, f := range definition.Fields {
+		fields = append(fields, recordField{name: f.Name, key: f.Name, typ: f.Type})
+	}
+	return &encoder{schema: codec.Schema(), codec: codec, fields: fields}, nil
+}
+
+// record converts the metric to the native representation of the record.
+// Record fields without a value in the metric are left out to use the default
+// value of the schema.
+func (s *Serializer) record(e *encoder, m telegraf.Metric) (map[string]interface{}, error) {
+	record := make(map[string]interface{}, len(e.fields))
+	for _, f := range e.fields {
+		var value interface{}
+		switch {
+		case f.key == s.Timestamp:
+			value = s.timestamp(m, f.typ)
+		case f.key == s.MeasurementField:
+			value = m.Name()
+		default:
+			if v, found := m.GetTag(f.key); found {
+				value = v
+			} else if v, found := m.GetField(f.key); found {
+				value = v
+			} else {
+				continue
+			}
+		}
+
+		v, err := convert(value, f.typ)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %w", f.name, err)
+		}
+		record[f.name] = v
+	}
+	return record, nil
+}
+
+func (s *Serializer) timestamp(m telegraf.Metric, typ interface{}) interface{} {
+	// Timestamps with logical type are encoded by the codec
+	if t, ok := typ.(map[string]interface{}); ok {
+		switch t["logicalType"] {
+		case "timestamp-millis", "timestamp-micros":
+			return m.Time()
+		}
+	}
+
+	switch s.TimestampFormat {
+	case "unix":
+		return m.Time().Unix()
+	case "unix_us":
+		return m.Time().UnixMicro()
+	case "unix_ns":
+		return m.Time().UnixNano()
+	}
+	return m.Time().UnixMilli()
+}
+
+// convert the value to the native representation of the given Avro type
+func convert(value interface{}, typ interface{}) (interface{}, error) {
+	switch t := typ.(type) {
+	case string:
+		return convertPrimitive(value, t)
+	case map[string]interface{}:
+		// Values of logical types are already converted
+		if _, found := t["logicalType"]; found {
+			return value, nil
+		}
+		if name, ok := t["type"].(string); ok {
+			return convertPrimitive(value, name)
+		}
+	case []interface{}:
+		// Use the first branch of the union the value can be converted to
+		for _, branch := range t {
+			name, ok := branch.(string)
+			if !ok || name == "null" {
+				continue
+			}
+			if v, err := convertPrimitive(value, name); err == nil {
+				return goavro.Union(name, v), nil
+			}
+		}
+		return nil, fmt.Errorf("value %v does not match any type of the union %v", value, t)
+	}
+	return nil, fmt.Errorf("unsupported type %v", typ)
+}
+
+func convertPrimitive(value interface{}, typ string) (interface{}, error) {
+	switch typ {
+	case "string":
+		return internal.ToString(value)
+	case "long":
+		if _, ok := value.(string); ok {
+			return nil, errors.New("cannot convert string to long")
+		}
+		return internal.ToInt64(value)
+	case "int":
+		if _, ok := value.(string); ok {
+			return nil, errors.New("cannot convert string to int")
+		}
+		return internal.ToInt32(value)
+	case "double":
+		if _, ok := value.(string); ok {
+			return nil, errors.New("cannot convert string to double")
+		}
+		return internal.ToFloat64(value)
+	case "float":
+		if _, ok := value.(string); ok {
+			return nil, errors.New("cannot convert string to float")
+		}
+		return internal.ToFloat32(value)
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return nil, fmt.Errorf("cannot convert %T to boolean", value)
+		}
+		return value, nil
+	}
+	return nil, fmt.Errorf("unsupported type %q", typ)
+}
+
+// fieldType returns the Avro type of the metric field value
+func fieldType(value interface{}) string {
+	switch value.(type) {
+	case int64, uint64:
+		return "long"
+	case float64:
+		return "double"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	}
+	return ""
+}
+
+// sanitize replaces all characters not allowed in Avro names
+func sanitize(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			b[i] = '_'
+		}
+	}
+	if len(b) == 0 || b[0] >= '0' && b[0] <= '9' {
+		return "_" + string(b)
+	}
+	return string(b)
+}
+
+func init() {
+	serializers.Add("avro",
+		func() telegraf.Serializer {
+			return &Serializer{AutoRegister: true}
+		},
+	)
+}
@@ -0,0 +1,311 @@
+package avro
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	parsers_avro "github.com/influxdata/telegraf/plugins/parsers/avro"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name       string
+		serializer *Serializer
+		expected   string
+	}{
+		{
+			name:       "invalid timestamp format",
+			serializer: &Serializer{TimestampFormat: "rfc3339"},
+			expected:   `invalid 'avro_timestamp_format' "rfc3339"`,
+		},
+		{
+			name:       "subject without registry",
+			serializer: &Serializer{Subject: "metrics-value"},
+			expected:   "'avro_subject' requires 'avro_schema_registry'",
+		},
+		{
+			name:       "invalid schema",
+			serializer: &Serializer{Schema: `{"type": "foo"}`},
+			expected:   "invalid 'avro_schema'",
+		},
+		{
+			name:       "no record",
+			serializer: &Serializer{Schemas: map[string]string{"cpu": `{"type": "string"}`}},
+			expected:   `invalid schema for "cpu": expected a record schema`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.serializer.Init(), tt.expected)
+		})
+	}
+}
+
+func TestDerivedSchemaWithRegistry(t *testing.T) {
+	registry := newTestRegistry()
+	server := httptest.NewServer(registry)
+	defer server.Close()
+
+	serializer := &Serializer{
+		SchemaRegistry: server.URL,
+		AutoRegister:   true,
+		Namespace:      "com.example",
+	}
+	require.NoError(t, serializer.Init())
+
+	m := metric.New(
+		"cpu",
+		map[string]string{"host": "server01", "cpu-id": "cpu0"},
+		map[string]interface{}{
+			"usage_idle": 42.5,
+			"cores":      int64(8),
+			"online":     true,
+			"model":      "foo",
+		},
+		time.Unix(1700000000, 123000000),
+	)
+	buf, err := serializer.Serialize(m)
+	require.NoError(t, err)
+
+	// Check the wire-format header
+	require.Equal(t, byte(0), buf[0])
+	require.Equal(t, uint32(1), binary.BigEndian.Uint32(buf[1:5]))
+	require.Equal(t, []string{"cpu-value"}, registry.subjects())
+
+	// Serializing again must use the cached ID
+	_, err = serializer.Serialize(m)
+	require.NoError(t, err)
+	require.Equal(t, 1, registry.registrations())
+
+	// The output must be readable by the parser
+	parser := &parsers_avro.Parser{
+		SchemaRegistry:  server.URL,
+		Tags:            []string{"host", "cpu_id"},
+		Timestamp:       "timestamp",
+		TimestampFormat: "unix_ms",
+		UnionMode:       "nullable",
+		Log:             testutil.Logger{},
+	}
+	require.NoError(t, parser.Init())
+	actual, err := parser.Parse(buf)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"com.example.cpu",
+			map[string]string{"host": "server01", "cpu_id": "cpu0"},
+			map[string]interface{}{
+				"usage_idle": 42.5,
+				"cores":      int64(8),
+				"online":     true,
+				"model":      "foo",
+				"timestamp":  int64(1700000000123),
+			},
+			time.Unix(1700000000, 123000000),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+
+	// An additional field requires a new schema
+	m.AddField("temperature", 51.0)
+	buf, err = serializer.Serialize(m)
+	require.NoError(t, err)
+	require.Equal(t, uint32(2), binary.BigEndian.Uint32(buf[1:5]))
+	require.Equal(t, 2, registry.registrations())
+}
+
+func TestConfiguredSchema(t *testing.T) {
+	schema := `{
+		"type": "record",
+		"name": "cpu",
+		"namespace": "com.example",
+		"fields": [
+			{"name": "time", "type": "long"},
+			{"name": "host", "type": "string"},
+			{"name": "region", "type": "string", "default": "unknown"},
+			{"name": "usage_idle", "type": "double"},
+			{"name": "cores", "type": ["null", "int"], "default": null}
+		]
+	}`
+
+	serializer := &Serializer{
+		Schema:          schema,
+		Timestamp:       "time",
+		TimestampFormat: "unix",
+	}
+	require.NoError(t, serializer.Init())
+
+	m := metric.New(
+		"cpu",
+		map[string]string{"host": "server01", "dropped": "tag"},
+		map[string]interface{}{"usage_idle": int64(42), "cores": int64(8)},
+		time.Unix(1700000000, 0),
+	)
+	buf, err := serializer.Serialize(m)
+	require.NoError(t, err)
+
+	parser := &parsers_avro.Parser{
+		Schema:    schema,
+		Tags:      []string{"host", "region"},
+		Timestamp: "time",
+		UnionMode: "nullable",
+		Log:       testutil.Logger{},
+	}
+	require.NoError(t, parser.Init())
+	actual, err := parser.Parse(buf)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"com.example.cpu",
+			map[string]string{"host": "server01", "region": "unknown"},
+			map[string]interface{}{"time": int64(1700000000), "usage_idle": 42.0, "cores": int32(8)},
+			time.Unix(1700000000, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual, testutil.IgnoreType())
+
+	// A required field without value cannot be serialized
+	m.RemoveTag("host")
+	_, err = serializer.Serialize(m)
+	require.Error(t, err)
+}
+
+func TestLookupOnly(t *testing.T) {
+	registry := newTestRegistry()
+	server := httptest.NewServer(registry)
+	defer server.Close()
+
+	serializer := &Serializer{
+		SchemaRegistry: server.URL,
+		Subject:        "metrics-value",
+	}
+	require.NoError(t, serializer.Init())
+
+	m := metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 42.0}, time.Unix(0, 0))
+	_, err := serializer.Serialize(m)
+	require.ErrorContains(t, err, `subject "metrics-value": schema registry returned 404 Not Found`)
+
+	// Register the schema externally
+	serializer.AutoRegister = true
+	_, err = serializer.Serialize(m)
+	require.NoError(t, err)
+	serializer.registry.ids.Purge()
+	serializer.AutoRegister = false
+	buf, err := serializer.Serialize(m)
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), binary.BigEndian.Uint32(buf[1:5]))
+	require.Equal(t, 1, registry.registrations())
+}
+
+func TestSerializeBatch(t *testing.T) {
+	serializer := &Serializer{}
+	require.NoError(t, serializer.Init())
+
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+		metric.New("mem", map[string]string{"host": "a"}, map[string]interface{}{"used": int64(2)}, time.Unix(0, 0)),
+	}
+
+	var expected []byte
+	for _, m := range metrics {
+		buf, err := serializer.Serialize(m)
+		require.NoError(t, err)
+		expected = append(expected, buf...)
+	}
+	actual, err := serializer.SerializeBatch(metrics)
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+}
+
+func TestSanitize(t *testing.T) {
+	require.Equal(t, "cpu_usage", sanitize("cpu-usage"))
+	require.Equal(t, "_1m", sanitize("1m"))
+	require.Equal(t, "_", sanitize(""))
+}
+
+// testRegistry is a minimal Confluent-compatible schema registry
+type testRegistry struct {
+	schemas []string
+	ids     map[string]int
+	names   map[string]bool
+	count   int
+	sync.Mutex
+}
+
+func newTestRegistry() *testRegistry {
+	return &testRegistry{
+		ids:   make(map[string]int),
+		names: make(map[string]bool),
+	}
+}
+
+func (r *testRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.Lock()
+	defer r.Unlock()
+
+	if req.Method == http.MethodGet {
+		id, err := strconv.Atoi(strings.TrimPrefix(req.URL.Path, "/schemas/ids/"))
+		if err != nil || id < 1 || id > len(r.schemas) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		//nolint:errcheck // Ignore errors in test
+		json.NewEncoder(w).Encode(map[string]string{"schema": r.schemas[id-1]})
+		return
+	}
+
+	var body struct {
+		Schema string `json:"schema"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	subject, register := strings.CutSuffix(strings.TrimPrefix(req.URL.Path, "/subjects/"), "/versions")
+
+	id, found := r.ids[subject+body.Schema]
+	if !found {
+		if !register {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error_code": 40403, "message": "Schema not found"}`)
+			return
+		}
+		r.schemas = append(r.schemas, body.Schema)
+		id = len(r.schemas)
+		r.ids[subject+body.Schema] = id
+		r.names[subject] = true
+		r.count++
+	}
+	fmt.Fprintf(w, `{"id": %d}`, id)
+}
+
+func (r *testRegistry) subjects() []string {
+	r.Lock()
+	defer r.Unlock()
+	subjects := make([]string, 0, len(r.names))
+	for s := range r.names {
+		subjects = append(subjects, s)
+	}
+	return subjects
+}
+
+func (r *testRegistry) registrations() int {
+	r.Lock()
+	defer r.Unlock()
+	return r.count
+}
@@ -0,0 +1,41 @@
+package avro
+
+import (
+	lru "github.com/hashicorp/golang-lru/v2"
+
+	"github.com/influxdata/telegraf/plugins/common/schemaregistry"
+)
+
+// schemaRegistry caches the IDs of the schemas registered in the registry
+type schemaRegistry struct {
+	client *schemaregistry.Client
+	ids    *lru.Cache[string, int]
+}
+
+func newSchemaRegistry(addr, caCertPath string) (*schemaRegistry, error) {
+	client, err := schemaregistry.NewClient(addr, caCertPath)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := lru.New[string, int](maxCachedSchemas)
+	if err != nil {
+		return nil, err
+	}
+	return &schemaRegistry{client: client, ids: ids}, nil
+}
+
+// id returns the ID of the schema under the given subject. The schema is
+// registered if requested, otherwise it must already exist in the registry.
+func (sr *schemaRegistry) id(subject, schema string, register bool) (int, error) {
+	key := subject + "\x00" + schema
+	if id, found := sr.ids.Get(key); found {
+		return id, nil
+	}
+
+	id, err := sr.client.ID(subject, schema, register)
+	if err != nil {
+		return 0, err
+	}
+	sr.ids.Add(key, id)
+	return id, nil
+}
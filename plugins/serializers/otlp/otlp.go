@@ -0,0 +1,153 @@
+package otlp
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/influxdata/influxdb-observability/influx2otel"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+
+	"github.com/influxdata/telegraf"
+	common_otel "github.com/influxdata/telegraf/plugins/common/opentelemetry"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+type Serializer struct {
+	Format             string            `toml:"otlp_format"`
+	ResourceTags       []string          `toml:"otlp_resource_tags"`
+	ResourceAttributes map[string]string `toml:"otlp_resource_attributes"`
+	Log                telegraf.Logger   `toml:"-"`
+
+	converter *influx2otel.LineProtocolToOtelMetrics
+}
+
+// resourceGroup collects the metrics sharing the same values of the resource
+// tags
+type resourceGroup struct {
+	attributes map[string]string
+	metrics    []telegraf.Metric
+}
+
+func (s *Serializer) Init() error {
+	switch s.Format {
+	case "":
+		s.Format = "protobuf"
+	case "protobuf", "json":
+	default:
+		return fmt.Errorf("invalid 'otlp_format' %q", s.Format)
+	}
+
+	converter, err := common_otel.NewConverter(s.Log)
+	if err != nil {
+		return err
+	}
+	s.converter = converter
+
+	return nil
+}
+
+// Serialize outputs an export request containing the single metric
+func (s *Serializer) Serialize(m telegraf.Metric) ([]byte, error) {
+	return s.serialize([]telegraf.Metric{m}, true)
+}
+
+// SerializeBatch outputs an export request containing all metrics. Metrics
+// that cannot be converted are skipped.
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	return s.serialize(metrics, false)
+}
+
+func (s *Serializer) serialize(metrics []telegraf.Metric, strict bool) ([]byte, error) {
+	md := pmetric.NewMetrics()
+	for _, group := range s.group(metrics) {
+		batch := s.converter.NewBatch()
+		for _, m := range group.metrics {
+			if err := s.add(batch, m); err != nil {
+				if strict {
+					return nil, err
+				}
+				s.Log.Warnf("Skipping metric %q: %v", m.Name(), err)
+			}
+		}
+
+		converted := batch.GetMetrics().ResourceMetrics()
+		for i := range converted.Len() {
+			attrs := converted.At(i).Resource().Attributes()
+			for k, v := range s.ResourceAttributes {
+				attrs.PutStr(k, v)
+			}
+			for k, v := range group.attributes {
+				attrs.PutStr(k, v)
+			}
+		}
+		converted.MoveAndAppendTo(md.ResourceMetrics())
+	}
+
+	if md.ResourceMetrics().Len() == 0 {
+		return nil, nil
+	}
+
+	request := pmetricotlp.NewExportRequestFromMetrics(md)
+	if s.Format == "json" {
+		return request.MarshalJSON()
+	}
+	return request.MarshalProto()
+}
+
+// group the metrics by the values of the resource tags keeping the order of
+// the metrics
+func (s *Serializer) group(metrics []telegraf.Metric) []*resourceGroup {
+	if len(s.ResourceTags) == 0 {
+		return []*resourceGroup{{metrics: metrics}}
+	}
+
+	groups := make(map[string]*resourceGroup)
+	var order []string
+	for _, m := range metrics {
+		attributes := make(map[string]string, len(s.ResourceTags))
+		var key strings.Builder
+		for _, tag := range s.ResourceTags {
+			if v, found := m.GetTag(tag); found {
+				attributes[tag] = v
+				key.WriteString(tag + "=" + v)
+			}
+			key.WriteByte(0)
+		}
+
+		g, found := groups[key.String()]
+		if !found {
+			g = &resourceGroup{attributes: attributes}
+			groups[key.String()] = g
+			order = append(order, key.String())
+		}
+		g.metrics = append(g.metrics, m)
+	}
+
+	result := make([]*resourceGroup, 0, len(order))
+	for _, key := range order {
+		result = append(result, groups[key])
+	}
+	return result
+}
+
+func (s *Serializer) add(batch *influx2otel.MetricsBatch, m telegraf.Metric) error {
+	// Resource tags are added to the resource instead of the data points
+	tags := m.Tags()
+	for k := range tags {
+		if slices.Contains(s.ResourceTags, k) {
+			delete(tags, k)
+		}
+	}
+
+	return common_otel.AddMetric(batch, m, tags)
+}
+
+func init() {
+	serializers.Add("otlp",
+		func() telegraf.Serializer {
+			return &Serializer{}
+		},
+	)
+}
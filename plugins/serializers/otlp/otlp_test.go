@@ -0,0 +1,153 @@
+package otlp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestInitFail(t *testing.T) {
+	serializer := &Serializer{Format: "yaml"}
+	require.ErrorContains(t, serializer.Init(), `invalid 'otlp_format' "yaml"`)
+}
+
+func TestSerializeTypes(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	metrics := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"host": "server01", "cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": 42.5},
+			now,
+			telegraf.Gauge,
+		),
+		metric.New(
+			"requests",
+			map[string]string{"host": "server01"},
+			map[string]interface{}{"counter": int64(1234)},
+			now,
+			telegraf.Counter,
+		),
+		metric.New(
+			"latency",
+			map[string]string{"host": "server02"},
+			map[string]interface{}{
+				"0.1":   5.0,
+				"0.5":   8.0,
+				"+Inf":  10.0,
+				"count": 10.0,
+				"sum":   3.5,
+			},
+			now,
+			telegraf.Histogram,
+		),
+	}
+
+	for _, format := range []string{"protobuf", "json"} {
+		t.Run(format, func(t *testing.T) {
+			serializer := &Serializer{
+				Format:             format,
+				ResourceTags:       []string{"host"},
+				ResourceAttributes: map[string]string{"service.name": "telegraf"},
+				Log:                testutil.Logger{},
+			}
+			require.NoError(t, serializer.Init())
+
+			buf, err := serializer.SerializeBatch(metrics)
+			require.NoError(t, err)
+			md := unmarshal(t, format, buf)
+
+			// Metrics are grouped by the resource tags
+			resources := md.ResourceMetrics()
+			require.Equal(t, 2, resources.Len())
+			require.Equal(t,
+				map[string]interface{}{"host": "server01", "service.name": "telegraf"},
+				resources.At(0).Resource().Attributes().AsRaw(),
+			)
+			require.Equal(t,
+				map[string]interface{}{"host": "server02", "service.name": "telegraf"},
+				resources.At(1).Resource().Attributes().AsRaw(),
+			)
+
+			converted := collect(md)
+			require.Len(t, converted, 3)
+
+			gauge := converted["cpu_usage_idle"]
+			require.Equal(t, pmetric.MetricTypeGauge, gauge.Type())
+			dp := gauge.Gauge().DataPoints().At(0)
+			require.InDelta(t, 42.5, dp.DoubleValue(), 1e-9)
+			require.Equal(t, map[string]interface{}{"cpu": "cpu0"}, dp.Attributes().AsRaw())
+			require.Equal(t, pcommon.NewTimestampFromTime(now), dp.Timestamp())
+
+			sum := converted["requests"]
+			require.Equal(t, pmetric.MetricTypeSum, sum.Type())
+			require.True(t, sum.Sum().IsMonotonic())
+			require.Equal(t, pmetric.AggregationTemporalityCumulative, sum.Sum().AggregationTemporality())
+			require.Equal(t, int64(1234), sum.Sum().DataPoints().At(0).IntValue())
+
+			histogram := converted["latency"]
+			require.Equal(t, pmetric.MetricTypeHistogram, histogram.Type())
+			hdp := histogram.Histogram().DataPoints().At(0)
+			require.Equal(t, uint64(10), hdp.Count())
+			require.InDelta(t, 3.5, hdp.Sum(), 1e-9)
+			require.Equal(t, []float64{0.1, 0.5}, hdp.ExplicitBounds().AsRaw())
+			require.Equal(t, []uint64{5, 3, 2}, hdp.BucketCounts().AsRaw())
+		})
+	}
+}
+
+func TestSerializeInvalid(t *testing.T) {
+	serializer := &Serializer{Log: testutil.Logger{}}
+	require.NoError(t, serializer.Init())
+
+	invalid := metric.New(
+		"requests",
+		map[string]string{},
+		map[string]interface{}{"counter": "many"},
+		time.Unix(0, 0),
+		telegraf.Counter,
+	)
+	_, err := serializer.Serialize(invalid)
+	require.ErrorContains(t, err, "unsupported counter value type string")
+
+	// Invalid metrics are skipped in batches
+	buf, err := serializer.SerializeBatch([]telegraf.Metric{invalid, testutil.TestMetric(1.0)})
+	require.NoError(t, err)
+	converted := collect(unmarshal(t, "protobuf", buf))
+	require.Len(t, converted, 1)
+	require.Contains(t, converted, "test1_value")
+}
+
+func unmarshal(t *testing.T, format string, buf []byte) pmetric.Metrics {
+	t.Helper()
+
+	request := pmetricotlp.NewExportRequest()
+	if format == "json" {
+		require.NoError(t, request.UnmarshalJSON(buf))
+	} else {
+		require.NoError(t, request.UnmarshalProto(buf))
+	}
+	return request.Metrics()
+}
+
+func collect(md pmetric.Metrics) map[string]pmetric.Metric {
+	metrics := make(map[string]pmetric.Metric)
+	for i := range md.ResourceMetrics().Len() {
+		scopes := md.ResourceMetrics().At(i).ScopeMetrics()
+		for j := range scopes.Len() {
+			ms := scopes.At(j).Metrics()
+			for k := range ms.Len() {
+				metrics[ms.At(k).Name()] = ms.At(k)
+			}
+		}
+	}
+	return metrics
+}
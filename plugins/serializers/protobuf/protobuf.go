@@ -0,0 +1,395 @@
+package protobuf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+type Serializer struct {
+	Files           []string          `toml:"protobuf_files"`
+	ImportPaths     []string          `toml:"protobuf_import_paths"`
+	DescriptorSet   string            `toml:"protobuf_descriptor_set"`
+	MessageType     string            `toml:"protobuf_type"`
+	NameField       string            `toml:"protobuf_name_field"`
+	TimestampField  string            `toml:"protobuf_timestamp_field"`
+	TimestampFormat string            `toml:"protobuf_timestamp_format"`
+	Tags            map[string]string `toml:"protobuf_tags"`
+	Fields          map[string]string `toml:"protobuf_fields"`
+	RemainingTags   string            `toml:"protobuf_remaining_tags"`
+	RemainingFields string            `toml:"protobuf_remaining_fields"`
+	Framing         string            `toml:"protobuf_framing"`
+	Log             telegraf.Logger   `toml:"-"`
+
+	msgType         protoreflect.MessageType
+	marshaller      proto.MarshalOptions
+	name            fieldPath
+	timestamp       fieldPath
+	tags            map[string]fieldPath
+	fields          map[string]fieldPath
+	remainingTags   fieldPath
+	remainingFields fieldPath
+}
+
+// fieldPath is the chain of fields leading from the message to the (nested)
+// target field
+type fieldPath []protoreflect.FieldDescriptor
+
+func (s *Serializer) Init() error {
+	switch s.TimestampFormat {
+	case "":
+		s.TimestampFormat = "unix_ns"
+	case "unix", "unix_ms", "unix_us", "unix_ns":
+	default:
+		return fmt.Errorf("invalid 'protobuf_timestamp_format' %q", s.TimestampFormat)
+	}
+	switch s.Framing {
+	case "":
+		s.Framing = "none"
+	case "none", "length-delimited":
+	default:
+		return fmt.Errorf("invalid 'protobuf_framing' %q", s.Framing)
+	}
+	if s.MessageType == "" {
+		return errors.New("'protobuf_type' required")
+	}
+
+	files, err := s.loadFiles()
+	if err != nil {
+		return err
+	}
+	descriptor, err := files.FindDescriptorByName(protoreflect.FullName(s.MessageType))
+	if err != nil {
+		return fmt.Errorf("looking up message type %q failed: %w", s.MessageType, err)
+	}
+	msgDesc, ok := descriptor.(protoreflect.MessageDescriptor)
+	if !ok {
+		return fmt.Errorf("%q is not a message descriptor (%T)", s.MessageType, descriptor)
+	}
+	s.msgType = dynamicpb.NewMessageType(msgDesc)
+	s.marshaller = proto.MarshalOptions{Deterministic: true}
+
+	// Resolve the mapping of the metric onto the message fields
+	if s.NameField != "" {
+		if s.name, err = resolveScalar(msgDesc, s.NameField); err != nil {
+			return fmt.Errorf("invalid 'protobuf_name_field': %w", err)
+		}
+	}
+	if s.TimestampField != "" {
+		if s.timestamp, err = resolve(msgDesc, s.TimestampField); err != nil {
+			return fmt.Errorf("invalid 'protobuf_timestamp_field': %w", err)
+		}
+		if fd := s.timestamp.target(); fd.Kind() == protoreflect.MessageKind && fd.Message().FullName() != "google.protobuf.Timestamp" {
+			return fmt.Errorf("invalid 'protobuf_timestamp_field': field %q must be a scalar or google.protobuf.Timestamp", s.TimestampField)
+		}
+	}
+	s.tags = make(map[string]fieldPath, len(s.Tags))
+	for key, path := range s.Tags {
+		if s.tags[key], err = resolveScalar(msgDesc, path); err != nil {
+			return fmt.Errorf("invalid mapping for tag %q: %w", key, err)
+		}
+	}
+	s.fields = make(map[string]fieldPath, len(s.Fields))
+	for key, path := range s.Fields {
+		if s.fields[key], err = resolveScalar(msgDesc, path); err != nil {
+			return fmt.Errorf("invalid mapping for field %q: %w", key, err)
+		}
+	}
+	if s.RemainingTags != "" {
+		if s.remainingTags, err = resolveMap(msgDesc, s.RemainingTags); err != nil {
+			return fmt.Errorf("invalid 'protobuf_remaining_tags': %w", err)
+		}
+	}
+	if s.RemainingFields != "" {
+		if s.remainingFields, err = resolveMap(msgDesc, s.RemainingFields); err != nil {
+			return fmt.Errorf("invalid 'protobuf_remaining_fields': %w", err)
+		}
+	}
+
+	return nil
+}
+
+// loadFiles reads the message definitions either from the descriptor set
+// or by compiling the given .proto files
+func (s *Serializer) loadFiles() (*protoregistry.Files, error) {
+	switch {
+	case s.DescriptorSet != "" && len(s.Files) > 0:
+		return nil, errors.New("'protobuf_files' and 'protobuf_descriptor_set' are mutually exclusive")
+	case s.DescriptorSet != "":
+		buf, err := os.ReadFile(s.DescriptorSet)
+		if err != nil {
+			return nil, fmt.Errorf("reading descriptor set failed: %w", err)
+		}
+		var set descriptorpb.FileDescriptorSet
+		if err := proto.Unmarshal(buf, &set); err != nil {
+			return nil, fmt.Errorf("decoding descriptor set failed: %w", err)
+		}
+		files, err := protodesc.NewFiles(&set)
+		if err != nil {
+			return nil, fmt.Errorf("loading descriptor set failed: %w", err)
+		}
+		return files, nil
+	case len(s.Files) > 0:
+		resolver := &protocompile.SourceResolver{ImportPaths: s.ImportPaths}
+		compiler := &protocompile.Compiler{
+			Resolver: protocompile.WithStandardImports(resolver),
+		}
+		compiled, err := compiler.Compile(context.Background(), s.Files...)
+		if err != nil {
+			return nil, fmt.Errorf("parsing protocol-buffer definition failed: %w", err)
+		}
+		var files protoregistry.Files
+		for _, f := range compiled {
+			if err := files.RegisterFile(f); err != nil {
+				return nil, fmt.Errorf("adding file %q to registry failed: %w", f.Path(), err)
+			}
+		}
+		return &files, nil
+	}
+	return nil, errors.New("either 'protobuf_files' or 'protobuf_descriptor_set' required")
+}
+
+// resolve the dot-separated path of (nested) message fields
+func resolve(desc protoreflect.MessageDescriptor, path string) (fieldPath, error) {
+	parts := strings.Split(path, ".")
+	p := make(fieldPath, 0, len(parts))
+	for i, part := range parts {
+		fd := desc.Fields().ByName(protoreflect.Name(part))
+		if fd == nil {
+			return nil, fmt.Errorf("message %q has no field %q", desc.FullName(), part)
+		}
+		p = append(p, fd)
+		if i == len(parts)-1 {
+			break
+		}
+		if fd.Kind() != protoreflect.MessageKind || fd.IsList() || fd.IsMap() {
+			return nil, fmt.Errorf("field %q is not a singular message", strings.Join(parts[:i+1], "."))
+		}
+		desc = fd.Message()
+	}
+
+	if fd := p.target(); fd.IsList() {
+		return nil, fmt.Errorf("repeated field %q not supported", path)
+	}
+	return p, nil
+}
+
+func resolveScalar(desc protoreflect.MessageDescriptor, path string) (fieldPath, error) {
+	p, err := resolve(desc, path)
+	if err != nil {
+		return nil, err
+	}
+	if fd := p.target(); fd.IsMap() || fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind {
+		return nil, fmt.Errorf("field %q is not a scalar", path)
+	}
+	return p, nil
+}
+
+func resolveMap(desc protoreflect.MessageDescriptor, path string) (fieldPath, error) {
+	p, err := resolve(desc, path)
+	if err != nil {
+		return nil, err
+	}
+	fd := p.target()
+	if !fd.IsMap() || fd.MapKey().Kind() != protoreflect.StringKind {
+		return nil, fmt.Errorf("field %q is not a map with string keys", path)
+	}
+	if k := fd.MapValue().Kind(); k == protoreflect.MessageKind || k == protoreflect.GroupKind {
+		return nil, fmt.Errorf("map %q must have scalar values", path)
+	}
+	return p, nil
+}
+
+func (p fieldPath) target() protoreflect.FieldDescriptor {
+	return p[len(p)-1]
+}
+
+// parent returns the message containing the target field, nested messages
+// are created as required
+func (p fieldPath) parent(msg protoreflect.Message) protoreflect.Message {
+	for _, fd := range p[:len(p)-1] {
+		msg = msg.Mutable(fd).Message()
+	}
+	return msg
+}
+
+func (p fieldPath) set(msg protoreflect.Message, value interface{}) error {
+	v, err := convert(p.target(), value)
+	if err != nil {
+		return err
+	}
+	p.parent(msg).Set(p.target(), v)
+	return nil
+}
+
+func (s *Serializer) Serialize(m telegraf.Metric) ([]byte, error) {
+	return s.serialize(nil, m, s.Framing == "length-delimited")
+}
+
+// SerializeBatch always uses length-delimited framing as concatenated
+// messages cannot be separated otherwise.
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	var buf []byte
+	for _, m := range metrics {
+		var err error
+		if buf, err = s.serialize(buf, m, true); err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+func (s *Serializer) serialize(buf []byte, m telegraf.Metric, delimited bool) ([]byte, error) {
+	msg, err := s.message(m)
+	if err != nil {
+		return nil, fmt.Errorf("converting metric %q failed: %w", m.Name(), err)
+	}
+	if delimited {
+		buf = protowire.AppendVarint(buf, uint64(s.marshaller.Size(msg)))
+	}
+	return s.marshaller.MarshalAppend(buf, msg)
+}
+
+func (s *Serializer) message(m telegraf.Metric) (proto.Message, error) {
+	msg := s.msgType.New()
+
+	if s.name != nil {
+		if err := s.name.set(msg, m.Name()); err != nil {
+			return nil, fmt.Errorf("name: %w", err)
+		}
+	}
+	if s.timestamp != nil {
+		if err := s.setTimestamp(msg, m.Time()); err != nil {
+			return nil, fmt.Errorf("timestamp: %w", err)
+		}
+	}
+
+	for _, tag := range m.TagList() {
+		if p, found := s.tags[tag.Key]; found {
+			if err := p.set(msg, tag.Value); err != nil {
+				return nil, fmt.Errorf("tag %q: %w", tag.Key, err)
+			}
+		} else if s.remainingTags != nil {
+			if err := setMapEntry(msg, s.remainingTags, tag.Key, tag.Value); err != nil {
+				return nil, fmt.Errorf("tag %q: %w", tag.Key, err)
+			}
+		}
+	}
+	for _, field := range m.FieldList() {
+		if p, found := s.fields[field.Key]; found {
+			if err := p.set(msg, field.Value); err != nil {
+				return nil, fmt.Errorf("field %q: %w", field.Key, err)
+			}
+		} else if s.remainingFields != nil {
+			if err := setMapEntry(msg, s.remainingFields, field.Key, field.Value); err != nil {
+				return nil, fmt.Errorf("field %q: %w", field.Key, err)
+			}
+		}
+	}
+
+	return msg.Interface(), nil
+}
+
+func (s *Serializer) setTimestamp(msg protoreflect.Message, t time.Time) error {
+	fd := s.timestamp.target()
+	if fd.Kind() == protoreflect.MessageKind {
+		parent := s.timestamp.parent(msg)
+		ts := parent.NewField(fd).Message()
+		fields := ts.Descriptor().Fields()
+		ts.Set(fields.ByName("seconds"), protoreflect.ValueOfInt64(t.Unix()))
+		ts.Set(fields.ByName("nanos"), protoreflect.ValueOfInt32(int32(t.Nanosecond())))
+		parent.Set(fd, protoreflect.ValueOfMessage(ts))
+		return nil
+	}
+	if fd.Kind() == protoreflect.StringKind {
+		return s.timestamp.set(msg, t.Format(time.RFC3339Nano))
+	}
+
+	switch s.TimestampFormat {
+	case "unix":
+		return s.timestamp.set(msg, t.Unix())
+	case "unix_ms":
+		return s.timestamp.set(msg, t.UnixMilli())
+	case "unix_us":
+		return s.timestamp.set(msg, t.UnixMicro())
+	}
+	return s.timestamp.set(msg, t.UnixNano())
+}
+
+func setMapEntry(msg protoreflect.Message, p fieldPath, key string, value interface{}) error {
+	fd := p.target()
+	v, err := convert(fd.MapValue(), value)
+	if err != nil {
+		return err
+	}
+	p.parent(msg).Mutable(fd).Map().Set(protoreflect.ValueOfString(key).MapKey(), v)
+	return nil
+}
+
+// convert the value to the type of the message field
+func convert(fd protoreflect.FieldDescriptor, value interface{}) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		v, err := internal.ToBool(value)
+		return protoreflect.ValueOfBool(v), err
+	case protoreflect.EnumKind:
+		if name, ok := value.(string); ok {
+			ev := fd.Enum().Values().ByName(protoreflect.Name(name))
+			if ev == nil {
+				return protoreflect.Value{}, fmt.Errorf("unknown value %q of enum %q", name, fd.Enum().FullName())
+			}
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		v, err := internal.ToInt32(value)
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(v)), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		v, err := internal.ToInt32(value)
+		return protoreflect.ValueOfInt32(v), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		v, err := internal.ToInt64(value)
+		return protoreflect.ValueOfInt64(v), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		v, err := internal.ToUint32(value)
+		return protoreflect.ValueOfUint32(v), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		v, err := internal.ToUint64(value)
+		return protoreflect.ValueOfUint64(v), err
+	case protoreflect.FloatKind:
+		v, err := internal.ToFloat32(value)
+		return protoreflect.ValueOfFloat32(v), err
+	case protoreflect.DoubleKind:
+		v, err := internal.ToFloat64(value)
+		return protoreflect.ValueOfFloat64(v), err
+	case protoreflect.StringKind:
+		v, err := internal.ToString(value)
+		return protoreflect.ValueOfString(v), err
+	case protoreflect.BytesKind:
+		v, err := internal.ToString(value)
+		return protoreflect.ValueOfBytes([]byte(v)), err
+	}
+	return protoreflect.Value{}, fmt.Errorf("unsupported field kind %q", fd.Kind())
+}
+
+func init() {
+	serializers.Add("protobuf",
+		func() telegraf.Serializer {
+			return &Serializer{}
+		},
+	)
+}
@@ -0,0 +1,279 @@
+package protobuf
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestInitFail(t *testing.T) {
+	files := []string{"testdata/metric.proto"}
+	tests := []struct {
+		name       string
+		serializer *Serializer
+		expected   string
+	}{
+		{
+			name:       "no type",
+			serializer: &Serializer{Files: files},
+			expected:   "'protobuf_type' required",
+		},
+		{
+			name:       "no definition",
+			serializer: &Serializer{MessageType: "telegraf.test.Metric"},
+			expected:   "either 'protobuf_files' or 'protobuf_descriptor_set' required",
+		},
+		{
+			name:       "files and descriptor set",
+			serializer: &Serializer{Files: files, DescriptorSet: "set.pb", MessageType: "telegraf.test.Metric"},
+			expected:   "mutually exclusive",
+		},
+		{
+			name:       "unknown type",
+			serializer: &Serializer{Files: files, MessageType: "telegraf.test.Foo"},
+			expected:   `looking up message type "telegraf.test.Foo" failed`,
+		},
+		{
+			name:       "invalid framing",
+			serializer: &Serializer{Files: files, MessageType: "telegraf.test.Metric", Framing: "fixed"},
+			expected:   `invalid 'protobuf_framing' "fixed"`,
+		},
+		{
+			name:       "unknown field",
+			serializer: &Serializer{Files: files, MessageType: "telegraf.test.Metric", NameField: "measurement"},
+			expected:   `message "telegraf.test.Metric" has no field "measurement"`,
+		},
+		{
+			name: "nested scalar",
+			serializer: &Serializer{
+				Files:       files,
+				MessageType: "telegraf.test.Metric",
+				Tags:        map[string]string{"host": "usage.host"},
+			},
+			expected: `invalid mapping for tag "host": field "usage" is not a singular message`,
+		},
+		{
+			name: "message as scalar",
+			serializer: &Serializer{
+				Files:       files,
+				MessageType: "telegraf.test.Metric",
+				Fields:      map[string]string{"value": "source"},
+			},
+			expected: `invalid mapping for field "value": field "source" is not a scalar`,
+		},
+		{
+			name: "repeated field",
+			serializer: &Serializer{
+				Files:       files,
+				MessageType: "telegraf.test.Metric",
+				Fields:      map[string]string{"value": "list"},
+			},
+			expected: `repeated field "list" not supported`,
+		},
+		{
+			name:       "no map",
+			serializer: &Serializer{Files: files, MessageType: "telegraf.test.Metric", RemainingTags: "name"},
+			expected:   `field "name" is not a map with string keys`,
+		},
+		{
+			name:       "invalid timestamp",
+			serializer: &Serializer{Files: files, MessageType: "telegraf.test.Metric", TimestampField: "source"},
+			expected:   `field "source" must be a scalar or google.protobuf.Timestamp`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.serializer.Init(), tt.expected)
+		})
+	}
+}
+
+func TestSerialize(t *testing.T) {
+	serializer := &Serializer{
+		Files:          []string{"testdata/metric.proto"},
+		MessageType:    "telegraf.test.Metric",
+		NameField:      "name",
+		TimestampField: "time",
+		Tags: map[string]string{
+			"host":   "source.host",
+			"region": "source.region",
+			"status": "status",
+		},
+		Fields: map[string]string{
+			"usage_idle": "usage",
+			"cores":      "count",
+		},
+		RemainingTags:   "labels",
+		RemainingFields: "values",
+	}
+	require.NoError(t, serializer.Init())
+
+	m := metric.New(
+		"cpu",
+		map[string]string{"host": "server01", "region": "eu", "status": "OK", "cpu": "cpu0"},
+		map[string]interface{}{
+			"usage_idle":   42.5,
+			"cores":        int64(8),
+			"usage_system": int64(3),
+			"usage_user":   1.5,
+		},
+		time.Unix(1700000000, 123456789),
+	)
+	buf, err := serializer.Serialize(m)
+	require.NoError(t, err)
+
+	expected := map[string]interface{}{
+		"name":   "cpu",
+		"time":   "2023-11-14T22:13:20.123456789Z",
+		"source": map[string]interface{}{"host": "server01", "region": "eu"},
+		"usage":  42.5,
+		"count":  8.0,
+		"status": "OK",
+		"labels": map[string]interface{}{"cpu": "cpu0"},
+		"values": map[string]interface{}{"usage_system": 3.0, "usage_user": 1.5},
+	}
+	require.Equal(t, expected, decodeJSON(t, serializer, buf))
+
+	// Values not matching the message field type are rejected
+	m.AddTag("status", "BROKEN")
+	_, err = serializer.Serialize(m)
+	require.ErrorContains(t, err, `tag "status": unknown value "BROKEN" of enum "telegraf.test.Status"`)
+}
+
+func TestSerializeBatch(t *testing.T) {
+	serializer := &Serializer{
+		DescriptorSet:   writeDescriptorSet(t, "testdata/metric.proto"),
+		MessageType:     "telegraf.test.Metric",
+		NameField:       "name",
+		TimestampField:  "time_ms",
+		TimestampFormat: "unix_ms",
+		Fields:          map[string]string{"value": "usage"},
+	}
+	require.NoError(t, serializer.Init())
+
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 1.0}, time.Unix(1, 0)),
+		metric.New("mem", map[string]string{}, map[string]interface{}{"value": int64(2)}, time.Unix(2, 0)),
+		metric.New("disk", map[string]string{}, map[string]interface{}{"other": 3.0}, time.Unix(3, 0)),
+	}
+	buf, err := serializer.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	expected := []map[string]interface{}{
+		{"name": "cpu", "timeMs": "1000", "usage": 1.0},
+		{"name": "mem", "timeMs": "2000", "usage": 2.0},
+		{"name": "disk", "timeMs": "3000"},
+	}
+	require.Equal(t, expected, decodeDelimited(t, serializer, buf))
+}
+
+func TestSerializeLengthDelimited(t *testing.T) {
+	serializer := &Serializer{
+		Files:       []string{"testdata/metric.proto"},
+		MessageType: "telegraf.test.Metric",
+		NameField:   "name",
+		Framing:     "length-delimited",
+	}
+	require.NoError(t, serializer.Init())
+
+	var buf []byte
+	for _, name := range []string{"cpu", "mem"} {
+		b, err := serializer.Serialize(testutil.TestMetric(1.0, name))
+		require.NoError(t, err)
+		buf = append(buf, b...)
+	}
+
+	expected := []map[string]interface{}{{"name": "cpu"}, {"name": "mem"}}
+	require.Equal(t, expected, decodeDelimited(t, serializer, buf))
+}
+
+func writeDescriptorSet(t *testing.T, files ...string) string {
+	t.Helper()
+
+	compiler := &protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{}),
+	}
+	compiled, err := compiler.Compile(context.Background(), files...)
+	require.NoError(t, err)
+
+	// The set must contain all dependencies
+	var set descriptorpb.FileDescriptorSet
+	seen := make(map[string]bool)
+	var add func(fd protoreflect.FileDescriptor)
+	add = func(fd protoreflect.FileDescriptor) {
+		if seen[fd.Path()] {
+			return
+		}
+		seen[fd.Path()] = true
+		for i := range fd.Imports().Len() {
+			add(fd.Imports().Get(i).FileDescriptor)
+		}
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(fd))
+	}
+	for _, fd := range compiled {
+		add(fd)
+	}
+
+	buf, err := proto.Marshal(&set)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "metric.pb")
+	require.NoError(t, os.WriteFile(path, buf, 0600))
+	return path
+}
+
+func decodeJSON(t *testing.T, serializer *Serializer, buf []byte) map[string]interface{} {
+	t.Helper()
+
+	msg := dynamicpb.NewMessage(serializer.msgType.Descriptor())
+	require.NoError(t, proto.Unmarshal(buf, msg))
+	return toJSON(t, msg)
+}
+
+func decodeDelimited(t *testing.T, serializer *Serializer, buf []byte) []map[string]interface{} {
+	t.Helper()
+
+	var messages []map[string]interface{}
+	r := bufio.NewReader(bytes.NewReader(buf))
+	for {
+		msg := dynamicpb.NewMessage(serializer.msgType.Descriptor())
+		err := protodelim.UnmarshalFrom(r, msg)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		messages = append(messages, toJSON(t, msg))
+	}
+	return messages
+}
+
+func toJSON(t *testing.T, msg proto.Message) map[string]interface{} {
+	t.Helper()
+
+	buf, err := protojson.Marshal(msg)
+	require.NoError(t, err)
+	var v map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf, &v))
+	return v
+}
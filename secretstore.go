@@ -18,6 +18,13 @@ type SecretStore interface {
 	GetResolver(key string) (ResolveFunc, error)
 }
 
+// SecretStoreNotifier is an optional interface for secret-stores able to
+// notify about changes of dynamic secrets, e.g. after rotating credentials.
+type SecretStoreNotifier interface {
+	// SetNotifier sets the function to call with the key of a changed secret.
+	SetNotifier(notify func(key string))
+}
+
 // ResolveFunc is a function to resolve the secret.
 // The returned flag indicates if the resolver is static (false), i.e.
 // the secret will not change over time, or dynamic (true) to handle
@@ -0,0 +1,80 @@
+package selfstat
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultDurationBuckets are the upper bounds, in seconds, of the buckets
+// used for duration histograms if not configured otherwise.
+var DefaultDurationBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Histogram counts observations in buckets with configurable upper bounds.
+// In contrast to timing stats, histograms keep all observations and are never
+// reset so the distribution can be evaluated over any time range.
+type Histogram struct {
+	measurement string
+	field       string
+	tags        map[string]string
+	buckets     []float64
+
+	counts []uint64
+	count  uint64
+	sum    float64
+	mu     sync.Mutex
+}
+
+// HistogramSample is the state of a histogram at the time of sampling. The
+// bucket counts are cumulative, i.e. each bucket contains the number of
+// observations less or equal to its upper bound.
+type HistogramSample struct {
+	Measurement string
+	Field       string
+	Tags        map[string]string
+	Buckets     map[float64]uint64
+	Count       uint64
+	Sum         float64
+}
+
+// Observe adds the given value to the histogram.
+func (h *Histogram) Observe(v float64) {
+	// The last bucket counts the observations above all upper bounds
+	idx, _ := slices.BinarySearch(h.buckets, v)
+
+	h.mu.Lock()
+	h.counts[idx]++
+	h.count++
+	h.sum += v
+	h.mu.Unlock()
+}
+
+// ObserveDuration adds the given duration in seconds to the histogram.
+func (h *Histogram) ObserveDuration(d time.Duration) {
+	h.Observe(d.Seconds())
+}
+
+func (h *Histogram) sample() HistogramSample {
+	s := HistogramSample{
+		Measurement: h.measurement,
+		Field:       h.field,
+		Tags:        make(map[string]string, len(h.tags)),
+		Buckets:     make(map[float64]uint64, len(h.buckets)),
+	}
+	for k, v := range h.tags {
+		s.Tags[k] = v
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var cumulative uint64
+	for i, upper := range h.buckets {
+		cumulative += h.counts[i]
+		s.Buckets[upper] = cumulative
+	}
+	s.Count = h.count
+	s.Sum = h.sum
+
+	return s
+}
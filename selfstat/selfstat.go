@@ -7,6 +7,7 @@ package selfstat
 
 import (
 	"hash/fnv"
+	"slices"
 	"sort"
 	"sync"
 	"time"
@@ -50,11 +51,22 @@ type Stat interface {
 // already been registered.
 //
 // The returned Stat can be incremented by the consumer of Register(), and it's
-// value will be returned as a telegraf metric when Metrics() is called.
+// value will be returned as a telegraf metric when Metrics() is called. Stats
+// holding a current value should be registered using RegisterGauge() instead,
+// stats set via Set() are treated as gauges in any case.
 func Register(measurement, field string, tags map[string]string) Stat {
 	return registry.register("internal_"+measurement, field, tags)
 }
 
+// RegisterGauge registers the given measurement, field, and tags in the
+// selfstat registry like Register(). In contrast to regular stats, which count
+// events, gauges hold a current value such as the size of a buffer. The
+// distinction is only relevant when exposing the stats outside of telegraf's
+// metric pipeline.
+func RegisterGauge(measurement, field string, tags map[string]string) Stat {
+	return registry.registerGauge("internal_"+measurement, field, tags)
+}
+
 // RegisterTiming registers the given measurement, field, and tags in the selfstat
 // registry. If given an identical measurement, it will return the stat that's
 // already been registered.
@@ -75,6 +87,108 @@ func RegisterTiming(measurement, field string, tags map[string]string) Stat {
 	return registry.registerTiming("internal_"+measurement, field, tags)
 }
 
+// RegisterHistogram registers a histogram with the given measurement, field,
+// and tags in the selfstat registry using the given bucket upper bounds. If
+// given an identical measurement, it will return the histogram that's already
+// been registered.
+//
+// Histograms are not returned by Metrics().
+func RegisterHistogram(measurement, field string, tags map[string]string, buckets []float64) *Histogram {
+	return registry.registerHistogram("internal_"+measurement, field, tags, buckets)
+}
+
+// RegisterDurationHistogram registers a histogram for durations, in seconds,
+// like RegisterHistogram() using the buckets set by SetDurationBuckets().
+func RegisterDurationHistogram(measurement, field string, tags map[string]string) *Histogram {
+	registry.mu.Lock()
+	buckets := registry.durationBuckets
+	registry.mu.Unlock()
+
+	return RegisterHistogram(measurement, field, tags, buckets)
+}
+
+// SetDurationBuckets sets the upper bounds, in seconds, of the buckets used
+// by duration histograms. The buckets only apply to histograms registered
+// afterwards. An empty list restores the DefaultDurationBuckets.
+func SetDurationBuckets(buckets []float64) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if len(buckets) == 0 {
+		registry.durationBuckets = DefaultDurationBuckets
+		return
+	}
+	registry.durationBuckets = slices.Clone(buckets)
+}
+
+// Kind is the type of a stat when exposing it outside of telegraf's metric
+// pipeline.
+type Kind int
+
+const (
+	// Counter stats accumulate events, e.g. the number of written metrics.
+	Counter Kind = iota
+	// Gauge stats hold a current value, e.g. the size of a buffer or an
+	// average timing.
+	Gauge
+)
+
+// Sample is the value of a stat at the time of sampling.
+type Sample struct {
+	Measurement string
+	Field       string
+	Tags        map[string]string
+	Kind        Kind
+	Value       int64
+}
+
+// sampler is implemented by all stats to read their value without side
+// effects, e.g. without resetting timings.
+type sampler interface {
+	sample() (Kind, int64)
+}
+
+// Samples returns the current value of all registered stats. In contrast to
+// Metrics(), sampling does not reset the timing stats so it can be used
+// independently of the inputs.internal plugin.
+func Samples() []Sample {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	samples := make([]Sample, 0, len(registry.stats))
+	for _, stats := range registry.stats {
+		for _, stat := range stats {
+			s, ok := stat.(sampler)
+			if !ok {
+				continue
+			}
+			kind, value := s.sample()
+			samples = append(samples, Sample{
+				Measurement: stat.Name(),
+				Field:       stat.FieldName(),
+				Tags:        stat.Tags(),
+				Kind:        kind,
+				Value:       value,
+			})
+		}
+	}
+	return samples
+}
+
+// Histograms returns the current state of all registered histograms.
+func Histograms() []HistogramSample {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	samples := make([]HistogramSample, 0, len(registry.histograms))
+	for _, histograms := range registry.histograms {
+		for _, h := range histograms {
+			samples = append(samples, h.sample())
+		}
+	}
+	return samples
+}
+
 // Metrics returns all registered stats as telegraf metrics.
 func Metrics() []telegraf.Metric {
 	registry.mu.Lock()
@@ -103,11 +217,22 @@ func Metrics() []telegraf.Metric {
 }
 
 type Registry struct {
-	stats map[uint64]map[string]Stat
-	mu    sync.Mutex
+	stats      map[uint64]map[string]Stat
+	histograms map[uint64]map[string]*Histogram
+	mu         sync.Mutex
+
+	durationBuckets []float64
 }
 
 func (r *Registry) register(measurement, field string, tags map[string]string) Stat {
+	return r.registerStat(measurement, field, tags, Counter)
+}
+
+func (r *Registry) registerGauge(measurement, field string, tags map[string]string) Stat {
+	return r.registerStat(measurement, field, tags, Gauge)
+}
+
+func (r *Registry) registerStat(measurement, field string, tags map[string]string, kind Kind) Stat {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -125,6 +250,7 @@ func (r *Registry) register(measurement, field string, tags map[string]string) S
 		measurement: measurement,
 		field:       field,
 		tags:        t,
+		kind:        kind,
 	}
 	registry.set(key, s)
 	return s
@@ -153,6 +279,38 @@ func (r *Registry) registerTiming(measurement, field string, tags map[string]str
 	return s
 }
 
+func (r *Registry) registerHistogram(measurement, field string, tags map[string]string, buckets []float64) *Histogram {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := key(measurement, tags)
+	if h, ok := r.histograms[key][field]; ok {
+		return h
+	}
+
+	t := make(map[string]string, len(tags))
+	for k, v := range tags {
+		t[k] = v
+	}
+
+	upper := slices.Clone(buckets)
+	slices.Sort(upper)
+	upper = slices.Compact(upper)
+
+	h := &Histogram{
+		measurement: measurement,
+		field:       field,
+		tags:        t,
+		buckets:     upper,
+		counts:      make([]uint64, len(upper)+1),
+	}
+	if _, ok := r.histograms[key]; !ok {
+		r.histograms[key] = make(map[string]*Histogram)
+	}
+	r.histograms[key][field] = h
+	return h
+}
+
 func (r *Registry) get(key uint64, field string) (Stat, bool) {
 	if _, ok := r.stats[key]; !ok {
 		return nil, false
@@ -192,6 +350,8 @@ func key(measurement string, tags map[string]string) uint64 {
 
 func init() {
 	registry = &Registry{
-		stats: make(map[uint64]map[string]Stat),
+		stats:           make(map[uint64]map[string]Stat),
+		histograms:      make(map[uint64]map[string]*Histogram),
+		durationBuckets: DefaultDurationBuckets,
 	}
 }
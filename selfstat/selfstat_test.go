@@ -3,6 +3,7 @@ package selfstat
 import (
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -19,7 +20,9 @@ var (
 // testCleanup resets the global registry for test cleanup & unlocks the test lock
 func testCleanup() {
 	registry = &Registry{
-		stats: make(map[uint64]map[string]Stat),
+		stats:           make(map[uint64]map[string]Stat),
+		histograms:      make(map[uint64]map[string]*Histogram),
+		durationBuckets: DefaultDurationBuckets,
 	}
 	testLock.Unlock()
 }
@@ -211,3 +214,98 @@ func TestRegisterCopy(t *testing.T) {
 	tags["new"] = "value"
 	require.NotEqual(t, tags, stat.Tags())
 }
+
+func TestSamples(t *testing.T) {
+	testLock.Lock()
+	defer testCleanup()
+
+	counter := Register("test", "counter", map[string]string{"test": "foo"})
+	gauge := RegisterGauge("test", "gauge", map[string]string{"test": "foo"})
+	timing := RegisterTiming("test", "timing", map[string]string{"test": "foo"})
+	counter.Incr(3)
+	gauge.Set(42)
+	timing.Incr(10)
+	timing.Incr(20)
+
+	samples := make([]Sample, 0, 3)
+	for _, s := range Samples() {
+		if s.Measurement == "internal_test" {
+			samples = append(samples, s)
+		}
+	}
+	require.ElementsMatch(t, []Sample{
+		{Measurement: "internal_test", Field: "counter", Tags: map[string]string{"test": "foo"}, Kind: Counter, Value: 3},
+		{Measurement: "internal_test", Field: "gauge", Tags: map[string]string{"test": "foo"}, Kind: Gauge, Value: 42},
+		{Measurement: "internal_test", Field: "timing", Tags: map[string]string{"test": "foo"}, Kind: Gauge, Value: 15},
+	}, samples)
+
+	// Sampling must not reset the timing average
+	require.Equal(t, int64(15), timing.Get())
+}
+
+func TestSamplesKind(t *testing.T) {
+	testLock.Lock()
+	defer testCleanup()
+
+	// The kind is determined by the registration only
+	counter := Register("test", "pending", map[string]string{"test": "foo"})
+	counter.Set(2)
+	gauge := RegisterGauge("test", "size", map[string]string{"test": "foo"})
+	gauge.Set(3)
+
+	samples := Samples()
+	require.Len(t, samples, 2)
+	kinds := make(map[string]Kind, len(samples))
+	for _, s := range samples {
+		kinds[s.Field] = s.Kind
+	}
+	require.Equal(t, map[string]Kind{"pending": Counter, "size": Gauge}, kinds)
+}
+
+func TestHistogram(t *testing.T) {
+	testLock.Lock()
+	defer testCleanup()
+
+	h := RegisterHistogram("test", "duration", map[string]string{"test": "foo"}, []float64{1, 0.1, 0.5, 1})
+	require.Same(t, h, RegisterHistogram("test", "duration", map[string]string{"test": "foo"}, nil))
+	for _, v := range []float64{0.05, 0.1, 0.3, 2} {
+		h.Observe(v)
+	}
+
+	// Histograms are not part of the metrics
+	for _, m := range Metrics() {
+		require.NotEqual(t, "internal_test", m.Name())
+	}
+
+	expected := []HistogramSample{
+		{
+			Measurement: "internal_test",
+			Field:       "duration",
+			Tags:        map[string]string{"test": "foo"},
+			Buckets:     map[float64]uint64{0.1: 2, 0.5: 3, 1: 3},
+			Count:       4,
+			Sum:         2.45,
+		},
+	}
+	actual := Histograms()
+	require.Len(t, actual, 1)
+	require.InDelta(t, expected[0].Sum, actual[0].Sum, 1e-9)
+	actual[0].Sum = expected[0].Sum
+	require.Equal(t, expected, actual)
+}
+
+func TestDurationBuckets(t *testing.T) {
+	testLock.Lock()
+	defer testCleanup()
+
+	SetDurationBuckets([]float64{2, 0.5})
+	h := RegisterDurationHistogram("test", "duration_seconds", map[string]string{"test": "foo"})
+	h.ObserveDuration(time.Second)
+	require.Equal(t, map[float64]uint64{0.5: 0, 2: 1}, h.sample().Buckets)
+
+	// Resetting the buckets must not affect existing histograms
+	SetDurationBuckets(nil)
+	require.Same(t, h, RegisterDurationHistogram("test", "duration_seconds", map[string]string{"test": "foo"}))
+	other := RegisterDurationHistogram("test", "other_seconds", map[string]string{"test": "foo"})
+	require.Len(t, other.sample().Buckets, len(DefaultDurationBuckets))
+}
@@ -9,6 +9,7 @@ type stat struct {
 	measurement string
 	field       string
 	tags        map[string]string
+	kind        Kind
 }
 
 func (s *stat) Incr(v int64) {
@@ -23,6 +24,10 @@ func (s *stat) Get() int64 {
 	return atomic.LoadInt64(&s.v)
 }
 
+func (s *stat) sample() (Kind, int64) {
+	return s.kind, s.Get()
+}
+
 func (s *stat) Name() string {
 	return s.measurement
 }
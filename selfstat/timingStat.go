@@ -39,6 +39,17 @@ func (s *timingStat) Get() int64 {
 	return avg
 }
 
+// sample returns the average of the timings received since the last call to
+// Get() without resetting it
+func (s *timingStat) sample() (Kind, int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.count > 0 {
+		return Gauge, s.v / s.count
+	}
+	return Gauge, s.prev
+}
+
 func (s *timingStat) Name() string {
 	return s.measurement
 }
@@ -0,0 +1,65 @@
+package testutil
+
+import (
+	"sync"
+
+	"github.com/influxdata/telegraf"
+)
+
+var _ telegraf.SecretStoreNotifier = &RotatingSecretStore{}
+
+// RotatingSecretStore is a secret-store providing dynamic secrets and
+// notifying about changes to test the rotation of secrets in plugins.
+type RotatingSecretStore struct {
+	secrets map[string]string
+	notify  func(key string)
+	sync.Mutex
+}
+
+// NewRotatingSecretStore returns a store with the given initial secrets
+func NewRotatingSecretStore(secrets map[string]string) *RotatingSecretStore {
+	return &RotatingSecretStore{secrets: secrets}
+}
+
+func (*RotatingSecretStore) SampleConfig() string {
+	return ""
+}
+
+func (*RotatingSecretStore) Init() error {
+	return nil
+}
+
+func (s *RotatingSecretStore) Get(key string) ([]byte, error) {
+	s.Lock()
+	defer s.Unlock()
+	return []byte(s.secrets[key]), nil
+}
+
+// Set changes the secret and notifies about the change
+func (s *RotatingSecretStore) Set(key, value string) error {
+	s.Lock()
+	s.secrets[key] = value
+	notify := s.notify
+	s.Unlock()
+	if notify != nil {
+		notify(key)
+	}
+	return nil
+}
+
+func (*RotatingSecretStore) List() ([]string, error) {
+	return nil, nil
+}
+
+func (s *RotatingSecretStore) GetResolver(key string) (telegraf.ResolveFunc, error) {
+	return func() ([]byte, bool, error) {
+		v, err := s.Get(key)
+		return v, true, err
+	}, nil
+}
+
+func (s *RotatingSecretStore) SetNotifier(notify func(key string)) {
+	s.Lock()
+	s.notify = notify
+	s.Unlock()
+}